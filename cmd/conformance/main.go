@@ -0,0 +1,63 @@
+// Command conformance runs the repository conformance checks against every
+// backend it can reach: the in-memory one, SQLite on a throwaway file, and
+// PostgreSQL when DATABASE_DRIVER=postgres. The PostgreSQL checks leave their
+// rows behind, so point DATABASE_URL at a scratch database.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"forum/config"
+	"forum/domain/repository"
+	"forum/infrastructure/database"
+	infra_repository "forum/infrastructure/repository"
+	"forum/infrastructure/repository/conformance"
+	"forum/infrastructure/repository/memory"
+	"forum/infrastructure/repository/postgres"
+)
+
+// report prints the results of one backend and tells whether all passed.
+func report(backend string, repos *repository.Repositories) bool {
+	passed := true
+	for _, result := range conformance.Run(context.Background(), repos) {
+		if result.Passed() {
+			fmt.Printf("PASS  %-8s %s\n", backend, result.Name)
+			continue
+		}
+		passed = false
+		fmt.Printf("FAIL  %-8s %s\n", backend, result.Name)
+		for _, e := range result.Errors {
+			fmt.Printf("      %s\n", e)
+		}
+	}
+	return passed
+}
+
+func main() {
+	cfg := config.Load()
+	passed := report("memory", memory.NewMemoryRepositories(cfg.MaxCommentDepth))
+
+	dir, err := os.MkdirTemp("", "conformance")
+	if err != nil {
+		log.Fatalf("Failed to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	sqliteDB := database.SetingUpDB(database.DriverSQLite, filepath.Join(dir, "forum.db"), cfg.DatabaseOptions())
+	passed = report("sqlite", infra_repository.NewSQLiteRepositories(sqliteDB, cfg.MaxCommentDepth)) && passed
+	sqliteDB.Close()
+
+	if cfg.DatabaseDriver == database.DriverPostgres {
+		postgresDB := database.SetingUpDB(database.DriverPostgres, cfg.DatabaseSource(), cfg.DatabaseOptions())
+		passed = report("postgres", postgres.NewPostgresRepositories(postgresDB, cfg.MaxCommentDepth)) && passed
+		postgresDB.Close()
+	}
+
+	if !passed {
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+}
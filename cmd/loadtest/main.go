@@ -0,0 +1,162 @@
+// Command loadtest runs concurrent feed readers and reaction writers against
+// a throwaway SQLite database and reports how many operations failed because
+// the database was locked.
+//
+//	loadtest [-readers 32] [-writers 8] [-duration 10s] [-posts 200] [-plain]
+//
+// The database is opened with the connection settings from the environment,
+// the same way the server opens it. -plain opens it the way the server did
+// before those settings existed: a bare file path and the default pool.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"forum/config"
+	"forum/domain/entity"
+	"forum/domain/repository"
+	"forum/infrastructure/database"
+	infra_repository "forum/infrastructure/repository"
+)
+
+// counters are the outcomes of one kind of operation.
+type counters struct {
+	ok, locked, failed atomic.Int64
+}
+
+func (c *counters) record(err error) {
+	switch {
+	case err == nil:
+		c.ok.Add(1)
+	case isLocked(err):
+		c.locked.Add(1)
+	default:
+		if c.failed.Add(1) == 1 {
+			log.Printf("First unexpected error: %v", err)
+		}
+	}
+}
+
+func (c *counters) String() string {
+	return fmt.Sprintf("%8d ok %8d locked %8d failed", c.ok.Load(), c.locked.Load(), c.failed.Load())
+}
+
+// isLocked reports whether err is SQLite refusing to wait for a lock. The
+// repositories wrap driver errors as text, so only the message is left.
+func isLocked(err error) bool {
+	return strings.Contains(err.Error(), "database is locked") ||
+		strings.Contains(err.Error(), "database table is locked")
+}
+
+func main() {
+	readers := flag.Int("readers", 32, "goroutines reading the feed and posts")
+	writers := flag.Int("writers", 8, "goroutines toggling reactions")
+	duration := flag.Duration("duration", 10*time.Second, "how long to run")
+	postCount := flag.Int("posts", 200, "posts to seed the database with")
+	plain := flag.Bool("plain", false, "open the database without connection settings")
+	flag.Parse()
+	if *writers < 1 || *postCount < 1 {
+		log.Fatal("The load test needs at least one writer and one post")
+	}
+
+	cfg := config.Load()
+	dir, err := os.MkdirTemp("", "loadtest")
+	if err != nil {
+		log.Fatalf("Failed to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "forum.db")
+
+	var db *sql.DB
+	if *plain {
+		db, err = sql.Open(database.DriverSQLite, path)
+		if err != nil {
+			log.Fatalf("Failed to open the database: %v", err)
+		}
+		database.RunMigrations(db, database.DriverSQLite)
+	} else {
+		db = database.SetingUpDB(database.DriverSQLite, path, cfg.DatabaseOptions())
+	}
+	defer db.Close()
+	repos := infra_repository.NewSQLiteRepositories(db, cfg.MaxCommentDepth)
+
+	ctx := context.Background()
+	users, posts := seed(ctx, repos, *writers*4, *postCount)
+	log.Printf("Seeded %d users and %d posts, running %d readers and %d writers for %s",
+		len(users), len(posts), *readers, *writers, *duration)
+
+	var reads, writes counters
+	deadline := time.Now().Add(*duration)
+	var wg sync.WaitGroup
+	for i := 0; i < *readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				if rand.IntN(2) == 0 {
+					_, err := repos.PostAggregate.GetFeedForUser(ctx, entity.PageRequest{Limit: cfg.PageSize})
+					reads.record(err)
+				} else {
+					_, err := repos.PostAggregate.GetPostWithAllDetails(ctx, posts[rand.IntN(len(posts))].ID)
+					reads.record(err)
+				}
+			}
+		}()
+	}
+	for i := 0; i < *writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				user, post := users[rand.IntN(len(users))], posts[rand.IntN(len(posts))]
+				_, err := repos.PostReaction.Toggle(ctx, user.ID, post.ID, rand.IntN(2) == 0)
+				writes.record(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	fmt.Printf("reads  %s\n", &reads)
+	fmt.Printf("writes %s\n", &writes)
+	if reads.locked.Load()+writes.locked.Load() > 0 {
+		db.Close()
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+}
+
+// seed creates the users that react and the posts they react to.
+func seed(ctx context.Context, repos *repository.Repositories, userCount, postCount int) ([]*entity.User, []*entity.Post) {
+	users := make([]*entity.User, userCount)
+	for i := range users {
+		name := fmt.Sprintf("load%d", i)
+		users[i] = &entity.User{UserName: name, Email: name + "@example.com", PasswordHash: "hash"}
+		if err := repos.User.Create(ctx, users[i]); err != nil {
+			log.Fatalf("Failed to create a user: %v", err)
+		}
+	}
+
+	posts := make([]*entity.Post, postCount)
+	for i := range posts {
+		posts[i] = &entity.Post{
+			Title:   fmt.Sprintf("Post %d", i),
+			Content: strings.Repeat("Some content to read. ", 20),
+			UserID:  users[i%len(users)].ID,
+		}
+		if err := repos.PostAggregate.CreatePostWithCategories(ctx, posts[i], nil); err != nil {
+			log.Fatalf("Failed to create a post: %v", err)
+		}
+	}
+	return users, posts
+}
@@ -0,0 +1,81 @@
+// Command migrate shows and changes which schema migrations have been
+// applied to the database.
+//
+//	migrate status    list every migration and when it was applied
+//	migrate up        apply all pending migrations
+//	migrate down [n]  roll back the last n migrations (default 1)
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"forum/config"
+	"forum/infrastructure/database"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: migrate status | up | down [n]")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	cfg := config.Load()
+	db, err := database.Open(cfg.DatabaseDriver, cfg.DatabaseSource(), cfg.DatabaseOptions())
+	if err != nil {
+		log.Fatal("Error opening database:", err)
+	}
+	defer db.Close()
+
+	switch os.Args[1] {
+	case "status":
+		states, err := database.MigrationStatus(db, cfg.DatabaseDriver)
+		if err != nil {
+			log.Fatalf("Failed to read migrations: %v", err)
+		}
+		for _, s := range states {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-30s %s\n", s.Version, s.Name, applied)
+		}
+
+	case "up":
+		applied, err := database.MigrateUp(db, cfg.DatabaseDriver)
+		for _, m := range applied {
+			fmt.Printf("Applied %04d %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Failed to migrate: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Already up to date")
+		}
+
+	case "down":
+		steps := 1
+		if len(os.Args) > 2 {
+			steps, err = strconv.Atoi(os.Args[2])
+			if err != nil || steps < 1 {
+				usage()
+			}
+		}
+		rolledBack, err := database.MigrateDown(db, cfg.DatabaseDriver, steps)
+		for _, m := range rolledBack {
+			fmt.Printf("Rolled back %04d %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Failed to roll back: %v", err)
+		}
+
+	default:
+		usage()
+	}
+}
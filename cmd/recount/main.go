@@ -0,0 +1,23 @@
+// Command recount recomputes the stored like, dislike and comment counters of
+// every post and comment, repairing any drift from the rows they count.
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"forum/config"
+	"forum/infrastructure/database"
+)
+
+func main() {
+	cfg := config.Load()
+	db := database.SetingUpDB(cfg.DatabaseDriver, cfg.DatabaseSource(), cfg.DatabaseOptions())
+	defer db.Close()
+
+	posts, comments, err := database.RecountCounters(db)
+	if err != nil {
+		log.Fatalf("Failed to recount: %v", err)
+	}
+	fmt.Printf("Repaired counters of %d posts and %d comments\n", posts, comments)
+}
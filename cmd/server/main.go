@@ -11,11 +11,11 @@ import (
 
 func main() {
 	cfg := config.Load()
-	db := database.SetingUpDB(cfg.DatabasePath)
+	db := database.SetingUpDB(cfg.DatabaseDriver, cfg.DatabaseSource(), cfg.DatabaseOptions())
 	defer db.Close()
 
 	fmt.Println("Server started on http://localhost:8080")
-	if err := server.MyServer(db).ListenAndServe(); err != nil {
+	if err := server.MyServer(db, cfg).ListenAndServe(); err != nil {
 		log.Fatalf("500 - Internal Server Error: %v", err)
 	}
 }
@@ -2,16 +2,131 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
+
+	"forum/infrastructure/database"
 )
+
 type Config struct {
-	DatabasePath  string
-	ServerPort    string
+	// DatabaseDriver is "sqlite3" or "postgres". SQLite opens DatabasePath,
+	// Postgres connects to DatabaseURL and needs a build with -tags postgres.
+	DatabaseDriver string
+	DatabasePath   string
+	DatabaseURL    string
+	// SQLite settings that every connection applies when it opens.
+	SQLiteBusyTimeout time.Duration
+	SQLiteJournalMode string
+	SQLiteSynchronous string
+	// Connection pool limits, for both drivers. Zero keeps the database/sql
+	// default.
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnMaxIdleTime time.Duration
+	ServerPort        string
+	MaxCommentDepth   int
+	UploadDir         string
+	MaxUploadSize     int64
+	MaxAttachments    int
+	ThumbnailSize     int
+	PageSize          int
+	// MarkdownCacheSize is how many rendered posts and comments are kept
+	// in memory.
+	MarkdownCacheSize int
+	// AvatarSize is the width and height of avatars, in pixels.
+	AvatarSize int
+	// UserNameCooldown is the least time between two name changes of a
+	// user, and UserNameReservation how long the name they gave up stays
+	// reserved for them.
+	UserNameCooldown    time.Duration
+	UserNameReservation time.Duration
+	// BaseURL is where users reach the forum; links in emails start with it.
+	BaseURL string
+	// MailDriver is "outbox", which writes each email to a file in
+	// MailOutboxDir, or "smtp", which sends it through SMTPHost.
+	MailDriver       string
+	MailFrom         string
+	MailOutboxDir    string
+	SMTPHost         string
+	SMTPPort         string
+	SMTPUsername     string
+	SMTPPassword     string
+	PasswordResetTTL time.Duration
+	// PasswordResetInterval spaces out the reset links sent to one email
+	// address, PasswordResetIPInterval those asked for from one IP address.
+	PasswordResetInterval   time.Duration
+	PasswordResetIPInterval time.Duration
+	// VerificationSecret signs the links that verify email addresses. When
+	// it is empty a random one is used, and links stop working when the
+	// server restarts.
+	VerificationSecret         string
+	VerificationTTL            time.Duration
+	VerificationResendInterval time.Duration
+	// RequestTimeout bounds how long a request may spend in the handlers and
+	// the database. Zero or less disables it.
+	RequestTimeout time.Duration
 }
 
 func Load() *Config {
 	return &Config{
-		DatabasePath: getEnv("DATABASE_PATH", "./forum.db"),
-		ServerPort:   getEnv("SERVER_PORT", ":8080"),
+		DatabaseDriver:             getEnv("DATABASE_DRIVER", "sqlite3"),
+		DatabasePath:               getEnv("DATABASE_PATH", "./forum.db"),
+		DatabaseURL:                getEnv("DATABASE_URL", ""),
+		SQLiteBusyTimeout:          getEnvDuration("SQLITE_BUSY_TIMEOUT", 5*time.Second),
+		SQLiteJournalMode:          getEnv("SQLITE_JOURNAL_MODE", "WAL"),
+		SQLiteSynchronous:          getEnv("SQLITE_SYNCHRONOUS", "NORMAL"),
+		DBMaxOpenConns:             getEnvInt("DB_MAX_OPEN_CONNS", 25),
+		DBMaxIdleConns:             getEnvInt("DB_MAX_IDLE_CONNS", 10),
+		DBConnMaxLifetime:          getEnvDuration("DB_CONN_MAX_LIFETIME", time.Hour),
+		DBConnMaxIdleTime:          getEnvDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+		ServerPort:                 getEnv("SERVER_PORT", ":8080"),
+		MaxCommentDepth:            getEnvInt("MAX_COMMENT_DEPTH", 5),
+		UploadDir:                  getEnv("UPLOAD_DIR", "./uploads"),
+		MaxUploadSize:              int64(getEnvInt("MAX_UPLOAD_SIZE", 5<<20)),
+		MaxAttachments:             getEnvInt("MAX_ATTACHMENTS", 4),
+		ThumbnailSize:              getEnvInt("THUMBNAIL_SIZE", 320),
+		PageSize:                   getEnvInt("PAGE_SIZE", 20),
+		MarkdownCacheSize:          getEnvInt("MARKDOWN_CACHE_SIZE", 10000),
+		AvatarSize:                 getEnvInt("AVATAR_SIZE", 256),
+		UserNameCooldown:           getEnvDuration("USERNAME_CHANGE_COOLDOWN", 30*24*time.Hour),
+		UserNameReservation:        getEnvDuration("USERNAME_RESERVATION", 90*24*time.Hour),
+		BaseURL:                    getEnv("BASE_URL", "http://localhost:8080"),
+		MailDriver:                 getEnv("MAIL_DRIVER", "outbox"),
+		MailFrom:                   getEnv("MAIL_FROM", "Forum <forum@localhost>"),
+		MailOutboxDir:              getEnv("MAIL_OUTBOX_DIR", "./outbox"),
+		SMTPHost:                   getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:                   getEnv("SMTP_PORT", "587"),
+		SMTPUsername:               getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:               getEnv("SMTP_PASSWORD", ""),
+		PasswordResetTTL:           getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		PasswordResetInterval:      getEnvDuration("PASSWORD_RESET_INTERVAL", 2*time.Minute),
+		PasswordResetIPInterval:    getEnvDuration("PASSWORD_RESET_IP_INTERVAL", 10*time.Second),
+		VerificationSecret:         getEnv("EMAIL_VERIFICATION_SECRET", ""),
+		VerificationTTL:            getEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		VerificationResendInterval: getEnvDuration("EMAIL_VERIFICATION_RESEND_INTERVAL", 2*time.Minute),
+		RequestTimeout:             getEnvDuration("REQUEST_TIMEOUT", 10*time.Second),
+	}
+}
+
+// DatabaseSource is what the configured driver is opened with.
+func (c *Config) DatabaseSource() string {
+	if c.DatabaseDriver == "postgres" {
+		return c.DatabaseURL
+	}
+	return c.DatabasePath
+}
+
+// DatabaseOptions are the connection settings the database is opened with.
+func (c *Config) DatabaseOptions() database.Options {
+	return database.Options{
+		BusyTimeout:     c.SQLiteBusyTimeout,
+		JournalMode:     c.SQLiteJournalMode,
+		Synchronous:     c.SQLiteSynchronous,
+		MaxOpenConns:    c.DBMaxOpenConns,
+		MaxIdleConns:    c.DBMaxIdleConns,
+		ConnMaxLifetime: c.DBConnMaxLifetime,
+		ConnMaxIdleTime: c.DBConnMaxIdleTime,
 	}
 }
 
@@ -21,3 +136,22 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+// getEnvDuration reads a duration such as "10s" or "500ms".
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
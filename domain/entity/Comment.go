@@ -7,9 +7,22 @@ import (
 )
 
 type Comment struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	Content   string    `json:"content" db:"content"`
-	UserID    uuid.UUID `json:"user_id" db:"user_id"`
-	PostID    uuid.UUID `json:"post_id" db:"post_id"`
-	CreatedAt time.Time `json:"createdat" db:"createdat"` // Note: schema shows 'createdat' not 'created_at'
+	ID        uuid.UUID  `json:"id" db:"id"`
+	Content   string     `json:"content" db:"content"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	PostID    uuid.UUID  `json:"post_id" db:"post_id"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty" db:"parent_id"`
+	CreatedAt time.Time  `json:"createdat" db:"createdat"` // Note: schema shows 'createdat' not 'created_at'
+	UpdatedAt *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// IsDeleted reports whether the comment has been replaced by a tombstone.
+func (c Comment) IsDeleted() bool {
+	return c.DeletedAt != nil
+}
+
+// IsEdited reports whether the comment changed after it was posted.
+func (c Comment) IsEdited() bool {
+	return c.UpdatedAt != nil
 }
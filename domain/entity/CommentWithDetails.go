@@ -1,8 +1,54 @@
 package entity
 
+import (
+	"html/template"
+
+	"github.com/google/uuid"
+)
+
 type CommentWithDetails struct {
 	Comment
-	Author       User `json:"author"`
-	LikeCount    int  `json:"like_count"`
-	DislikeCount int  `json:"dislike_count"`
+	Author       User          `json:"author"`
+	LikeCount    int           `json:"like_count"`
+	DislikeCount int           `json:"dislike_count"`
+	ReplyCount   int           `json:"reply_count"`
+	Depth        int           `json:"depth"`
+	CanReply     bool          `json:"can_reply"`
+	ContentHTML  template.HTML `json:"content_html"`
+}
+
+// ThreadComments orders comments so that every reply directly follows its
+// parent (depth first) and records how deep each one is nested. Siblings keep
+// their input order, so callers should pass comments sorted oldest first.
+// Comments whose parent is missing are treated as top-level comments.
+func ThreadComments(comments []CommentWithDetails, maxDepth int) []CommentWithDetails {
+	present := make(map[uuid.UUID]bool, len(comments))
+	for _, c := range comments {
+		present[c.ID] = true
+	}
+
+	var roots []int
+	children := make(map[uuid.UUID][]int)
+	for i, c := range comments {
+		if c.ParentID != nil && present[*c.ParentID] {
+			children[*c.ParentID] = append(children[*c.ParentID], i)
+		} else {
+			roots = append(roots, i)
+		}
+	}
+
+	threaded := make([]CommentWithDetails, 0, len(comments))
+	var walk func(indexes []int, depth int)
+	walk = func(indexes []int, depth int) {
+		for _, i := range indexes {
+			c := comments[i]
+			c.Depth = depth
+			c.CanReply = depth < maxDepth && !c.IsDeleted()
+			threaded = append(threaded, c)
+			walk(children[c.ID], depth+1)
+		}
+	}
+	walk(roots, 0)
+
+	return threaded
 }
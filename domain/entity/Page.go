@@ -0,0 +1,157 @@
+package entity
+
+import (
+	"encoding/base64"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// PostCursor marks a position in a list of posts ordered by (created_at, id),
+// or by (score, created_at, id) when the list is sorted by a score computed
+// at the time Now. Backward cursors ask for the posts before the position
+// instead of after it. Lists of comments use it the same way, with the time
+// and id of a comment.
+type PostCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+	Backward  bool
+	Score     float64
+	Now       time.Time
+}
+
+// Encode returns the cursor as an opaque string that is safe in a URL.
+func (c PostCursor) Encode() string {
+	direction := "a"
+	if c.Backward {
+		direction = "b"
+	}
+	raw := direction + "|" + strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + "|" + c.ID.String()
+	if !c.Now.IsZero() {
+		raw += "|" + strconv.FormatFloat(c.Score, 'g', -1, 64) + "|" + strconv.FormatInt(c.Now.UnixNano(), 10)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodePostCursor(s string) (*PostCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if (len(parts) != 3 && len(parts) != 5) || (parts[0] != "a" && parts[0] != "b") {
+		return nil, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(parts[2])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := &PostCursor{
+		CreatedAt: time.Unix(0, nanos).UTC(),
+		ID:        id,
+		Backward:  parts[0] == "b",
+	}
+
+	if len(parts) == 5 {
+		cursor.Score, err = strconv.ParseFloat(parts[3], 64)
+		if err != nil || math.IsNaN(cursor.Score) || math.IsInf(cursor.Score, 0) {
+			return nil, ErrInvalidCursor
+		}
+		now, err := strconv.ParseInt(parts[4], 10, 64)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		cursor.Now = time.Unix(0, now).UTC()
+	}
+
+	return cursor, nil
+}
+
+// PageRequest asks for Limit posts starting at Cursor, or the first page
+// when Cursor is nil.
+type PageRequest struct {
+	Cursor *PostCursor
+	Limit  int
+}
+
+// PostPage is one page of posts, newest first, with the cursors of the
+// neighbouring pages when they exist.
+type PostPage struct {
+	Posts []*PostWithDetails
+	Next  *PostCursor
+	Prev  *PostCursor
+}
+
+// DefaultPageSize is used when a PageRequest does not set a limit.
+const DefaultPageSize = 20
+
+// Size is the number of posts the page should hold.
+func (p PageRequest) Size() int {
+	if p.Limit <= 0 {
+		return DefaultPageSize
+	}
+	return p.Limit
+}
+
+// NewPostPage turns the posts read for a page, fetched with one extra post
+// to detect more pages and in reverse order for backward cursors, into a
+// page in display order. For score sorted lists, scores holds each post's
+// score as of now, which the cursors carry along.
+func NewPostPage(page PageRequest, posts []*PostWithDetails, scores map[uuid.UUID]float64, now time.Time) *PostPage {
+	posts, hasPrev, hasNext := trimPage(page, posts)
+	result := &PostPage{Posts: posts}
+	if len(posts) == 0 {
+		return result
+	}
+
+	cursorAt := func(post *PostWithDetails, backward bool) *PostCursor {
+		cursor := &PostCursor{CreatedAt: post.CreatedAt, ID: post.ID, Backward: backward}
+		if scores != nil {
+			cursor.Score = scores[post.ID]
+			cursor.Now = now
+		}
+		return cursor
+	}
+
+	if hasPrev {
+		result.Prev = cursorAt(posts[0], true)
+	}
+	if hasNext {
+		result.Next = cursorAt(posts[len(posts)-1], false)
+	}
+	return result
+}
+
+// trimPage drops the extra item read for a page and puts the items of a
+// backward page in display order. It tells whether there are pages before
+// and after this one.
+func trimPage[T any](page PageRequest, items []T) (trimmed []T, hasPrev, hasNext bool) {
+	limit := page.Size()
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+
+	backward := page.Cursor != nil && page.Cursor.Backward
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	hasPrev = (backward && hasMore) || (!backward && page.Cursor != nil)
+	hasNext = backward || hasMore
+	return items, hasPrev, hasNext
+}
@@ -7,8 +7,10 @@ import (
 )
 
 type Post struct {
-	ID        uuid.UUID `json:"id" db:"post_id"`
-	Content   string    `json:"content" db:"content"`
-	UserID    uuid.UUID `json:"user_id" db:"user_id"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ID        uuid.UUID  `json:"id" db:"post_id"`
+	Title     string     `json:"title" db:"title"`
+	Content   string     `json:"content" db:"content"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty" db:"updated_at"`
 }
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PostAttachment is an image uploaded with a post. FileName and ThumbnailName
+// are names inside the upload directory, never paths supplied by the user.
+type PostAttachment struct {
+	ID            uuid.UUID `json:"id" db:"id"`
+	PostID        uuid.UUID `json:"post_id" db:"post_id"`
+	FileName      string    `json:"file_name" db:"file_name"`
+	ThumbnailName string    `json:"thumbnail_name" db:"thumbnail_name"`
+	ContentType   string    `json:"content_type" db:"content_type"`
+	Size          int64     `json:"size" db:"size"`
+	Width         int       `json:"width" db:"width"`
+	Height        int       `json:"height" db:"height"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+func (a PostAttachment) URL() string {
+	return "/uploads/" + a.FileName
+}
+
+func (a PostAttachment) ThumbnailURL() string {
+	return "/uploads/" + a.ThumbnailName
+}
@@ -0,0 +1,17 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PostRevision is a snapshot of a post as it looked before one of its edits.
+type PostRevision struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	PostID    uuid.UUID `json:"post_id" db:"post_id"`
+	Version   int       `json:"version" db:"version"`
+	Title     string    `json:"title" db:"title"`
+	Content   string    `json:"content" db:"content"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
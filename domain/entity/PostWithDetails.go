@@ -1,11 +1,16 @@
 package entity
 
+import "html/template"
+
 type PostWithDetails struct {
 	Post
-	Author       User        `json:"author"`
-	Categories   []*Category `json:"categories,omitempty"`
-	Comments     []CommentWithDetails   `json:"comments,omitempty"`
-	LikeCount    int         `json:"like_count"`
-	DislikeCount int         `json:"dislike_count"`
+	Author       User                 `json:"author"`
+	Categories   []*Category          `json:"categories,omitempty"`
+	Comments     []CommentWithDetails `json:"comments,omitempty"`
+	Attachments  []*PostAttachment    `json:"attachments,omitempty"`
+	LikeCount    int                  `json:"like_count"`
+	DislikeCount int                  `json:"dislike_count"`
+	CommentCount int                  `json:"comment_count"`
+	IsEdited     bool                 `json:"is_edited"`
+	ContentHTML  template.HTML        `json:"content_html"`
 }
-
@@ -0,0 +1,11 @@
+package entity
+
+// ReactionState is what a reaction toggle leaves behind: the user's
+// reaction, if any, and the new counters of the post or comment.
+type ReactionState struct {
+	// Reaction is true for a like, false for a dislike and nil when the
+	// toggle removed the user's reaction.
+	Reaction     *bool `json:"reaction"`
+	LikeCount    int   `json:"like_count"`
+	DislikeCount int   `json:"dislike_count"`
+}
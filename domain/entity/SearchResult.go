@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Highlighted terms in SearchResult.Title and SearchResult.Snippet are wrapped
+// in these control characters, which never appear in escaped HTML.
+const (
+	HighlightStart = "\x02"
+	HighlightEnd   = "\x03"
+)
+
+var highlightStripper = strings.NewReplacer(HighlightStart, "", HighlightEnd, "")
+
+// StripHighlight removes the highlight markers from text, so that what a
+// member wrote can never pass for a highlighted match.
+func StripHighlight(text string) string {
+	return highlightStripper.Replace(text)
+}
+
+// SearchResult is a post matching a search, either through its own title and
+// content or through one of its comments.
+type SearchResult struct {
+	PostID      uuid.UUID     `json:"post_id"`
+	Title       string        `json:"title"`
+	Snippet     string        `json:"snippet"`
+	AuthorName  string        `json:"author_name"`
+	CreatedAt   time.Time     `json:"created_at"`
+	InComment   bool          `json:"in_comment"`
+	Rank        float64       `json:"rank"`
+	TitleHTML   template.HTML `json:"title_html"`
+	SnippetHTML template.HTML `json:"snippet_html"`
+}
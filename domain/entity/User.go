@@ -11,5 +11,38 @@ type User struct {
 	UserName     string    `json:"user_name" db:"user_name"`
 	Email        string    `json:"email" db:"email"`
 	PasswordHash string    `json:"-" db:"password_hash"` // Don't expose password hash
+	Role         string    `json:"role" db:"role"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	Bio          string    `json:"bio" db:"bio"`
+	// AvatarName is the avatar's name inside the upload directory, empty
+	// when the user has none.
+	AvatarName string `json:"avatar_name" db:"avatar_name"`
+	// UserNameChangedAt is when the user last changed their name, nil if
+	// they never did.
+	UserNameChangedAt *time.Time `json:"user_name_changed_at" db:"user_name_changed_at"`
+	// EmailVerifiedAt is when the user followed the link mailed to them,
+	// nil until they do.
+	EmailVerifiedAt *time.Time `json:"email_verified_at" db:"email_verified_at"`
+}
+
+// Roles a user can have. Moderators are promoted directly in the database:
+// UPDATE user SET role = 'moderator' WHERE user_name = '...';
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+)
+
+func (u *User) IsModerator() bool {
+	return u.Role == RoleModerator
+}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+func (u *User) AvatarURL() string {
+	if u.AvatarName == "" {
+		return ""
+	}
+	return "/uploads/" + u.AvatarName
 }
@@ -0,0 +1,15 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserNameChange records that the user UserID gave up the name UserName.
+type UserNameChange struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	UserName  string    `json:"user_name" db:"user_name"`
+	ChangedAt time.Time `json:"changed_at" db:"changed_at"`
+}
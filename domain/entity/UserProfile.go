@@ -0,0 +1,45 @@
+package entity
+
+// UserStats sums up what a user has contributed and how it was received.
+// Deleted comments are left out.
+type UserStats struct {
+	PostCount        int `json:"post_count"`
+	CommentCount     int `json:"comment_count"`
+	LikesReceived    int `json:"likes_received"`
+	DislikesReceived int `json:"dislikes_received"`
+}
+
+// UserComment is a comment listed on its author's profile, with the title of
+// the post it was written under.
+type UserComment struct {
+	CommentWithDetails
+	PostTitle string `json:"post_title"`
+}
+
+// CommentPage is one page of comments, newest first, with the cursors of the
+// neighbouring pages when they exist.
+type CommentPage struct {
+	Comments []*UserComment
+	Next     *PostCursor
+	Prev     *PostCursor
+}
+
+// NewCommentPage turns the comments read for a page, fetched the same way as
+// for NewPostPage, into a page in display order.
+func NewCommentPage(page PageRequest, comments []*UserComment) *CommentPage {
+	comments, hasPrev, hasNext := trimPage(page, comments)
+	result := &CommentPage{Comments: comments}
+	if len(comments) == 0 {
+		return result
+	}
+
+	if hasPrev {
+		first := comments[0]
+		result.Prev = &PostCursor{CreatedAt: first.CreatedAt, ID: first.ID, Backward: true}
+	}
+	if hasNext {
+		last := comments[len(comments)-1]
+		result.Next = &PostCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+	return result
+}
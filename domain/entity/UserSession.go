@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,6 +11,51 @@ type UserSession struct {
 	ID           uuid.UUID `json:"id" db:"id"`
 	UserID       uuid.UUID `json:"user_id" db:"user_id"`
 	SessionToken string    `json:"-" db:"session_token"` // Don't expose session token
+	UserAgent    string    `json:"user_agent" db:"user_agent"`
+	IPAddress    string    `json:"ip_address" db:"ip_address"`
+	LastSeenAt   time.Time `json:"last_seen_at" db:"last_seen_at"`
 	ExpiresAt    time.Time `json:"expires_at" db:"expires_at"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 }
+
+// browsers and systems are matched against the user agent in order, so
+// that browsers built on another one are found before it.
+var (
+	browsers = []struct{ token, name string }{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"}, {"Safari/", "Safari"}, {"curl/", "curl"},
+	}
+	systems = []struct{ token, name string }{
+		{"Android", "Android"}, {"iPhone", "iOS"}, {"iPad", "iPadOS"}, {"CrOS", "ChromeOS"},
+		{"Windows", "Windows"}, {"Mac OS X", "macOS"}, {"Linux", "Linux"},
+	}
+)
+
+// DeviceName describes the browser and system the session was opened from,
+// like "Firefox on Linux".
+func (s *UserSession) DeviceName() string {
+	var browser, system string
+	for _, b := range browsers {
+		if strings.Contains(s.UserAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, sys := range systems {
+		if strings.Contains(s.UserAgent, sys.token) {
+			system = sys.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return "Browser on " + system
+	default:
+		return "Unknown device"
+	}
+}
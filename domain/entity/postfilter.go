@@ -1,10 +1,49 @@
 package entity
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type PostFilter struct {
 	CategoryIDs []uuid.UUID
 	AuthorID    *uuid.UUID
 	MyPosts     bool
 	LikedPosts  bool
+	Query       string
+	Sort        SortMode
+	// Window limits SortTop to posts created within it; zero means all time.
+	Window time.Duration
+	// Now is the time that post ages are measured from. It is kept in page
+	// cursors so hot scores do not shift between pages.
+	Now time.Time
+}
+
+type SortMode string
+
+const (
+	SortNew           SortMode = "new"
+	SortTop           SortMode = "top"
+	SortHot           SortMode = "hot"
+	SortControversial SortMode = "controversial"
+)
+
+func ParseSortMode(s string) (SortMode, bool) {
+	switch mode := SortMode(s); mode {
+	case SortNew, SortTop, SortHot, SortControversial:
+		return mode, true
+	case "":
+		return SortNew, true
+	}
+	return "", false
+}
+
+// TopWindows are the time windows that SortTop can be limited to.
+var TopWindows = map[string]time.Duration{
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+	"year":  365 * 24 * time.Hour,
+	"all":   0,
 }
@@ -36,3 +36,13 @@ var (
 	ErrMissingRegistrationFields = errors.New("name, email, and password are required")
 	ErrRegistrationFailed        = errors.New("registration process failed")
 )
+
+// Password Reset Errors
+var (
+	ErrResetTokenNotFound = errors.New("password reset link is invalid, expired or already used")
+)
+
+// Session Errors
+var (
+	ErrSessionNotFound = errors.New("session not found")
+)
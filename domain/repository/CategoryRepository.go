@@ -1,15 +1,17 @@
 package repository
 
 import (
+	"context"
+
 	"forum/domain/entity"
 
 	"github.com/google/uuid"
 )
 
 type CategoryRepository interface {
-	Create(category *entity.Category) error
-	GetByID(categoryID *uuid.UUID) (*entity.Category, error)
-	GetByName(name string) (*entity.Category, error)
-	GetAll() ([]*entity.Category, error)
-	CheckNameExists(name string) (bool, error)
+	Create(ctx context.Context, category *entity.Category) error
+	GetByID(ctx context.Context, categoryID *uuid.UUID) (*entity.Category, error)
+	GetByName(ctx context.Context, name string) (*entity.Category, error)
+	GetAll(ctx context.Context) ([]*entity.Category, error)
+	CheckNameExists(ctx context.Context, name string) (bool, error)
 }
@@ -1,15 +1,20 @@
 package repository
 
 import (
+	"context"
+
 	"forum/domain/entity"
 
 	"github.com/google/uuid"
 )
 
 type CommentReactionRepository interface {
-	Create(reaction *entity.CommentReaction) error
-	GetByUserAndComment(userID, commentID uuid.UUID) (*entity.CommentReaction, error)
-	Update(reaction *entity.CommentReaction) error
-	Delete(reactionID uuid.UUID) error
-	 GetReactionCountsByCommentID(commentID uuid.UUID) (likes int, dislikes int, err error)
+	Create(ctx context.Context, reaction *entity.CommentReaction) error
+	GetByUserAndComment(ctx context.Context, userID, commentID uuid.UUID) (*entity.CommentReaction, error)
+	Update(ctx context.Context, reaction *entity.CommentReaction) error
+	Delete(ctx context.Context, reactionID uuid.UUID) error
+	 GetReactionCountsByCommentID(ctx context.Context, commentID uuid.UUID) (likes int, dislikes int, err error)
+	// Toggle removes the user's reaction when it equals like and sets it to
+	// like otherwise, in one atomic step.
+	Toggle(ctx context.Context, userID, commentID uuid.UUID, like bool) (*entity.ReactionState, error)
 }
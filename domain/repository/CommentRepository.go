@@ -1,18 +1,26 @@
 package repository
 
 import (
+	"context"
+
 	"forum/domain/entity"
 
 	"github.com/google/uuid"
 )
 
 type CommentRepository interface {
-	Create(comment *entity.Comment) error
-	GetByID(commentID uuid.UUID) (*entity.Comment, error)
-	GetByPostID(postID uuid.UUID) ([]entity.Comment, error)
-	Delete(commentID uuid.UUID) error
-	GetCountByPostID(postID uuid.UUID) (int, error)
-	GetCountByUserID(userID uuid.UUID) (int, error)
-	GetWithDetails(commentID uuid.UUID) (*entity.CommentWithDetails, error)
-	GetByPostIDWithDetails(postID uuid.UUID) ([]entity.CommentWithDetails, error)
+	Create(ctx context.Context, comment *entity.Comment) error
+	GetByID(ctx context.Context, commentID uuid.UUID) (*entity.Comment, error)
+	GetByPostID(ctx context.Context, postID uuid.UUID) ([]entity.Comment, error)
+	Update(ctx context.Context, comment *entity.Comment) error
+	Delete(ctx context.Context, commentID uuid.UUID) error
+	SoftDelete(ctx context.Context, commentID uuid.UUID) error
+	GetCountByPostID(ctx context.Context, postID uuid.UUID) (int, error)
+	GetCountByUserID(ctx context.Context, userID uuid.UUID) (int, error)
+	GetWithDetails(ctx context.Context, commentID uuid.UUID) (*entity.CommentWithDetails, error)
+	GetByPostIDWithDetails(ctx context.Context, postID uuid.UUID) ([]entity.CommentWithDetails, error)
+	GetByPostIDsWithDetails(ctx context.Context, postIDs []uuid.UUID) (map[uuid.UUID][]entity.CommentWithDetails, error)
+	// GetByUserIDWithDetails returns one page of a user's comments, newest
+	// first, leaving out deleted ones.
+	GetByUserIDWithDetails(ctx context.Context, userID uuid.UUID, page entity.PageRequest) (*entity.CommentPage, error)
 }
@@ -0,0 +1,7 @@
+package repository
+
+// FileStorage stores uploaded files under flat, server-generated names.
+type FileStorage interface {
+	Save(name string, data []byte) error
+	Delete(name string) error
+}
@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+
+	"forum/domain/entity"
+)
+
+// Mailer delivers email to users.
+type Mailer interface {
+	Send(ctx context.Context, email *entity.Email) error
+}
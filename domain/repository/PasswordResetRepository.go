@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"forum/domain/entity"
+
+	"github.com/google/uuid"
+)
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, token *entity.PasswordResetToken) error
+	// GetByTokenHash returns custom_errors.ErrResetTokenNotFound when no
+	// token has that hash.
+	GetByTokenHash(ctx context.Context, tokenHash string) (*entity.PasswordResetToken, error)
+	// MarkUsed records that the token was used at usedAt. It returns
+	// custom_errors.ErrResetTokenNotFound unless the token exists and was
+	// not used yet, so that only one of two concurrent resets succeeds.
+	MarkUsed(ctx context.Context, tokenID uuid.UUID, usedAt time.Time) error
+	// DeleteUnusedByUserID invalidates every token of the user that was
+	// not used.
+	DeleteUnusedByUserID(ctx context.Context, userID uuid.UUID) error
+}
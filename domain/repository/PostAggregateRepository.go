@@ -1,15 +1,19 @@
 package repository
 
 import (
+	"context"
+
 	"forum/domain/entity"
 
 	"github.com/google/uuid"
 )
 
 type PostAggregateRepository interface {
-	CreatePostWithCategories(post *entity.Post, categoryIDs []*uuid.UUID) error
-	GetPostWithAllDetails(postID uuid.UUID) (*entity.PostWithDetails, error)
-	GetFeedForUser() ([]*entity.PostWithDetails, error)
-	GetPostsWithDetailsByUser(userID uuid.UUID) ([]*entity.PostWithDetails, error)
-	GetFilteredPostsWithDetails(filter entity.PostFilter) ([]*entity.PostWithDetails, error)
+	CreatePostWithCategories(ctx context.Context, post *entity.Post, categoryIDs []*uuid.UUID) error
+	GetPostWithAllDetails(ctx context.Context, postID uuid.UUID) (*entity.PostWithDetails, error)
+	GetFeedForUser(ctx context.Context, page entity.PageRequest) (*entity.PostPage, error)
+	GetPostsWithDetailsByUser(ctx context.Context, userID uuid.UUID) ([]*entity.PostWithDetails, error)
+	GetFilteredPostsWithDetails(ctx context.Context, filter entity.PostFilter, page entity.PageRequest) (*entity.PostPage, error)
+	UpdatePostWithRevision(ctx context.Context, post *entity.Post) error
+	DeletePostWithDependencies(ctx context.Context, postID uuid.UUID) error
 }
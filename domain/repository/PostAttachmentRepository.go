@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"forum/domain/entity"
+
+	"github.com/google/uuid"
+)
+
+type PostAttachmentRepository interface {
+	Create(ctx context.Context, attachment *entity.PostAttachment) error
+	GetByPostID(ctx context.Context, postID uuid.UUID) ([]*entity.PostAttachment, error)
+	GetByPostIDs(ctx context.Context, postIDs []uuid.UUID) (map[uuid.UUID][]*entity.PostAttachment, error)
+}
@@ -1,18 +1,21 @@
 package repository
 
 import (
+	"context"
+
 	"forum/domain/entity"
 
 	"github.com/google/uuid"
 )
 
 type PostCategoryRepository interface {
-	Create(postCategory *entity.PostCategory) error
-	Delete(postID, categoryID uuid.UUID) error
-	DeleteByPostID(postID uuid.UUID) error
-	DeleteByCategoryID(categoryID uuid.UUID) error
-	GetCategoriesByPostID(postID uuid.UUID) ([]*entity.Category, error)
-	GetPostsByCategoryID(categoryID uuid.UUID) ([]*entity.Post, error)
-	CheckAssociationExists(postID, categoryID uuid.UUID) (bool, error)
-	GetAllAssociations() ([]*entity.PostCategory, error)
+	Create(ctx context.Context, postCategory *entity.PostCategory) error
+	Delete(ctx context.Context, postID, categoryID uuid.UUID) error
+	DeleteByPostID(ctx context.Context, postID uuid.UUID) error
+	DeleteByCategoryID(ctx context.Context, categoryID uuid.UUID) error
+	GetCategoriesByPostID(ctx context.Context, postID uuid.UUID) ([]*entity.Category, error)
+	GetCategoriesByPostIDs(ctx context.Context, postIDs []uuid.UUID) (map[uuid.UUID][]*entity.Category, error)
+	GetPostsByCategoryID(ctx context.Context, categoryID uuid.UUID) ([]*entity.Post, error)
+	CheckAssociationExists(ctx context.Context, postID, categoryID uuid.UUID) (bool, error)
+	GetAllAssociations(ctx context.Context) ([]*entity.PostCategory, error)
 }
@@ -1,22 +1,27 @@
 package repository
 
 import (
+	"context"
+
 	"forum/domain/entity"
 
 	"github.com/google/uuid"
 )
 
 type PostReactionRepository interface {
-	Create(reaction *entity.PostReaction) error
-	GetByID(reactionID uuid.UUID) (*entity.PostReaction, error)
-	GetByUserAndPost(userID, postID uuid.UUID) (*entity.PostReaction, error)
-	GetByPostID(postID uuid.UUID) ([]*entity.PostReaction, error)
-	GetByUserID(userID uuid.UUID) ([]*entity.PostReaction, error)
-	Update(reaction *entity.PostReaction) error
-	Delete(reactionID uuid.UUID) error
-	DeleteByUserAndPost(userID, postID uuid.UUID) error
-	GetLikeCountByPostID(postID uuid.UUID) (int, error)
-	GetDislikeCountByPostID(postID uuid.UUID) (int, error)
-	GetReactionCountsByPostID(postID uuid.UUID) (likes int, dislikes int, err error)
-	HasUserReacted(userID, postID uuid.UUID) (bool, *bool, error) // exists, reaction_value, error
+	Create(ctx context.Context, reaction *entity.PostReaction) error
+	GetByID(ctx context.Context, reactionID uuid.UUID) (*entity.PostReaction, error)
+	GetByUserAndPost(ctx context.Context, userID, postID uuid.UUID) (*entity.PostReaction, error)
+	GetByPostID(ctx context.Context, postID uuid.UUID) ([]*entity.PostReaction, error)
+	GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.PostReaction, error)
+	Update(ctx context.Context, reaction *entity.PostReaction) error
+	Delete(ctx context.Context, reactionID uuid.UUID) error
+	DeleteByUserAndPost(ctx context.Context, userID, postID uuid.UUID) error
+	GetLikeCountByPostID(ctx context.Context, postID uuid.UUID) (int, error)
+	GetDislikeCountByPostID(ctx context.Context, postID uuid.UUID) (int, error)
+	GetReactionCountsByPostID(ctx context.Context, postID uuid.UUID) (likes int, dislikes int, err error)
+	HasUserReacted(ctx context.Context, userID, postID uuid.UUID) (bool, *bool, error) // exists, reaction_value, error
+	// Toggle removes the user's reaction when it equals like and sets it to
+	// like otherwise, in one atomic step.
+	Toggle(ctx context.Context, userID, postID uuid.UUID, like bool) (*entity.ReactionState, error)
 }
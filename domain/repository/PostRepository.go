@@ -1,19 +1,21 @@
 package repository
 
 import (
+	"context"
+
 	"forum/domain/entity"
 
 	"github.com/google/uuid"
 )
 
 type PostRepository interface {
-	Create(post *entity.Post) error
-	GetByID(postID uuid.UUID) (*entity.Post, error)
-	GetbyuserId(Userid uuid.UUID) ([]*entity.Post, error)
-	GetAll() ([]*entity.Post, error)
-	GetByCategory(categoryID uuid.UUID) ([]*entity.Post, error)
-	Update(post *entity.Post) error
-	Delete(postID uuid.UUID) error
-	GetWithDetails(postID uuid.UUID) (*entity.PostWithDetails, error)
-	GetFiltered(filter entity.PostFilter) ([]*entity.Post, error)
+	Create(ctx context.Context, post *entity.Post) error
+	GetByID(ctx context.Context, postID uuid.UUID) (*entity.Post, error)
+	GetbyuserId(ctx context.Context, Userid uuid.UUID) ([]*entity.Post, error)
+	GetAll(ctx context.Context) ([]*entity.Post, error)
+	GetByCategory(ctx context.Context, categoryID uuid.UUID) ([]*entity.Post, error)
+	Update(ctx context.Context, post *entity.Post) error
+	Delete(ctx context.Context, postID uuid.UUID) error
+	GetWithDetails(ctx context.Context, postID uuid.UUID) (*entity.PostWithDetails, error)
+	GetFiltered(ctx context.Context, filter entity.PostFilter) ([]*entity.Post, error)
 }
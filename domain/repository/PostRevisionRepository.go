@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+
+	"forum/domain/entity"
+
+	"github.com/google/uuid"
+)
+
+type PostRevisionRepository interface {
+	GetByPostID(ctx context.Context, postID uuid.UUID) ([]*entity.PostRevision, error)
+}
@@ -0,0 +1,20 @@
+package repository
+
+// Repositories is one complete set of repository implementations sharing
+// the same storage, as built by each backend.
+type Repositories struct {
+	User            UserRepository
+	Session         UserSessionRepository
+	PasswordReset   PasswordResetRepository
+	Post            PostRepository
+	PostCategory    PostCategoryRepository
+	Category        CategoryRepository
+	PostReaction    PostReactionRepository
+	PostRevision    PostRevisionRepository
+	Attachment      PostAttachmentRepository
+	Search          SearchRepository
+	CommentReaction CommentReactionRepository
+	Comment         CommentRepository
+	PostAggregate   PostAggregateRepository
+	Tx              TxManager
+}
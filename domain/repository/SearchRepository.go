@@ -0,0 +1,11 @@
+package repository
+
+import (
+	"context"
+
+	"forum/domain/entity"
+)
+
+type SearchRepository interface {
+	Search(ctx context.Context, query string, limit int) ([]*entity.SearchResult, error)
+}
@@ -0,0 +1,13 @@
+package repository
+
+import "context"
+
+// TxManager runs several repository calls as one unit of work. Repositories
+// called with the context passed to fn take part in the transaction; it is
+// committed when fn returns nil and rolled back otherwise. fn may be run more
+// than once when the database asks for a retry, so it must not have side
+// effects outside the repositories. Calls to WithinTx inside fn join the
+// transaction that is already running.
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -1,10 +1,12 @@
 package repository
 
 import (
+	"context"
+
 	"forum/domain/entity"
 )
 
 type UserAggregateRepository interface {
-	CreateUserSession(user *entity.User) (*entity.UserSession, error)
-	AuthenticateUser(email, password string) (*entity.User, *entity.UserSession, error)
+	CreateUserSession(ctx context.Context, user *entity.User) (*entity.UserSession, error)
+	AuthenticateUser(ctx context.Context, email, password string) (*entity.User, *entity.UserSession, error)
 }
@@ -1,14 +1,30 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"forum/domain/entity"
 
 	"github.com/google/uuid"
 )
 
 type UserRepository interface {
-	Create(user *entity.User) error
-	GetByID(userID uuid.UUID) (*entity.User, error)
-	GetByEmail(email string) (*entity.User, error)
-	GetByUserName(userName string) (*entity.User, error)
+	Create(ctx context.Context, user *entity.User) error
+	GetByID(ctx context.Context, userID uuid.UUID) (*entity.User, error)
+	GetByEmail(ctx context.Context, email string) (*entity.User, error)
+	GetByUserName(ctx context.Context, userName string) (*entity.User, error)
+	GetStats(ctx context.Context, userID uuid.UUID) (*entity.UserStats, error)
+	UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error
+	// MarkEmailVerified records that the user verified their email at
+	// verifiedAt. A user verified before keeps the earlier time.
+	MarkEmailVerified(ctx context.Context, userID uuid.UUID, verifiedAt time.Time) error
+	// UpdateProfile saves the bio and the avatar name of user.
+	UpdateProfile(ctx context.Context, user *entity.User) error
+	// ChangeUserName renames the user and records the name they had, both
+	// or neither.
+	ChangeUserName(ctx context.Context, userID uuid.UUID, newName string, changedAt time.Time) error
+	// GetLastUserNameChange returns the latest time anyone gave up
+	// userName, or custom_errors.ErrUserNotFound if no one ever did.
+	GetLastUserNameChange(ctx context.Context, userName string) (*entity.UserNameChange, error)
 }
@@ -1,16 +1,30 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"forum/domain/entity"
 
 	"github.com/google/uuid"
 )
 
 type UserSessionRepository interface {
-	Create(session *entity.UserSession) error
-	GetByToken(token string) (*entity.UserSession, error)
-	GetByUserID(userID uuid.UUID) (*entity.UserSession, error)
-	Update(session *entity.UserSession) error
-	Delete(sessionID uuid.UUID) error
-	DeleteAllUserSessions(userID uuid.UUID) error
+	Create(ctx context.Context, session *entity.UserSession) error
+	GetByToken(ctx context.Context, token string) (*entity.UserSession, error)
+	GetByUserID(ctx context.Context, userID uuid.UUID) (*entity.UserSession, error)
+	// ListByUserID returns the sessions of a user that have not expired at
+	// now, the most recently used first.
+	ListByUserID(ctx context.Context, userID uuid.UUID, now time.Time) ([]*entity.UserSession, error)
+	Update(ctx context.Context, session *entity.UserSession) error
+	// Touch records that a session was used at seenAt from ipAddress.
+	Touch(ctx context.Context, sessionID uuid.UUID, seenAt time.Time, ipAddress string) error
+	Delete(ctx context.Context, sessionID uuid.UUID) error
+	// DeleteUserSession deletes a session only if it belongs to userID. It
+	// returns custom_errors.ErrSessionNotFound otherwise.
+	DeleteUserSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	// DeleteExpiredUserSessions deletes the sessions of a user that expired
+	// before now.
+	DeleteExpiredUserSessions(ctx context.Context, userID uuid.UUID, now time.Time) error
+	DeleteAllUserSessions(ctx context.Context, userID uuid.UUID) error
 }
@@ -10,3 +10,10 @@ require (
 	github.com/google/uuid v1.6.0
 	golang.org/x/crypto v0.39.0
 )
+
+require (
+	github.com/fergusstrange/embedded-postgres v1.25.0
+	github.com/lib/pq v1.10.9
+)
+
+require github.com/xi2/xz v0.0.0-20171230120015-48954b6210f8 // indirect
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"database/sql"
+)
+
+// RecountCounters recomputes every like_count, dislike_count and
+// comment_count from the rows they count, and returns how many posts and
+// comments had drifted. The counters are normally kept up to date by the
+// triggers created in the first migration.
+func RecountCounters(db *sql.DB) (posts int64, comments int64, err error) {
+	err = inTransaction(db, func(tx *sql.Tx) error {
+		posts, comments, err = recount(tx)
+		return err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return posts, comments, nil
+}
+
+func recount(q querier) (posts int64, comments int64, err error) {
+	result, err := q.Exec(`
+	UPDATE posts SET like_count = c.likes, dislike_count = c.dislikes, comment_count = c.comments
+	FROM (
+		SELECT p.id,
+			(SELECT COUNT(*) FROM post_reaction WHERE post_id = p.id AND reaction = 1) AS likes,
+			(SELECT COUNT(*) FROM post_reaction WHERE post_id = p.id AND reaction = 0) AS dislikes,
+			(SELECT COUNT(*) FROM comments WHERE post_id = p.id) AS comments
+		FROM posts p
+	) c
+	WHERE posts.id = c.id
+		AND (posts.like_count, posts.dislike_count, posts.comment_count) <> (c.likes, c.dislikes, c.comments)`)
+	if err != nil {
+		return 0, 0, err
+	}
+	if posts, err = result.RowsAffected(); err != nil {
+		return 0, 0, err
+	}
+
+	result, err = q.Exec(`
+	UPDATE comments SET like_count = c.likes, dislike_count = c.dislikes, comment_count = c.replies
+	FROM (
+		SELECT cm.id,
+			(SELECT COUNT(*) FROM comment_reaction WHERE comment_id = cm.id AND reaction = 1) AS likes,
+			(SELECT COUNT(*) FROM comment_reaction WHERE comment_id = cm.id AND reaction = 0) AS dislikes,
+			(SELECT COUNT(*) FROM comments WHERE parent_id = cm.id) AS replies
+		FROM comments cm
+	) c
+	WHERE comments.id = c.id
+		AND (comments.like_count, comments.dislike_count, comments.comment_count) <> (c.likes, c.dislikes, c.replies)`)
+	if err != nil {
+		return 0, 0, err
+	}
+	if comments, err = result.RowsAffected(); err != nil {
+		return 0, 0, err
+	}
+
+	return posts, comments, nil
+}
@@ -0,0 +1,367 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migrations live in migrations/ as NNNN_name.up.sql and NNNN_name.down.sql,
+// with the PostgreSQL ones in migrations/postgres/. They are applied in
+// version order, each in its own transaction, and recorded in
+// schema_migrations. A released migration must never be edited; schema
+// changes go in a new one, for both databases.
+//
+//go:embed migrations/*.sql migrations/postgres/*.sql
+var migrationFiles embed.FS
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationState is a known migration and when it was applied, if it was.
+type MigrationState struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// querier is what *sql.DB and *sql.Tx have in common.
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func loadMigrations(driver string) ([]Migration, error) {
+	dir := "migrations"
+	if driver == DriverPostgres {
+		dir = "migrations/postgres"
+	}
+
+	names, err := fs.Glob(migrationFiles, dir+"/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, file := range names {
+		base := path.Base(file)
+		stem, direction, ok := strings.Cut(strings.TrimSuffix(base, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s: name must end in .up.sql or .down.sql", base)
+		}
+		number, name, ok := strings.Cut(stem, "_")
+		version, err := strconv.Atoi(number)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must start with a version number", base)
+		}
+
+		body, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func createMigrationsTable(q querier, driver string) error {
+	timestamp := "DATETIME"
+	if driver == DriverPostgres {
+		timestamp = "TIMESTAMPTZ"
+	}
+
+	_, err := q.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		applied_at ` + timestamp + ` NOT NULL,
+		PRIMARY KEY(version)
+	);
+	`)
+	return err
+}
+
+func tableExists(q querier, driver, table string) (bool, error) {
+	query := "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)"
+	if driver == DriverPostgres {
+		query = "SELECT to_regclass($1) IS NOT NULL"
+	}
+
+	var exists bool
+	err := q.QueryRow(query, table).Scan(&exists)
+	return exists, err
+}
+
+// bind rewrites the ? placeholders of a query for the driver.
+func bind(driver, query string) string {
+	if driver != DriverPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func appliedMigrations(db *sql.DB, driver string) (map[int]time.Time, error) {
+	applied := make(map[int]time.Time)
+
+	exists, err := tableExists(db, driver, "schema_migrations")
+	if err != nil || !exists {
+		return applied, err
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// MigrationStatus lists every known migration, oldest first, and whether it
+// has been applied. A database created before versioned migrations shows all
+// of them as pending until MigrateUp has recorded its baseline.
+func MigrationStatus(db *sql.DB, driver string) ([]MigrationState, error) {
+	migrations, err := loadMigrations(driver)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, len(migrations))
+	for i, m := range migrations {
+		states[i].Migration = m
+		if at, ok := applied[m.Version]; ok {
+			states[i].AppliedAt = &at
+		}
+	}
+	return states, nil
+}
+
+// MigrateUp applies every pending migration in order and returns the ones
+// it applied. It stops at the first one that fails, which is rolled back.
+func MigrateUp(db *sql.DB, driver string) ([]Migration, error) {
+	migrations, err := loadMigrations(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	if driver == DriverSQLite {
+		baselined, err := baselineLegacySchema(db, migrations[0])
+		if err != nil {
+			return nil, fmt.Errorf("baseline existing schema: %w", err)
+		}
+		if baselined {
+			done = append(done, migrations[0])
+		}
+	}
+
+	if err := createMigrationsTable(db, driver); err != nil {
+		return done, err
+	}
+	applied, err := appliedMigrations(db, driver)
+	if err != nil {
+		return done, err
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		err := inTransaction(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(m.Up); err != nil {
+				return err
+			}
+			_, err := tx.Exec(bind(driver, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"),
+				m.Version, m.Name, time.Now())
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// MigrateDown rolls back the given number of most recently applied
+// migrations, newest first, and returns the ones it rolled back.
+func MigrateDown(db *sql.DB, driver string, steps int) ([]Migration, error) {
+	migrations, err := loadMigrations(driver)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+
+	applied, err := appliedMigrations(db, driver)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]int, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+	if steps < len(versions) {
+		versions = versions[:steps]
+	}
+
+	var done []Migration
+	for _, version := range versions {
+		m, ok := known[version]
+		if !ok {
+			return done, fmt.Errorf("migration %04d was applied by a newer version and cannot be rolled back by this one", version)
+		}
+		err := inTransaction(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(m.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec(bind(driver, "DELETE FROM schema_migrations WHERE version = ?"), m.Version)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+func inTransaction(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// baselineLegacySchema handles databases created before versioned
+// migrations: they have tables but no schema_migrations. The columns that
+// used to be added on startup are added if missing, the first migration,
+// which only creates what does not exist yet, is run over the result and it
+// is recorded as applied. It reports whether the database needed this. Only
+// SQLite databases predate versioned migrations.
+func baselineLegacySchema(db *sql.DB, initial Migration) (bool, error) {
+	versioned, err := tableExists(db, DriverSQLite, "schema_migrations")
+	if err != nil || versioned {
+		return false, err
+	}
+	legacy, err := tableExists(db, DriverSQLite, "posts")
+	if err != nil || !legacy {
+		return false, err
+	}
+
+	err = inTransaction(db, func(tx *sql.Tx) error {
+		columns := []struct{ table, column, definition string }{
+			{"posts", "title", "TEXT NOT NULL DEFAULT ''"},
+			{"posts", "updated_at", "DATETIME"},
+			{"posts", "like_count", "INTEGER NOT NULL DEFAULT 0"},
+			{"posts", "dislike_count", "INTEGER NOT NULL DEFAULT 0"},
+			{"posts", "comment_count", "INTEGER NOT NULL DEFAULT 0"},
+			{"user", "role", "TEXT NOT NULL DEFAULT 'user'"},
+			{"comments", "updated_at", "DATETIME"},
+			{"comments", "deleted_at", "DATETIME"},
+			{"comments", "parent_id", "CHAR(36) REFERENCES comments(id)"},
+			{"comments", "like_count", "INTEGER NOT NULL DEFAULT 0"},
+			{"comments", "dislike_count", "INTEGER NOT NULL DEFAULT 0"},
+			{"comments", "comment_count", "INTEGER NOT NULL DEFAULT 0"},
+		}
+		for _, c := range columns {
+			if err := addColumnIfNotExists(tx, c.table, c.column, c.definition); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.Exec(initial.Up); err != nil {
+			return err
+		}
+		// Counters that were just added start at zero.
+		if _, _, err := recount(tx); err != nil {
+			return err
+		}
+
+		if err := createMigrationsTable(tx, DriverSQLite); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+			initial.Version, initial.Name, time.Now())
+		return err
+	})
+	return err == nil, err
+}
+
+func addColumnIfNotExists(q querier, table, column, definition string) error {
+	var exists bool
+	err := q.QueryRow("SELECT EXISTS (SELECT 1 FROM pragma_table_info(?) WHERE name = ?)", table, column).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	_, err = q.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
+	if err != nil {
+		return fmt.Errorf("add %s column to %s table: %w", column, table, err)
+	}
+	return nil
+}
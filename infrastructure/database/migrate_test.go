@@ -0,0 +1,191 @@
+package database_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"forum/infrastructure/database"
+)
+
+// legacyRows are the rows of the committed forum.db, which was created
+// before versioned migrations.
+var legacyRows = map[string]int{
+	"user": 9, "posts": 23, "comments": 31, "categories": 5, "post_categories": 37,
+	"user_sessions": 9, "comment_reaction": 11, "post_reaction": 11,
+}
+
+// openLegacyCopy opens a copy of the committed forum.db.
+func openLegacyCopy(t *testing.T) *sql.DB {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "..", "forum.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "forum.db")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.OpenDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// schema lists the definition of every table, index and trigger, sorted.
+func schema(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	rows, err := db.Query(`SELECT type || ' ' || name || ': ' || COALESCE(sql, '') FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite_%' ORDER BY type, name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var definitions []string
+	for rows.Next() {
+		var definition string
+		if err := rows.Scan(&definition); err != nil {
+			t.Fatal(err)
+		}
+		definitions = append(definitions, definition)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return definitions
+}
+
+// versions returns the versions of migrations.
+func versions(migrations []database.Migration) []int {
+	var list []int
+	for _, m := range migrations {
+		list = append(list, m.Version)
+	}
+	return list
+}
+
+// pending returns the versions MigrationStatus reports as not applied.
+func pending(t *testing.T, db *sql.DB) []int {
+	t.Helper()
+	states, err := database.MigrationStatus(db, database.DriverSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []int
+	for _, state := range states {
+		if state.AppliedAt == nil {
+			list = append(list, state.Version)
+		}
+	}
+	return list
+}
+
+// checkLegacyRows fails unless every table of the legacy database still
+// holds its rows, and the counters added to it match them.
+func checkLegacyRows(t *testing.T, db *sql.DB) {
+	t.Helper()
+	for table, want := range legacyRows {
+		var got int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM "` + table + `"`).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s has %d rows, want %d", table, got, want)
+		}
+	}
+
+	var comments, counted int
+	err := db.QueryRow(`SELECT (SELECT COUNT(*) FROM comments), (SELECT SUM(comment_count) FROM posts)`).Scan(&comments, &counted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counted != comments {
+		t.Errorf("posts count %d comments, there are %d", counted, comments)
+	}
+}
+
+func TestMigrateLegacyDatabaseRoundTrip(t *testing.T) {
+	all := pending(t, openLegacyCopy(t))
+	if len(all) < 2 {
+		t.Fatalf("only migrations %v are known", all)
+	}
+
+	tests := []struct {
+		name  string
+		steps int
+	}{
+		{"newest migration", 1},
+		{"two migrations", 2},
+		{"all but the baseline", len(all) - 1},
+		{"every migration", len(all)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openLegacyCopy(t)
+
+			applied, err := database.MigrateUp(db, database.DriverSQLite)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := versions(applied); !slices.Equal(got, all) {
+				t.Fatalf("MigrateUp applied %v, want %v", got, all)
+			}
+			if got := pending(t, db); len(got) != 0 {
+				t.Fatalf("migrations %v are pending after MigrateUp", got)
+			}
+			checkLegacyRows(t, db)
+			upgraded := schema(t, db)
+
+			// The newest steps migrations are rolled back, newest first.
+			want := slices.Clone(all[len(all)-tt.steps:])
+			slices.Reverse(want)
+			rolledBack, err := database.MigrateDown(db, database.DriverSQLite, tt.steps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := versions(rolledBack); !slices.Equal(got, want) {
+				t.Fatalf("MigrateDown rolled back %v, want %v", got, want)
+			}
+			slices.Reverse(want)
+			if got := pending(t, db); !slices.Equal(got, want) {
+				t.Fatalf("migrations %v are pending after MigrateDown, want %v", got, want)
+			}
+			downgraded := schema(t, db)
+			if tt.steps == len(all) {
+				if len(downgraded) != 1 || !strings.HasPrefix(downgraded[0], "table schema_migrations:") {
+					t.Fatalf("rolling back every migration left\n%s", strings.Join(downgraded, "\n"))
+				}
+			}
+
+			applied, err = database.MigrateUp(db, database.DriverSQLite)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := versions(applied); !slices.Equal(got, want) {
+				t.Fatalf("MigrateUp reapplied %v, want %v", got, want)
+			}
+			if tt.steps < len(all) {
+				// Only the baseline owns the legacy data.
+				checkLegacyRows(t, db)
+				if got := schema(t, db); !slices.Equal(got, upgraded) {
+					t.Errorf("the schema after going down and up again differs:\n%s\nwant\n%s",
+						strings.Join(got, "\n"), strings.Join(upgraded, "\n"))
+				}
+			}
+
+			// Rolling back again leaves the same schema as the first time.
+			if _, err := database.MigrateDown(db, database.DriverSQLite, tt.steps); err != nil {
+				t.Fatal(err)
+			}
+			if got := schema(t, db); !slices.Equal(got, downgraded) {
+				t.Errorf("the schema after rolling back twice differs:\n%s\nwant\n%s",
+					strings.Join(got, "\n"), strings.Join(downgraded, "\n"))
+			}
+		})
+	}
+}
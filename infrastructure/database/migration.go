@@ -8,90 +8,25 @@ import (
 	"github.com/google/uuid"
 )
 
-func RunMigrations(db *sql.DB) {
-	_, err := db.Exec("PRAGMA foreign_keys = ON")
+// RunMigrations brings the schema up to date, then sets up what depends on
+// how SQLite was built or has to exist in every database.
+func RunMigrations(db *sql.DB, driver string) {
+	applied, err := MigrateUp(db, driver)
 	if err != nil {
-		log.Fatal("Failed to enable foreign key constraints:", err)
+		log.Fatal("Failed to migrate database:", err)
 	}
-	createUsersTable(db)
-	createPostsTable(db)
-	createCommentsTable(db)
-	createCategoriesTable(db)
-	createPostCategoriesTable(db)
-	createUserSessionsTable(db)
-	createCommentReactionTable(db)
-	createPostReactionTable(db)
-}
-
-func createUsersTable(db *sql.DB) {
-	query := `
-	CREATE TABLE IF NOT EXISTS user (
-		id CHAR(36) NOT NULL,
-		user_name TEXT NOT NULL,
-		email TEXT NOT NULL,
-		password_hash TEXT NOT NULL,
-		created_at DATETIME NOT NULL,
-		PRIMARY KEY(id)
-	);
-	CREATE INDEX IF NOT EXISTS idx_user_email ON user(email);
-	`
-	_, err := db.Exec(query)
-	if err != nil {
-		log.Fatal("Failed to create user table:", err)
+	for _, m := range applied {
+		log.Printf("Applied migration %04d %s", m.Version, m.Name)
 	}
-}
 
-func createPostsTable(db *sql.DB) {
-	query := `
-	CREATE TABLE IF NOT EXISTS posts (
-		id CHAR(36) NOT NULL,
-		content TEXT NOT NULL,
-		user_id CHAR(36) NOT NULL,
-		created_at DATETIME NOT NULL,
-		PRIMARY KEY(id),
-		FOREIGN KEY(user_id) REFERENCES user(id)
-	);
-	`
-	_, err := db.Exec(query)
-	if err != nil {
-		log.Fatal("Failed to create posts table:", err)
+	seedDefaultCategories(db, driver)
+	// PostgreSQL has its search index built into the schema.
+	if driver == DriverSQLite {
+		createSearchIndex(db)
 	}
 }
 
-func createCommentsTable(db *sql.DB) {
-	query := `
-	CREATE TABLE IF NOT EXISTS comments (
-		id CHAR(36) NOT NULL,
-		content TEXT NOT NULL,
-		user_id CHAR(36) NOT NULL,
-		post_id CHAR(36) NOT NULL,
-		createdat DATETIME NOT NULL,
-		PRIMARY KEY(id),
-		FOREIGN KEY(user_id) REFERENCES user(id),
-		FOREIGN KEY(post_id) REFERENCES posts(id)
-	);
-	`
-	_, err := db.Exec(query)
-	if err != nil {
-		log.Fatal("Failed to create comments table:", err)
-	}
-}
-
-func createCategoriesTable(db *sql.DB) {
-	// Create the table
-	query := `
-	CREATE TABLE IF NOT EXISTS categories (
-		id CHAR(36) NOT NULL,
-		name TEXT NOT NULL UNIQUE,
-		created_at DATETIME NOT NULL,
-		PRIMARY KEY(id)
-	);
-	`
-	_, err := db.Exec(query)
-	if err != nil {
-		log.Fatal("Failed to create categories table:", err)
-	}
-
+func seedDefaultCategories(db *sql.DB, driver string) {
 	defaultCategories := []struct {
 		ID   string
 		Name string
@@ -105,88 +40,101 @@ func createCategoriesTable(db *sql.DB) {
 
 	for _, cat := range defaultCategories {
 		insertQuery := `
-		INSERT OR IGNORE INTO categories (id, name, created_at)
-		VALUES (?, ?, ?);
+		INSERT INTO categories (id, name, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT DO NOTHING;
 		`
-		_, err := db.Exec(insertQuery, cat.ID, cat.Name, time.Now())
+		_, err := db.Exec(bind(driver, insertQuery), cat.ID, cat.Name, time.Now())
 		if err != nil {
 			log.Printf("Warning: Failed to insert category %s: %v", cat.Name, err)
 		}
 	}
 }
 
-func createPostCategoriesTable(db *sql.DB) {
-	query := `
-	CREATE TABLE IF NOT EXISTS post_categories (
-		post_id CHAR(36) NOT NULL,
-		category_id CHAR(36) NOT NULL,
-		PRIMARY KEY(post_id, category_id),
-		FOREIGN KEY(post_id) REFERENCES posts(id),
-		FOREIGN KEY(category_id) REFERENCES categories(id)
-	);
-	`
-	_, err := db.Exec(query)
-	if err != nil {
-		log.Fatal("Failed to create post_categories table:", err)
+// createSearchIndex sets up the FTS5 tables used for full-text search and the
+// triggers that keep them in sync with posts and comments. SQLite must be
+// built with FTS5 (go build -tags sqlite_fts5); without it the triggers are
+// removed and search falls back to LIKE queries.
+func createSearchIndex(db *sql.DB) {
+	var enabled bool
+	err := db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled)
+	if err != nil || !enabled {
+		log.Println("Warning: SQLite was built without FTS5, search will use slower LIKE queries")
+		// Triggers left over from an FTS5 build would make every write fail.
+		_, err = db.Exec(`
+		DROP TRIGGER IF EXISTS posts_fts_insert;
+		DROP TRIGGER IF EXISTS posts_fts_delete;
+		DROP TRIGGER IF EXISTS posts_fts_update;
+		DROP TRIGGER IF EXISTS comments_fts_insert;
+		DROP TRIGGER IF EXISTS comments_fts_delete;
+		DROP TRIGGER IF EXISTS comments_fts_update;
+		`)
+		if err != nil {
+			log.Fatal("Failed to drop search triggers:", err)
+		}
+		return
 	}
-}
 
-func createUserSessionsTable(db *sql.DB) {
-	query := `
-	CREATE TABLE IF NOT EXISTS user_sessions (
-		id CHAR(36) NOT NULL,
-		user_id CHAR(36) NOT NULL,
-		session_token TEXT NOT NULL,
-		expires_at DATETIME NOT NULL,
-		created_at DATETIME NOT NULL,
-		PRIMARY KEY(id),
-		FOREIGN KEY(user_id) REFERENCES user(id)
-	);
-	CREATE INDEX IF NOT EXISTS idx_user_sessions_token ON user_sessions(session_token);
-	CREATE INDEX IF NOT EXISTS idx_user_sessions_user_id ON user_sessions(user_id);
-	`
-	_, err := db.Exec(query)
+	// If the triggers are missing the index is new or went stale while
+	// running without FTS5, so it has to be rebuilt once they are in place.
+	var synced bool
+	err = db.QueryRow("SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'trigger' AND name = 'posts_fts_insert')").Scan(&synced)
 	if err != nil {
-		log.Fatal("Failed to create user_sessions table:", err)
+		log.Fatal("Failed to check search index:", err)
 	}
-}
 
-func createCommentReactionTable(db *sql.DB) {
 	query := `
-	CREATE TABLE IF NOT EXISTS comment_reaction (
-		id CHAR(36) NOT NULL,
-		user_id CHAR(36) NOT NULL,
-		comment_id CHAR(36) NOT NULL,
-		reaction INTEGER NOT NULL CHECK (reaction IN (0, 1)),
-		created_at DATETIME NOT NULL,
-		PRIMARY KEY(id),
-		FOREIGN KEY(user_id) REFERENCES user(id),
-		FOREIGN KEY(comment_id) REFERENCES comments(id),
-		UNIQUE(user_id, comment_id)
+	CREATE VIRTUAL TABLE IF NOT EXISTS posts_fts USING fts5(
+		post_id UNINDEXED,
+		title,
+		content,
+		tokenize = 'unicode61 remove_diacritics 2'
+	);
+	CREATE VIRTUAL TABLE IF NOT EXISTS comments_fts USING fts5(
+		comment_id UNINDEXED,
+		post_id UNINDEXED,
+		content,
+		tokenize = 'unicode61 remove_diacritics 2'
 	);
+
+	CREATE TRIGGER IF NOT EXISTS posts_fts_insert AFTER INSERT ON posts BEGIN
+		INSERT INTO posts_fts(post_id, title, content) VALUES (new.id, new.title, new.content);
+	END;
+	CREATE TRIGGER IF NOT EXISTS posts_fts_delete AFTER DELETE ON posts BEGIN
+		DELETE FROM posts_fts WHERE post_id = old.id;
+	END;
+	CREATE TRIGGER IF NOT EXISTS posts_fts_update AFTER UPDATE OF title, content ON posts BEGIN
+		DELETE FROM posts_fts WHERE post_id = old.id;
+		INSERT INTO posts_fts(post_id, title, content) VALUES (new.id, new.title, new.content);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS comments_fts_insert AFTER INSERT ON comments WHEN new.deleted_at IS NULL BEGIN
+		INSERT INTO comments_fts(comment_id, post_id, content) VALUES (new.id, new.post_id, new.content);
+	END;
+	CREATE TRIGGER IF NOT EXISTS comments_fts_delete AFTER DELETE ON comments BEGIN
+		DELETE FROM comments_fts WHERE comment_id = old.id;
+	END;
+	CREATE TRIGGER IF NOT EXISTS comments_fts_update AFTER UPDATE OF content, deleted_at ON comments BEGIN
+		DELETE FROM comments_fts WHERE comment_id = old.id;
+		INSERT INTO comments_fts(comment_id, post_id, content)
+			SELECT new.id, new.post_id, new.content WHERE new.deleted_at IS NULL;
+	END;
 	`
-	_, err := db.Exec(query)
+	_, err = db.Exec(query)
 	if err != nil {
-		log.Fatal("Failed to create comment_reaction table:", err)
+		log.Fatal("Failed to create search index:", err)
 	}
-}
 
-func createPostReactionTable(db *sql.DB) {
-	query := `
-	CREATE TABLE IF NOT EXISTS post_reaction (
-		id CHAR(36) NOT NULL,
-		user_id CHAR(36) NOT NULL,
-		post_id CHAR(36) NOT NULL,
-		reaction INTEGER NOT NULL CHECK (reaction IN (0, 1)),
-		created_at DATETIME NOT NULL,
-		PRIMARY KEY(id),
-		FOREIGN KEY(user_id) REFERENCES user(id),
-		FOREIGN KEY(post_id) REFERENCES posts(id),
-		UNIQUE(user_id, post_id)
-	);
-	`
-	_, err := db.Exec(query)
-	if err != nil {
-		log.Fatal("Failed to create post_reaction table:", err)
+	if !synced {
+		_, err = db.Exec(`
+		DELETE FROM posts_fts;
+		INSERT INTO posts_fts(post_id, title, content) SELECT id, title, content FROM posts;
+		DELETE FROM comments_fts;
+		INSERT INTO comments_fts(comment_id, post_id, content)
+			SELECT id, post_id, content FROM comments WHERE deleted_at IS NULL;
+		`)
+		if err != nil {
+			log.Fatal("Failed to build search index:", err)
+		}
 	}
 }
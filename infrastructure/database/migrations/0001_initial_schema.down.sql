@@ -0,0 +1,11 @@
+-- Dependent tables go first; their triggers are dropped with them.
+DROP TABLE IF EXISTS post_attachments;
+DROP TABLE IF EXISTS post_revisions;
+DROP TABLE IF EXISTS post_reaction;
+DROP TABLE IF EXISTS comment_reaction;
+DROP TABLE IF EXISTS user_sessions;
+DROP TABLE IF EXISTS post_categories;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS user;
@@ -0,0 +1,173 @@
+-- The schema as it stood before versioned migrations. Every statement is
+-- idempotent so that databases created by the old RunMigrations can be
+-- brought up to this point and then recorded as version 1.
+
+CREATE TABLE IF NOT EXISTS user (
+	id CHAR(36) NOT NULL,
+	user_name TEXT NOT NULL,
+	email TEXT NOT NULL,
+	password_hash TEXT NOT NULL,
+	role TEXT NOT NULL DEFAULT 'user',
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY(id)
+);
+CREATE INDEX IF NOT EXISTS idx_user_email ON user(email);
+
+CREATE TABLE IF NOT EXISTS posts (
+	id CHAR(36) NOT NULL,
+	title TEXT NOT NULL DEFAULT '',
+	content TEXT NOT NULL,
+	user_id CHAR(36) NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME,
+	like_count INTEGER NOT NULL DEFAULT 0,
+	dislike_count INTEGER NOT NULL DEFAULT 0,
+	comment_count INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY(id),
+	FOREIGN KEY(user_id) REFERENCES user(id)
+);
+CREATE INDEX IF NOT EXISTS idx_posts_created_at_id ON posts(created_at, id);
+
+CREATE TABLE IF NOT EXISTS comments (
+	id CHAR(36) NOT NULL,
+	content TEXT NOT NULL,
+	user_id CHAR(36) NOT NULL,
+	post_id CHAR(36) NOT NULL,
+	parent_id CHAR(36),
+	createdat DATETIME NOT NULL,
+	updated_at DATETIME,
+	deleted_at DATETIME,
+	like_count INTEGER NOT NULL DEFAULT 0,
+	dislike_count INTEGER NOT NULL DEFAULT 0,
+	comment_count INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY(id),
+	FOREIGN KEY(user_id) REFERENCES user(id),
+	FOREIGN KEY(post_id) REFERENCES posts(id),
+	FOREIGN KEY(parent_id) REFERENCES comments(id)
+);
+
+CREATE TABLE IF NOT EXISTS categories (
+	id CHAR(36) NOT NULL,
+	name TEXT NOT NULL UNIQUE,
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY(id)
+);
+
+CREATE TABLE IF NOT EXISTS post_categories (
+	post_id CHAR(36) NOT NULL,
+	category_id CHAR(36) NOT NULL,
+	PRIMARY KEY(post_id, category_id),
+	FOREIGN KEY(post_id) REFERENCES posts(id),
+	FOREIGN KEY(category_id) REFERENCES categories(id)
+);
+
+CREATE TABLE IF NOT EXISTS user_sessions (
+	id CHAR(36) NOT NULL,
+	user_id CHAR(36) NOT NULL,
+	session_token TEXT NOT NULL,
+	expires_at DATETIME NOT NULL,
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY(id),
+	FOREIGN KEY(user_id) REFERENCES user(id)
+);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_token ON user_sessions(session_token);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user_id ON user_sessions(user_id);
+
+CREATE TABLE IF NOT EXISTS comment_reaction (
+	id CHAR(36) NOT NULL,
+	user_id CHAR(36) NOT NULL,
+	comment_id CHAR(36) NOT NULL,
+	reaction INTEGER NOT NULL CHECK (reaction IN (0, 1)),
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY(id),
+	FOREIGN KEY(user_id) REFERENCES user(id),
+	FOREIGN KEY(comment_id) REFERENCES comments(id),
+	UNIQUE(user_id, comment_id)
+);
+
+CREATE TABLE IF NOT EXISTS post_reaction (
+	id CHAR(36) NOT NULL,
+	user_id CHAR(36) NOT NULL,
+	post_id CHAR(36) NOT NULL,
+	reaction INTEGER NOT NULL CHECK (reaction IN (0, 1)),
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY(id),
+	FOREIGN KEY(user_id) REFERENCES user(id),
+	FOREIGN KEY(post_id) REFERENCES posts(id),
+	UNIQUE(user_id, post_id)
+);
+
+CREATE TABLE IF NOT EXISTS post_revisions (
+	id CHAR(36) NOT NULL,
+	post_id CHAR(36) NOT NULL,
+	version INTEGER NOT NULL,
+	title TEXT NOT NULL,
+	content TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY(id),
+	FOREIGN KEY(post_id) REFERENCES posts(id),
+	UNIQUE(post_id, version)
+);
+
+CREATE TABLE IF NOT EXISTS post_attachments (
+	id CHAR(36) NOT NULL,
+	post_id CHAR(36) NOT NULL,
+	file_name TEXT NOT NULL,
+	thumbnail_name TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	width INTEGER NOT NULL,
+	height INTEGER NOT NULL,
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY(id),
+	FOREIGN KEY(post_id) REFERENCES posts(id)
+);
+CREATE INDEX IF NOT EXISTS idx_post_attachments_post_id ON post_attachments(post_id);
+
+-- Reaction and comment counters. A comment's comment_count is its number of
+-- direct replies. Tombstoned comments keep being counted, as they are still
+-- shown in their thread.
+CREATE TRIGGER IF NOT EXISTS post_reaction_count_insert AFTER INSERT ON post_reaction BEGIN
+	UPDATE posts SET like_count = like_count + (new.reaction = 1), dislike_count = dislike_count + (new.reaction = 0)
+	WHERE id = new.post_id;
+END;
+CREATE TRIGGER IF NOT EXISTS post_reaction_count_delete AFTER DELETE ON post_reaction BEGIN
+	UPDATE posts SET like_count = like_count - (old.reaction = 1), dislike_count = dislike_count - (old.reaction = 0)
+	WHERE id = old.post_id;
+END;
+CREATE TRIGGER IF NOT EXISTS post_reaction_count_update AFTER UPDATE OF reaction, post_id ON post_reaction BEGIN
+	UPDATE posts SET like_count = like_count - (old.reaction = 1), dislike_count = dislike_count - (old.reaction = 0)
+	WHERE id = old.post_id;
+	UPDATE posts SET like_count = like_count + (new.reaction = 1), dislike_count = dislike_count + (new.reaction = 0)
+	WHERE id = new.post_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS comment_reaction_count_insert AFTER INSERT ON comment_reaction BEGIN
+	UPDATE comments SET like_count = like_count + (new.reaction = 1), dislike_count = dislike_count + (new.reaction = 0)
+	WHERE id = new.comment_id;
+END;
+CREATE TRIGGER IF NOT EXISTS comment_reaction_count_delete AFTER DELETE ON comment_reaction BEGIN
+	UPDATE comments SET like_count = like_count - (old.reaction = 1), dislike_count = dislike_count - (old.reaction = 0)
+	WHERE id = old.comment_id;
+END;
+CREATE TRIGGER IF NOT EXISTS comment_reaction_count_update AFTER UPDATE OF reaction, comment_id ON comment_reaction BEGIN
+	UPDATE comments SET like_count = like_count - (old.reaction = 1), dislike_count = dislike_count - (old.reaction = 0)
+	WHERE id = old.comment_id;
+	UPDATE comments SET like_count = like_count + (new.reaction = 1), dislike_count = dislike_count + (new.reaction = 0)
+	WHERE id = new.comment_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS comments_count_insert AFTER INSERT ON comments BEGIN
+	UPDATE posts SET comment_count = comment_count + 1 WHERE id = new.post_id;
+	UPDATE comments SET comment_count = comment_count + 1 WHERE id = new.parent_id;
+END;
+CREATE TRIGGER IF NOT EXISTS comments_count_delete AFTER DELETE ON comments BEGIN
+	UPDATE posts SET comment_count = comment_count - 1 WHERE id = old.post_id;
+	UPDATE comments SET comment_count = comment_count - 1 WHERE id = old.parent_id;
+END;
+CREATE TRIGGER IF NOT EXISTS comments_count_update AFTER UPDATE OF post_id, parent_id ON comments BEGIN
+	UPDATE posts SET comment_count = comment_count - 1 WHERE id = old.post_id;
+	UPDATE comments SET comment_count = comment_count - 1 WHERE id = old.parent_id;
+	UPDATE posts SET comment_count = comment_count + 1 WHERE id = new.post_id;
+	UPDATE comments SET comment_count = comment_count + 1 WHERE id = new.parent_id;
+END;
@@ -0,0 +1,7 @@
+-- Members can be signed in on several devices at once. Each session keeps
+-- what it was opened from and when it was last used, so that the member can
+-- tell their devices apart. Sessions from before know neither.
+ALTER TABLE user_sessions ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE user_sessions ADD COLUMN ip_address TEXT NOT NULL DEFAULT '';
+ALTER TABLE user_sessions ADD COLUMN last_seen_at DATETIME;
+UPDATE user_sessions SET last_seen_at = created_at;
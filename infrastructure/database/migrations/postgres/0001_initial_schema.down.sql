@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS post_attachments;
+DROP TABLE IF EXISTS post_revisions;
+DROP TABLE IF EXISTS post_reaction;
+DROP TABLE IF EXISTS comment_reaction;
+DROP TABLE IF EXISTS user_sessions;
+DROP TABLE IF EXISTS post_categories;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS "user";
+DROP FUNCTION IF EXISTS post_reaction_count();
+DROP FUNCTION IF EXISTS comment_reaction_count();
+DROP FUNCTION IF EXISTS comments_count();
@@ -0,0 +1,168 @@
+-- The PostgreSQL schema matches the SQLite one table for table. "user" is a
+-- reserved word here and always has to be quoted. Reactions stay 0/1
+-- integers so that queries can be shared between the two databases.
+
+CREATE TABLE "user" (
+	id UUID NOT NULL,
+	user_name TEXT NOT NULL,
+	email TEXT NOT NULL,
+	password_hash TEXT NOT NULL,
+	role TEXT NOT NULL DEFAULT 'user',
+	created_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY(id)
+);
+CREATE INDEX idx_user_email ON "user"(email);
+
+CREATE TABLE posts (
+	id UUID NOT NULL,
+	title TEXT NOT NULL DEFAULT '',
+	content TEXT NOT NULL,
+	user_id UUID NOT NULL REFERENCES "user"(id),
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ,
+	like_count INTEGER NOT NULL DEFAULT 0,
+	dislike_count INTEGER NOT NULL DEFAULT 0,
+	comment_count INTEGER NOT NULL DEFAULT 0,
+	search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', title || ' ' || content)) STORED,
+	PRIMARY KEY(id)
+);
+CREATE INDEX idx_posts_created_at_id ON posts(created_at, id);
+CREATE INDEX idx_posts_search ON posts USING GIN (search_vector);
+
+CREATE TABLE comments (
+	id UUID NOT NULL,
+	content TEXT NOT NULL,
+	user_id UUID NOT NULL REFERENCES "user"(id),
+	post_id UUID NOT NULL REFERENCES posts(id),
+	parent_id UUID REFERENCES comments(id),
+	createdat TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ,
+	deleted_at TIMESTAMPTZ,
+	like_count INTEGER NOT NULL DEFAULT 0,
+	dislike_count INTEGER NOT NULL DEFAULT 0,
+	comment_count INTEGER NOT NULL DEFAULT 0,
+	search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED,
+	PRIMARY KEY(id)
+);
+CREATE INDEX idx_comments_search ON comments USING GIN (search_vector);
+
+CREATE TABLE categories (
+	id UUID NOT NULL,
+	name TEXT NOT NULL UNIQUE,
+	created_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY(id)
+);
+
+CREATE TABLE post_categories (
+	post_id UUID NOT NULL REFERENCES posts(id),
+	category_id UUID NOT NULL REFERENCES categories(id),
+	PRIMARY KEY(post_id, category_id)
+);
+
+CREATE TABLE user_sessions (
+	id UUID NOT NULL,
+	user_id UUID NOT NULL REFERENCES "user"(id),
+	session_token TEXT NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY(id)
+);
+CREATE INDEX idx_user_sessions_token ON user_sessions(session_token);
+CREATE INDEX idx_user_sessions_user_id ON user_sessions(user_id);
+
+CREATE TABLE comment_reaction (
+	id UUID NOT NULL,
+	user_id UUID NOT NULL REFERENCES "user"(id),
+	comment_id UUID NOT NULL REFERENCES comments(id),
+	reaction SMALLINT NOT NULL CHECK (reaction IN (0, 1)),
+	created_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY(id),
+	UNIQUE(user_id, comment_id)
+);
+
+CREATE TABLE post_reaction (
+	id UUID NOT NULL,
+	user_id UUID NOT NULL REFERENCES "user"(id),
+	post_id UUID NOT NULL REFERENCES posts(id),
+	reaction SMALLINT NOT NULL CHECK (reaction IN (0, 1)),
+	created_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY(id),
+	UNIQUE(user_id, post_id)
+);
+
+CREATE TABLE post_revisions (
+	id UUID NOT NULL,
+	post_id UUID NOT NULL REFERENCES posts(id),
+	version INTEGER NOT NULL,
+	title TEXT NOT NULL,
+	content TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY(id),
+	UNIQUE(post_id, version)
+);
+
+CREATE TABLE post_attachments (
+	id UUID NOT NULL,
+	post_id UUID NOT NULL REFERENCES posts(id),
+	file_name TEXT NOT NULL,
+	thumbnail_name TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size BIGINT NOT NULL,
+	width INTEGER NOT NULL,
+	height INTEGER NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY(id)
+);
+CREATE INDEX idx_post_attachments_post_id ON post_attachments(post_id, created_at);
+
+-- Reaction and comment counters, kept up to date the same way as in SQLite.
+CREATE FUNCTION post_reaction_count() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP IN ('UPDATE', 'DELETE') THEN
+		UPDATE posts SET like_count = like_count - (OLD.reaction = 1)::int, dislike_count = dislike_count - (OLD.reaction = 0)::int
+		WHERE id = OLD.post_id;
+	END IF;
+	IF TG_OP IN ('INSERT', 'UPDATE') THEN
+		UPDATE posts SET like_count = like_count + (NEW.reaction = 1)::int, dislike_count = dislike_count + (NEW.reaction = 0)::int
+		WHERE id = NEW.post_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER post_reaction_count AFTER INSERT OR DELETE OR UPDATE OF reaction, post_id ON post_reaction
+	FOR EACH ROW EXECUTE FUNCTION post_reaction_count();
+
+CREATE FUNCTION comment_reaction_count() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP IN ('UPDATE', 'DELETE') THEN
+		UPDATE comments SET like_count = like_count - (OLD.reaction = 1)::int, dislike_count = dislike_count - (OLD.reaction = 0)::int
+		WHERE id = OLD.comment_id;
+	END IF;
+	IF TG_OP IN ('INSERT', 'UPDATE') THEN
+		UPDATE comments SET like_count = like_count + (NEW.reaction = 1)::int, dislike_count = dislike_count + (NEW.reaction = 0)::int
+		WHERE id = NEW.comment_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER comment_reaction_count AFTER INSERT OR DELETE OR UPDATE OF reaction, comment_id ON comment_reaction
+	FOR EACH ROW EXECUTE FUNCTION comment_reaction_count();
+
+CREATE FUNCTION comments_count() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP IN ('UPDATE', 'DELETE') THEN
+		UPDATE posts SET comment_count = comment_count - 1 WHERE id = OLD.post_id;
+		UPDATE comments SET comment_count = comment_count - 1 WHERE id = OLD.parent_id;
+	END IF;
+	IF TG_OP IN ('INSERT', 'UPDATE') THEN
+		UPDATE posts SET comment_count = comment_count + 1 WHERE id = NEW.post_id;
+		UPDATE comments SET comment_count = comment_count + 1 WHERE id = NEW.parent_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER comments_count AFTER INSERT OR DELETE OR UPDATE OF post_id, parent_id ON comments
+	FOR EACH ROW EXECUTE FUNCTION comments_count();
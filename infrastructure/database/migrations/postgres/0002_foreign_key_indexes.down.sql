@@ -0,0 +1,7 @@
+DROP INDEX idx_comments_post_id;
+DROP INDEX idx_comments_parent_id;
+DROP INDEX idx_comments_user_id;
+DROP INDEX idx_posts_user_id;
+DROP INDEX idx_post_reaction_post_id;
+DROP INDEX idx_comment_reaction_comment_id;
+DROP INDEX idx_post_categories_category_id;
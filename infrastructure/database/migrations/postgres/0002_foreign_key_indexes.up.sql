@@ -0,0 +1,8 @@
+-- Lookups by post, parent comment and reacted item had to scan whole tables.
+CREATE INDEX idx_comments_post_id ON comments(post_id);
+CREATE INDEX idx_comments_parent_id ON comments(parent_id);
+CREATE INDEX idx_comments_user_id ON comments(user_id);
+CREATE INDEX idx_posts_user_id ON posts(user_id);
+CREATE INDEX idx_post_reaction_post_id ON post_reaction(post_id);
+CREATE INDEX idx_comment_reaction_comment_id ON comment_reaction(comment_id);
+CREATE INDEX idx_post_categories_category_id ON post_categories(category_id);
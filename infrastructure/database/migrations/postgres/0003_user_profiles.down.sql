@@ -0,0 +1,7 @@
+DROP INDEX idx_user_name_history_user_id;
+DROP INDEX idx_user_name_history_user_name;
+DROP TABLE user_name_history;
+DROP INDEX idx_user_user_name;
+ALTER TABLE "user" DROP COLUMN user_name_changed_at;
+ALTER TABLE "user" DROP COLUMN avatar_name;
+ALTER TABLE "user" DROP COLUMN bio;
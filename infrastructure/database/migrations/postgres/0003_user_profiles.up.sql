@@ -0,0 +1,19 @@
+-- Members can write a bio, upload an avatar and change their name. Every
+-- name a member gives up is kept, so that links to it keep working and no
+-- one else can take it over right away. Names are unique, which settles
+-- two members renaming themselves to the same name at once.
+ALTER TABLE "user" ADD COLUMN bio TEXT NOT NULL DEFAULT '';
+ALTER TABLE "user" ADD COLUMN avatar_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE "user" ADD COLUMN user_name_changed_at TIMESTAMPTZ;
+CREATE UNIQUE INDEX idx_user_user_name ON "user"(user_name);
+
+CREATE TABLE user_name_history (
+	id UUID NOT NULL,
+	user_id UUID NOT NULL,
+	user_name TEXT NOT NULL,
+	changed_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY(id),
+	FOREIGN KEY(user_id) REFERENCES "user"(id)
+);
+CREATE INDEX idx_user_name_history_user_name ON user_name_history(user_name, changed_at);
+CREATE INDEX idx_user_name_history_user_id ON user_name_history(user_id);
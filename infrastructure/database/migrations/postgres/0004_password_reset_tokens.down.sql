@@ -0,0 +1,3 @@
+DROP INDEX idx_password_reset_tokens_user_id;
+DROP INDEX idx_password_reset_tokens_token_hash;
+DROP TABLE password_reset_tokens;
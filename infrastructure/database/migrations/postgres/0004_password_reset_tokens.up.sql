@@ -0,0 +1,14 @@
+-- Links sent to users who forgot their password. Only a hash of the token
+-- is stored; used_at is set when the link is used, which makes it single-use.
+CREATE TABLE password_reset_tokens (
+	id UUID NOT NULL,
+	user_id UUID NOT NULL,
+	token_hash TEXT NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY(id),
+	FOREIGN KEY(user_id) REFERENCES "user"(id)
+);
+CREATE UNIQUE INDEX idx_password_reset_tokens_token_hash ON password_reset_tokens(token_hash);
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
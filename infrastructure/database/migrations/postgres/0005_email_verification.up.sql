@@ -0,0 +1,4 @@
+-- New accounts stay unverified until their owner follows the link mailed to
+-- them. Accounts that existed before count as verified.
+ALTER TABLE "user" ADD COLUMN email_verified_at TIMESTAMPTZ;
+UPDATE "user" SET email_verified_at = created_at;
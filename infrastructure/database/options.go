@@ -0,0 +1,71 @@
+package database
+
+import (
+	"database/sql"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options tunes the connections of a database. Zero fields keep the defaults
+// of the driver and of database/sql.
+type Options struct {
+	// BusyTimeout is how long a SQLite connection waits for a lock that
+	// another connection holds before failing with "database is locked".
+	BusyTimeout time.Duration
+	// JournalMode is the SQLite journal mode. In WAL mode readers go on
+	// while a write is in progress instead of waiting for it.
+	JournalMode string
+	// Synchronous is the SQLite synchronous setting. NORMAL is safe in WAL
+	// mode and saves an fsync on every commit.
+	Synchronous string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// sqliteSource adds the settings of opts to a SQLite file path. The driver
+// applies them to every connection it opens, where a PRAGMA run through the
+// pool would only reach one of them. Foreign keys are always enforced, and
+// transactions take the write lock when they begin: two transactions that
+// both read before writing would otherwise each wait for the other, and
+// SQLite fails one of them at once instead of waiting out the busy timeout.
+func sqliteSource(path string, opts Options) string {
+	params := url.Values{}
+	params.Set("_foreign_keys", "on")
+	params.Set("_txlock", "immediate")
+	if opts.BusyTimeout > 0 {
+		params.Set("_busy_timeout", strconv.FormatInt(opts.BusyTimeout.Milliseconds(), 10))
+	}
+	if opts.JournalMode != "" {
+		params.Set("_journal_mode", opts.JournalMode)
+	}
+	if opts.Synchronous != "" {
+		params.Set("_synchronous", opts.Synchronous)
+	}
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + params.Encode()
+}
+
+// configurePool applies the pool limits of opts to db.
+func configurePool(db *sql.DB, opts Options) {
+	if opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+	if opts.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	}
+}
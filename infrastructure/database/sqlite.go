@@ -2,25 +2,46 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
+	"slices"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func SetingUpDB(datapath string) *sql.DB {
-	db, err := OpenDB(datapath)
+// Supported database drivers. The postgres driver is only linked in when
+// building with -tags postgres.
+const (
+	DriverSQLite   = "sqlite3"
+	DriverPostgres = "postgres"
+)
+
+func SetingUpDB(driver, source string, opts Options) *sql.DB {
+	db, err := Open(driver, source, opts)
 	if err != nil {
 		log.Fatal("Error opening database:", err)
 	}
-	RunMigrations(db)
+	RunMigrations(db, driver)
 	return db
 }
 
 func OpenDB(filePath string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", filePath)
+	return Open(DriverSQLite, filePath, Options{})
+}
+
+// Open connects to the database and applies opts to its connections.
+func Open(driver, source string, opts Options) (*sql.DB, error) {
+	if driver == DriverPostgres && !slices.Contains(sql.Drivers(), DriverPostgres) {
+		return nil, fmt.Errorf("this binary was built without PostgreSQL support, rebuild it with -tags postgres")
+	}
+	if driver == DriverSQLite {
+		source = sqliteSource(source, opts)
+	}
+	db, err := sql.Open(driver, source)
 	if err != nil {
 		return nil, err
 	}
+	configurePool(db, opts)
 	err = db.Ping()
 	if err != nil {
 		return nil, err
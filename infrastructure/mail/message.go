@@ -0,0 +1,57 @@
+// Package mail implements repository.Mailer: SMTPMailer delivers through a
+// mail server, OutboxMailer writes every message to a directory so the mail
+// flows can be followed locally without one.
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net/mail"
+	"strings"
+	"time"
+
+	"forum/domain/entity"
+)
+
+var ErrInvalidHeader = errors.New("email header contains a line break")
+
+// formatMessage renders email as an RFC 5322 message from from. Header
+// values containing line breaks are refused, so user input cannot add
+// headers or recipients.
+func formatMessage(from string, email *entity.Email) ([]byte, error) {
+	for _, value := range []string{from, email.To, email.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+	if _, err := mail.ParseAddress(email.To); err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", email.To, err)
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.Trim(from[at+1:], "> ")
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", email.To)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", email.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	// The bodies are short lines of text, and links in them stay readable
+	// in the outbox files without quoted-printable.
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(email.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,44 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"forum/domain/entity"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+// OutboxMailer delivers nothing: it writes each message to its own .eml file
+// in a directory, where it can be read or opened with a mail client.
+type OutboxMailer struct {
+	dir  string
+	from string
+}
+
+func NewOutboxMailer(dir, from string) (repository.Mailer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &OutboxMailer{dir: dir, from: from}, nil
+}
+
+func (m *OutboxMailer) Send(ctx context.Context, email *entity.Email) error {
+	msg, err := formatMessage(m.from, email)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.New())
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, msg, 0o600); err != nil {
+		return err
+	}
+	log.Printf("Mail to %s written to %s", email.To, path)
+	return nil
+}
@@ -0,0 +1,93 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+
+	"forum/domain/entity"
+	"forum/domain/repository"
+)
+
+// SMTPMailer sends every message through one SMTP server. The connection is
+// upgraded with STARTTLS whenever the server offers it, and credentials are
+// only sent over TLS.
+type SMTPMailer struct {
+	addr string
+	host string
+	// from is the From header, such as "Forum <forum@example.com>", and
+	// sender the bare address in it.
+	from   string
+	sender string
+	auth   smtp.Auth
+}
+
+// NewSMTPMailer sends from from through host:port. Without a username no
+// authentication is attempted.
+func NewSMTPMailer(host, port, username, password, from string) (repository.Mailer, error) {
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", from, err)
+	}
+
+	m := &SMTPMailer{addr: net.JoinHostPort(host, port), host: host, from: from, sender: address.Address}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, email *entity.Email) error {
+	msg, err := formatMessage(m.from, email)
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.auth != nil {
+		if err := client.Auth(m.auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(m.sender); err != nil {
+		return err
+	}
+	if err := client.Rcpt(email.To); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
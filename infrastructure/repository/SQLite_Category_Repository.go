@@ -1,6 +1,7 @@
 package infra_repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
@@ -21,14 +22,14 @@ func NewSQLiteCategoryRepository(db *sql.DB) repository.CategoryRepository {
 	return &SQLiteCategoryRepository{db: db}
 }
 
-func (r *SQLiteCategoryRepository) Create(category *entity.Category) error {
+func (r *SQLiteCategoryRepository) Create(ctx context.Context, category *entity.Category) error {
 	category.ID = uuid.New()
 	category.CreatedAt = time.Now()
 
 	query := `INSERT INTO categories (id, name, created_at)
-			  VALUES (?, ?, ?, ?)`
+			  VALUES (?, ?, ?)`
 
-	_, err := r.db.Exec(query, category.ID.String(), category.Name, category.CreatedAt)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, category.ID.String(), category.Name, category.CreatedAt)
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
 			return custom_errors.ErrCategoryExists
@@ -38,10 +39,10 @@ func (r *SQLiteCategoryRepository) Create(category *entity.Category) error {
 	return nil
 }
 
-func (r *SQLiteCategoryRepository) GetByID(categoryID *uuid.UUID) (*entity.Category, error) {
+func (r *SQLiteCategoryRepository) GetByID(ctx context.Context, categoryID *uuid.UUID) (*entity.Category, error) {
 	query := `SELECT id, name, created_at FROM categories WHERE id = ?`
 
-	row := r.db.QueryRow(query, categoryID.String())
+	row := conn(ctx, r.db).QueryRowContext(ctx, query, categoryID.String())
 
 	category := &entity.Category{}
 	var idStr string
@@ -62,10 +63,10 @@ func (r *SQLiteCategoryRepository) GetByID(categoryID *uuid.UUID) (*entity.Categ
 	return category, nil
 }
 
-func (r *SQLiteCategoryRepository) GetByName(name string) (*entity.Category, error) {
+func (r *SQLiteCategoryRepository) GetByName(ctx context.Context, name string) (*entity.Category, error) {
 	query := `SELECT id, name, created_at FROM categories WHERE name = ?`
 
-	row := r.db.QueryRow(query, name)
+	row := conn(ctx, r.db).QueryRowContext(ctx, query, name)
 
 	category := &entity.Category{}
 	var idStr string
@@ -86,10 +87,10 @@ func (r *SQLiteCategoryRepository) GetByName(name string) (*entity.Category, err
 	return category, nil
 }
 
-func (r *SQLiteCategoryRepository) GetAll() ([]*entity.Category, error) {
+func (r *SQLiteCategoryRepository) GetAll(ctx context.Context) ([]*entity.Category, error) {
 	query := `SELECT id, name, created_at FROM categories ORDER BY name ASC`
 
-	rows, err := r.db.Query(query)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
@@ -118,10 +119,10 @@ func (r *SQLiteCategoryRepository) GetAll() ([]*entity.Category, error) {
 	return categories, nil
 }
 
-func (r *SQLiteCategoryRepository) Update(category *entity.Category) error {
+func (r *SQLiteCategoryRepository) Update(ctx context.Context, category *entity.Category) error {
 	query := `UPDATE categories SET name = ? WHERE id = ?`
 
-	result, err := r.db.Exec(query, category.Name, category.ID.String())
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, category.Name, category.ID.String())
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
 			return custom_errors.ErrCategoryExists
@@ -141,10 +142,10 @@ func (r *SQLiteCategoryRepository) Update(category *entity.Category) error {
 	return nil
 }
 
-func (r *SQLiteCategoryRepository) Delete(categoryID uuid.UUID) error {
+func (r *SQLiteCategoryRepository) Delete(ctx context.Context, categoryID uuid.UUID) error {
 	query := `DELETE FROM categories WHERE id = ?`
 
-	result, err := r.db.Exec(query, categoryID.String())
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, categoryID.String())
 	if err != nil {
 		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
@@ -161,11 +162,11 @@ func (r *SQLiteCategoryRepository) Delete(categoryID uuid.UUID) error {
 	return nil
 }
 
-func (r *SQLiteCategoryRepository) CheckNameExists(name string) (bool, error) {
+func (r *SQLiteCategoryRepository) CheckNameExists(ctx context.Context, name string) (bool, error) {
 	query := `SELECT COUNT(*) FROM categories WHERE name = ?`
 
 	var count int
-	err := r.db.QueryRow(query, name).Scan(&count)
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, name).Scan(&count)
 	if err != nil {
 		return false, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
@@ -173,14 +174,14 @@ func (r *SQLiteCategoryRepository) CheckNameExists(name string) (bool, error) {
 	return count > 0, nil
 }
 
-func (r *SQLiteCategoryRepository) GetWithPostCount() ([]*entity.Category, error) {
+func (r *SQLiteCategoryRepository) GetWithPostCount(ctx context.Context) ([]*entity.Category, error) {
 	query := `SELECT c.id, c.name, c.created_at, COUNT(pc.post_id) as post_count
 			  FROM categories c 
 			  LEFT JOIN post_categories pc ON c.id = pc.category_id 
 			  GROUP BY c.id, c.name, c.created_at 
 			  ORDER BY c.name ASC`
 
-	rows, err := r.db.Query(query)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
@@ -1,6 +1,7 @@
 package infra_repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
@@ -21,14 +22,14 @@ func NewSQLiteCommentReactionRepository(db *sql.DB) repository.CommentReactionRe
 	return &SQLiteCommentReactionRepository{db: db}
 }
 
-func (r *SQLiteCommentReactionRepository) Create(reaction *entity.CommentReaction) error {
+func (r *SQLiteCommentReactionRepository) Create(ctx context.Context, reaction *entity.CommentReaction) error {
 	reaction.ID = uuid.New()
 	reaction.CreatedAt = time.Now()
 
 	query := `INSERT INTO comment_reaction (id, user_id, comment_id, reaction, created_at)
 			  VALUES (?, ?, ?, ?, ?)`
 
-	_, err := r.db.Exec(query, reaction.ID.String(), reaction.UserID.String(),
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, reaction.ID.String(), reaction.UserID.String(),
 		reaction.CommentID.String(), reaction.Reaction, reaction.CreatedAt)
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
@@ -39,10 +40,10 @@ func (r *SQLiteCommentReactionRepository) Create(reaction *entity.CommentReactio
 	return nil
 }
 
-func (r *SQLiteCommentReactionRepository) GetByID(reactionID uuid.UUID) (*entity.CommentReaction, error) {
+func (r *SQLiteCommentReactionRepository) GetByID(ctx context.Context, reactionID uuid.UUID) (*entity.CommentReaction, error) {
 	query := `SELECT id, user_id, comment_id, reaction, created_at FROM comment_reaction WHERE id = ?`
 
-	row := r.db.QueryRow(query, reactionID.String())
+	row := conn(ctx, r.db).QueryRowContext(ctx, query, reactionID.String())
 
 	reaction := &entity.CommentReaction{}
 	var idStr, userIDStr, commentIDStr string
@@ -73,11 +74,11 @@ func (r *SQLiteCommentReactionRepository) GetByID(reactionID uuid.UUID) (*entity
 	return reaction, nil
 }
 
-func (r *SQLiteCommentReactionRepository) GetByUserAndComment(userID, commentID uuid.UUID) (*entity.CommentReaction, error) {
+func (r *SQLiteCommentReactionRepository) GetByUserAndComment(ctx context.Context, userID, commentID uuid.UUID) (*entity.CommentReaction, error) {
 	query := `SELECT id, user_id, comment_id, reaction, created_at 
 			  FROM comment_reaction WHERE user_id = ? AND comment_id = ?`
 
-	row := r.db.QueryRow(query, userID.String(), commentID.String())
+	row := conn(ctx, r.db).QueryRowContext(ctx, query, userID.String(), commentID.String())
 
 	reaction := &entity.CommentReaction{}
 	var idStr, userIDStr, commentIDStr string
@@ -108,11 +109,11 @@ func (r *SQLiteCommentReactionRepository) GetByUserAndComment(userID, commentID
 	return reaction, nil
 }
 
-func (r *SQLiteCommentReactionRepository) GetByCommentID(commentID uuid.UUID) ([]*entity.CommentReaction, error) {
+func (r *SQLiteCommentReactionRepository) GetByCommentID(ctx context.Context, commentID uuid.UUID) ([]*entity.CommentReaction, error) {
 	query := `SELECT id, user_id, comment_id, reaction, created_at 
 			  FROM comment_reaction WHERE comment_id = ? ORDER BY created_at DESC`
 
-	rows, err := r.db.Query(query, commentID.String())
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, commentID.String())
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
@@ -150,11 +151,11 @@ func (r *SQLiteCommentReactionRepository) GetByCommentID(commentID uuid.UUID) ([
 	return reactions, nil
 }
 
-func (r *SQLiteCommentReactionRepository) GetByUserID(userID uuid.UUID) ([]*entity.CommentReaction, error) {
+func (r *SQLiteCommentReactionRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.CommentReaction, error) {
 	query := `SELECT id, user_id, comment_id, reaction, created_at 
 			  FROM comment_reaction WHERE user_id = ? ORDER BY created_at DESC`
 
-	rows, err := r.db.Query(query, userID.String())
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID.String())
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
@@ -192,10 +193,10 @@ func (r *SQLiteCommentReactionRepository) GetByUserID(userID uuid.UUID) ([]*enti
 	return reactions, nil
 }
 
-func (r *SQLiteCommentReactionRepository) Update(reaction *entity.CommentReaction) error {
+func (r *SQLiteCommentReactionRepository) Update(ctx context.Context, reaction *entity.CommentReaction) error {
 	query := `UPDATE comment_reaction SET reaction = ? WHERE id = ?`
 
-	result, err := r.db.Exec(query, reaction.Reaction, reaction.ID.String())
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, reaction.Reaction, reaction.ID.String())
 	if err != nil {
 		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
@@ -212,10 +213,10 @@ func (r *SQLiteCommentReactionRepository) Update(reaction *entity.CommentReactio
 	return nil
 }
 
-func (r *SQLiteCommentReactionRepository) Delete(reactionID uuid.UUID) error {
+func (r *SQLiteCommentReactionRepository) Delete(ctx context.Context, reactionID uuid.UUID) error {
 	query := `DELETE FROM comment_reaction WHERE id = ?`
 
-	result, err := r.db.Exec(query, reactionID.String())
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, reactionID.String())
 	if err != nil {
 		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
@@ -232,10 +233,10 @@ func (r *SQLiteCommentReactionRepository) Delete(reactionID uuid.UUID) error {
 	return nil
 }
 
-func (r *SQLiteCommentReactionRepository) DeleteByUserAndComment(userID, commentID uuid.UUID) error {
+func (r *SQLiteCommentReactionRepository) DeleteByUserAndComment(ctx context.Context, userID, commentID uuid.UUID) error {
 	query := `DELETE FROM comment_reaction WHERE user_id = ? AND comment_id = ?`
 
-	result, err := r.db.Exec(query, userID.String(), commentID.String())
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, userID.String(), commentID.String())
 	if err != nil {
 		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
@@ -252,46 +253,45 @@ func (r *SQLiteCommentReactionRepository) DeleteByUserAndComment(userID, comment
 	return nil
 }
 
-func (r *SQLiteCommentReactionRepository) GetLikeCountByCommentID(commentID uuid.UUID) (int, error) {
+func (r *SQLiteCommentReactionRepository) GetLikeCountByCommentID(ctx context.Context, commentID uuid.UUID) (int, error) {
 	query := `SELECT COUNT(*) FROM comment_reaction WHERE comment_id = ? AND reaction = 1`
 
 	var count int
-	err := r.db.QueryRow(query, commentID.String()).Scan(&count)
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, commentID.String()).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
 	return count, nil
 }
 
-func (r *SQLiteCommentReactionRepository) GetDislikeCountByCommentID(commentID uuid.UUID) (int, error) {
+func (r *SQLiteCommentReactionRepository) GetDislikeCountByCommentID(ctx context.Context, commentID uuid.UUID) (int, error) {
 	query := `SELECT COUNT(*) FROM comment_reaction WHERE comment_id = ? AND reaction = 0`
 
 	var count int
-	err := r.db.QueryRow(query, commentID.String()).Scan(&count)
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, commentID.String()).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
 	return count, nil
 }
 
-func (r *SQLiteCommentReactionRepository) GetReactionCountsByCommentID(commentID uuid.UUID) (likes int, dislikes int, err error) {
-	query := `SELECT 
-				COALESCE(SUM(CASE WHEN reaction = 1 THEN 1 ELSE 0 END), 0) as likes,
-				COALESCE(SUM(CASE WHEN reaction = 0 THEN 1 ELSE 0 END), 0) as dislikes
-			  FROM comment_reaction WHERE comment_id = ?`
+func (r *SQLiteCommentReactionRepository) GetReactionCountsByCommentID(ctx context.Context, commentID uuid.UUID) (likes int, dislikes int, err error) {
+	query := `SELECT like_count, dislike_count FROM comments WHERE id = ?`
 
-	err = r.db.QueryRow(query, commentID.String()).Scan(&likes, &dislikes)
-	if err != nil {
+	err = conn(ctx, r.db).QueryRowContext(ctx, query, commentID.String()).Scan(&likes, &dislikes)
+	if err == sql.ErrNoRows {
+		return 0, 0, nil
+	} else if err != nil {
 		return 0, 0, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
 	return likes, dislikes, nil
 }
 
-func (r *SQLiteCommentReactionRepository) HasUserReacted(userID, commentID uuid.UUID) (bool, *bool, error) {
+func (r *SQLiteCommentReactionRepository) HasUserReacted(ctx context.Context, userID, commentID uuid.UUID) (bool, *bool, error) {
 	query := `SELECT reaction FROM comment_reaction WHERE user_id = ? AND comment_id = ?`
 
 	var reaction bool
-	err := r.db.QueryRow(query, userID.String(), commentID.String()).Scan(&reaction)
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, userID.String(), commentID.String()).Scan(&reaction)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return false, nil, nil
@@ -301,3 +301,7 @@ func (r *SQLiteCommentReactionRepository) HasUserReacted(userID, commentID uuid.
 
 	return true, &reaction, nil
 }
+
+func (r *SQLiteCommentReactionRepository) Toggle(ctx context.Context, userID, commentID uuid.UUID, like bool) (*entity.ReactionState, error) {
+	return commentReactions.toggle(ctx, r.db, userID, commentID, like)
+}
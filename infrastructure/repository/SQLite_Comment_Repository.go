@@ -1,6 +1,7 @@
 package infra_repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -16,44 +17,56 @@ type SQLiteCommentRepository struct {
 	db *sql.DB
 	userRepo         repository.UserRepository
 	commentReaction	repository.CommentReactionRepository
+	maxDepth         int
 }
 
+// NewSQLiteCommentRepository builds the comment repository. maxDepth is the
+// deepest reply level that still accepts replies when threads are built.
 func NewSQLiteCommentRepository(
 	db *sql.DB,
 	userRepo *repository.UserRepository,
 	commentReaction	*repository.CommentReactionRepository,
+	maxDepth int,
 	) repository.CommentRepository {
 	return &SQLiteCommentRepository{
 		db: db,
 		userRepo:  *userRepo,
 		commentReaction: *commentReaction,
+		maxDepth: maxDepth,
 	}
 }
 
-func (r *SQLiteCommentRepository) Create(comment *entity.Comment) error {
+func (r *SQLiteCommentRepository) Create(ctx context.Context, comment *entity.Comment) error {
 	comment.ID = uuid.New()
 	comment.CreatedAt = time.Now()
 
-	query := `INSERT INTO comments (id, content, user_id, post_id, createdat)
-			  VALUES (?, ?, ?, ?, ?)`
+	var parentID interface{}
+	if comment.ParentID != nil {
+		parentID = comment.ParentID.String()
+	}
+
+	query := `INSERT INTO comments (id, content, user_id, post_id, parent_id, createdat)
+			  VALUES (?, ?, ?, ?, ?, ?)`
 
-	_, err := r.db.Exec(query, comment.ID.String(), comment.Content,
-		comment.UserID.String(), comment.PostID.String(), comment.CreatedAt)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, comment.ID.String(), comment.Content,
+		comment.UserID.String(), comment.PostID.String(), parentID, comment.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
 	return nil
 }
 
-func (r *SQLiteCommentRepository) GetByID(commentID uuid.UUID) (*entity.Comment, error) {
-	query := `SELECT id, content, user_id, post_id, createdat FROM comments WHERE id = ?`
+func (r *SQLiteCommentRepository) GetByID(ctx context.Context, commentID uuid.UUID) (*entity.Comment, error) {
+	query := `SELECT id, content, user_id, post_id, parent_id, createdat, updated_at, deleted_at FROM comments WHERE id = ?`
 
-	row := r.db.QueryRow(query, commentID.String())
+	row := conn(ctx, r.db).QueryRowContext(ctx, query, commentID.String())
 
 	comment := &entity.Comment{}
 	var idStr, userIDStr, postIDStr string
+	var parentIDStr sql.NullString
+	var updatedAt, deletedAt sql.NullTime
 
-	err := row.Scan(&idStr, &comment.Content, &userIDStr, &postIDStr, &comment.CreatedAt)
+	err := row.Scan(&idStr, &comment.Content, &userIDStr, &postIDStr, &parentIDStr, &comment.CreatedAt, &updatedAt, &deletedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, custom_errors.ErrCommentNotFound
@@ -76,14 +89,29 @@ func (r *SQLiteCommentRepository) GetByID(commentID uuid.UUID) (*entity.Comment,
 		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
 
+	if parentIDStr.Valid {
+		parentID, err := uuid.Parse(parentIDStr.String)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+		}
+		comment.ParentID = &parentID
+	}
+
+	if updatedAt.Valid {
+		comment.UpdatedAt = &updatedAt.Time
+	}
+	if deletedAt.Valid {
+		comment.DeletedAt = &deletedAt.Time
+	}
+
 	return comment, nil
 }
 
-func (r *SQLiteCommentRepository) GetByPostID(postID uuid.UUID) ([]entity.Comment, error) {
-	query := `SELECT id, content, user_id, post_id, createdat 
+func (r *SQLiteCommentRepository) GetByPostID(ctx context.Context, postID uuid.UUID) ([]entity.Comment, error) {
+	query := `SELECT id, content, user_id, post_id, parent_id, createdat, updated_at, deleted_at 
 			  FROM comments WHERE post_id = ? ORDER BY createdat ASC`
 
-	rows, err := r.db.Query(query, postID.String())
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, postID.String())
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
@@ -94,8 +122,10 @@ func (r *SQLiteCommentRepository) GetByPostID(postID uuid.UUID) ([]entity.Commen
 	for rows.Next() {
 		comment := entity.Comment{}
 		var idStr, userIDStr, postIDStr string
+		var parentIDStr sql.NullString
+		var updatedAt, deletedAt sql.NullTime
 
-		err := rows.Scan(&idStr, &comment.Content, &userIDStr, &postIDStr, &comment.CreatedAt)
+		err := rows.Scan(&idStr, &comment.Content, &userIDStr, &postIDStr, &parentIDStr, &comment.CreatedAt, &updatedAt, &deletedAt)
 		if err != nil {
 			return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 		}
@@ -115,17 +145,32 @@ func (r *SQLiteCommentRepository) GetByPostID(postID uuid.UUID) ([]entity.Commen
 			return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 		}
 
+		if parentIDStr.Valid {
+			parentID, err := uuid.Parse(parentIDStr.String)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+			}
+			comment.ParentID = &parentID
+		}
+
+		if updatedAt.Valid {
+			comment.UpdatedAt = &updatedAt.Time
+		}
+		if deletedAt.Valid {
+			comment.DeletedAt = &deletedAt.Time
+		}
+
 		comments = append(comments, comment)
 	}
 
 	return comments, nil
 }
 
-func (r *SQLiteCommentRepository) GetByUserID(userID uuid.UUID) ([]*entity.Comment, error) {
-	query := `SELECT id, content, user_id, post_id, createdat 
+func (r *SQLiteCommentRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.Comment, error) {
+	query := `SELECT id, content, user_id, post_id, parent_id, createdat, updated_at, deleted_at 
 			  FROM comments WHERE user_id = ? ORDER BY createdat DESC`
 
-	rows, err := r.db.Query(query, userID.String())
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID.String())
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
@@ -136,8 +181,10 @@ func (r *SQLiteCommentRepository) GetByUserID(userID uuid.UUID) ([]*entity.Comme
 	for rows.Next() {
 		comment := &entity.Comment{}
 		var idStr, userIDStr, postIDStr string
+		var parentIDStr sql.NullString
+		var updatedAt, deletedAt sql.NullTime
 
-		err := rows.Scan(&idStr, &comment.Content, &userIDStr, &postIDStr, &comment.CreatedAt)
+		err := rows.Scan(&idStr, &comment.Content, &userIDStr, &postIDStr, &parentIDStr, &comment.CreatedAt, &updatedAt, &deletedAt)
 		if err != nil {
 			return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 		}
@@ -157,17 +204,32 @@ func (r *SQLiteCommentRepository) GetByUserID(userID uuid.UUID) ([]*entity.Comme
 			return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 		}
 
+		if parentIDStr.Valid {
+			parentID, err := uuid.Parse(parentIDStr.String)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+			}
+			comment.ParentID = &parentID
+		}
+
+		if updatedAt.Valid {
+			comment.UpdatedAt = &updatedAt.Time
+		}
+		if deletedAt.Valid {
+			comment.DeletedAt = &deletedAt.Time
+		}
+
 		comments = append(comments, comment)
 	}
 
 	return comments, nil
 }
 
-func (r *SQLiteCommentRepository) GetByPostIDWithPagination(postID uuid.UUID, limit, offset int) ([]*entity.Comment, error) {
-	query := `SELECT id, content, user_id, post_id, createdat 
+func (r *SQLiteCommentRepository) GetByPostIDWithPagination(ctx context.Context, postID uuid.UUID, limit, offset int) ([]*entity.Comment, error) {
+	query := `SELECT id, content, user_id, post_id, parent_id, createdat, updated_at, deleted_at 
 			  FROM comments WHERE post_id = ? ORDER BY createdat ASC LIMIT ? OFFSET ?`
 
-	rows, err := r.db.Query(query, postID.String(), limit, offset)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, postID.String(), limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
@@ -178,8 +240,10 @@ func (r *SQLiteCommentRepository) GetByPostIDWithPagination(postID uuid.UUID, li
 	for rows.Next() {
 		comment := &entity.Comment{}
 		var idStr, userIDStr, postIDStr string
+		var parentIDStr sql.NullString
+		var updatedAt, deletedAt sql.NullTime
 
-		err := rows.Scan(&idStr, &comment.Content, &userIDStr, &postIDStr, &comment.CreatedAt)
+		err := rows.Scan(&idStr, &comment.Content, &userIDStr, &postIDStr, &parentIDStr, &comment.CreatedAt, &updatedAt, &deletedAt)
 		if err != nil {
 			return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 		}
@@ -199,16 +263,34 @@ func (r *SQLiteCommentRepository) GetByPostIDWithPagination(postID uuid.UUID, li
 			return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 		}
 
+		if parentIDStr.Valid {
+			parentID, err := uuid.Parse(parentIDStr.String)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+			}
+			comment.ParentID = &parentID
+		}
+
+		if updatedAt.Valid {
+			comment.UpdatedAt = &updatedAt.Time
+		}
+		if deletedAt.Valid {
+			comment.DeletedAt = &deletedAt.Time
+		}
+
 		comments = append(comments, comment)
 	}
 
 	return comments, nil
 }
 
-func (r *SQLiteCommentRepository) Update(comment *entity.Comment) error {
-	query := `UPDATE comments SET content = ? WHERE id = ?`
+func (r *SQLiteCommentRepository) Update(ctx context.Context, comment *entity.Comment) error {
+	now := time.Now()
+	comment.UpdatedAt = &now
 
-	result, err := r.db.Exec(query, comment.Content, comment.ID.String())
+	query := `UPDATE comments SET content = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, comment.Content, comment.UpdatedAt, comment.ID.String())
 	if err != nil {
 		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
@@ -225,10 +307,10 @@ func (r *SQLiteCommentRepository) Update(comment *entity.Comment) error {
 	return nil
 }
 
-func (r *SQLiteCommentRepository) Delete(commentID uuid.UUID) error {
+func (r *SQLiteCommentRepository) Delete(ctx context.Context, commentID uuid.UUID) error {
 	query := `DELETE FROM comments WHERE id = ?`
 
-	result, err := r.db.Exec(query, commentID.String())
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, commentID.String())
 	if err != nil {
 		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
@@ -245,30 +327,54 @@ func (r *SQLiteCommentRepository) Delete(commentID uuid.UUID) error {
 	return nil
 }
 
-func (r *SQLiteCommentRepository) GetCountByPostID(postID uuid.UUID) (int, error) {
-	query := `SELECT COUNT(*) FROM comments WHERE post_id = ?`
+// SoftDelete turns a comment into a tombstone: its content is wiped and it is
+// marked as deleted, but the row stays so the conversation keeps its shape.
+func (r *SQLiteCommentRepository) SoftDelete(ctx context.Context, commentID uuid.UUID) error {
+	query := `UPDATE comments SET content = '', deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
 
-	var count int
-	err := r.db.QueryRow(query, postID.String()).Scan(&count)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, time.Now(), commentID.String())
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+
+	if rowsAffected == 0 {
+		return custom_errors.ErrCommentNotFound
+	}
+
+	return nil
+}
+
+func (r *SQLiteCommentRepository) GetCountByPostID(ctx context.Context, postID uuid.UUID) (int, error) {
+	query := `SELECT comment_count FROM posts WHERE id = ?`
+
+	var count int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, postID.String()).Scan(&count)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
 		return 0, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
 	return count, nil
 }
 
-func (r *SQLiteCommentRepository) GetCountByUserID(userID uuid.UUID) (int, error) {
+func (r *SQLiteCommentRepository) GetCountByUserID(ctx context.Context, userID uuid.UUID) (int, error) {
 	query := `SELECT COUNT(*) FROM comments WHERE user_id = ?`
 
 	var count int
-	err := r.db.QueryRow(query, userID.String()).Scan(&count)
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, userID.String()).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
 	return count, nil
 }
 
-func (r *SQLiteCommentRepository) GetWithDetails(commentID uuid.UUID) (*entity.CommentWithDetails, error) {
-	comment, err := r.GetByID(commentID)
+func (r *SQLiteCommentRepository) GetWithDetails(ctx context.Context, commentID uuid.UUID) (*entity.CommentWithDetails, error) {
+	comment, err := r.GetByID(ctx, commentID)
 	if err != nil {
 		return nil, err
 	}
@@ -278,29 +384,144 @@ func (r *SQLiteCommentRepository) GetWithDetails(commentID uuid.UUID) (*entity.C
 	}, nil
 }
 
-func (r *SQLiteCommentRepository) GetByPostIDWithDetails(postID uuid.UUID) ([]entity.CommentWithDetails, error) {
-	comments, err := r.GetByPostID(postID)
+func (r *SQLiteCommentRepository) GetByPostIDWithDetails(ctx context.Context, postID uuid.UUID) ([]entity.CommentWithDetails, error) {
+	comments, err := r.GetByPostIDsWithDetails(ctx, []uuid.UUID{postID})
 	if err != nil {
 		return nil, err
 	}
+	return comments[postID], nil
+}
 
-	var commentsWithDetails []entity.CommentWithDetails
-	for _, comment := range comments {
-		user,err:=r.userRepo.GetByID(comment.UserID)
-		if err != nil {
-			fmt.Printf("comment user error ")
+// GetByPostIDsWithDetails loads the comments of several posts, with their
+// authors, in a single query and threads each post's
+// comments.
+func (r *SQLiteCommentRepository) GetByPostIDsWithDetails(ctx context.Context, postIDs []uuid.UUID) (map[uuid.UUID][]entity.CommentWithDetails, error) {
+	result := make(map[uuid.UUID][]entity.CommentWithDetails)
+	if len(postIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders, args := inClause(postIDs)
+	query := `SELECT ` + commentWithDetailsColumns + `
+			  FROM comments c
+			  INNER JOIN user u ON u.id = c.user_id
+			  WHERE c.post_id IN (` + placeholders + `)
+			  ORDER BY c.createdat ASC, c.id ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var details entity.CommentWithDetails
+		if err := scanCommentWithDetails(rows, &details); err != nil {
+			return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 		}
-		likes,dislikes,err:=r.commentReaction.GetReactionCountsByCommentID(comment.ID)
+		result[details.PostID] = append(result[details.PostID], details)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+
+	for postID, comments := range result {
+		result[postID] = entity.ThreadComments(comments, r.maxDepth)
+	}
+	return result, nil
+}
+
+// commentWithDetailsColumns are the columns that scanCommentWithDetails reads,
+// from comments c joined with their authors u.
+const commentWithDetailsColumns = `c.id, c.content, c.user_id, c.post_id, c.parent_id, c.createdat, c.updated_at, c.deleted_at,
+				c.like_count, c.dislike_count, c.comment_count,
+				u.user_name, u.email, u.role, u.created_at`
+
+// scanCommentWithDetails reads the commentWithDetailsColumns of a row,
+// followed by any extra columns into extra.
+func scanCommentWithDetails(rows *sql.Rows, details *entity.CommentWithDetails, extra ...interface{}) error {
+	comment := &details.Comment
+	var idStr, userIDStr, postIDStr string
+	var parentIDStr sql.NullString
+	var updatedAt, deletedAt sql.NullTime
+
+	dest := []interface{}{&idStr, &comment.Content, &userIDStr, &postIDStr, &parentIDStr, &comment.CreatedAt, &updatedAt, &deletedAt,
+		&details.LikeCount, &details.DislikeCount, &details.ReplyCount,
+		&details.Author.UserName, &details.Author.Email, &details.Author.Role, &details.Author.CreatedAt}
+	err := rows.Scan(append(dest, extra...)...)
+	if err != nil {
+		return err
+	}
+
+	comment.ID, err = uuid.Parse(idStr)
+	if err != nil {
+		return err
+	}
+
+	comment.UserID, err = uuid.Parse(userIDStr)
+	if err != nil {
+		return err
+	}
+	details.Author.ID = comment.UserID
+
+	comment.PostID, err = uuid.Parse(postIDStr)
+	if err != nil {
+		return err
+	}
+
+	if parentIDStr.Valid {
+		parentID, err := uuid.Parse(parentIDStr.String)
 		if err != nil {
-			fmt.Printf("comment user fell error ")
+			return err
 		}
-		commentsWithDetails = append(commentsWithDetails, entity.CommentWithDetails{
-			Comment: comment,
-			Author: *user,
-			LikeCount: likes,
-			DislikeCount: dislikes,
-		})
+		comment.ParentID = &parentID
 	}
 
-	return commentsWithDetails, nil
+	if updatedAt.Valid {
+		comment.UpdatedAt = &updatedAt.Time
+	}
+	if deletedAt.Valid {
+		comment.DeletedAt = &deletedAt.Time
+	}
+	return nil
+}
+
+// commentOrder orders comments newest first, like postOrder does for posts.
+var commentOrder = []sortKey{
+	{"c.createdat", func(c *entity.PostCursor) (string, []interface{}) {
+		return "COALESCE((SELECT createdat FROM comments WHERE id = ?), ?)", []interface{}{c.ID.String(), c.CreatedAt}
+	}},
+	{"c.id", func(c *entity.PostCursor) (string, []interface{}) {
+		return "?", []interface{}{c.ID.String()}
+	}},
+}
+
+func (r *SQLiteCommentRepository) GetByUserIDWithDetails(ctx context.Context, userID uuid.UUID, page entity.PageRequest) (*entity.CommentPage, error) {
+	condition, order, args := keysetCondition(page, commentOrder)
+	query := `SELECT ` + commentWithDetailsColumns + `, p.title
+			  FROM comments c
+			  INNER JOIN user u ON u.id = c.user_id
+			  INNER JOIN posts p ON p.id = c.post_id
+			  WHERE c.user_id = ? AND c.deleted_at IS NULL AND ` + condition + `
+			  ORDER BY ` + order + ` LIMIT ?`
+	args = append([]interface{}{userID.String()}, args...)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, append(args, page.Size()+1)...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var comments []*entity.UserComment
+	for rows.Next() {
+		comment := &entity.UserComment{}
+		if err := scanCommentWithDetails(rows, &comment.CommentWithDetails, &comment.PostTitle); err != nil {
+			return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return entity.NewCommentPage(page, comments), nil
 }
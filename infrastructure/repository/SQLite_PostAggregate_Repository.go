@@ -1,12 +1,15 @@
 package infra_repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
 	"forum/domain/repository"
 
 	"github.com/google/uuid"
@@ -19,6 +22,7 @@ type SQLitePostAggregateRepository struct {
 	userRepo         repository.UserRepository
 	reactionRepo     repository.PostReactionRepository
 	commentRepo      repository.CommentRepository
+	attachmentRepo   repository.PostAttachmentRepository
 }
 
 func NewSQLitePostAggregateRepository(
@@ -28,6 +32,7 @@ func NewSQLitePostAggregateRepository(
 	userRepo *repository.UserRepository,
 	reactionRepo *repository.PostReactionRepository,
 	commentRepo *repository.CommentRepository,
+	attachmentRepo *repository.PostAttachmentRepository,
 ) repository.PostAggregateRepository {
 	return &SQLitePostAggregateRepository{
 		db:               db,
@@ -36,89 +41,343 @@ func NewSQLitePostAggregateRepository(
 		userRepo:         *userRepo,
 		reactionRepo:     *reactionRepo,
 		commentRepo:      *commentRepo,
+		attachmentRepo:   *attachmentRepo,
 	}
 }
 
 // CreatePostWithCategories creates a post and associates it with categories
-func (r *SQLitePostAggregateRepository) CreatePostWithCategories(post *entity.Post, categoryIDs []*uuid.UUID) error {
-	tx, err := r.db.Begin()
-	if err != nil {
-		return err
+func (r *SQLitePostAggregateRepository) CreatePostWithCategories(ctx context.Context, post *entity.Post, categoryIDs []*uuid.UUID) error {
+	return inTx(ctx, r.db, func(ctx context.Context) error {
+		err := r.postRepo.Create(ctx, post)
+		if err != nil {
+			return err
+		}
+
+		// Associate categories
+		for _, categoryID := range categoryIDs {
+			postCategory := &entity.PostCategory{
+				PostID:     post.ID,
+				CategoryID: *categoryID,
+			}
+			err = r.postCategoryRepo.Create(ctx, postCategory)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// sortKey is one column of a post ordering, with the SQL value of that
+// column at a page cursor.
+type sortKey struct {
+	column string
+	bound  func(cursor *entity.PostCursor) (string, []interface{})
+}
+
+var (
+	// The cursor post's own timestamp is looked up so that it compares
+	// exactly as stored; the one in the cursor is only used if that post has
+	// been deleted since.
+	createdAtKey = sortKey{"p.created_at", func(c *entity.PostCursor) (string, []interface{}) {
+		return "COALESCE((SELECT created_at FROM posts WHERE id = ?), ?)", []interface{}{c.ID.String(), c.CreatedAt}
+	}}
+	idKey = sortKey{"p.id", func(c *entity.PostCursor) (string, []interface{}) {
+		return "?", []interface{}{c.ID.String()}
+	}}
+	// scoreKey refers to the score column added by sortScore.
+	scoreKey = sortKey{"score", func(c *entity.PostCursor) (string, []interface{}) {
+		return "?", []interface{}{c.Score}
+	}}
+)
+
+// postOrder orders posts by the given keys, then newest first. The id comes
+// last so that no two posts ever tie and every ordering is deterministic.
+func postOrder(keys ...sortKey) []sortKey {
+	return append(keys, createdAtKey, idKey)
+}
+
+// keysetCondition returns the WHERE condition and ORDER BY clause that select
+// the posts after (or before) the page cursor, for posts sorted on keys in
+// descending order.
+func keysetCondition(page entity.PageRequest, keys []sortKey) (string, string, []interface{}) {
+	direction := "DESC"
+	if page.Cursor != nil && page.Cursor.Backward {
+		direction = "ASC"
 	}
-	defer tx.Rollback()
 
-	err = r.postRepo.Create(post)
-	if err != nil {
-		return err
+	columns := make([]string, len(keys))
+	order := make([]string, len(keys))
+	for i, key := range keys {
+		columns[i] = key.column
+		order[i] = key.column + " " + direction
+	}
+	if page.Cursor == nil {
+		return "1=1", strings.Join(order, ", "), nil
 	}
 
-	// Associate categories
-	for _, categoryID := range categoryIDs {
-		postCategory := &entity.PostCategory{
-			PostID:     post.ID,
-			CategoryID: *categoryID,
-		}
-		err = r.postCategoryRepo.Create(postCategory)
-		if err != nil {
-			return err
-		}
+	bounds := make([]string, len(keys))
+	var args []interface{}
+	for i, key := range keys {
+		bound, boundArgs := key.bound(page.Cursor)
+		bounds[i] = bound
+		args = append(args, boundArgs...)
 	}
 
-	return tx.Commit()
+	comparison := " < "
+	if page.Cursor.Backward {
+		comparison = " > "
+	}
+	condition := "(" + strings.Join(columns, ", ") + ")" + comparison + "(" + strings.Join(bounds, ", ") + ")"
+	return condition, strings.Join(order, ", "), args
+}
+
+// julianDay converts t to the day number used by SQLite's julianday().
+func julianDay(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/(24*float64(time.Hour))+2440587.5, 'f', -1, 64)
 }
 
-func (r *SQLitePostAggregateRepository) GetFeedForUser() ([]*entity.PostWithDetails, error) {
-	posts, err := r.postRepo.GetAll()
+// sortScore returns the SQL expression that a sort mode ranks posts by, or
+// an empty string for SortNew. The expressions use the stored reaction
+// counters and measure ages from now, which is inlined as a number so the
+// expression can be referenced by alias anywhere in the query.
+func sortScore(mode entity.SortMode, now time.Time) string {
+	likes := "p.like_count"
+	dislikes := "p.dislike_count"
+
+	switch mode {
+	case entity.SortTop:
+		return likes + " - " + dislikes
+	case entity.SortHot:
+		// Net votes divided by (age in hours + 2)^2, so new posts need few
+		// votes to rise and old ones sink steadily.
+		age := "((" + julianDay(now) + " - julianday(p.created_at)) * 24 + 2)"
+		return "(" + likes + " - " + dislikes + ") * 1.0 / (" + age + " * " + age + ")"
+	case entity.SortControversial:
+		// Many votes, split as evenly as possible.
+		return "CASE WHEN " + likes + " = 0 OR " + dislikes + " = 0 THEN 0.0 ELSE (" + likes + " + " + dislikes + ") * 1.0 * MIN(" +
+			likes + ", " + dislikes + ") / MAX(" + likes + ", " + dislikes + ") END"
+	}
+	return ""
+}
+
+// postWithAuthorColumns are the columns read by scanPostWithAuthor, for a
+// query on posts p joined with their author u.
+const postWithAuthorColumns = `p.id, p.title, p.content, p.user_id, p.created_at, p.updated_at,
+			p.like_count, p.dislike_count, p.comment_count,
+			u.id as author_id, u.user_name, u.email, u.role, u.created_at as user_created_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPostWithAuthor reads the postWithAuthorColumns of a row, followed by
+// any extra columns into extra.
+func scanPostWithAuthor(row rowScanner, extra ...interface{}) (*entity.PostWithDetails, error) {
+	var postID, postUserID, authorID string
+	var post entity.Post
+	var author entity.User
+	var updatedAt sql.NullTime
+	var likes, dislikes, comments int
+
+	dest := []interface{}{
+		&postID, &post.Title, &post.Content, &postUserID, &post.CreatedAt, &updatedAt,
+		&likes, &dislikes, &comments,
+		&authorID, &author.UserName, &author.Email, &author.Role, &author.CreatedAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
 	if err != nil {
 		return nil, err
 	}
-	postWithDetails := make([]*entity.PostWithDetails, 0, len(posts))
-	for _, post := range posts {
-		p, err := r.GetPostWithAllDetails(post.ID)
+
+	post.ID, _ = uuid.Parse(postID)
+	post.UserID, _ = uuid.Parse(postUserID)
+	author.ID, _ = uuid.Parse(authorID)
+	if updatedAt.Valid {
+		post.UpdatedAt = &updatedAt.Time
+	}
+
+	return &entity.PostWithDetails{
+		Post:         post,
+		Author:       author,
+		LikeCount:    likes,
+		DislikeCount: dislikes,
+		CommentCount: comments,
+		IsEdited:     post.UpdatedAt != nil,
+	}, nil
+}
+
+func (r *SQLitePostAggregateRepository) queryPostsWithAuthors(ctx context.Context, query string, args ...interface{}) ([]*entity.PostWithDetails, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var posts []*entity.PostWithDetails
+	for rows.Next() {
+		post, err := scanPostWithAuthor(rows)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 		}
-		postWithDetails = append(postWithDetails, p)
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
-	return postWithDetails, nil
+	return posts, nil
 }
 
-func (r *SQLitePostAggregateRepository) GetPostWithAllDetails(postID uuid.UUID) (*entity.PostWithDetails, error) {
-	post, err := r.postRepo.GetByID(postID)
+// loadPostDetails fills in the categories, comments and attachments of a set of posts. It runs one query for each kind of detail,
+// however many posts there are.
+func (r *SQLitePostAggregateRepository) loadPostDetails(ctx context.Context, posts []*entity.PostWithDetails) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	categories, err := r.postCategoryRepo.GetCategoriesByPostIDs(ctx, ids)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	author, err := r.userRepo.GetByID(post.UserID)
+	comments, err := r.commentRepo.GetByPostIDsWithDetails(ctx, ids)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	categories, err := r.postCategoryRepo.GetCategoriesByPostID(postID)
+	attachments, err := r.attachmentRepo.GetByPostIDs(ctx, ids)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	for _, post := range posts {
+		post.Categories = categories[post.ID]
+		post.Comments = comments[post.ID]
+		post.Attachments = attachments[post.ID]
 	}
+	return nil
+}
+
+// GetFeedForUser returns one page of the feed, newest posts first.
+func (r *SQLitePostAggregateRepository) GetFeedForUser(ctx context.Context, page entity.PageRequest) (*entity.PostPage, error) {
+	condition, order, args := keysetCondition(page, postOrder())
+	query := "SELECT " + postWithAuthorColumns + `
+		FROM posts p
+		INNER JOIN user u ON p.user_id = u.id
+		WHERE ` + condition + " ORDER BY " + order + " LIMIT ?"
 
-	likes, dislikes, err := r.reactionRepo.GetReactionCountsByPostID(postID)
+	posts, err := r.queryPostsWithAuthors(ctx, query, append(args, page.Size()+1)...)
 	if err != nil {
-		fmt.Println("reaction count")
 		return nil, err
 	}
 
-	comments, err := r.commentRepo.GetByPostIDWithDetails(postID)
-	if err != nil {
-		fmt.Println("comments error")
+	result := entity.NewPostPage(page, posts, nil, time.Time{})
+	if err := r.loadPostDetails(ctx, result.Posts); err != nil {
 		return nil, err
 	}
+	return result, nil
+}
 
-	return &entity.PostWithDetails{
-		Post:         *post,
-		Author:       *author,
-		Comments:     comments,
-		Categories:   categories,
-		LikeCount:    likes,
-		DislikeCount: dislikes,
-	}, nil
+func (r *SQLitePostAggregateRepository) GetPostWithAllDetails(ctx context.Context, postID uuid.UUID) (*entity.PostWithDetails, error) {
+	query := "SELECT " + postWithAuthorColumns + `
+		FROM posts p
+		INNER JOIN user u ON p.user_id = u.id
+		WHERE p.id = ?`
+
+	post, err := scanPostWithAuthor(conn(ctx, r.db).QueryRowContext(ctx, query, postID.String()))
+	if err == sql.ErrNoRows {
+		return nil, custom_errors.ErrPostNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+
+	if err := r.loadPostDetails(ctx, []*entity.PostWithDetails{post}); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// UpdatePostWithRevision stores the current version of a post in
+// post_revisions and then overwrites it, all inside one transaction.
+func (r *SQLitePostAggregateRepository) UpdatePostWithRevision(ctx context.Context, post *entity.Post) error {
+	now := time.Now()
+	err := inTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO post_revisions (id, post_id, version, title, content, created_at)
+			SELECT ?, id,
+				(SELECT COUNT(*) + 1 FROM post_revisions WHERE post_id = posts.id),
+				title, content, COALESCE(updated_at, created_at)
+			FROM posts WHERE id = ?`,
+			uuid.New().String(), post.ID.String())
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, `UPDATE posts SET title = ?, content = ?, updated_at = ? WHERE id = ?`,
+			post.Title, post.Content, now, post.ID.String())
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return custom_errors.ErrPostNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	post.UpdatedAt = &now
+	return nil
+}
+
+// DeletePostWithDependencies removes a post together with everything that
+// references it. The schema has no ON DELETE CASCADE, so the dependent rows
+// are deleted explicitly, children first, inside one transaction.
+func (r *SQLitePostAggregateRepository) DeletePostWithDependencies(ctx context.Context, postID uuid.UUID) error {
+	id := postID.String()
+	dependents := []string{
+		`DELETE FROM comment_reaction WHERE comment_id IN (SELECT id FROM comments WHERE post_id = ?)`,
+		`DELETE FROM comments WHERE post_id = ?`,
+		`DELETE FROM post_reaction WHERE post_id = ?`,
+		`DELETE FROM post_categories WHERE post_id = ?`,
+		`DELETE FROM post_revisions WHERE post_id = ?`,
+		`DELETE FROM post_attachments WHERE post_id = ?`,
+	}
+
+	return inTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+		for _, query := range dependents {
+			if _, err := tx.ExecContext(ctx, query, id); err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.ExecContext(ctx, `DELETE FROM posts WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return custom_errors.ErrPostNotFound
+		}
+		return nil
+	})
 }
 
 type SQLiteUserAggregateRepository struct {
@@ -140,30 +399,23 @@ func NewSQLiteUserAggregateRepository(
 }
 
 // CreateUserSession creates a new session for a user
-func (r *SQLiteUserAggregateRepository) CreateUserSession(user *entity.User) (*entity.UserSession, error) {
-	tx, err := r.db.Begin()
-	if err != nil {
-		return nil, err
-	}
-	defer tx.Rollback()
-
+func (r *SQLiteUserAggregateRepository) CreateUserSession(ctx context.Context, user *entity.User) (*entity.UserSession, error) {
 	session := &entity.UserSession{
 		UserID:       user.ID,
 		SessionToken: uuid.New().String(),
 		ExpiresAt:    time.Now().Add(24 * time.Hour),
 	}
 
-	err = r.sessionRepo.Create(session)
+	err := r.sessionRepo.Create(ctx, session)
 	if err != nil {
 		return nil, err
 	}
-
-	return session, tx.Commit()
+	return session, nil
 }
 
 // AuthenticateUser verifies user credentials and creates a session
-func (r *SQLiteUserAggregateRepository) AuthenticateUser(email, password string) (*entity.User, *entity.UserSession, error) {
-	user, err := r.userRepo.GetByEmail(email)
+func (r *SQLiteUserAggregateRepository) AuthenticateUser(ctx context.Context, email, password string) (*entity.User, *entity.UserSession, error) {
+	user, err := r.userRepo.GetByEmail(ctx, email)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -175,7 +427,7 @@ func (r *SQLiteUserAggregateRepository) AuthenticateUser(email, password string)
 		ExpiresAt:    time.Now().Add(24 * time.Hour),
 	}
 
-	err = r.sessionRepo.Create(session)
+	err = r.sessionRepo.Create(ctx, session)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -183,53 +435,58 @@ func (r *SQLiteUserAggregateRepository) AuthenticateUser(email, password string)
 	return user, session, nil
 }
 
-func (r *SQLitePostAggregateRepository) GetPostsWithDetailsByUser(userID uuid.UUID) ([]*entity.PostWithDetails, error) {
-	posts, err := r.postRepo.GetbyuserId(userID)
+func (r *SQLitePostAggregateRepository) GetPostsWithDetailsByUser(ctx context.Context, userID uuid.UUID) ([]*entity.PostWithDetails, error) {
+	query := "SELECT " + postWithAuthorColumns + `
+		FROM posts p
+		INNER JOIN user u ON p.user_id = u.id
+		WHERE p.user_id = ?
+		ORDER BY p.created_at DESC, p.id DESC`
+
+	posts, err := r.queryPostsWithAuthors(ctx, query, userID.String())
 	if err != nil {
 		return nil, err
 	}
 
-	var postsWithDetails []*entity.PostWithDetails
-	for _, post := range posts {
-		pwd, err := r.GetPostWithAllDetails(post.ID)
-		if err != nil {
-			return nil, err
-		}
-		postsWithDetails = append(postsWithDetails, pwd)
+	if err := r.loadPostDetails(ctx, posts); err != nil {
+		return nil, err
 	}
-
-	return postsWithDetails, nil
+	return posts, nil
 }
 
-func (r *SQLitePostAggregateRepository) GetFilteredPostsWithDetails(filter entity.PostFilter) ([]*entity.PostWithDetails, error) {
-	query := `
-		SELECT DISTINCT 
-			p.id, p.content, p.user_id, p.created_at,
-			u.id as author_id, u.user_name, u.email, u.created_at as user_created_at
-		FROM posts p
-		INNER JOIN user u ON p.user_id = u.id
-		INNER JOIN post_categories pc ON p.id = pc.post_id
-	`
+func (r *SQLitePostAggregateRepository) GetFilteredPostsWithDetails(ctx context.Context, filter entity.PostFilter, page entity.PageRequest) (*entity.PostPage, error) {
+	now := filter.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
 
-	conditions := []string{}
-	args := []interface{}{}
+	// Every condition below matches a post at most once, so each row is a
+	// distinct post and the rows come back exactly in the ORDER BY order.
+	columns := postWithAuthorColumns
+	joins := " INNER JOIN user u ON p.user_id = u.id"
+	order := postOrder()
+	var joinArgs []interface{}
+
+	score := sortScore(filter.Sort, now)
+	if score != "" {
+		columns += ", " + score + " AS score"
+		order = postOrder(scoreKey)
+	}
 
-	// Add liked posts join if needed
+	// Liked posts; a user has at most one reaction per post
 	if filter.LikedPosts && filter.AuthorID != nil {
-		query += " INNER JOIN post_reaction pr ON p.id = pr.post_id AND pr.user_id = ? AND pr.reaction = 1"
-		args = append(args, filter.AuthorID.String())
+		joins += " INNER JOIN post_reaction pr ON p.id = pr.post_id AND pr.user_id = ? AND pr.reaction = 1"
+		joinArgs = append(joinArgs, filter.AuthorID.String())
 	}
 
-	query += " WHERE 1=1"
+	conditions := []string{}
+	args := []interface{}{}
 
 	// Filter by categories
 	if len(filter.CategoryIDs) > 0 {
-		placeholders := make([]string, len(filter.CategoryIDs))
-		for i, catID := range filter.CategoryIDs {
-			placeholders[i] = "?"
-			args = append(args, catID.String())
-		}
-		conditions = append(conditions, "pc.category_id IN ("+strings.Join(placeholders, ",")+")")
+		placeholders, categoryArgs := inClause(filter.CategoryIDs)
+		conditions = append(conditions,
+			"EXISTS (SELECT 1 FROM post_categories pc WHERE pc.post_id = p.id AND pc.category_id IN ("+placeholders+"))")
+		args = append(args, categoryArgs...)
 	}
 
 	// Filter by user's own posts
@@ -238,72 +495,63 @@ func (r *SQLitePostAggregateRepository) GetFilteredPostsWithDetails(filter entit
 		args = append(args, filter.AuthorID.String())
 	}
 
-	// Apply conditions
-	if len(conditions) > 0 {
-		query += " AND " + strings.Join(conditions, " AND ")
+	// Filter by search text in the post or its comments
+	if strings.TrimSpace(filter.Query) != "" {
+		condition, searchArgs := searchCondition(ctx, r.db, filter.Query)
+		conditions = append(conditions, condition)
+		args = append(args, searchArgs...)
+	}
+
+	// Only posts from the top window
+	if filter.Sort == entity.SortTop && filter.Window > 0 {
+		conditions = append(conditions, "julianday(p.created_at) >= "+julianDay(now.Add(-filter.Window)))
 	}
 
-	query += " ORDER BY p.created_at DESC"
+	// Only the requested page
+	keyset, orderBy, keysetArgs := keysetCondition(page, order)
+	conditions = append(conditions, keyset)
+	args = append(args, keysetArgs...)
 
-	// Execute query
-	rows, err := r.db.Query(query, args...)
+	query := "SELECT " + columns + " FROM posts p" + joins +
+		" WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY " + orderBy + " LIMIT ?"
+	args = append(append(joinArgs, args...), page.Size()+1)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute filter query: %w", err)
 	}
 	defer rows.Close()
 
-	// Collect unique posts
-	postMap := make(map[uuid.UUID]*entity.PostWithDetails)
+	var posts []*entity.PostWithDetails
+	var scores map[uuid.UUID]float64
+	if score != "" {
+		scores = make(map[uuid.UUID]float64)
+	}
 
 	for rows.Next() {
-		var postID, postUserID, authorID string
-		var post entity.Post
-		var author entity.User
-
-		err := rows.Scan(
-			&postID, &post.Content, &postUserID, &post.CreatedAt,
-			&authorID, &author.UserName, &author.Email, &author.CreatedAt,
-		)
+		var postScore float64
+		var extra []interface{}
+		if score != "" {
+			extra = append(extra, &postScore)
+		}
+		post, err := scanPostWithAuthor(rows, extra...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan post: %w", err)
 		}
-
-		post.ID, _ = uuid.Parse(postID)
-		post.UserID, _ = uuid.Parse(postUserID)
-		author.ID, _ = uuid.Parse(authorID)
-
-		// Only add if not already in map
-		if _, exists := postMap[post.ID]; !exists {
-			postMap[post.ID] = &entity.PostWithDetails{
-				Post:   post,
-				Author: author,
-			}
+		posts = append(posts, post)
+		if scores != nil {
+			scores[post.ID] = postScore
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read posts: %w", err)
+	}
 
-	var results []*entity.PostWithDetails
-	for _, postDetails := range postMap {
-		// Get categories
-		categories, err := r.postCategoryRepo.GetCategoriesByPostID(postDetails.ID)
-		if err == nil {
-			postDetails.Categories = categories
-		}
-
-		// Get reaction counts
-		likes, dislikes, err := r.reactionRepo.GetReactionCountsByPostID(postDetails.ID)
-		if err == nil {
-			postDetails.LikeCount = likes
-			postDetails.DislikeCount = dislikes
-		}
-
-		// Get comments
-		comments, err := r.commentRepo.GetByPostIDWithDetails(postDetails.ID)
-		if err == nil {
-			postDetails.Comments = comments
-		}
-
-		results = append(results, postDetails)
+	result := entity.NewPostPage(page, posts, scores, now)
+	if err := r.loadPostDetails(ctx, result.Posts); err != nil {
+		return nil, err
 	}
 
-	return results, nil
+	return result, nil
 }
@@ -0,0 +1,86 @@
+package infra_repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"forum/domain/entity"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type SQLitePostAttachmentRepository struct {
+	db *sql.DB
+}
+
+func NewSQLitePostAttachmentRepository(db *sql.DB) repository.PostAttachmentRepository {
+	return &SQLitePostAttachmentRepository{db: db}
+}
+
+func (r *SQLitePostAttachmentRepository) Create(ctx context.Context, attachment *entity.PostAttachment) error {
+	if attachment.ID == uuid.Nil {
+		attachment.ID = uuid.New()
+	}
+	attachment.CreatedAt = time.Now()
+
+	query := `INSERT INTO post_attachments (id, post_id, file_name, thumbnail_name, content_type, size, width, height, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, attachment.ID.String(), attachment.PostID.String(), attachment.FileName,
+		attachment.ThumbnailName, attachment.ContentType, attachment.Size, attachment.Width, attachment.Height,
+		attachment.CreatedAt)
+	return err
+}
+
+func (r *SQLitePostAttachmentRepository) GetByPostID(ctx context.Context, postID uuid.UUID) ([]*entity.PostAttachment, error) {
+	attachments, err := r.GetByPostIDs(ctx, []uuid.UUID{postID})
+	if err != nil {
+		return nil, err
+	}
+	return attachments[postID], nil
+}
+
+// GetByPostIDs loads the attachments of several posts in one query.
+func (r *SQLitePostAttachmentRepository) GetByPostIDs(ctx context.Context, postIDs []uuid.UUID) (map[uuid.UUID][]*entity.PostAttachment, error) {
+	attachments := make(map[uuid.UUID][]*entity.PostAttachment)
+	if len(postIDs) == 0 {
+		return attachments, nil
+	}
+
+	placeholders, args := inClause(postIDs)
+	query := `SELECT id, post_id, file_name, thumbnail_name, content_type, size, width, height, created_at
+			  FROM post_attachments WHERE post_id IN (` + placeholders + `) ORDER BY created_at ASC, rowid ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		attachment := &entity.PostAttachment{}
+		var idStr, postIDStr string
+
+		err := rows.Scan(&idStr, &postIDStr, &attachment.FileName, &attachment.ThumbnailName,
+			&attachment.ContentType, &attachment.Size, &attachment.Width, &attachment.Height, &attachment.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		attachment.ID, err = uuid.Parse(idStr)
+		if err != nil {
+			return nil, err
+		}
+
+		attachment.PostID, err = uuid.Parse(postIDStr)
+		if err != nil {
+			return nil, err
+		}
+
+		attachments[attachment.PostID] = append(attachments[attachment.PostID], attachment)
+	}
+
+	return attachments, rows.Err()
+}
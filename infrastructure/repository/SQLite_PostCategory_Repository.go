@@ -1,6 +1,7 @@
 package infra_repository
 
 import (
+	"context"
 	"database/sql"
 
 	"forum/domain/entity"
@@ -16,44 +17,44 @@ func NewSQLitePostCategoryRepository(db *sql.DB) repository.PostCategoryReposito
 	return &SQLitePostCategoryRepository{db: db}
 }
 
-func (r *SQLitePostCategoryRepository) Create(postCategory *entity.PostCategory) error {
+func (r *SQLitePostCategoryRepository) Create(ctx context.Context, postCategory *entity.PostCategory) error {
 	query := `INSERT INTO post_categories (post_id, category_id) VALUES (?, ?)`
 
-	_, err := r.db.Exec(query, postCategory.PostID.String(), postCategory.CategoryID.String())
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, postCategory.PostID.String(), postCategory.CategoryID.String())
 	return err
 }
 
-func (r *SQLitePostCategoryRepository) Delete(postID, categoryID uuid.UUID) error {
+func (r *SQLitePostCategoryRepository) Delete(ctx context.Context, postID, categoryID uuid.UUID) error {
 	query := `DELETE FROM post_categories WHERE post_id = ? AND category_id = ?`
 
-	_, err := r.db.Exec(query, postID.String(), categoryID.String())
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, postID.String(), categoryID.String())
 	return err
 }
 
-func (r *SQLitePostCategoryRepository) DeleteByPostID(postID uuid.UUID) error {
+func (r *SQLitePostCategoryRepository) DeleteByPostID(ctx context.Context, postID uuid.UUID) error {
 	query := `DELETE FROM post_categories WHERE post_id = ?`
 
-	_, err := r.db.Exec(query, postID.String())
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, postID.String())
 	return err
 }
 
-func (r *SQLitePostCategoryRepository) DeleteByCategoryID(categoryID uuid.UUID) error {
+func (r *SQLitePostCategoryRepository) DeleteByCategoryID(ctx context.Context, categoryID uuid.UUID) error {
 	query := `DELETE FROM post_categories WHERE category_id = ?`
 
-	_, err := r.db.Exec(query, categoryID.String())
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, categoryID.String())
 	return err
 }
 
 
 
-func (r *SQLitePostCategoryRepository) GetCategoriesByPostID(postID uuid.UUID) ([]*entity.Category, error) {
+func (r *SQLitePostCategoryRepository) GetCategoriesByPostID(ctx context.Context, postID uuid.UUID) ([]*entity.Category, error) {
 	query := `SELECT c.id, c.name, c.created_at 
 			  FROM categories c 
 			  INNER JOIN post_categories pc ON c.id = pc.category_id 
 			  WHERE pc.post_id = ? 
 			  ORDER BY c.name ASC`
 
-	rows, err := r.db.Query(query, postID.String())
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, postID.String())
 	if err != nil {
 		return nil, err
 	}
@@ -81,14 +82,59 @@ func (r *SQLitePostCategoryRepository) GetCategoriesByPostID(postID uuid.UUID) (
 	return categories, nil
 }
 
-func (r *SQLitePostCategoryRepository) GetPostsByCategoryID(categoryID uuid.UUID) ([]*entity.Post, error) {
-	query := `SELECT p.id, p.content, p.user_id, p.created_at 
+// GetCategoriesByPostIDs loads the categories of several posts in one query.
+func (r *SQLitePostCategoryRepository) GetCategoriesByPostIDs(ctx context.Context, postIDs []uuid.UUID) (map[uuid.UUID][]*entity.Category, error) {
+	categories := make(map[uuid.UUID][]*entity.Category)
+	if len(postIDs) == 0 {
+		return categories, nil
+	}
+
+	placeholders, args := inClause(postIDs)
+	query := `SELECT pc.post_id, c.id, c.name, c.created_at 
+			  FROM categories c 
+			  INNER JOIN post_categories pc ON c.id = pc.category_id 
+			  WHERE pc.post_id IN (` + placeholders + `) 
+			  ORDER BY c.name ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		category := &entity.Category{}
+		var postIDStr, idStr string
+
+		err := rows.Scan(&postIDStr, &idStr, &category.Name, &category.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		postID, err := uuid.Parse(postIDStr)
+		if err != nil {
+			return nil, err
+		}
+
+		category.ID, err = uuid.Parse(idStr)
+		if err != nil {
+			return nil, err
+		}
+
+		categories[postID] = append(categories[postID], category)
+	}
+
+	return categories, rows.Err()
+}
+
+func (r *SQLitePostCategoryRepository) GetPostsByCategoryID(ctx context.Context, categoryID uuid.UUID) ([]*entity.Post, error) {
+	query := `SELECT p.id, p.title, p.content, p.user_id, p.created_at, p.updated_at 
 			  FROM posts p 
 			  INNER JOIN post_categories pc ON p.id = pc.post_id 
 			  WHERE pc.category_id = ? 
 			  ORDER BY p.created_at DESC`
 
-	rows, err := r.db.Query(query, categoryID.String())
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, categoryID.String())
 	if err != nil {
 		return nil, err
 	}
@@ -99,8 +145,9 @@ func (r *SQLitePostCategoryRepository) GetPostsByCategoryID(categoryID uuid.UUID
 	for rows.Next() {
 		post := &entity.Post{}
 		var idStr, userIDStr string
+		var updatedAt sql.NullTime
 
-		err := rows.Scan(&idStr, &post.Content, &userIDStr, &post.CreatedAt)
+		err := rows.Scan(&idStr, &post.Title, &post.Content, &userIDStr, &post.CreatedAt, &updatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -115,17 +162,21 @@ func (r *SQLitePostCategoryRepository) GetPostsByCategoryID(categoryID uuid.UUID
 			return nil, err
 		}
 
+		if updatedAt.Valid {
+			post.UpdatedAt = &updatedAt.Time
+		}
+
 		posts = append(posts, post)
 	}
 
 	return posts, nil
 }
 
-func (r *SQLitePostCategoryRepository) CheckAssociationExists(postID, categoryID uuid.UUID) (bool, error) {
+func (r *SQLitePostCategoryRepository) CheckAssociationExists(ctx context.Context, postID, categoryID uuid.UUID) (bool, error) {
 	query := `SELECT COUNT(*) FROM post_categories WHERE post_id = ? AND category_id = ?`
 
 	var count int
-	err := r.db.QueryRow(query, postID.String(), categoryID.String()).Scan(&count)
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, postID.String(), categoryID.String()).Scan(&count)
 	if err != nil {
 		return false, err
 	}
@@ -133,10 +184,10 @@ func (r *SQLitePostCategoryRepository) CheckAssociationExists(postID, categoryID
 	return count > 0, nil
 }
 
-func (r *SQLitePostCategoryRepository) GetAllAssociations() ([]*entity.PostCategory, error) {
+func (r *SQLitePostCategoryRepository) GetAllAssociations(ctx context.Context) ([]*entity.PostCategory, error) {
 	query := `SELECT post_id, category_id FROM post_categories`
 
-	rows, err := r.db.Query(query)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
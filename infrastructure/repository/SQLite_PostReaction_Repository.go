@@ -1,6 +1,7 @@
 package infra_repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -20,22 +21,22 @@ func NewSQLitePostReactionRepository(db *sql.DB) repository.PostReactionReposito
 	return &SQLitePostReactionRepository{db: db}
 }
 
-func (r *SQLitePostReactionRepository) Create(reaction *entity.PostReaction) error {
+func (r *SQLitePostReactionRepository) Create(ctx context.Context, reaction *entity.PostReaction) error {
 	reaction.ID = uuid.New()
 	reaction.CreatedAt = time.Now()
 
 	query := `INSERT INTO post_reaction (id, user_id, post_id, reaction, created_at)
 			  VALUES (?, ?, ?, ?, ?)`
 
-	_, err := r.db.Exec(query, reaction.ID.String(), reaction.UserID.String(),
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, reaction.ID.String(), reaction.UserID.String(),
 		reaction.PostID.String(), reaction.Reaction, reaction.CreatedAt)
 	return err
 }
 
-func (r *SQLitePostReactionRepository) GetByID(reactionID uuid.UUID) (*entity.PostReaction, error) {
+func (r *SQLitePostReactionRepository) GetByID(ctx context.Context, reactionID uuid.UUID) (*entity.PostReaction, error) {
 	query := `SELECT id, user_id, post_id, reaction, created_at FROM post_reaction WHERE id = ?`
 
-	row := r.db.QueryRow(query, reactionID.String())
+	row := conn(ctx, r.db).QueryRowContext(ctx, query, reactionID.String())
 
 	reaction := &entity.PostReaction{}
 	var idStr, userIDStr, postIDStr string
@@ -63,11 +64,11 @@ func (r *SQLitePostReactionRepository) GetByID(reactionID uuid.UUID) (*entity.Po
 	return reaction, nil
 }
 
-func (r *SQLitePostReactionRepository) GetByUserAndPost(userID, postID uuid.UUID) (*entity.PostReaction, error) {
+func (r *SQLitePostReactionRepository) GetByUserAndPost(ctx context.Context, userID, postID uuid.UUID) (*entity.PostReaction, error) {
 	query := `SELECT id, user_id, post_id, reaction, created_at 
 			  FROM post_reaction WHERE user_id = ? AND post_id = ?`
 
-	row := r.db.QueryRow(query, userID.String(), postID.String())
+	row := conn(ctx, r.db).QueryRowContext(ctx, query, userID.String(), postID.String())
 
 	reaction := &entity.PostReaction{}
 	var idStr, userIDStr, postIDStr string
@@ -95,11 +96,11 @@ func (r *SQLitePostReactionRepository) GetByUserAndPost(userID, postID uuid.UUID
 	return reaction, nil
 }
 
-func (r *SQLitePostReactionRepository) GetByPostID(postID uuid.UUID) ([]*entity.PostReaction, error) {
+func (r *SQLitePostReactionRepository) GetByPostID(ctx context.Context, postID uuid.UUID) ([]*entity.PostReaction, error) {
 	query := `SELECT id, user_id, post_id, reaction, created_at 
 			  FROM post_reaction WHERE post_id = ? ORDER BY created_at DESC`
 
-	rows, err := r.db.Query(query, postID.String())
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, postID.String())
 	if err != nil {
 		return nil, err
 	}
@@ -137,11 +138,11 @@ func (r *SQLitePostReactionRepository) GetByPostID(postID uuid.UUID) ([]*entity.
 	return reactions, nil
 }
 
-func (r *SQLitePostReactionRepository) GetByUserID(userID uuid.UUID) ([]*entity.PostReaction, error) {
+func (r *SQLitePostReactionRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.PostReaction, error) {
 	query := `SELECT id, user_id, post_id, reaction, created_at 
 			  FROM post_reaction WHERE user_id = ? ORDER BY created_at DESC`
 
-	rows, err := r.db.Query(query, userID.String())
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID.String())
 	if err != nil {
 		return nil, err
 	}
@@ -179,61 +180,60 @@ func (r *SQLitePostReactionRepository) GetByUserID(userID uuid.UUID) ([]*entity.
 	return reactions, nil
 }
 
-func (r *SQLitePostReactionRepository) Update(reaction *entity.PostReaction) error {
+func (r *SQLitePostReactionRepository) Update(ctx context.Context, reaction *entity.PostReaction) error {
 	query := `UPDATE post_reaction SET reaction = ? WHERE id = ?`
 
-	_, err := r.db.Exec(query, reaction.Reaction, reaction.ID.String())
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, reaction.Reaction, reaction.ID.String())
 	return err
 }
 
-func (r *SQLitePostReactionRepository) Delete(reactionID uuid.UUID) error {
+func (r *SQLitePostReactionRepository) Delete(ctx context.Context, reactionID uuid.UUID) error {
 	query := `DELETE FROM post_reaction WHERE id = ?`
 
-	_, err := r.db.Exec(query, reactionID.String())
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, reactionID.String())
 	return err
 }
 
-func (r *SQLitePostReactionRepository) DeleteByUserAndPost(userID, postID uuid.UUID) error {
+func (r *SQLitePostReactionRepository) DeleteByUserAndPost(ctx context.Context, userID, postID uuid.UUID) error {
 	query := `DELETE FROM post_reaction WHERE user_id = ? AND post_id = ?`
 
-	_, err := r.db.Exec(query, userID.String(), postID.String())
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, userID.String(), postID.String())
 	return err
 }
 
-func (r *SQLitePostReactionRepository) GetLikeCountByPostID(postID uuid.UUID) (int, error) {
+func (r *SQLitePostReactionRepository) GetLikeCountByPostID(ctx context.Context, postID uuid.UUID) (int, error) {
 	query := `SELECT COUNT(*) FROM post_reaction WHERE post_id = ? AND reaction = 1`
 
 	var count int
-	err := r.db.QueryRow(query, postID.String()).Scan(&count)
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, postID.String()).Scan(&count)
 	return count, err
 }
 
-func (r *SQLitePostReactionRepository) GetDislikeCountByPostID(postID uuid.UUID) (int, error) {
+func (r *SQLitePostReactionRepository) GetDislikeCountByPostID(ctx context.Context, postID uuid.UUID) (int, error) {
 	query := `SELECT COUNT(*) FROM post_reaction WHERE post_id = ? AND reaction = 0`
 
 	var count int
-	err := r.db.QueryRow(query, postID.String()).Scan(&count)
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, postID.String()).Scan(&count)
 	return count, err
 }
 
-func (r *SQLitePostReactionRepository) GetReactionCountsByPostID(postID uuid.UUID) (likes int, dislikes int, err error) {
-	query := `SELECT 
-				COALESCE(SUM(CASE WHEN reaction = 1 THEN 1 ELSE 0 END), 0) as likes,
-				COALESCE(SUM(CASE WHEN reaction = 0 THEN 1 ELSE 0 END), 0) as dislikes
-			  FROM post_reaction WHERE post_id = ?`
+func (r *SQLitePostReactionRepository) GetReactionCountsByPostID(ctx context.Context, postID uuid.UUID) (likes int, dislikes int, err error) {
+	query := `SELECT like_count, dislike_count FROM posts WHERE id = ?`
 
-	err = r.db.QueryRow(query, postID.String()).Scan(&likes, &dislikes)
-	if err != nil {
+	err = conn(ctx, r.db).QueryRowContext(ctx, query, postID.String()).Scan(&likes, &dislikes)
+	if err == sql.ErrNoRows {
+		return 0, 0, nil
+	} else if err != nil {
 		return 0, 0, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
 	}
 	return likes, dislikes, nil
 }
 
-func (r *SQLitePostReactionRepository) HasUserReacted(userID, postID uuid.UUID) (bool, *bool, error) {
+func (r *SQLitePostReactionRepository) HasUserReacted(ctx context.Context, userID, postID uuid.UUID) (bool, *bool, error) {
 	query := `SELECT reaction FROM post_reaction WHERE user_id = ? AND post_id = ?`
 
 	var reaction bool
-	err := r.db.QueryRow(query, userID.String(), postID.String()).Scan(&reaction)
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, userID.String(), postID.String()).Scan(&reaction)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return false, nil, nil
@@ -243,3 +243,7 @@ func (r *SQLitePostReactionRepository) HasUserReacted(userID, postID uuid.UUID)
 
 	return true, &reaction, nil
 }
+
+func (r *SQLitePostReactionRepository) Toggle(ctx context.Context, userID, postID uuid.UUID, like bool) (*entity.ReactionState, error) {
+	return postReactions.toggle(ctx, r.db, userID, postID, like)
+}
@@ -0,0 +1,56 @@
+package infra_repository
+
+import (
+	"context"
+	"database/sql"
+
+	"forum/domain/entity"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type SQLitePostRevisionRepository struct {
+	db *sql.DB
+}
+
+func NewSQLitePostRevisionRepository(db *sql.DB) repository.PostRevisionRepository {
+	return &SQLitePostRevisionRepository{db: db}
+}
+
+func (r *SQLitePostRevisionRepository) GetByPostID(ctx context.Context, postID uuid.UUID) ([]*entity.PostRevision, error) {
+	query := `SELECT id, post_id, version, title, content, created_at
+			  FROM post_revisions WHERE post_id = ? ORDER BY version ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, postID.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*entity.PostRevision
+
+	for rows.Next() {
+		revision := &entity.PostRevision{}
+		var idStr, postIDStr string
+
+		err := rows.Scan(&idStr, &postIDStr, &revision.Version, &revision.Title, &revision.Content, &revision.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		revision.ID, err = uuid.Parse(idStr)
+		if err != nil {
+			return nil, err
+		}
+
+		revision.PostID, err = uuid.Parse(postIDStr)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
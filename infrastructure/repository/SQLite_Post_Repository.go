@@ -1,12 +1,13 @@
 package infra_repository
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
 	"strings"
 	"time"
 
 	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
 	"forum/domain/repository"
 
 	"github.com/google/uuid"
@@ -19,28 +20,32 @@ func NewSQLitePostRepository(db *sql.DB) repository.PostRepository {
 	return &SQLitePostRepository{db: db}
 }
 
-func (r *SQLitePostRepository) Create(post *entity.Post) error {
+func (r *SQLitePostRepository) Create(ctx context.Context, post *entity.Post) error {
 	post.ID = uuid.New()
 	post.CreatedAt = time.Now()
 
-	query := `INSERT INTO posts (id, content, user_id, created_at)
-			  VALUES (?, ?, ?, ?)`
+	query := `INSERT INTO posts (id, title, content, user_id, created_at)
+			  VALUES (?, ?, ?, ?, ?)`
 
-	_, err := r.db.Exec(query, post.ID.String(), post.Content,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, post.ID.String(), post.Title, post.Content,
 		post.UserID.String(), post.CreatedAt)
 	return err
 }
 
-func (r *SQLitePostRepository) GetByID(postID uuid.UUID) (*entity.Post, error) {
-	query := `SELECT id, content, user_id, created_at FROM posts WHERE id = ?`
+func (r *SQLitePostRepository) GetByID(ctx context.Context, postID uuid.UUID) (*entity.Post, error) {
+	query := `SELECT id, title, content, user_id, created_at, updated_at FROM posts WHERE id = ?`
 
-	row := r.db.QueryRow(query, postID.String())
+	row := conn(ctx, r.db).QueryRowContext(ctx, query, postID.String())
 
 	post := &entity.Post{}
 	var idStr, userIDStr string
+	var updatedAt sql.NullTime
 
-	err := row.Scan(&idStr, &post.Content, &userIDStr, &post.CreatedAt)
+	err := row.Scan(&idStr, &post.Title, &post.Content, &userIDStr, &post.CreatedAt, &updatedAt)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, custom_errors.ErrPostNotFound
+		}
 		return nil, err
 	}
 
@@ -54,13 +59,17 @@ func (r *SQLitePostRepository) GetByID(postID uuid.UUID) (*entity.Post, error) {
 		return nil, err
 	}
 
+	if updatedAt.Valid {
+		post.UpdatedAt = &updatedAt.Time
+	}
+
 	return post, nil
 }
 
-func (r *SQLitePostRepository) GetAll() ([]*entity.Post, error) {
-	query := `SELECT id, content, user_id, created_at FROM posts ORDER BY created_at DESC`
+func (r *SQLitePostRepository) GetAll(ctx context.Context) ([]*entity.Post, error) {
+	query := `SELECT id, title, content, user_id, created_at, updated_at FROM posts ORDER BY created_at DESC`
 
-	rows, err := r.db.Query(query)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -71,8 +80,9 @@ func (r *SQLitePostRepository) GetAll() ([]*entity.Post, error) {
 	for rows.Next() {
 		post := &entity.Post{}
 		var idStr, userIDStr string
+		var updatedAt sql.NullTime
 
-		err := rows.Scan(&idStr, &post.Content, &userIDStr, &post.CreatedAt)
+		err := rows.Scan(&idStr, &post.Title, &post.Content, &userIDStr, &post.CreatedAt, &updatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -87,17 +97,21 @@ func (r *SQLitePostRepository) GetAll() ([]*entity.Post, error) {
 			return nil, err
 		}
 
+		if updatedAt.Valid {
+			post.UpdatedAt = &updatedAt.Time
+		}
+
 		posts = append(posts, post)
 	}
 
 	return posts, nil
 }
 
-func (r *SQLitePostRepository) GetByUserID(userID uuid.UUID) ([]*entity.Post, error) {
-	query := `SELECT id, content, user_id, created_at 
+func (r *SQLitePostRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.Post, error) {
+	query := `SELECT id, title, content, user_id, created_at, updated_at 
 			  FROM posts WHERE user_id = ? ORDER BY created_at DESC`
 
-	rows, err := r.db.Query(query, userID.String())
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID.String())
 	if err != nil {
 		return nil, err
 	}
@@ -108,8 +122,9 @@ func (r *SQLitePostRepository) GetByUserID(userID uuid.UUID) ([]*entity.Post, er
 	for rows.Next() {
 		post := &entity.Post{}
 		var idStr, userIDStr string
+		var updatedAt sql.NullTime
 
-		err := rows.Scan(&idStr, &post.Content, &userIDStr, &post.CreatedAt)
+		err := rows.Scan(&idStr, &post.Title, &post.Content, &userIDStr, &post.CreatedAt, &updatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -124,17 +139,21 @@ func (r *SQLitePostRepository) GetByUserID(userID uuid.UUID) ([]*entity.Post, er
 			return nil, err
 		}
 
+		if updatedAt.Valid {
+			post.UpdatedAt = &updatedAt.Time
+		}
+
 		posts = append(posts, post)
 	}
 
 	return posts, nil
 }
 
-func (r *SQLitePostRepository) GetWithPagination(limit, offset int) ([]*entity.Post, error) {
-	query := `SELECT id, content, user_id, created_at 
+func (r *SQLitePostRepository) GetWithPagination(ctx context.Context, limit, offset int) ([]*entity.Post, error) {
+	query := `SELECT id, title, content, user_id, created_at, updated_at 
 			  FROM posts ORDER BY created_at DESC LIMIT ? OFFSET ?`
 
-	rows, err := r.db.Query(query, limit, offset)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -145,8 +164,9 @@ func (r *SQLitePostRepository) GetWithPagination(limit, offset int) ([]*entity.P
 	for rows.Next() {
 		post := &entity.Post{}
 		var idStr, userIDStr string
+		var updatedAt sql.NullTime
 
-		err := rows.Scan(&idStr, &post.Content, &userIDStr, &post.CreatedAt)
+		err := rows.Scan(&idStr, &post.Title, &post.Content, &userIDStr, &post.CreatedAt, &updatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -161,20 +181,24 @@ func (r *SQLitePostRepository) GetWithPagination(limit, offset int) ([]*entity.P
 			return nil, err
 		}
 
+		if updatedAt.Valid {
+			post.UpdatedAt = &updatedAt.Time
+		}
+
 		posts = append(posts, post)
 	}
 
 	return posts, nil
 }
 
-func (r *SQLitePostRepository) GetByCategory(categoryID uuid.UUID) ([]*entity.Post, error) {
-	query := `SELECT p.id, p.content, p.user_id, p.created_at 
+func (r *SQLitePostRepository) GetByCategory(ctx context.Context, categoryID uuid.UUID) ([]*entity.Post, error) {
+	query := `SELECT p.id, p.title, p.content, p.user_id, p.created_at, p.updated_at 
 			  FROM posts p 
 			  INNER JOIN post_categories pc ON p.id = pc.post_id 
 			  WHERE pc.category_id = ? 
 			  ORDER BY p.created_at DESC`
 
-	rows, err := r.db.Query(query, categoryID.String())
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, categoryID.String())
 	if err != nil {
 		return nil, err
 	}
@@ -185,8 +209,9 @@ func (r *SQLitePostRepository) GetByCategory(categoryID uuid.UUID) ([]*entity.Po
 	for rows.Next() {
 		post := &entity.Post{}
 		var idStr, userIDStr string
+		var updatedAt sql.NullTime
 
-		err := rows.Scan(&idStr, &post.Content, &userIDStr, &post.CreatedAt)
+		err := rows.Scan(&idStr, &post.Title, &post.Content, &userIDStr, &post.CreatedAt, &updatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -201,20 +226,24 @@ func (r *SQLitePostRepository) GetByCategory(categoryID uuid.UUID) ([]*entity.Po
 			return nil, err
 		}
 
+		if updatedAt.Valid {
+			post.UpdatedAt = &updatedAt.Time
+		}
+
 		posts = append(posts, post)
 	}
 
 	return posts, nil
 }
 
-func (r *SQLitePostRepository) GetByCategoryWithPagination(categoryID uuid.UUID, limit, offset int) ([]*entity.Post, error) {
-	query := `SELECT p.id, p.content, p.user_id, p.created_at 
+func (r *SQLitePostRepository) GetByCategoryWithPagination(ctx context.Context, categoryID uuid.UUID, limit, offset int) ([]*entity.Post, error) {
+	query := `SELECT p.id, p.title, p.content, p.user_id, p.created_at, p.updated_at 
 			  FROM posts p 
 			  INNER JOIN post_categories pc ON p.id = pc.post_id 
 			  WHERE pc.category_id = ? 
 			  ORDER BY p.created_at DESC LIMIT ? OFFSET ?`
 
-	rows, err := r.db.Query(query, categoryID.String(), limit, offset)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, categoryID.String(), limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -225,8 +254,9 @@ func (r *SQLitePostRepository) GetByCategoryWithPagination(categoryID uuid.UUID,
 	for rows.Next() {
 		post := &entity.Post{}
 		var idStr, userIDStr string
+		var updatedAt sql.NullTime
 
-		err := rows.Scan(&idStr, &post.Content, &userIDStr, &post.CreatedAt)
+		err := rows.Scan(&idStr, &post.Title, &post.Content, &userIDStr, &post.CreatedAt, &updatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -241,14 +271,18 @@ func (r *SQLitePostRepository) GetByCategoryWithPagination(categoryID uuid.UUID,
 			return nil, err
 		}
 
+		if updatedAt.Valid {
+			post.UpdatedAt = &updatedAt.Time
+		}
+
 		posts = append(posts, post)
 	}
 
 	return posts, nil
 }
 
-func (r *SQLitePostRepository) GetMostLiked(limit int) ([]*entity.Post, error) {
-	query := `SELECT p.id, p.content, p.user_id, p.created_at 
+func (r *SQLitePostRepository) GetMostLiked(ctx context.Context, limit int) ([]*entity.Post, error) {
+	query := `SELECT p.id, p.title, p.content, p.user_id, p.created_at, p.updated_at 
 			  FROM posts p 
 			  LEFT JOIN (
 				  SELECT post_id, COUNT(*) as like_count 
@@ -259,7 +293,7 @@ func (r *SQLitePostRepository) GetMostLiked(limit int) ([]*entity.Post, error) {
 			  ORDER BY COALESCE(lr.like_count, 0) DESC, p.created_at DESC 
 			  LIMIT ?`
 
-	rows, err := r.db.Query(query, limit)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -270,8 +304,9 @@ func (r *SQLitePostRepository) GetMostLiked(limit int) ([]*entity.Post, error) {
 	for rows.Next() {
 		post := &entity.Post{}
 		var idStr, userIDStr string
+		var updatedAt sql.NullTime
 
-		err := rows.Scan(&idStr, &post.Content, &userIDStr, &post.CreatedAt)
+		err := rows.Scan(&idStr, &post.Title, &post.Content, &userIDStr, &post.CreatedAt, &updatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -286,17 +321,21 @@ func (r *SQLitePostRepository) GetMostLiked(limit int) ([]*entity.Post, error) {
 			return nil, err
 		}
 
+		if updatedAt.Valid {
+			post.UpdatedAt = &updatedAt.Time
+		}
+
 		posts = append(posts, post)
 	}
 
 	return posts, nil
 }
 
-func (r *SQLitePostRepository) GetRecent(limit int) ([]*entity.Post, error) {
-	query := `SELECT id, content, user_id, created_at 
+func (r *SQLitePostRepository) GetRecent(ctx context.Context, limit int) ([]*entity.Post, error) {
+	query := `SELECT id, title, content, user_id, created_at, updated_at 
 			  FROM posts ORDER BY created_at DESC LIMIT ?`
 
-	rows, err := r.db.Query(query, limit)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -307,8 +346,9 @@ func (r *SQLitePostRepository) GetRecent(limit int) ([]*entity.Post, error) {
 	for rows.Next() {
 		post := &entity.Post{}
 		var idStr, userIDStr string
+		var updatedAt sql.NullTime
 
-		err := rows.Scan(&idStr, &post.Content, &userIDStr, &post.CreatedAt)
+		err := rows.Scan(&idStr, &post.Title, &post.Content, &userIDStr, &post.CreatedAt, &updatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -323,28 +363,35 @@ func (r *SQLitePostRepository) GetRecent(limit int) ([]*entity.Post, error) {
 			return nil, err
 		}
 
+		if updatedAt.Valid {
+			post.UpdatedAt = &updatedAt.Time
+		}
+
 		posts = append(posts, post)
 	}
 
 	return posts, nil
 }
 
-func (r *SQLitePostRepository) Update(post *entity.Post) error {
-	query := `UPDATE posts SET content = ? WHERE id = ?`
+func (r *SQLitePostRepository) Update(ctx context.Context, post *entity.Post) error {
+	now := time.Now()
+	post.UpdatedAt = &now
+
+	query := `UPDATE posts SET title = ?, content = ?, updated_at = ? WHERE id = ?`
 
-	_, err := r.db.Exec(query, post.Content, post.ID.String())
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, post.Title, post.Content, post.UpdatedAt, post.ID.String())
 	return err
 }
 
-func (r *SQLitePostRepository) Delete(postID uuid.UUID) error {
+func (r *SQLitePostRepository) Delete(ctx context.Context, postID uuid.UUID) error {
 	query := `DELETE FROM posts WHERE id = ?`
 
-	_, err := r.db.Exec(query, postID.String())
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, postID.String())
 	return err
 }
 
-func (r *SQLitePostRepository) GetWithDetails(postID uuid.UUID) (*entity.PostWithDetails, error) {
-	post, err := r.GetByID(postID)
+func (r *SQLitePostRepository) GetWithDetails(ctx context.Context, postID uuid.UUID) (*entity.PostWithDetails, error) {
+	post, err := r.GetByID(ctx, postID)
 	if err != nil {
 		return nil, err
 	}
@@ -354,8 +401,8 @@ func (r *SQLitePostRepository) GetWithDetails(postID uuid.UUID) (*entity.PostWit
 	}, nil
 }
 
-func (r *SQLitePostRepository) GetAllWithDetails() ([]*entity.PostWithDetails, error) {
-	posts, err := r.GetAll()
+func (r *SQLitePostRepository) GetAllWithDetails(ctx context.Context) ([]*entity.PostWithDetails, error) {
+	posts, err := r.GetAll(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -381,9 +428,9 @@ func joinConditions(conds []string, sep string) string {
 	return result
 }
 
-func (r *SQLitePostRepository) GetFiltered(filter entity.PostFilter) ([]*entity.Post, error) {
+func (r *SQLitePostRepository) GetFiltered(ctx context.Context, filter entity.PostFilter) ([]*entity.Post, error) {
 	query := `
-		SELECT DISTINCT p.id, p.content, p.user_id, p.created_at
+		SELECT DISTINCT p.id, p.title, p.content, p.user_id, p.created_at, p.updated_at
 		FROM posts p
 		LEFT JOIN post_categories pc ON p.id = pc.post_id
 	`
@@ -391,11 +438,6 @@ func (r *SQLitePostRepository) GetFiltered(filter entity.PostFilter) ([]*entity.
 	conditions := []string{}
 	args := []interface{}{}
 
-	if filter.CategoryIDs != nil {
-		conditions = append(conditions, "pc.category_id = ?")
-		args = append(args, filter.CategoryIDs)
-	}
-
 	if len(filter.CategoryIDs) > 0 {
 		placeholders := []string{}
 		for _, id := range filter.CategoryIDs {
@@ -405,15 +447,13 @@ func (r *SQLitePostRepository) GetFiltered(filter entity.PostFilter) ([]*entity.
 		conditions = append(conditions, "pc.category_id IN ("+strings.Join(placeholders, ",")+")")
 	}
 
-	fmt.Println("Filter:", filter.CategoryIDs)
-
 	if len(conditions) > 0 {
 		query += " WHERE " + joinConditions(conditions, " AND ")
 	}
 
 	query += " ORDER BY p.created_at DESC"
 
-	rows, err := r.db.Query(query, args...)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -423,8 +463,9 @@ func (r *SQLitePostRepository) GetFiltered(filter entity.PostFilter) ([]*entity.
 	for rows.Next() {
 		post := &entity.Post{}
 		var idStr, userIDStr string
+		var updatedAt sql.NullTime
 
-		if err := rows.Scan(&idStr, &post.Content, &userIDStr, &post.CreatedAt); err != nil {
+		if err := rows.Scan(&idStr, &post.Title, &post.Content, &userIDStr, &post.CreatedAt, &updatedAt); err != nil {
 			return nil, err
 		}
 
@@ -438,16 +479,20 @@ func (r *SQLitePostRepository) GetFiltered(filter entity.PostFilter) ([]*entity.
 			return nil, err
 		}
 
+		if updatedAt.Valid {
+			post.UpdatedAt = &updatedAt.Time
+		}
+
 		posts = append(posts, post)
 	}
 
 	return posts, nil
 }
 
-func (r *SQLitePostRepository) GetbyuserId(userID uuid.UUID) ([]*entity.Post, error) {
-	query := `SELECT id, content, user_id, created_at FROM posts WHERE user_id = ? ORDER BY created_at DESC`
+func (r *SQLitePostRepository) GetbyuserId(ctx context.Context, userID uuid.UUID) ([]*entity.Post, error) {
+	query := `SELECT id, title, content, user_id, created_at, updated_at FROM posts WHERE user_id = ? ORDER BY created_at DESC`
 
-	rows, err := r.db.Query(query, userID.String())
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID.String())
 	if err != nil {
 		return nil, err
 	}
@@ -458,8 +503,9 @@ func (r *SQLitePostRepository) GetbyuserId(userID uuid.UUID) ([]*entity.Post, er
 	for rows.Next() {
 		post := &entity.Post{}
 		var idStr, userIDStr string
+		var updatedAt sql.NullTime
 
-		err := rows.Scan(&idStr, &post.Content, &userIDStr, &post.CreatedAt)
+		err := rows.Scan(&idStr, &post.Title, &post.Content, &userIDStr, &post.CreatedAt, &updatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -474,6 +520,10 @@ func (r *SQLitePostRepository) GetbyuserId(userID uuid.UUID) ([]*entity.Post, er
 			return nil, err
 		}
 
+		if updatedAt.Valid {
+			post.UpdatedAt = &updatedAt.Time
+		}
+
 		posts = append(posts, post)
 	}
 
@@ -0,0 +1,323 @@
+package infra_repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+// maxSearchTerms bounds how many words of a query are used.
+const maxSearchTerms = 10
+
+// commentRankWeight makes a match in a comment count for less than the same
+// match in the post itself. bm25 scores are negative, lower is better.
+const commentRankWeight = 0.5
+
+type SQLiteSearchRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteSearchRepository(db *sql.DB) repository.SearchRepository {
+	return &SQLiteSearchRepository{db: db}
+}
+
+// hasFullTextSearch reports whether the FTS5 index can be used. It is absent
+// when SQLite was built without FTS5.
+func hasFullTextSearch(ctx context.Context, db *sql.DB) bool {
+	var ok bool
+	err := conn(ctx, db).QueryRowContext(ctx, `SELECT sqlite_compileoption_used('ENABLE_FTS5')
+		AND EXISTS (SELECT 1 FROM sqlite_master WHERE name = 'posts_fts')`).Scan(&ok)
+	return err == nil && ok
+}
+
+// searchTerms splits a user query into words. Everything but letters and
+// digits is dropped, so the FTS5 query syntax can never be injected.
+func searchTerms(query string) []string {
+	terms := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	return terms
+}
+
+// ftsMatchQuery requires every term, matching the last one as a prefix so
+// that partly typed words still find something.
+func ftsMatchQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + term + `"`
+	}
+	return strings.Join(quoted, " ") + "*"
+}
+
+func likePattern(term string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(term) + "%"
+}
+
+// searchCondition returns a WHERE condition on the post alias p that matches
+// posts whose title, content or comments contain all the query terms.
+func searchCondition(ctx context.Context, db *sql.DB, query string) (string, []interface{}) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return "0", nil
+	}
+
+	if hasFullTextSearch(ctx, db) {
+		match := ftsMatchQuery(terms)
+		return `p.id IN (SELECT post_id FROM posts_fts WHERE posts_fts MATCH ?
+			UNION SELECT post_id FROM comments_fts WHERE comments_fts MATCH ?)`, []interface{}{match, match}
+	}
+
+	conditions := make([]string, len(terms))
+	args := make([]interface{}, 0, len(terms)*3)
+	for i, term := range terms {
+		conditions[i] = `(p.title LIKE ? ESCAPE '\' OR p.content LIKE ? ESCAPE '\'
+			OR EXISTS (SELECT 1 FROM comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL AND c.content LIKE ? ESCAPE '\'))`
+		pattern := likePattern(term)
+		args = append(args, pattern, pattern, pattern)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+func (r *SQLiteSearchRepository) Search(ctx context.Context, query string, limit int) ([]*entity.SearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	if !hasFullTextSearch(ctx, r.db) {
+		return r.searchLike(ctx, terms, limit)
+	}
+
+	results := make(map[uuid.UUID]*entity.SearchResult)
+
+	postQuery := `
+		SELECT posts_fts.post_id,
+			highlight(posts_fts, 1, char(2), char(3)),
+			snippet(posts_fts, 2, char(2), char(3), '…', 24),
+			bm25(posts_fts, 0.0, 10.0, 1.0),
+			u.user_name, p.created_at, p.title, p.content
+		FROM posts_fts
+		INNER JOIN posts p ON p.id = posts_fts.post_id
+		INNER JOIN user u ON u.id = p.user_id
+		WHERE posts_fts MATCH ?
+		ORDER BY 4
+		LIMIT ?`
+	err := r.collect(ctx, results, postQuery, terms, limit, false)
+	if err != nil {
+		return nil, err
+	}
+
+	commentQuery := `
+		SELECT comments_fts.post_id,
+			p.title,
+			snippet(comments_fts, 2, char(2), char(3), '…', 24),
+			bm25(comments_fts),
+			u.user_name, p.created_at, p.title, c.content
+		FROM comments_fts
+		INNER JOIN comments c ON c.id = comments_fts.comment_id
+		INNER JOIN posts p ON p.id = comments_fts.post_id
+		INNER JOIN user u ON u.id = p.user_id
+		WHERE comments_fts MATCH ?
+		ORDER BY 4
+		LIMIT ?`
+	err = r.collect(ctx, results, commentQuery, terms, limit, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return rankResults(results, limit), nil
+}
+
+// collect adds the rows of a search query to results, keeping one result per
+// post. A match in the post itself is preferred over one in its comments.
+func (r *SQLiteSearchRepository) collect(ctx context.Context, results map[uuid.UUID]*entity.SearchResult, query string, terms []string, limit int, inComment bool) error {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, ftsMatchQuery(terms), limit)
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		result := &entity.SearchResult{InComment: inComment}
+		var postID, title, text string
+		err := rows.Scan(&postID, &result.Title, &result.Snippet, &result.Rank, &result.AuthorName, &result.CreatedAt, &title, &text)
+		if err != nil {
+			return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+		}
+		result.PostID, _ = uuid.Parse(postID)
+		if strings.ContainsAny(title+text, entity.HighlightStart+entity.HighlightEnd) {
+			// The stored text holds the markers themselves, so they cannot be
+			// told apart from the ones SQLite added. Mark it here instead.
+			if !inComment {
+				result.Title, _ = markTerms(title, terms)
+			}
+			result.Snippet, _ = markTerms(excerpt(entity.StripHighlight(text), terms), terms)
+		}
+		if inComment {
+			// The title is not searched, so nothing in it is highlighted.
+			result.Title = entity.StripHighlight(result.Title)
+			result.Rank *= commentRankWeight
+		}
+
+		existing, ok := results[result.PostID]
+		if !ok {
+			results[result.PostID] = result
+			continue
+		}
+		if !existing.InComment {
+			existing.Rank = min(existing.Rank, result.Rank)
+			continue
+		}
+		result.Rank = min(existing.Rank, result.Rank)
+		results[result.PostID] = result
+	}
+	return rows.Err()
+}
+
+// searchLike is used when FTS5 is not available. Results are ordered by date
+// and the highlighting is done here instead of by SQLite.
+func (r *SQLiteSearchRepository) searchLike(ctx context.Context, terms []string, limit int) ([]*entity.SearchResult, error) {
+	condition, args := searchCondition(ctx, r.db, strings.Join(terms, " "))
+	query := `
+		SELECT p.id, p.title, p.content, u.user_name, p.created_at
+		FROM posts p
+		INNER JOIN user u ON u.id = p.user_id
+		WHERE ` + condition + `
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT ?`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var results []*entity.SearchResult
+	for rows.Next() {
+		result := &entity.SearchResult{}
+		var postID, content string
+		err := rows.Scan(&postID, &result.Title, &content, &result.AuthorName, &result.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+		}
+		result.PostID, _ = uuid.Parse(postID)
+
+		title, titleHit := markTerms(result.Title, terms)
+		result.Title = title
+		snippet, contentHit := markTerms(excerpt(content, terms), terms)
+		result.Snippet = snippet
+		result.InComment = !titleHit && !contentHit
+
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+func rankResults(results map[uuid.UUID]*entity.SearchResult, limit int) []*entity.SearchResult {
+	ranked := make([]*entity.SearchResult, 0, len(results))
+	for _, result := range results {
+		ranked = append(ranked, result)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Rank != ranked[j].Rank {
+			return ranked[i].Rank < ranked[j].Rank
+		}
+		if !ranked[i].CreatedAt.Equal(ranked[j].CreatedAt) {
+			return ranked[i].CreatedAt.After(ranked[j].CreatedAt)
+		}
+		return ranked[i].PostID.String() > ranked[j].PostID.String()
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+// excerpt cuts a window of text around the first term found in it.
+func excerpt(text string, terms []string) string {
+	const radius = 80
+
+	lower := strings.ToLower(text)
+	at := -1
+	for _, term := range terms {
+		if i := strings.Index(lower, strings.ToLower(term)); i >= 0 && (at < 0 || i < at) {
+			at = i
+		}
+	}
+
+	runes := []rune(text)
+	if at < 0 {
+		at = 0
+	} else {
+		at = len([]rune(text[:at]))
+	}
+	start, end := max(0, at-radius), min(len(runes), at+radius)
+
+	out := string(runes[start:end])
+	if start > 0 {
+		out = "…" + out
+	}
+	if end < len(runes) {
+		out += "…"
+	}
+	return out
+}
+
+// markTerms wraps every case-insensitive occurrence of the terms in the
+// highlight markers and reports whether any was found. Markers already in
+// text are removed first.
+func markTerms(text string, terms []string) (string, bool) {
+	text = entity.StripHighlight(text)
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		// Lowercasing changed byte offsets; skip highlighting rather than
+		// risk cutting a character in half.
+		for _, term := range terms {
+			if strings.Contains(lower, strings.ToLower(term)) {
+				return text, true
+			}
+		}
+		return text, false
+	}
+
+	marked := make([]bool, len(text))
+	found := false
+	for _, term := range terms {
+		term = strings.ToLower(term)
+		for from := 0; ; {
+			i := strings.Index(lower[from:], term)
+			if i < 0 {
+				break
+			}
+			for k := from + i; k < from+i+len(term); k++ {
+				marked[k] = true
+			}
+			found = true
+			from += i + len(term)
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if marked[i] && (i == 0 || !marked[i-1]) {
+			b.WriteString(entity.HighlightStart)
+		}
+		b.WriteByte(text[i])
+		if marked[i] && (i == len(text)-1 || !marked[i+1]) {
+			b.WriteString(entity.HighlightEnd)
+		}
+	}
+	return b.String(), found
+}
@@ -0,0 +1,101 @@
+package infra_repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"forum/domain/repository"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// maxTxAttempts is how often a transaction is tried when SQLite reports
+// that the database is busy.
+const maxTxAttempts = 5
+
+// txRetryDelay is the wait before the second attempt; it doubles after that.
+const txRetryDelay = 10 * time.Millisecond
+
+// txKey is the context key of the transaction started by WithinTx.
+type txKey struct{}
+
+// querier is what *sql.DB and *sql.Tx have in common.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// conn returns the transaction that ctx carries, or db outside of one. Every
+// query goes through it so that repositories join a running transaction.
+func conn(ctx context.Context, db *sql.DB) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// inTx runs fn in the transaction that ctx carries, or in a new one that is
+// committed when fn succeeds.
+func inTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// isBusy reports whether err means another connection holds the lock. The
+// repositories wrap driver errors as text, so the message is checked too.
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return err != nil && (strings.Contains(err.Error(), "database is locked") ||
+		strings.Contains(err.Error(), "database table is locked"))
+}
+
+type SQLiteTxManager struct {
+	db *sql.DB
+}
+
+func NewSQLiteTxManager(db *sql.DB) repository.TxManager {
+	return &SQLiteTxManager{db: db}
+}
+
+// WithinTx runs fn in a transaction and starts it over while SQLite answers
+// that the database is busy, which happens when two transactions that read
+// the same rows both try to write.
+func (m *SQLiteTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	delay := txRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := inTx(ctx, m.db, fn)
+		if !isBusy(err) || attempt == maxTxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
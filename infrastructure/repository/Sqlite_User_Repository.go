@@ -1,10 +1,14 @@
 package infra_repository
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 	"time"
 
 	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
 	"forum/domain/repository"
 
 	"github.com/google/uuid"
@@ -18,85 +22,79 @@ func NewSQLiteUserRepository(db *sql.DB) repository.UserRepository {
 	return &SQLiteUserRepository{db: db}
 }
 
-func (r *SQLiteUserRepository) Create(user *entity.User) error {
+func (r *SQLiteUserRepository) Create(ctx context.Context, user *entity.User) error {
 	user.ID = uuid.New()
 	user.CreatedAt = time.Now()
+	if user.Role == "" {
+		user.Role = entity.RoleUser
+	}
 	
-	query := `INSERT INTO user (id, user_name, email, password_hash, created_at)
-			  VALUES (?, ?, ?, ?, ?)`
+	query := `INSERT INTO user (id, user_name, email, password_hash, role, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?)`
 	
-	_, err := r.db.Exec(query, user.ID.String(), user.UserName, user.Email, user.PasswordHash, user.CreatedAt)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, user.ID.String(), user.UserName, user.Email, user.PasswordHash, user.Role, user.CreatedAt)
+	if isUserNameTaken(err) {
+		return custom_errors.ErrNameTaken
+	}
 	return err
 }
 
-func (r *SQLiteUserRepository) GetByID(userID uuid.UUID) (*entity.User, error) {
-	query := `SELECT id, user_name, email, password_hash, created_at FROM user WHERE id = ?`
-	
-	row := r.db.QueryRow(query, userID.String())
-	
-	user := &entity.User{}
-	var idStr string
-	
-	err := row.Scan(&idStr, &user.UserName, &user.Email, &user.PasswordHash, &user.CreatedAt)
-	if err != nil {
-		return nil, err
-	}
-	
-	user.ID, err = uuid.Parse(idStr)
-	if err != nil {
-		return nil, err
-	}
-	
-	return user, nil
+// isUserNameTaken reports whether err is the unique index on user names
+// refusing a name that someone already has.
+func isUserNameTaken(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed: user.user_name")
 }
 
-func (r *SQLiteUserRepository) GetByEmail(email string) (*entity.User, error) {
-	query := `SELECT id, user_name, email, password_hash, created_at FROM user WHERE email = ?`
-	
-	row := r.db.QueryRow(query, email)
-	
+// userColumns are the columns scanUser reads, in order.
+const userColumns = `id, user_name, email, password_hash, role, created_at, bio, avatar_name, user_name_changed_at, email_verified_at`
+
+func scanUser(row *sql.Row) (*entity.User, error) {
 	user := &entity.User{}
 	var idStr string
-	
-	err := row.Scan(&idStr, &user.UserName, &user.Email, &user.PasswordHash, &user.CreatedAt)
-	if err != nil {
+	var changedAt, verifiedAt sql.NullTime
+
+	err := row.Scan(&idStr, &user.UserName, &user.Email, &user.PasswordHash, &user.Role, &user.CreatedAt,
+		&user.Bio, &user.AvatarName, &changedAt, &verifiedAt)
+	if err == sql.ErrNoRows {
+		return nil, custom_errors.ErrUserNotFound
+	} else if err != nil {
 		return nil, err
 	}
-	
-	user.ID, err = uuid.Parse(idStr)
-	if err != nil {
-		return nil, err
+	if changedAt.Valid {
+		user.UserNameChangedAt = &changedAt.Time
 	}
-	
-	return user, nil
-}
-
-func (r *SQLiteUserRepository) GetByUserName(userName string) (*entity.User, error) {
-	query := `SELECT id, user_name, email, password_hash, created_at FROM user WHERE user_name = ?`
-	
-	row := r.db.QueryRow(query, userName)
-	
-	user := &entity.User{}
-	var idStr string
-	
-	err := row.Scan(&idStr, &user.UserName, &user.Email, &user.PasswordHash, &user.CreatedAt)
-	if err != nil {
-		return nil, err
+	if verifiedAt.Valid {
+		user.EmailVerifiedAt = &verifiedAt.Time
 	}
-	
+
 	user.ID, err = uuid.Parse(idStr)
 	if err != nil {
 		return nil, err
 	}
-	
+
 	return user, nil
 }
 
-func (r *SQLiteUserRepository) CheckEmailExists(email string) (bool, error) {
+func (r *SQLiteUserRepository) GetByID(ctx context.Context, userID uuid.UUID) (*entity.User, error) {
+	query := `SELECT ` + userColumns + ` FROM user WHERE id = ?`
+	return scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, userID.String()))
+}
+
+func (r *SQLiteUserRepository) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	query := `SELECT ` + userColumns + ` FROM user WHERE email = ?`
+	return scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, email))
+}
+
+func (r *SQLiteUserRepository) GetByUserName(ctx context.Context, userName string) (*entity.User, error) {
+	query := `SELECT ` + userColumns + ` FROM user WHERE user_name = ?`
+	return scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, userName))
+}
+
+func (r *SQLiteUserRepository) CheckEmailExists(ctx context.Context, email string) (bool, error) {
 	query := `SELECT COUNT(*) FROM user WHERE email = ?`
 	
 	var count int
-	err := r.db.QueryRow(query, email).Scan(&count)
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, email).Scan(&count)
 	if err != nil {
 		return false, err
 	}
@@ -104,14 +102,125 @@ func (r *SQLiteUserRepository) CheckEmailExists(email string) (bool, error) {
 	return count > 0, nil
 }
 
-func (r *SQLiteUserRepository) CheckUserNameExists(userName string) (bool, error) {
+func (r *SQLiteUserRepository) CheckUserNameExists(ctx context.Context, userName string) (bool, error) {
 	query := `SELECT COUNT(*) FROM user WHERE user_name = ?`
 	
 	var count int
-	err := r.db.QueryRow(query, userName).Scan(&count)
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, userName).Scan(&count)
 	if err != nil {
 		return false, err
 	}
 	
 	return count > 0, nil
-}
\ No newline at end of file
+}
+
+// userStatsQuery sums up the posts and the comments of a user that are not
+// deleted, with the reactions they received.
+const userStatsQuery = `
+	SELECT COALESCE(SUM(is_post), 0), COALESCE(SUM(1 - is_post), 0),
+		COALESCE(SUM(like_count), 0), COALESCE(SUM(dislike_count), 0)
+	FROM (
+		SELECT 1 AS is_post, like_count, dislike_count FROM posts WHERE user_id = ?
+		UNION ALL
+		SELECT 0, like_count, dislike_count FROM comments WHERE user_id = ? AND deleted_at IS NULL
+	) AS contributions`
+
+func (r *SQLiteUserRepository) GetStats(ctx context.Context, userID uuid.UUID) (*entity.UserStats, error) {
+	stats := &entity.UserStats{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, userStatsQuery, userID.String(), userID.String()).
+		Scan(&stats.PostCount, &stats.CommentCount, &stats.LikesReceived, &stats.DislikesReceived)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return stats, nil
+}
+
+func (r *SQLiteUserRepository) UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	query := `UPDATE user SET password_hash = ? WHERE id = ?`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, passwordHash, userID.String())
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return custom_errors.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteUserRepository) MarkEmailVerified(ctx context.Context, userID uuid.UUID, verifiedAt time.Time) error {
+	query := `UPDATE user SET email_verified_at = COALESCE(email_verified_at, ?) WHERE id = ?`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, verifiedAt, userID.String())
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return custom_errors.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteUserRepository) UpdateProfile(ctx context.Context, user *entity.User) error {
+	query := `UPDATE user SET bio = ?, avatar_name = ? WHERE id = ?`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, user.Bio, user.AvatarName, user.ID.String())
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return custom_errors.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteUserRepository) ChangeUserName(ctx context.Context, userID uuid.UUID, newName string, changedAt time.Time) error {
+	return inTx(ctx, r.db, func(ctx context.Context) error {
+		var oldName string
+		err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT user_name FROM user WHERE id = ?`, userID.String()).Scan(&oldName)
+		if err == sql.ErrNoRows {
+			return custom_errors.ErrUserNotFound
+		} else if err != nil {
+			return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+		}
+
+		_, err = conn(ctx, r.db).ExecContext(ctx,
+			`INSERT INTO user_name_history (id, user_id, user_name, changed_at) VALUES (?, ?, ?, ?)`,
+			uuid.New().String(), userID.String(), oldName, changedAt)
+		if err != nil {
+			return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+		}
+
+		_, err = conn(ctx, r.db).ExecContext(ctx,
+			`UPDATE user SET user_name = ?, user_name_changed_at = ? WHERE id = ?`,
+			newName, changedAt, userID.String())
+		if isUserNameTaken(err) {
+			return custom_errors.ErrNameTaken
+		} else if err != nil {
+			return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+		}
+		return nil
+	})
+}
+
+func (r *SQLiteUserRepository) GetLastUserNameChange(ctx context.Context, userName string) (*entity.UserNameChange, error) {
+	query := `SELECT id, user_id, user_name, changed_at FROM user_name_history
+			  WHERE user_name = ? ORDER BY changed_at DESC LIMIT 1`
+
+	change := &entity.UserNameChange{}
+	var idStr, userIDStr string
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, userName).Scan(&idStr, &userIDStr, &change.UserName, &change.ChangedAt)
+	if err == sql.ErrNoRows {
+		return nil, custom_errors.ErrUserNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+
+	if change.ID, err = uuid.Parse(idStr); err != nil {
+		return nil, err
+	}
+	if change.UserID, err = uuid.Parse(userIDStr); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
@@ -1,12 +1,14 @@
 package infra_repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"time"
 
 	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
 	"forum/domain/repository"
 
 	"github.com/google/uuid"
@@ -20,32 +22,23 @@ func NewSQLiteUserSessionRepository(db *sql.DB) repository.UserSessionRepository
 	return &SQLiteUserSessionRepository{db: db}
 }
 
-func (r *SQLiteUserSessionRepository) Create(session *entity.UserSession) error {
-	session.ID = uuid.New()
-	session.CreatedAt = time.Now()
-	query := `INSERT INTO user_sessions (id, user_id, session_token, expires_at, created_at)
-			  VALUES (?, ?, ?, ?, ?)`
-
-	_, err := r.db.Exec(query, session.ID.String(), session.UserID.String(),
-		session.SessionToken, session.ExpiresAt, session.CreatedAt)
-	return err
-}
-
-func (r *SQLiteUserSessionRepository) GetByToken(token string) (*entity.UserSession, error) {
-	query := `SELECT id, user_id, session_token, expires_at, created_at 
-			  FROM user_sessions WHERE session_token = ?`
-
-	row := r.db.QueryRow(query, token)
+// sessionColumns are the columns scanSession reads, in order.
+const sessionColumns = `id, user_id, session_token, user_agent, ip_address, last_seen_at, expires_at, created_at`
 
+// scanSession reads one session from a row or from the current row of rows.
+func scanSession(row rowScanner) (*entity.UserSession, error) {
 	session := &entity.UserSession{}
 	var idStr, userIDStr string
+	var lastSeenAt sql.NullTime
 
-	err := row.Scan(&idStr, &userIDStr, &session.SessionToken, &session.ExpiresAt, &session.CreatedAt)
+	err := row.Scan(&idStr, &userIDStr, &session.SessionToken, &session.UserAgent, &session.IPAddress,
+		&lastSeenAt, &session.ExpiresAt, &session.CreatedAt)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("you need to login")
-		}
-		return nil, fmt.Errorf("failed to scan session: %v", err)
+		return nil, err
+	}
+	session.LastSeenAt = session.CreatedAt
+	if lastSeenAt.Valid {
+		session.LastSeenAt = lastSeenAt.Time
 	}
 
 	session.ID, err = uuid.Parse(idStr)
@@ -61,58 +54,116 @@ func (r *SQLiteUserSessionRepository) GetByToken(token string) (*entity.UserSess
 	return session, nil
 }
 
-func (r *SQLiteUserSessionRepository) GetByUserID(userID uuid.UUID) (*entity.UserSession, error) {
-	query := `SELECT id, user_id, session_token, expires_at, created_at 
-			  FROM user_sessions WHERE user_id = ? `
+func (r *SQLiteUserSessionRepository) Create(ctx context.Context, session *entity.UserSession) error {
+	session.ID = uuid.New()
+	session.CreatedAt = time.Now()
+	session.LastSeenAt = session.CreatedAt
+	query := `INSERT INTO user_sessions (id, user_id, session_token, user_agent, ip_address, last_seen_at, expires_at, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, session.ID.String(), session.UserID.String(),
+		session.SessionToken, session.UserAgent, session.IPAddress, session.LastSeenAt, session.ExpiresAt, session.CreatedAt)
+	return err
+}
+
+func (r *SQLiteUserSessionRepository) GetByToken(ctx context.Context, token string) (*entity.UserSession, error) {
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions WHERE session_token = ?`
 
-	rows, err := r.db.Query(query, userID.String())
+	session, err := scanSession(conn(ctx, r.db).QueryRowContext(ctx, query, token))
 	if err != nil {
-		return nil, err
+		if err == sql.ErrNoRows {
+			return nil, errors.New("you need to login")
+		}
+		return nil, fmt.Errorf("failed to scan session: %v", err)
 	}
-	defer rows.Close()
+	return session, nil
+}
 
-	var sessions *entity.UserSession
+// GetByUserID returns the newest session of a user, or nil if there is none.
+func (r *SQLiteUserSessionRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*entity.UserSession, error) {
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions WHERE user_id = ? ORDER BY created_at DESC LIMIT 1`
 
-	for rows.Next() {
-		session := &entity.UserSession{}
-		var idStr, userIDStr string
+	session, err := scanSession(conn(ctx, r.db).QueryRowContext(ctx, query, userID.String()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
 
-		err := rows.Scan(&idStr, &userIDStr, &session.SessionToken, &session.ExpiresAt, &session.CreatedAt)
-		if err != nil {
-			return nil, err
-		}
+func (r *SQLiteUserSessionRepository) ListByUserID(ctx context.Context, userID uuid.UUID, now time.Time) ([]*entity.UserSession, error) {
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions
+			  WHERE user_id = ? AND julianday(expires_at) > julianday(?)
+			  ORDER BY julianday(last_seen_at) DESC, julianday(created_at) DESC`
 
-		session.ID, err = uuid.Parse(idStr)
-		if err != nil {
-			return nil, err
-		}
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID.String(), now)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	defer rows.Close()
 
-		session.UserID, err = uuid.Parse(userIDStr)
+	var sessions []*entity.UserSession
+	for rows.Next() {
+		session, err := scanSession(rows)
 		if err != nil {
 			return nil, err
 		}
+		sessions = append(sessions, session)
 	}
-
-	return sessions, nil
+	return sessions, rows.Err()
 }
 
-func (r *SQLiteUserSessionRepository) Update(session *entity.UserSession) error {
+func (r *SQLiteUserSessionRepository) Update(ctx context.Context, session *entity.UserSession) error {
 	query := `UPDATE user_sessions SET expires_at = ? WHERE id = ?`
 
-	_, err := r.db.Exec(query, session.ExpiresAt, session.ID.String())
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, session.ExpiresAt, session.ID.String())
 	return err
 }
 
-func (r *SQLiteUserSessionRepository) Delete(sessionID uuid.UUID) error {
+func (r *SQLiteUserSessionRepository) Touch(ctx context.Context, sessionID uuid.UUID, seenAt time.Time, ipAddress string) error {
+	query := `UPDATE user_sessions SET last_seen_at = ?, ip_address = ? WHERE id = ?`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, seenAt, ipAddress, sessionID.String())
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return nil
+}
+
+func (r *SQLiteUserSessionRepository) Delete(ctx context.Context, sessionID uuid.UUID) error {
 	query := `DELETE FROM user_sessions WHERE id = ?`
 
-	_, err := r.db.Exec(query, sessionID.String())
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, sessionID.String())
 	return err
 }
 
-func (r *SQLiteUserSessionRepository) DeleteAllUserSessions(userID uuid.UUID) error {
+func (r *SQLiteUserSessionRepository) DeleteUserSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	query := `DELETE FROM user_sessions WHERE id = ? AND user_id = ?`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, sessionID.String(), userID.String())
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return custom_errors.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteUserSessionRepository) DeleteExpiredUserSessions(ctx context.Context, userID uuid.UUID, now time.Time) error {
+	query := `DELETE FROM user_sessions WHERE user_id = ? AND julianday(expires_at) <= julianday(?)`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, userID.String(), now)
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return nil
+}
+
+func (r *SQLiteUserSessionRepository) DeleteAllUserSessions(ctx context.Context, userID uuid.UUID) error {
 	query := `DELETE FROM user_sessions WHERE user_id = ?`
 
-	_, err := r.db.Exec(query, userID.String())
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, userID.String())
 	return err
 }
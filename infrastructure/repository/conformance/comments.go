@@ -0,0 +1,151 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+func newComment(ctx context.Context, t T, repos *repository.Repositories, user *entity.User, post *entity.Post, parent *entity.Comment) *entity.Comment {
+	t.Helper()
+	comment := &entity.Comment{Content: unique("comment"), UserID: user.ID, PostID: post.ID}
+	if parent != nil {
+		comment.ParentID = &parent.ID
+	}
+	must(t, repos.Comment.Create(ctx, comment), "create comment")
+	tick()
+	return comment
+}
+
+func checkComments(ctx context.Context, t T, repos *repository.Repositories) {
+	user := newUser(ctx, t, repos)
+	post := newPost(ctx, t, repos, user, "discussed")
+
+	first := newComment(ctx, t, repos, user, post, nil)
+	second := newComment(ctx, t, repos, user, post, nil)
+	reply := newComment(ctx, t, repos, user, post, first)
+	if first.ID == uuid.Nil || first.CreatedAt.IsZero() {
+		t.Errorf("Create did not set the ID and CreatedAt: %+v", first)
+	}
+
+	got, err := repos.Comment.GetByID(ctx, reply.ID)
+	must(t, err, "GetByID")
+	if got.Content != reply.Content || got.ParentID == nil || *got.ParentID != first.ID || got.IsDeleted() || got.IsEdited() {
+		t.Errorf("GetByID returned %+v, want %+v", got, reply)
+	}
+	if _, err := repos.Comment.GetByID(ctx, uuid.New()); !errors.Is(err, custom_errors.ErrCommentNotFound) {
+		t.Errorf("GetByID of an unknown comment returned %v, want %v", err, custom_errors.ErrCommentNotFound)
+	}
+
+	if count, err := repos.Comment.GetCountByPostID(ctx, post.ID); err != nil || count != 3 {
+		t.Errorf("GetCountByPostID returned %d, %v; want 3", count, err)
+	}
+	if count, err := repos.Comment.GetCountByUserID(ctx, user.ID); err != nil || count != 3 {
+		t.Errorf("GetCountByUserID returned %d, %v; want 3", count, err)
+	}
+
+	plain, err := repos.Comment.GetByPostID(ctx, post.ID)
+	must(t, err, "GetByPostID")
+	if len(plain) != 3 || plain[0].ID != first.ID || plain[1].ID != second.ID || plain[2].ID != reply.ID {
+		t.Errorf("GetByPostID did not return the comments oldest first: %+v", plain)
+	}
+
+	threaded, err := repos.Comment.GetByPostIDWithDetails(ctx, post.ID)
+	must(t, err, "GetByPostIDWithDetails")
+	if len(threaded) != 3 {
+		t.Fatalf("GetByPostIDWithDetails returned %d comments, want 3", len(threaded))
+	}
+	want := []struct {
+		id      uuid.UUID
+		depth   int
+		replies int
+	}{{first.ID, 0, 1}, {reply.ID, 1, 0}, {second.ID, 0, 0}}
+	for i, w := range want {
+		c := threaded[i]
+		if c.ID != w.id || c.Depth != w.depth || c.ReplyCount != w.replies {
+			t.Errorf("threaded comment %d is %s at depth %d with %d replies, want %s at depth %d with %d replies",
+				i, c.ID, c.Depth, c.ReplyCount, w.id, w.depth, w.replies)
+		}
+		if c.Author.ID != user.ID || c.Author.UserName != user.UserName {
+			t.Errorf("threaded comment %d has author %+v, want %s", i, c.Author, user.UserName)
+		}
+	}
+
+	reply.Content = "edited"
+	must(t, repos.Comment.Update(ctx, reply), "update comment")
+	got, err = repos.Comment.GetByID(ctx, reply.ID)
+	must(t, err, "GetByID after Update")
+	if got.Content != "edited" || !got.IsEdited() {
+		t.Errorf("Update was not stored: %+v", got)
+	}
+
+	must(t, repos.Comment.SoftDelete(ctx, first.ID), "soft delete comment")
+	got, err = repos.Comment.GetByID(ctx, first.ID)
+	must(t, err, "GetByID after SoftDelete")
+	if !got.IsDeleted() || got.Content != "" {
+		t.Errorf("SoftDelete did not leave a tombstone: %+v", got)
+	}
+	if err := repos.Comment.SoftDelete(ctx, first.ID); !errors.Is(err, custom_errors.ErrCommentNotFound) {
+		t.Errorf("SoftDelete of a deleted comment returned %v, want %v", err, custom_errors.ErrCommentNotFound)
+	}
+	threaded, err = repos.Comment.GetByPostIDWithDetails(ctx, post.ID)
+	must(t, err, "GetByPostIDWithDetails after SoftDelete")
+	if len(threaded) != 3 || threaded[0].ID != first.ID || threaded[0].CanReply || threaded[1].ID != reply.ID {
+		t.Errorf("a deleted comment does not keep its replies threaded below it")
+	}
+
+	must(t, repos.Comment.Delete(ctx, second.ID), "delete comment")
+	if _, err := repos.Comment.GetByID(ctx, second.ID); !errors.Is(err, custom_errors.ErrCommentNotFound) {
+		t.Errorf("GetByID of a deleted comment returned %v, want %v", err, custom_errors.ErrCommentNotFound)
+	}
+}
+
+func checkCommentReactions(ctx context.Context, t T, repos *repository.Repositories) {
+	author, liker, disliker := newUser(ctx, t, repos), newUser(ctx, t, repos), newUser(ctx, t, repos)
+	post := newPost(ctx, t, repos, author, "comment reactions")
+	comment := newComment(ctx, t, repos, author, post, nil)
+
+	counts := func(wantLikes, wantDislikes int, when string) {
+		t.Helper()
+		likes, dislikes, err := repos.CommentReaction.GetReactionCountsByCommentID(ctx, comment.ID)
+		if err != nil || likes != wantLikes || dislikes != wantDislikes {
+			t.Errorf("%s: counts are %d/%d (%v), want %d/%d", when, likes, dislikes, err, wantLikes, wantDislikes)
+		}
+	}
+
+	like := &entity.CommentReaction{UserID: liker.ID, CommentID: comment.ID, Reaction: true}
+	must(t, repos.CommentReaction.Create(ctx, like), "create like")
+	must(t, repos.CommentReaction.Create(ctx, &entity.CommentReaction{UserID: disliker.ID, CommentID: comment.ID}), "create dislike")
+	counts(1, 1, "after one like and one dislike")
+
+	err := repos.CommentReaction.Create(ctx, &entity.CommentReaction{UserID: liker.ID, CommentID: comment.ID, Reaction: true})
+	if !errors.Is(err, custom_errors.ErrReactionExists) {
+		t.Errorf("a second reaction by the same user returned %v, want %v", err, custom_errors.ErrReactionExists)
+	}
+
+	got, err := repos.CommentReaction.GetByUserAndComment(ctx, liker.ID, comment.ID)
+	if err != nil || got.ID != like.ID || !got.Reaction {
+		t.Errorf("GetByUserAndComment returned %+v, %v", got, err)
+	}
+	if _, err := repos.CommentReaction.GetByUserAndComment(ctx, author.ID, comment.ID); !errors.Is(err, custom_errors.ErrReactionNotFound) {
+		t.Errorf("GetByUserAndComment without a reaction returned %v, want %v", err, custom_errors.ErrReactionNotFound)
+	}
+
+	like.Reaction = false
+	must(t, repos.CommentReaction.Update(ctx, like), "update reaction")
+	counts(0, 2, "after turning the like into a dislike")
+
+	threaded, err := repos.Comment.GetByPostIDWithDetails(ctx, post.ID)
+	must(t, err, "GetByPostIDWithDetails")
+	if len(threaded) != 1 || threaded[0].LikeCount != 0 || threaded[0].DislikeCount != 2 {
+		t.Errorf("comment details do not carry the reaction counts: %+v", threaded)
+	}
+
+	must(t, repos.CommentReaction.Delete(ctx, like.ID), "delete reaction")
+	counts(0, 1, "after deleting a reaction")
+}
@@ -0,0 +1,160 @@
+// Package conformance holds behavioural checks that every implementation of
+// the domain repositories must pass, so that the in-memory, SQLite and
+// PostgreSQL backends stay interchangeable.
+//
+// The checks only look at rows they create themselves, so they can run
+// against a database that already holds data. They leave their rows behind.
+//
+// go test runs them against the in-memory backend and a throwaway SQLite
+// database, see conformance_test.go in those packages:
+//
+//	for _, check := range conformance.Checks {
+//		t.Run(check.Name, func(t *testing.T) {
+//			check.Run(context.Background(), t, repos)
+//		})
+//	}
+//
+// With -tags postgres, go test also runs them against PostgreSQL: the
+// database at POSTGRES_TEST_URL, or an embedded server. cmd/conformance runs
+// them from the command line against an existing database.
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+// T is the part of *testing.T that the checks use.
+type T interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// Check is one group of assertions on a set of repositories.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context, t T, repos *repository.Repositories)
+}
+
+// Checks are all the conformance checks.
+var Checks = []Check{
+	{"users", checkUsers},
+	{"sessions", checkSessions},
+	{"session_devices", checkSessionDevices},
+	{"categories", checkCategories},
+	{"posts", checkPosts},
+	{"post_categories", checkPostCategories},
+	{"post_reactions", checkPostReactions},
+	{"comments", checkComments},
+	{"comment_reactions", checkCommentReactions},
+	{"attachments", checkAttachments},
+	{"post_aggregate", checkPostAggregate},
+	{"post_revisions", checkPostRevisions},
+	{"feed_pages", checkFeedPages},
+	{"feed_sorting", checkFeedSorting},
+	{"search", checkSearch},
+	{"search_highlight", checkSearchHighlight},
+	{"transactions", checkTransactions},
+	{"reaction_toggle", checkReactionToggle},
+	{"user_stats", checkUserStats},
+	{"user_comments", checkUserComments},
+	{"user_profile_update", checkUserProfileUpdate},
+	{"user_name_change", checkUserNameChange},
+	{"user_name_taken", checkUserNameTaken},
+	{"password_reset", checkPasswordReset},
+	{"email_verification", checkEmailVerification},
+}
+
+// Find returns the check called name.
+func Find(name string) (Check, bool) {
+	for _, check := range Checks {
+		if check.Name == name {
+			return check, true
+		}
+	}
+	return Check{}, false
+}
+
+// Result is the outcome of running one check outside of go test.
+type Result struct {
+	Name   string
+	Errors []string
+}
+
+func (r Result) Passed() bool {
+	return len(r.Errors) == 0
+}
+
+// Run runs every check against the repositories and reports the outcome of
+// each.
+func Run(ctx context.Context, repos *repository.Repositories) []Result {
+	results := make([]Result, len(Checks))
+	for i, check := range Checks {
+		rec := &recorder{}
+		rec.run(func() { check.Run(ctx, rec, repos) })
+		results[i] = Result{Name: check.Name, Errors: rec.errors}
+	}
+	return results
+}
+
+// recorder is a T that collects failures instead of reporting them to the
+// testing package.
+type recorder struct {
+	errors []string
+}
+
+// errFatal stops a check after Fatalf.
+var errFatal = errors.New("conformance: fatal")
+
+func (r *recorder) run(fn func()) {
+	defer func() {
+		if v := recover(); v != nil && v != errFatal {
+			r.errors = append(r.errors, fmt.Sprintf("panic: %v", v))
+		}
+	}()
+	fn()
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+	panic(errFatal)
+}
+
+// unique returns a name that no earlier run has used. It is made of letters
+// and digits only, so it is also a single search term.
+func unique(prefix string) string {
+	id := uuid.New()
+	return fmt.Sprintf("%s%x", prefix, id[:6])
+}
+
+// sameTime compares times as stored: PostgreSQL keeps microseconds only.
+func sameTime(a, b time.Time) bool {
+	d := a.Sub(b)
+	return d > -time.Millisecond && d < time.Millisecond
+}
+
+// tick makes sure that rows created after it get a later timestamp than
+// those created before, whatever the precision of the backend.
+func tick() {
+	time.Sleep(2 * time.Millisecond)
+}
+
+func must(t T, err error, what string) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+}
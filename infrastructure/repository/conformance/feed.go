@@ -0,0 +1,248 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+func pagePostIDs(page *entity.PostPage) []uuid.UUID {
+	ids := make([]uuid.UUID, len(page.Posts))
+	for i, post := range page.Posts {
+		ids[i] = post.ID
+	}
+	return ids
+}
+
+func checkPostAggregate(ctx context.Context, t T, repos *repository.Repositories) {
+	user, reactor := newUser(ctx, t, repos), newUser(ctx, t, repos)
+	first, second := newCategory(ctx, t, repos), newCategory(ctx, t, repos)
+
+	post := &entity.Post{Title: "aggregate", Content: "original", UserID: user.ID}
+	must(t, repos.PostAggregate.CreatePostWithCategories(ctx, post, []*uuid.UUID{&first.ID, &second.ID}), "create post with categories")
+	if post.ID == uuid.Nil {
+		t.Fatalf("CreatePostWithCategories did not set the ID")
+	}
+	must(t, repos.PostReaction.Create(ctx, &entity.PostReaction{UserID: reactor.ID, PostID: post.ID, Reaction: true}), "create like")
+	comment := newComment(ctx, t, repos, reactor, post, nil)
+	must(t, repos.CommentReaction.Create(ctx, &entity.CommentReaction{UserID: user.ID, CommentID: comment.ID, Reaction: true}), "create comment like")
+	must(t, repos.Attachment.Create(ctx, &entity.PostAttachment{PostID: post.ID, FileName: unique("f") + ".png",
+		ThumbnailName: unique("t") + ".png", ContentType: "image/png"}), "create attachment")
+
+	details, err := repos.PostAggregate.GetPostWithAllDetails(ctx, post.ID)
+	must(t, err, "GetPostWithAllDetails")
+	if details.Title != post.Title || details.Author.ID != user.ID || details.Author.PasswordHash != "" {
+		t.Errorf("GetPostWithAllDetails returned the wrong post or author: %+v", details)
+	}
+	if len(details.Categories) != 2 || len(details.Comments) != 1 || len(details.Attachments) != 1 {
+		t.Errorf("GetPostWithAllDetails has %d categories, %d comments and %d attachments, want 2, 1 and 1",
+			len(details.Categories), len(details.Comments), len(details.Attachments))
+	}
+	if details.LikeCount != 1 || details.DislikeCount != 0 || details.CommentCount != 1 || details.IsEdited {
+		t.Errorf("GetPostWithAllDetails has counters %d/%d, %d comments, edited %v; want 1/0, 1, false",
+			details.LikeCount, details.DislikeCount, details.CommentCount, details.IsEdited)
+	}
+	if _, err := repos.PostAggregate.GetPostWithAllDetails(ctx, uuid.New()); !errors.Is(err, custom_errors.ErrPostNotFound) {
+		t.Errorf("GetPostWithAllDetails of an unknown post returned %v, want %v", err, custom_errors.ErrPostNotFound)
+	}
+
+	byUser, err := repos.PostAggregate.GetPostsWithDetailsByUser(ctx, user.ID)
+	must(t, err, "GetPostsWithDetailsByUser")
+	if len(byUser) != 1 || byUser[0].ID != post.ID || len(byUser[0].Categories) != 2 {
+		t.Errorf("GetPostsWithDetailsByUser returned %d posts, want the new post with its categories", len(byUser))
+	}
+
+	for _, title := range []string{"first edit", "second edit"} {
+		tick()
+		post.Title = title
+		must(t, repos.PostAggregate.UpdatePostWithRevision(ctx, post), "update post with revision")
+	}
+	if post.UpdatedAt == nil {
+		t.Errorf("UpdatePostWithRevision did not set UpdatedAt")
+	}
+	revisions, err := repos.PostRevision.GetByPostID(ctx, post.ID)
+	must(t, err, "GetByPostID of revisions")
+	if len(revisions) != 2 || revisions[0].Version != 1 || revisions[0].Title != "aggregate" ||
+		revisions[1].Version != 2 || revisions[1].Title != "first edit" {
+		t.Errorf("UpdatePostWithRevision kept the revisions %+v, want versions 1 and 2 of the earlier titles", revisions)
+	}
+	details, err = repos.PostAggregate.GetPostWithAllDetails(ctx, post.ID)
+	must(t, err, "GetPostWithAllDetails after update")
+	if details.Title != "second edit" || !details.IsEdited {
+		t.Errorf("UpdatePostWithRevision was not stored: %+v", details.Post)
+	}
+	if err := repos.PostAggregate.UpdatePostWithRevision(ctx, &entity.Post{ID: uuid.New(), Title: "x"}); !errors.Is(err, custom_errors.ErrPostNotFound) {
+		t.Errorf("UpdatePostWithRevision of an unknown post returned %v, want %v", err, custom_errors.ErrPostNotFound)
+	}
+
+	must(t, repos.PostAggregate.DeletePostWithDependencies(ctx, post.ID), "delete post with dependencies")
+	if _, err := repos.Post.GetByID(ctx, post.ID); !errors.Is(err, custom_errors.ErrPostNotFound) {
+		t.Errorf("the post is still there after DeletePostWithDependencies: %v", err)
+	}
+	if _, err := repos.Comment.GetByID(ctx, comment.ID); !errors.Is(err, custom_errors.ErrCommentNotFound) {
+		t.Errorf("the post's comment is still there after DeletePostWithDependencies: %v", err)
+	}
+	if categories, err := repos.PostCategory.GetCategoriesByPostID(ctx, post.ID); err != nil || len(categories) != 0 {
+		t.Errorf("the post's categories are still there after DeletePostWithDependencies: %d, %v", len(categories), err)
+	}
+	if revisions, err := repos.PostRevision.GetByPostID(ctx, post.ID); err != nil || len(revisions) != 0 {
+		t.Errorf("the post's revisions are still there after DeletePostWithDependencies: %d, %v", len(revisions), err)
+	}
+	if attachments, err := repos.Attachment.GetByPostID(ctx, post.ID); err != nil || len(attachments) != 0 {
+		t.Errorf("the post's attachments are still there after DeletePostWithDependencies: %d, %v", len(attachments), err)
+	}
+	if err := repos.PostAggregate.DeletePostWithDependencies(ctx, post.ID); !errors.Is(err, custom_errors.ErrPostNotFound) {
+		t.Errorf("a second DeletePostWithDependencies returned %v, want %v", err, custom_errors.ErrPostNotFound)
+	}
+}
+
+// checkPostRevisions edits one post from many goroutines at once. Versions
+// are numbered from the revisions already stored, so the edits must commit one
+// after the other: each keeps the title the previous one wrote, and no version
+// is used twice or skipped.
+func checkPostRevisions(ctx context.Context, t T, repos *repository.Repositories) {
+	user := newUser(ctx, t, repos)
+	post := newPost(ctx, t, repos, user, "original")
+
+	const edits = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, edits)
+	for i := 0; i < edits; i++ {
+		edit := &entity.Post{ID: post.ID, Title: fmt.Sprintf("edit %d", i+1), Content: "edited", UserID: user.ID}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := repos.PostAggregate.UpdatePostWithRevision(ctx, edit); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent UpdatePostWithRevision: %v", err)
+	}
+
+	revisions, err := repos.PostRevision.GetByPostID(ctx, post.ID)
+	must(t, err, "GetByPostID of revisions")
+	if len(revisions) != edits {
+		t.Fatalf("%d concurrent edits kept %d revisions", edits, len(revisions))
+	}
+	current, err := repos.Post.GetByID(ctx, post.ID)
+	must(t, err, "GetByID after the edits")
+
+	// Every title but the current one was saved exactly once, in order.
+	seen := map[string]bool{current.Title: true}
+	for i, revision := range revisions {
+		if revision.Version != i+1 {
+			t.Errorf("revision %d has version %d", i+1, revision.Version)
+		}
+		if seen[revision.Title] {
+			t.Errorf("the title %q was saved twice", revision.Title)
+		}
+		seen[revision.Title] = true
+	}
+	if revisions[0].Title != "original" || len(seen) != edits+1 {
+		t.Errorf("the revisions start with %q and hold %d titles, want the original and all %d",
+			revisions[0].Title, len(seen), edits+1)
+	}
+}
+
+func checkFeedPages(ctx context.Context, t T, repos *repository.Repositories) {
+	user := newUser(ctx, t, repos)
+	var posts []*entity.Post
+	for i := 0; i < 5; i++ {
+		posts = append(posts, newPost(ctx, t, repos, user, unique("paged")))
+		tick()
+	}
+	newest := []uuid.UUID{posts[4].ID, posts[3].ID, posts[2].ID, posts[1].ID, posts[0].ID}
+	filter := entity.PostFilter{MyPosts: true, AuthorID: &user.ID}
+
+	var seen []uuid.UUID
+	var pages []*entity.PostPage
+	request := entity.PageRequest{Limit: 2}
+	for len(pages) < 5 {
+		page, err := repos.PostAggregate.GetFilteredPostsWithDetails(ctx, filter, request)
+		must(t, err, "GetFilteredPostsWithDetails")
+		pages = append(pages, page)
+		seen = append(seen, pagePostIDs(page)...)
+		if page.Next == nil {
+			break
+		}
+		request.Cursor = page.Next
+	}
+	if !sameIDs(seen, newest) {
+		t.Fatalf("paging forward returned %v, want %v", seen, newest)
+	}
+	if len(pages) != 3 || pages[0].Prev != nil || pages[1].Prev == nil || pages[2].Next != nil {
+		t.Errorf("paging forward gave %d pages with the wrong cursors", len(pages))
+	}
+
+	back, err := repos.PostAggregate.GetFilteredPostsWithDetails(ctx, filter, entity.PageRequest{Limit: 2, Cursor: pages[2].Prev})
+	must(t, err, "GetFilteredPostsWithDetails backwards")
+	if !sameIDs(pagePostIDs(back), newest[2:4]) || back.Prev == nil || back.Next == nil {
+		t.Errorf("paging back returned %v, want %v with both cursors", pagePostIDs(back), newest[2:4])
+	}
+	back, err = repos.PostAggregate.GetFilteredPostsWithDetails(ctx, filter, entity.PageRequest{Limit: 2, Cursor: back.Prev})
+	must(t, err, "GetFilteredPostsWithDetails backwards")
+	if !sameIDs(pagePostIDs(back), newest[:2]) || back.Prev != nil || back.Next == nil {
+		t.Errorf("paging back to the start returned %v, want %v without a previous page", pagePostIDs(back), newest[:2])
+	}
+}
+
+func checkFeedSorting(ctx context.Context, t T, repos *repository.Repositories) {
+	author := newUser(ctx, t, repos)
+	voters := []*entity.User{newUser(ctx, t, repos), newUser(ctx, t, repos), newUser(ctx, t, repos)}
+
+	// Created oldest first with net votes 0, +2 and -1.
+	quiet := newPost(ctx, t, repos, author, "quiet")
+	tick()
+	liked := newPost(ctx, t, repos, author, "liked")
+	tick()
+	disliked := newPost(ctx, t, repos, author, "disliked")
+	votes := []struct {
+		post     *entity.Post
+		voter    *entity.User
+		reaction bool
+	}{
+		{liked, voters[0], true}, {liked, voters[1], true},
+		{disliked, voters[0], false},
+	}
+	for _, v := range votes {
+		must(t, repos.PostReaction.Create(ctx, &entity.PostReaction{UserID: v.voter.ID, PostID: v.post.ID, Reaction: v.reaction}), "create reaction")
+	}
+
+	sorted := func(mode entity.SortMode) []uuid.UUID {
+		t.Helper()
+		page, err := repos.PostAggregate.GetFilteredPostsWithDetails(ctx,
+			entity.PostFilter{MyPosts: true, AuthorID: &author.ID, Sort: mode}, entity.PageRequest{})
+		must(t, err, "GetFilteredPostsWithDetails sorted by "+string(mode))
+		return pagePostIDs(page)
+	}
+	if got, want := sorted(entity.SortNew), []uuid.UUID{disliked.ID, liked.ID, quiet.ID}; !sameIDs(got, want) {
+		t.Errorf("sorting by new returned %v, want %v", got, want)
+	}
+	if got, want := sorted(entity.SortTop), []uuid.UUID{liked.ID, quiet.ID, disliked.ID}; !sameIDs(got, want) {
+		t.Errorf("sorting by top returned %v, want %v", got, want)
+	}
+	if got := sorted(entity.SortHot); len(got) != 3 || got[0] != liked.ID {
+		t.Errorf("sorting by hot returned %v, want %s first", got, liked.ID)
+	}
+
+	page, err := repos.PostAggregate.GetFilteredPostsWithDetails(ctx,
+		entity.PostFilter{LikedPosts: true, AuthorID: &voters[0].ID}, entity.PageRequest{})
+	must(t, err, "GetFilteredPostsWithDetails of liked posts")
+	if got, want := pagePostIDs(page), []uuid.UUID{liked.ID}; !sameIDs(got, want) {
+		t.Errorf("liked posts returned %v, want %v", got, want)
+	}
+	if page.Posts[0].LikeCount != 2 || page.Posts[0].DislikeCount != 0 {
+		t.Errorf("liked post has counters %d/%d, want 2/0", page.Posts[0].LikeCount, page.Posts[0].DislikeCount)
+	}
+}
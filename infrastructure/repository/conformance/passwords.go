@@ -0,0 +1,94 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+func newResetToken(ctx context.Context, t T, repos *repository.Repositories, user *entity.User, expiresAt time.Time) *entity.PasswordResetToken {
+	t.Helper()
+	token := &entity.PasswordResetToken{UserID: user.ID, TokenHash: unique("hash"), ExpiresAt: expiresAt}
+	must(t, repos.PasswordReset.Create(ctx, token), "create reset token")
+	return token
+}
+
+func checkPasswordReset(ctx context.Context, t T, repos *repository.Repositories) {
+	user := newUser(ctx, t, repos)
+	must(t, repos.User.UpdatePasswordHash(ctx, user.ID, "new hash"), "UpdatePasswordHash")
+	stored, err := repos.User.GetByID(ctx, user.ID)
+	must(t, err, "GetByID")
+	if stored.PasswordHash != "new hash" {
+		t.Errorf("after UpdatePasswordHash the hash is %q, want %q", stored.PasswordHash, "new hash")
+	}
+	if err := repos.User.UpdatePasswordHash(ctx, uuid.New(), "hash"); !errors.Is(err, custom_errors.ErrUserNotFound) {
+		t.Errorf("UpdatePasswordHash of an unknown user returned %v, want %v", err, custom_errors.ErrUserNotFound)
+	}
+
+	expiresAt := time.Now().Add(time.Hour)
+	token := newResetToken(ctx, t, repos, user, expiresAt)
+	if token.ID == uuid.Nil || token.CreatedAt.IsZero() {
+		t.Errorf("Create did not set the ID and CreatedAt: %+v", token)
+	}
+	found, err := repos.PasswordReset.GetByTokenHash(ctx, token.TokenHash)
+	must(t, err, "GetByTokenHash")
+	if found.ID != token.ID || found.UserID != user.ID || !sameTime(found.ExpiresAt, expiresAt) || found.UsedAt != nil {
+		t.Errorf("GetByTokenHash returned %+v, want %+v", found, token)
+	}
+	if !found.IsUsable(time.Now()) || found.IsUsable(expiresAt) {
+		t.Errorf("a new token is not usable until it expires: %+v", found)
+	}
+	if _, err := repos.PasswordReset.GetByTokenHash(ctx, unique("hash")); !errors.Is(err, custom_errors.ErrResetTokenNotFound) {
+		t.Errorf("GetByTokenHash of an unknown hash returned %v, want %v", err, custom_errors.ErrResetTokenNotFound)
+	}
+
+	// Only one of many concurrent uses wins.
+	const attempts = 8
+	var wg sync.WaitGroup
+	results := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- repos.PasswordReset.MarkUsed(ctx, token.ID, time.Now())
+		}()
+	}
+	wg.Wait()
+	close(results)
+	var won int
+	for err := range results {
+		if err == nil {
+			won++
+		} else if !errors.Is(err, custom_errors.ErrResetTokenNotFound) {
+			t.Errorf("concurrent MarkUsed: %v", err)
+		}
+	}
+	if won != 1 {
+		t.Errorf("%d of %d concurrent MarkUsed calls succeeded, want 1", won, attempts)
+	}
+	used, err := repos.PasswordReset.GetByTokenHash(ctx, token.TokenHash)
+	must(t, err, "GetByTokenHash")
+	if used.UsedAt == nil || used.IsUsable(time.Now()) {
+		t.Errorf("a used token is %+v, want UsedAt set", used)
+	}
+
+	// DeleteUnusedByUserID keeps used tokens and the tokens of others.
+	pending := newResetToken(ctx, t, repos, user, expiresAt)
+	other := newResetToken(ctx, t, repos, newUser(ctx, t, repos), expiresAt)
+	must(t, repos.PasswordReset.DeleteUnusedByUserID(ctx, user.ID), "DeleteUnusedByUserID")
+	if _, err := repos.PasswordReset.GetByTokenHash(ctx, pending.TokenHash); !errors.Is(err, custom_errors.ErrResetTokenNotFound) {
+		t.Errorf("an unused token survived DeleteUnusedByUserID: %v", err)
+	}
+	for _, kept := range []*entity.PasswordResetToken{token, other} {
+		if _, err := repos.PasswordReset.GetByTokenHash(ctx, kept.TokenHash); err != nil {
+			t.Errorf("DeleteUnusedByUserID removed token %s: %v", kept.ID, err)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+func newCategory(ctx context.Context, t T, repos *repository.Repositories) *entity.Category {
+	t.Helper()
+	category := &entity.Category{Name: unique("c")}
+	must(t, repos.Category.Create(ctx, category), "create category")
+	return category
+}
+
+func newPost(ctx context.Context, t T, repos *repository.Repositories, user *entity.User, title string) *entity.Post {
+	t.Helper()
+	post := &entity.Post{Title: title, Content: "content of " + title, UserID: user.ID}
+	must(t, repos.Post.Create(ctx, post), "create post")
+	return post
+}
+
+func postIDs(posts []*entity.Post) []uuid.UUID {
+	ids := make([]uuid.UUID, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	return ids
+}
+
+func sameIDs(a, b []uuid.UUID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func checkCategories(ctx context.Context, t T, repos *repository.Repositories) {
+	category := newCategory(ctx, t, repos)
+	if category.ID == uuid.Nil || category.CreatedAt.IsZero() {
+		t.Errorf("Create did not set the ID and CreatedAt: %+v", category)
+	}
+
+	err := repos.Category.Create(ctx, &entity.Category{Name: category.Name})
+	if !errors.Is(err, custom_errors.ErrCategoryExists) {
+		t.Errorf("Create with a taken name returned %v, want %v", err, custom_errors.ErrCategoryExists)
+	}
+
+	got, err := repos.Category.GetByID(ctx, &category.ID)
+	if err != nil || got.Name != category.Name {
+		t.Errorf("GetByID returned %+v, %v", got, err)
+	}
+	got, err = repos.Category.GetByName(ctx, category.Name)
+	if err != nil || got.ID != category.ID {
+		t.Errorf("GetByName returned %+v, %v", got, err)
+	}
+	missing := uuid.New()
+	if _, err := repos.Category.GetByID(ctx, &missing); !errors.Is(err, custom_errors.ErrCategoryNotFound) {
+		t.Errorf("GetByID of an unknown category returned %v, want %v", err, custom_errors.ErrCategoryNotFound)
+	}
+	if _, err := repos.Category.GetByName(ctx, unique("c")); !errors.Is(err, custom_errors.ErrCategoryNotFound) {
+		t.Errorf("GetByName of an unknown category returned %v, want %v", err, custom_errors.ErrCategoryNotFound)
+	}
+
+	if exists, err := repos.Category.CheckNameExists(ctx, category.Name); err != nil || !exists {
+		t.Errorf("CheckNameExists of an existing name returned %v, %v", exists, err)
+	}
+	if exists, err := repos.Category.CheckNameExists(ctx, unique("c")); err != nil || exists {
+		t.Errorf("CheckNameExists of an unknown name returned %v, %v", exists, err)
+	}
+
+	all, err := repos.Category.GetAll(ctx)
+	must(t, err, "GetAll")
+	found := false
+	for _, c := range all {
+		found = found || c.ID == category.ID
+	}
+	if !found {
+		t.Errorf("GetAll does not include the new category")
+	}
+	if !sort.SliceIsSorted(all, func(i, j int) bool { return all[i].Name < all[j].Name }) {
+		t.Errorf("GetAll is not sorted by name")
+	}
+}
+
+func checkPosts(ctx context.Context, t T, repos *repository.Repositories) {
+	user := newUser(ctx, t, repos)
+	category := newCategory(ctx, t, repos)
+
+	older := newPost(ctx, t, repos, user, "older")
+	tick()
+	newer := newPost(ctx, t, repos, user, "newer")
+	if newer.ID == uuid.Nil || newer.CreatedAt.IsZero() {
+		t.Errorf("Create did not set the ID and CreatedAt: %+v", newer)
+	}
+	must(t, repos.PostCategory.Create(ctx, &entity.PostCategory{PostID: older.ID, CategoryID: category.ID}), "add category")
+
+	got, err := repos.Post.GetByID(ctx, older.ID)
+	must(t, err, "GetByID")
+	if got.Title != older.Title || got.Content != older.Content || got.UserID != user.ID ||
+		!sameTime(got.CreatedAt, older.CreatedAt) || got.UpdatedAt != nil {
+		t.Errorf("GetByID returned %+v, want %+v", got, older)
+	}
+	if _, err := repos.Post.GetByID(ctx, uuid.New()); !errors.Is(err, custom_errors.ErrPostNotFound) {
+		t.Errorf("GetByID of an unknown post returned %v, want %v", err, custom_errors.ErrPostNotFound)
+	}
+
+	byUser, err := repos.Post.GetbyuserId(ctx, user.ID)
+	must(t, err, "GetbyuserId")
+	if want := []uuid.UUID{newer.ID, older.ID}; !sameIDs(postIDs(byUser), want) {
+		t.Errorf("GetbyuserId returned %v, want %v newest first", postIDs(byUser), want)
+	}
+
+	byCategory, err := repos.Post.GetByCategory(ctx, category.ID)
+	must(t, err, "GetByCategory")
+	if want := []uuid.UUID{older.ID}; !sameIDs(postIDs(byCategory), want) {
+		t.Errorf("GetByCategory returned %v, want %v", postIDs(byCategory), want)
+	}
+	filtered, err := repos.Post.GetFiltered(ctx, entity.PostFilter{CategoryIDs: []uuid.UUID{category.ID}})
+	must(t, err, "GetFiltered")
+	if want := []uuid.UUID{older.ID}; !sameIDs(postIDs(filtered), want) {
+		t.Errorf("GetFiltered by category returned %v, want %v", postIDs(filtered), want)
+	}
+
+	older.Title, older.Content = "edited", "edited content"
+	must(t, repos.Post.Update(ctx, older), "update post")
+	got, err = repos.Post.GetByID(ctx, older.ID)
+	must(t, err, "GetByID after Update")
+	if got.Title != "edited" || got.Content != "edited content" || got.UpdatedAt == nil {
+		t.Errorf("Update was not stored: %+v", got)
+	}
+
+	must(t, repos.Post.Delete(ctx, newer.ID), "delete post")
+	if _, err := repos.Post.GetByID(ctx, newer.ID); !errors.Is(err, custom_errors.ErrPostNotFound) {
+		t.Errorf("GetByID of a deleted post returned %v, want %v", err, custom_errors.ErrPostNotFound)
+	}
+}
+
+func checkPostCategories(ctx context.Context, t T, repos *repository.Repositories) {
+	user := newUser(ctx, t, repos)
+	post := newPost(ctx, t, repos, user, "categorised")
+	other := newPost(ctx, t, repos, user, "other")
+	first, second := newCategory(ctx, t, repos), newCategory(ctx, t, repos)
+	if first.Name > second.Name {
+		first, second = second, first
+	}
+
+	for _, pc := range []entity.PostCategory{
+		{PostID: post.ID, CategoryID: second.ID},
+		{PostID: post.ID, CategoryID: first.ID},
+		{PostID: other.ID, CategoryID: first.ID},
+	} {
+		must(t, repos.PostCategory.Create(ctx, &pc), "create association")
+	}
+	if err := repos.PostCategory.Create(ctx, &entity.PostCategory{PostID: post.ID, CategoryID: first.ID}); err == nil {
+		t.Errorf("Create of an existing association returned no error")
+	}
+
+	if exists, err := repos.PostCategory.CheckAssociationExists(ctx, post.ID, first.ID); err != nil || !exists {
+		t.Errorf("CheckAssociationExists returned %v, %v for an existing association", exists, err)
+	}
+
+	byPost, err := repos.PostCategory.GetCategoriesByPostIDs(ctx, []uuid.UUID{post.ID, other.ID})
+	must(t, err, "GetCategoriesByPostIDs")
+	if got := byPost[post.ID]; len(got) != 2 || got[0].ID != first.ID || got[1].ID != second.ID {
+		t.Errorf("GetCategoriesByPostIDs did not return both categories sorted by name: %+v", got)
+	}
+	if got := byPost[other.ID]; len(got) != 1 || got[0].ID != first.ID {
+		t.Errorf("GetCategoriesByPostIDs mixed up the posts: %+v", got)
+	}
+
+	must(t, repos.PostCategory.Delete(ctx, post.ID, second.ID), "delete association")
+	if exists, _ := repos.PostCategory.CheckAssociationExists(ctx, post.ID, second.ID); exists {
+		t.Errorf("Delete left the association in place")
+	}
+
+	must(t, repos.PostCategory.DeleteByPostID(ctx, post.ID), "delete by post")
+	categories, err := repos.PostCategory.GetCategoriesByPostID(ctx, post.ID)
+	must(t, err, "GetCategoriesByPostID")
+	if len(categories) != 0 {
+		t.Errorf("DeleteByPostID left %d categories", len(categories))
+	}
+	if exists, _ := repos.PostCategory.CheckAssociationExists(ctx, other.ID, first.ID); !exists {
+		t.Errorf("DeleteByPostID removed another post's category")
+	}
+}
+
+func checkPostReactions(ctx context.Context, t T, repos *repository.Repositories) {
+	author, liker, disliker := newUser(ctx, t, repos), newUser(ctx, t, repos), newUser(ctx, t, repos)
+	post := newPost(ctx, t, repos, author, "reacted")
+
+	counts := func(wantLikes, wantDislikes int, when string) {
+		t.Helper()
+		likes, dislikes, err := repos.PostReaction.GetReactionCountsByPostID(ctx, post.ID)
+		if err != nil || likes != wantLikes || dislikes != wantDislikes {
+			t.Errorf("%s: counts are %d/%d (%v), want %d/%d", when, likes, dislikes, err, wantLikes, wantDislikes)
+		}
+	}
+
+	like := &entity.PostReaction{UserID: liker.ID, PostID: post.ID, Reaction: true}
+	must(t, repos.PostReaction.Create(ctx, like), "create like")
+	dislike := &entity.PostReaction{UserID: disliker.ID, PostID: post.ID, Reaction: false}
+	must(t, repos.PostReaction.Create(ctx, dislike), "create dislike")
+	counts(1, 1, "after one like and one dislike")
+
+	if err := repos.PostReaction.Create(ctx, &entity.PostReaction{UserID: liker.ID, PostID: post.ID, Reaction: false}); err == nil {
+		t.Errorf("a second reaction by the same user was accepted")
+	}
+
+	got, err := repos.PostReaction.GetByUserAndPost(ctx, liker.ID, post.ID)
+	if err != nil || got.ID != like.ID || !got.Reaction {
+		t.Errorf("GetByUserAndPost returned %+v, %v", got, err)
+	}
+	if _, err := repos.PostReaction.GetByUserAndPost(ctx, author.ID, post.ID); err == nil {
+		t.Errorf("GetByUserAndPost without a reaction returned no error")
+	}
+	if reacted, value, err := repos.PostReaction.HasUserReacted(ctx, disliker.ID, post.ID); err != nil || !reacted || value == nil || *value {
+		t.Errorf("HasUserReacted for a dislike returned %v, %v, %v", reacted, value, err)
+	}
+	if reacted, _, err := repos.PostReaction.HasUserReacted(ctx, author.ID, post.ID); err != nil || reacted {
+		t.Errorf("HasUserReacted without a reaction returned %v, %v", reacted, err)
+	}
+
+	like.Reaction = false
+	must(t, repos.PostReaction.Update(ctx, like), "update reaction")
+	counts(0, 2, "after turning the like into a dislike")
+
+	must(t, repos.PostReaction.Delete(ctx, like.ID), "delete reaction")
+	counts(0, 1, "after deleting a reaction")
+	must(t, repos.PostReaction.DeleteByUserAndPost(ctx, disliker.ID, post.ID), "delete by user and post")
+	counts(0, 0, "after deleting every reaction")
+
+	if likes, dislikes, err := repos.PostReaction.GetReactionCountsByPostID(ctx, uuid.New()); err != nil || likes != 0 || dislikes != 0 {
+		t.Errorf("counts of an unknown post are %d/%d (%v), want 0/0", likes, dislikes, err)
+	}
+}
+
+func checkAttachments(ctx context.Context, t T, repos *repository.Repositories) {
+	user := newUser(ctx, t, repos)
+	post := newPost(ctx, t, repos, user, "with images")
+	other := newPost(ctx, t, repos, user, "without images")
+
+	var created []*entity.PostAttachment
+	for i := 0; i < 2; i++ {
+		attachment := &entity.PostAttachment{PostID: post.ID, FileName: unique("f") + ".png", ThumbnailName: unique("t") + ".png",
+			ContentType: "image/png", Size: 1024, Width: 64, Height: 48}
+		must(t, repos.Attachment.Create(ctx, attachment), "create attachment")
+		created = append(created, attachment)
+		tick()
+	}
+
+	byPost, err := repos.Attachment.GetByPostIDs(ctx, []uuid.UUID{post.ID, other.ID})
+	must(t, err, "GetByPostIDs")
+	got := byPost[post.ID]
+	if len(got) != 2 || got[0].ID != created[0].ID || got[1].ID != created[1].ID {
+		t.Fatalf("GetByPostIDs did not return the attachments oldest first: %+v", got)
+	}
+	if got[0].FileName != created[0].FileName || got[0].Size != 1024 || got[0].Width != 64 || got[0].Height != 48 {
+		t.Errorf("attachment was not stored as created: %+v", got[0])
+	}
+	if len(byPost[other.ID]) != 0 {
+		t.Errorf("GetByPostIDs returned attachments for a post without any")
+	}
+}
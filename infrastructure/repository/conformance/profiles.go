@@ -0,0 +1,231 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+func userCommentIDs(page *entity.CommentPage) []uuid.UUID {
+	ids := make([]uuid.UUID, len(page.Comments))
+	for i, comment := range page.Comments {
+		ids[i] = comment.ID
+	}
+	return ids
+}
+
+func checkUserStats(ctx context.Context, t T, repos *repository.Repositories) {
+	user, fan, critic := newUser(ctx, t, repos), newUser(ctx, t, repos), newUser(ctx, t, repos)
+
+	stats, err := repos.User.GetStats(ctx, user.ID)
+	must(t, err, "GetStats")
+	if *stats != (entity.UserStats{}) {
+		t.Errorf("a new user has stats %+v, want none", stats)
+	}
+
+	post := newPost(ctx, t, repos, user, "stats")
+	newPost(ctx, t, repos, user, "more stats")
+	kept := newComment(ctx, t, repos, user, post, nil)
+	deleted := newComment(ctx, t, repos, user, post, nil)
+	newComment(ctx, t, repos, fan, post, nil)
+
+	_, err = repos.PostReaction.Toggle(ctx, fan.ID, post.ID, true)
+	must(t, err, "like post")
+	_, err = repos.PostReaction.Toggle(ctx, critic.ID, post.ID, false)
+	must(t, err, "dislike post")
+	_, err = repos.CommentReaction.Toggle(ctx, fan.ID, kept.ID, true)
+	must(t, err, "like comment")
+	_, err = repos.CommentReaction.Toggle(ctx, fan.ID, deleted.ID, true)
+	must(t, err, "like deleted comment")
+	must(t, repos.Comment.SoftDelete(ctx, deleted.ID), "soft delete comment")
+
+	stats, err = repos.User.GetStats(ctx, user.ID)
+	must(t, err, "GetStats")
+	want := entity.UserStats{PostCount: 2, CommentCount: 1, LikesReceived: 2, DislikesReceived: 1}
+	if *stats != want {
+		t.Errorf("GetStats returned %+v, want %+v", stats, want)
+	}
+
+	if _, err := repos.User.GetByUserName(ctx, unique("nobody")); !errors.Is(err, custom_errors.ErrUserNotFound) {
+		t.Errorf("GetByUserName of an unknown user returned %v, want %v", err, custom_errors.ErrUserNotFound)
+	}
+}
+
+func checkUserComments(ctx context.Context, t T, repos *repository.Repositories) {
+	user, other := newUser(ctx, t, repos), newUser(ctx, t, repos)
+	post := newPost(ctx, t, repos, other, unique("title"))
+
+	var comments []*entity.Comment
+	for i := 0; i < 5; i++ {
+		comments = append(comments, newComment(ctx, t, repos, user, post, nil))
+	}
+	newComment(ctx, t, repos, other, post, nil)
+	deleted := newComment(ctx, t, repos, user, post, nil)
+	must(t, repos.Comment.SoftDelete(ctx, deleted.ID), "soft delete comment")
+	newest := []uuid.UUID{comments[4].ID, comments[3].ID, comments[2].ID, comments[1].ID, comments[0].ID}
+
+	var seen []uuid.UUID
+	var pages []*entity.CommentPage
+	request := entity.PageRequest{Limit: 2}
+	for len(pages) < 5 {
+		page, err := repos.Comment.GetByUserIDWithDetails(ctx, user.ID, request)
+		must(t, err, "GetByUserIDWithDetails")
+		pages = append(pages, page)
+		seen = append(seen, userCommentIDs(page)...)
+		if page.Next == nil {
+			break
+		}
+		request.Cursor = page.Next
+	}
+	if !sameIDs(seen, newest) {
+		t.Fatalf("paging forward returned %v, want %v", seen, newest)
+	}
+	if len(pages) != 3 || pages[0].Prev != nil || pages[1].Prev == nil || pages[2].Next != nil {
+		t.Errorf("paging forward gave %d pages with the wrong cursors", len(pages))
+	}
+
+	first := pages[0].Comments[0]
+	if first.PostTitle != post.Title || first.PostID != post.ID || first.Content != comments[4].Content ||
+		first.Author.ID != user.ID || first.Author.UserName != user.UserName {
+		t.Errorf("GetByUserIDWithDetails returned %+v, want comment %s under %q by %s",
+			first, comments[4].ID, post.Title, user.UserName)
+	}
+
+	back, err := repos.Comment.GetByUserIDWithDetails(ctx, user.ID, entity.PageRequest{Limit: 2, Cursor: pages[2].Prev})
+	must(t, err, "GetByUserIDWithDetails backwards")
+	if !sameIDs(userCommentIDs(back), newest[2:4]) || back.Prev == nil || back.Next == nil {
+		t.Errorf("paging back returned %v, want %v with both cursors", userCommentIDs(back), newest[2:4])
+	}
+
+	empty, err := repos.Comment.GetByUserIDWithDetails(ctx, uuid.New(), entity.PageRequest{})
+	if err != nil || len(empty.Comments) != 0 || empty.Prev != nil || empty.Next != nil {
+		t.Errorf("comments of an unknown user returned %+v, %v", empty, err)
+	}
+}
+
+func checkUserProfileUpdate(ctx context.Context, t T, repos *repository.Repositories) {
+	user := newUser(ctx, t, repos)
+	if user.Bio != "" || user.AvatarName != "" || user.AvatarURL() != "" || user.UserNameChangedAt != nil {
+		t.Errorf("a new user has profile %+v, want an empty one", user)
+	}
+
+	user.Bio = "Line one\nline two"
+	user.AvatarName = unique("avatar") + ".png"
+	must(t, repos.User.UpdateProfile(ctx, user), "UpdateProfile")
+
+	stored, err := repos.User.GetByID(ctx, user.ID)
+	must(t, err, "GetByID")
+	if stored.Bio != user.Bio || stored.AvatarName != user.AvatarName || stored.UserName != user.UserName {
+		t.Errorf("after UpdateProfile the user is %+v, want bio %q and avatar %q", stored, user.Bio, user.AvatarName)
+	}
+
+	if err := repos.User.UpdateProfile(ctx, &entity.User{ID: uuid.New()}); !errors.Is(err, custom_errors.ErrUserNotFound) {
+		t.Errorf("UpdateProfile of an unknown user returned %v, want %v", err, custom_errors.ErrUserNotFound)
+	}
+}
+
+func checkUserNameChange(ctx context.Context, t T, repos *repository.Repositories) {
+	user := newUser(ctx, t, repos)
+	first, second, third := user.UserName, unique("n"), unique("n")
+
+	earlier := time.Now().Add(-time.Hour)
+	must(t, repos.User.ChangeUserName(ctx, user.ID, second, earlier), "ChangeUserName")
+	later := earlier.Add(30 * time.Minute)
+	must(t, repos.User.ChangeUserName(ctx, user.ID, third, later), "ChangeUserName again")
+
+	stored, err := repos.User.GetByUserName(ctx, third)
+	must(t, err, "GetByUserName of the new name")
+	if stored.ID != user.ID || stored.UserNameChangedAt == nil || !sameTime(*stored.UserNameChangedAt, later) {
+		t.Errorf("after two renames the user is %+v, want %s changed at %s", stored, user.ID, later)
+	}
+	if _, err := repos.User.GetByUserName(ctx, first); !errors.Is(err, custom_errors.ErrUserNotFound) {
+		t.Errorf("GetByUserName of a name given up returned %v, want %v", err, custom_errors.ErrUserNotFound)
+	}
+
+	for _, want := range []struct {
+		name string
+		at   time.Time
+	}{{first, earlier}, {second, later}} {
+		change, err := repos.User.GetLastUserNameChange(ctx, want.name)
+		must(t, err, "GetLastUserNameChange")
+		if change.UserID != user.ID || change.UserName != want.name || !sameTime(change.ChangedAt, want.at) {
+			t.Errorf("GetLastUserNameChange(%q) returned %+v, want a change by %s at %s", want.name, change, user.ID, want.at)
+		}
+	}
+
+	// Another user takes the first name and gives it up again: the latest
+	// change is theirs.
+	other := newUser(ctx, t, repos)
+	must(t, repos.User.ChangeUserName(ctx, other.ID, first, later), "take the first name")
+	must(t, repos.User.ChangeUserName(ctx, other.ID, unique("n"), later.Add(time.Minute)), "give up the first name")
+	change, err := repos.User.GetLastUserNameChange(ctx, first)
+	must(t, err, "GetLastUserNameChange")
+	if change.UserID != other.ID {
+		t.Errorf("GetLastUserNameChange(%q) returned a change by %s, want %s", first, change.UserID, other.ID)
+	}
+
+	if _, err := repos.User.GetLastUserNameChange(ctx, unique("never")); !errors.Is(err, custom_errors.ErrUserNotFound) {
+		t.Errorf("GetLastUserNameChange of an unused name returned %v, want %v", err, custom_errors.ErrUserNotFound)
+	}
+	if err := repos.User.ChangeUserName(ctx, uuid.New(), unique("n"), later); !errors.Is(err, custom_errors.ErrUserNotFound) {
+		t.Errorf("ChangeUserName of an unknown user returned %v, want %v", err, custom_errors.ErrUserNotFound)
+	}
+}
+
+func checkUserNameTaken(ctx context.Context, t T, repos *repository.Repositories) {
+	user := newUser(ctx, t, repos)
+	twin := &entity.User{UserName: user.UserName, Email: unique("e") + "@example.com", PasswordHash: "hash"}
+	if err := repos.User.Create(ctx, twin); !errors.Is(err, custom_errors.ErrNameTaken) {
+		t.Errorf("Create with a name in use returned %v, want %v", err, custom_errors.ErrNameTaken)
+	}
+	other := newUser(ctx, t, repos)
+	if err := repos.User.ChangeUserName(ctx, other.ID, user.UserName, time.Now()); !errors.Is(err, custom_errors.ErrNameTaken) {
+		t.Errorf("ChangeUserName to a name in use returned %v, want %v", err, custom_errors.ErrNameTaken)
+	}
+	stored, err := repos.User.GetByID(ctx, other.ID)
+	must(t, err, "GetByID")
+	if stored.UserName != other.UserName || stored.UserNameChangedAt != nil {
+		t.Errorf("a refused rename left the user as %+v, want the name %q", stored, other.UserName)
+	}
+
+	// Only one of many users renaming themselves to the same name at once
+	// gets it.
+	const attempts = 8
+	name := unique("n")
+	users := make([]*entity.User, attempts)
+	for i := range users {
+		users[i] = newUser(ctx, t, repos)
+	}
+	var wg sync.WaitGroup
+	results := make(chan error, attempts)
+	for _, user := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- repos.User.ChangeUserName(ctx, user.ID, name, time.Now())
+		}()
+	}
+	wg.Wait()
+	close(results)
+	var won int
+	for err := range results {
+		if err == nil {
+			won++
+		} else if !errors.Is(err, custom_errors.ErrNameTaken) {
+			t.Errorf("concurrent ChangeUserName: %v", err)
+		}
+	}
+	if won != 1 {
+		t.Errorf("%d of %d concurrent renames to one name succeeded, want 1", won, attempts)
+	}
+	if _, err := repos.User.GetByUserName(ctx, name); err != nil {
+		t.Errorf("GetByUserName of the contested name returned %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+// toggler is the Toggle method of either reaction repository.
+type toggler func(ctx context.Context, userID, targetID uuid.UUID, like bool) (*entity.ReactionState, error)
+
+func checkReactionToggle(ctx context.Context, t T, repos *repository.Repositories) {
+	author := newUser(ctx, t, repos)
+	post := newPost(ctx, t, repos, author, "toggled")
+	comment := newComment(ctx, t, repos, author, post, nil)
+
+	checkToggle(ctx, t, "post", repos.PostReaction.Toggle, post.ID, func() (int, int, error) {
+		return repos.PostReaction.GetReactionCountsByPostID(ctx, post.ID)
+	}, repos)
+	checkToggle(ctx, t, "comment", repos.CommentReaction.Toggle, comment.ID, func() (int, int, error) {
+		return repos.CommentReaction.GetReactionCountsByCommentID(ctx, comment.ID)
+	}, repos)
+
+	if _, err := repos.PostReaction.Toggle(ctx, author.ID, uuid.New(), true); !errors.Is(err, custom_errors.ErrPostNotFound) {
+		t.Errorf("toggling a reaction on an unknown post returned %v", err)
+	}
+	if _, err := repos.CommentReaction.Toggle(ctx, author.ID, uuid.New(), true); !errors.Is(err, custom_errors.ErrCommentNotFound) {
+		t.Errorf("toggling a reaction on an unknown comment returned %v", err)
+	}
+}
+
+// checkToggle runs the toggle semantics on one target, then toggles from many
+// goroutines at once. Every user toggles the same reaction a known number of
+// times, so whatever the interleaving the user ends up reacting exactly when
+// that number is odd.
+func checkToggle(ctx context.Context, t T, kind string, toggle toggler, targetID uuid.UUID, counts func() (int, int, error), repos *repository.Repositories) {
+	t.Helper()
+	user := newUser(ctx, t, repos)
+
+	liked, disliked := true, false
+	steps := []struct {
+		like            bool
+		want            *bool
+		likes, dislikes int
+	}{
+		{true, &liked, 1, 0},
+		{true, nil, 0, 0},
+		{false, &disliked, 0, 1},
+		{true, &liked, 1, 0},
+		{true, nil, 0, 0},
+	}
+	for i, step := range steps {
+		state, err := toggle(ctx, user.ID, targetID, step.like)
+		if err != nil {
+			t.Fatalf("%s toggle %d: %v", kind, i+1, err)
+		}
+		if (state.Reaction == nil) != (step.want == nil) || (state.Reaction != nil && *state.Reaction != *step.want) ||
+			state.LikeCount != step.likes || state.DislikeCount != step.dislikes {
+			t.Errorf("%s toggle %d returned %+v, want reaction %v and counts %d/%d",
+				kind, i+1, state, step.want, step.likes, step.dislikes)
+		}
+	}
+
+	const users = 12
+	var wantLikes, wantDislikes int
+	var wg sync.WaitGroup
+	errs := make(chan error, users*4)
+	for i := 0; i < users; i++ {
+		user := newUser(ctx, t, repos)
+		like := i%2 == 0
+		times := i%4 + 1
+		if times%2 == 1 {
+			if like {
+				wantLikes++
+			} else {
+				wantDislikes++
+			}
+		}
+		for j := 0; j < times; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := toggle(ctx, user.ID, targetID, like); err != nil {
+					errs <- err
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent %s toggle: %v", kind, err)
+	}
+
+	likes, dislikes, err := counts()
+	if err != nil || likes != wantLikes || dislikes != wantDislikes {
+		t.Errorf("after concurrent %s toggles the counts are %d/%d (%v), want %d/%d",
+			kind, likes, dislikes, err, wantLikes, wantDislikes)
+	}
+	state, err := toggle(ctx, user.ID, targetID, true)
+	if err != nil || state.LikeCount != wantLikes+1 || state.DislikeCount != wantDislikes {
+		t.Errorf("after concurrent %s toggles Toggle reports %+v (%v), want counts %d/%d",
+			kind, state, err, wantLikes+1, wantDislikes)
+	}
+}
@@ -0,0 +1,94 @@
+package conformance
+
+import (
+	"context"
+	"strings"
+
+	"forum/domain/entity"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+func checkSearch(ctx context.Context, t T, repos *repository.Repositories) {
+	user := newUser(ctx, t, repos)
+	titleWord, commentWord := unique("w"), unique("w")
+
+	inTitle := &entity.Post{Title: "about " + titleWord, Content: "nothing to see", UserID: user.ID}
+	must(t, repos.Post.Create(ctx, inTitle), "create post")
+	other := newPost(ctx, t, repos, user, "unrelated")
+	comment := &entity.Comment{Content: "a reply mentioning " + commentWord, UserID: user.ID, PostID: other.ID}
+	must(t, repos.Comment.Create(ctx, comment), "create comment")
+
+	results, err := repos.Search.Search(ctx, titleWord, 10)
+	must(t, err, "Search")
+	if len(results) != 1 || results[0].PostID != inTitle.ID || results[0].InComment || results[0].AuthorName != user.UserName {
+		t.Errorf("searching a title word returned %+v, want only the post by %s", results, user.UserName)
+	}
+
+	results, err = repos.Search.Search(ctx, commentWord, 10)
+	must(t, err, "Search")
+	if len(results) != 1 || results[0].PostID != other.ID || !results[0].InComment {
+		t.Errorf("searching a comment word returned %+v, want the commented post marked InComment", results)
+	}
+
+	if results, err := repos.Search.Search(ctx, unique("w"), 10); err != nil || len(results) != 0 {
+		t.Errorf("searching an unused word returned %d results, %v", len(results), err)
+	}
+	if results, err := repos.Search.Search(ctx, "  ", 10); err != nil || len(results) != 0 {
+		t.Errorf("searching blanks returned %d results, %v", len(results), err)
+	}
+
+	for word, want := range map[string]uuid.UUID{titleWord: inTitle.ID, commentWord: other.ID} {
+		page, err := repos.PostAggregate.GetFilteredPostsWithDetails(ctx, entity.PostFilter{Query: word}, entity.PageRequest{})
+		must(t, err, "GetFilteredPostsWithDetails with a query")
+		if got := pagePostIDs(page); !sameIDs(got, []uuid.UUID{want}) {
+			t.Errorf("filtering by %q returned %v, want %v", word, got, want)
+		}
+	}
+}
+
+// markedSpans returns the highlighted parts of text.
+func markedSpans(text string) []string {
+	var spans []string
+	for _, part := range strings.Split(text, entity.HighlightStart)[1:] {
+		span, _, _ := strings.Cut(part, entity.HighlightEnd)
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+// checkSearchHighlight searches a post whose stored text holds the highlight
+// markers. Only the searched word may come back highlighted.
+func checkSearchHighlight(ctx context.Context, t T, repos *repository.Repositories) {
+	user := newUser(ctx, t, repos)
+	word := unique("w")
+	marker := entity.HighlightStart + "fake" + entity.HighlightEnd
+	post := &entity.Post{
+		Title:   marker + " about " + word + entity.HighlightEnd,
+		Content: entity.HighlightStart + "nothing but " + word + " and " + marker,
+		UserID:  user.ID,
+	}
+	must(t, repos.Post.Create(ctx, post), "create post")
+	other := newPost(ctx, t, repos, user, marker+" unrelated")
+	comment := &entity.Comment{Content: marker + " a reply about " + word, UserID: user.ID, PostID: other.ID}
+	must(t, repos.Comment.Create(ctx, comment), "create comment")
+
+	results, err := repos.Search.Search(ctx, word, 10)
+	must(t, err, "Search")
+	if len(results) != 2 {
+		t.Fatalf("searching %q returned %d results, want the post and the commented post", word, len(results))
+	}
+	for _, result := range results {
+		for _, field := range []struct{ name, text string }{{"title", result.Title}, {"snippet", result.Snippet}} {
+			if strings.Count(field.text, entity.HighlightStart) != strings.Count(field.text, entity.HighlightEnd) {
+				t.Errorf("the %s %q has unbalanced markers", field.name, field.text)
+			}
+			for _, span := range markedSpans(field.text) {
+				if !strings.EqualFold(span, word) {
+					t.Errorf("the %s %q highlights %q, want only %q", field.name, field.text, span, word)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+)
+
+var errAbort = errors.New("conformance: abort transaction")
+
+func checkTransactions(ctx context.Context, t T, repos *repository.Repositories) {
+	committed := &entity.Category{Name: unique("c")}
+	nested := &entity.Category{Name: unique("c")}
+	err := repos.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := repos.Category.Create(ctx, committed); err != nil {
+			return err
+		}
+		if _, err := repos.Category.GetByName(ctx, committed.Name); err != nil {
+			t.Errorf("a transaction does not see its own write: %v", err)
+		}
+		return repos.Tx.WithinTx(ctx, func(ctx context.Context) error {
+			return repos.Category.Create(ctx, nested)
+		})
+	})
+	must(t, err, "WithinTx")
+	for _, category := range []*entity.Category{committed, nested} {
+		if _, err := repos.Category.GetByName(ctx, category.Name); err != nil {
+			t.Errorf("a committed transaction lost category %s: %v", category.Name, err)
+		}
+	}
+
+	user := newUser(ctx, t, repos)
+	rolledBack := &entity.Category{Name: unique("c")}
+	var post *entity.Post
+	err = repos.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := repos.Category.Create(ctx, rolledBack); err != nil {
+			return err
+		}
+		post = &entity.Post{Title: "rolled back", Content: "never stored", UserID: user.ID}
+		if err := repos.PostAggregate.CreatePostWithCategories(ctx, post, nil); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("WithinTx returned %v, want the error of its function", err)
+	}
+	if _, err := repos.Category.GetByName(ctx, rolledBack.Name); !errors.Is(err, custom_errors.ErrCategoryNotFound) {
+		t.Errorf("a rolled back transaction left its category behind: %v", err)
+	}
+	if _, err := repos.Post.GetByID(ctx, post.ID); !errors.Is(err, custom_errors.ErrPostNotFound) {
+		t.Errorf("a rolled back transaction left the post of a nested aggregate call behind: %v", err)
+	}
+}
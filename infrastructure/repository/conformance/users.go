@@ -0,0 +1,193 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+func newUser(ctx context.Context, t T, repos *repository.Repositories) *entity.User {
+	t.Helper()
+	name := unique("u")
+	user := &entity.User{UserName: name, Email: name + "@example.com", PasswordHash: "hash"}
+	must(t, repos.User.Create(ctx, user), "create user")
+	return user
+}
+
+func checkUsers(ctx context.Context, t T, repos *repository.Repositories) {
+	user := newUser(ctx, t, repos)
+	if user.ID == uuid.Nil || user.CreatedAt.IsZero() {
+		t.Errorf("Create did not set the ID and CreatedAt: %+v", user)
+	}
+	if user.Role != entity.RoleUser {
+		t.Errorf("Create set role %q, want %q", user.Role, entity.RoleUser)
+	}
+
+	lookups := map[string]func() (*entity.User, error){
+		"GetByID":       func() (*entity.User, error) { return repos.User.GetByID(ctx, user.ID) },
+		"GetByEmail":    func() (*entity.User, error) { return repos.User.GetByEmail(ctx, user.Email) },
+		"GetByUserName": func() (*entity.User, error) { return repos.User.GetByUserName(ctx, user.UserName) },
+	}
+	for name, lookup := range lookups {
+		got, err := lookup()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got.ID != user.ID || got.UserName != user.UserName || got.Email != user.Email ||
+			got.PasswordHash != user.PasswordHash || got.Role != user.Role || !sameTime(got.CreatedAt, user.CreatedAt) {
+			t.Errorf("%s returned %+v, want %+v", name, got, user)
+		}
+	}
+
+	if _, err := repos.User.GetByID(ctx, uuid.New()); err == nil {
+		t.Errorf("GetByID of an unknown user returned no error")
+	}
+	if _, err := repos.User.GetByEmail(ctx, unique("nobody")+"@example.com"); err == nil {
+		t.Errorf("GetByEmail of an unknown email returned no error")
+	}
+}
+
+func checkSessions(ctx context.Context, t T, repos *repository.Repositories) {
+	user := newUser(ctx, t, repos)
+
+	first := &entity.UserSession{UserID: user.ID, SessionToken: unique("t"), ExpiresAt: time.Now().Add(time.Hour)}
+	must(t, repos.Session.Create(ctx, first), "create session")
+	tick()
+	second := &entity.UserSession{UserID: user.ID, SessionToken: unique("t"), ExpiresAt: time.Now().Add(time.Hour)}
+	must(t, repos.Session.Create(ctx, second), "create session")
+
+	got, err := repos.Session.GetByToken(ctx, first.SessionToken)
+	must(t, err, "GetByToken")
+	if got.ID != first.ID || got.UserID != user.ID || !sameTime(got.ExpiresAt, first.ExpiresAt) {
+		t.Errorf("GetByToken returned %+v, want %+v", got, first)
+	}
+	if _, err := repos.Session.GetByToken(ctx, unique("t")); err == nil {
+		t.Errorf("GetByToken of an unknown token returned no error")
+	}
+
+	newest, err := repos.Session.GetByUserID(ctx, user.ID)
+	must(t, err, "GetByUserID")
+	if newest == nil || newest.ID != second.ID {
+		t.Errorf("GetByUserID returned %+v, want the newest session %s", newest, second.ID)
+	}
+	if none, err := repos.Session.GetByUserID(ctx, uuid.New()); err != nil || none != nil {
+		t.Errorf("GetByUserID of a user without sessions returned %+v, %v; want nil, nil", none, err)
+	}
+
+	first.ExpiresAt = time.Now().Add(48 * time.Hour)
+	must(t, repos.Session.Update(ctx, first), "update session")
+	got, err = repos.Session.GetByToken(ctx, first.SessionToken)
+	must(t, err, "GetByToken after Update")
+	if !sameTime(got.ExpiresAt, first.ExpiresAt) {
+		t.Errorf("Update did not store ExpiresAt: got %v, want %v", got.ExpiresAt, first.ExpiresAt)
+	}
+
+	must(t, repos.Session.Delete(ctx, first.ID), "delete session")
+	if _, err := repos.Session.GetByToken(ctx, first.SessionToken); err == nil {
+		t.Errorf("GetByToken found a deleted session")
+	}
+
+	must(t, repos.Session.DeleteAllUserSessions(ctx, user.ID), "delete all sessions")
+	if _, err := repos.Session.GetByToken(ctx, second.SessionToken); err == nil {
+		t.Errorf("GetByToken found a session after DeleteAllUserSessions")
+	}
+}
+
+func sessionIDs(sessions []*entity.UserSession) []uuid.UUID {
+	ids := make([]uuid.UUID, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.ID
+	}
+	return ids
+}
+
+func checkSessionDevices(ctx context.Context, t T, repos *repository.Repositories) {
+	user, other := newUser(ctx, t, repos), newUser(ctx, t, repos)
+	now := time.Now()
+
+	phone := &entity.UserSession{UserID: user.ID, SessionToken: unique("t"), UserAgent: "Phone/1.0",
+		IPAddress: "10.0.0.1", ExpiresAt: now.Add(time.Hour)}
+	must(t, repos.Session.Create(ctx, phone), "create phone session")
+	tick()
+	laptop := &entity.UserSession{UserID: user.ID, SessionToken: unique("t"), UserAgent: "Laptop/2.0",
+		IPAddress: "2001:db8::1", ExpiresAt: now.Add(time.Hour)}
+	must(t, repos.Session.Create(ctx, laptop), "create laptop session")
+	expired := &entity.UserSession{UserID: user.ID, SessionToken: unique("t"), ExpiresAt: now.Add(-time.Hour)}
+	must(t, repos.Session.Create(ctx, expired), "create expired session")
+	othersExpired := &entity.UserSession{UserID: other.ID, SessionToken: unique("t"), ExpiresAt: now.Add(-time.Hour)}
+	must(t, repos.Session.Create(ctx, othersExpired), "create expired session of another user")
+
+	got, err := repos.Session.GetByToken(ctx, phone.SessionToken)
+	must(t, err, "GetByToken")
+	if got.UserAgent != phone.UserAgent || got.IPAddress != phone.IPAddress || !sameTime(got.LastSeenAt, got.CreatedAt) {
+		t.Errorf("GetByToken returned %+v, want agent %q, address %q and last seen when created",
+			got, phone.UserAgent, phone.IPAddress)
+	}
+
+	sessions, err := repos.Session.ListByUserID(ctx, user.ID, now)
+	must(t, err, "ListByUserID")
+	if want := []uuid.UUID{laptop.ID, phone.ID}; !sameIDs(sessionIDs(sessions), want) {
+		t.Errorf("ListByUserID returned %v, want the unexpired sessions %v", sessionIDs(sessions), want)
+	}
+
+	seenAt := now.Add(time.Minute)
+	must(t, repos.Session.Touch(ctx, phone.ID, seenAt, "10.0.0.9"), "Touch")
+	sessions, err = repos.Session.ListByUserID(ctx, user.ID, now)
+	must(t, err, "ListByUserID after Touch")
+	if want := []uuid.UUID{phone.ID, laptop.ID}; !sameIDs(sessionIDs(sessions), want) {
+		t.Fatalf("after Touch ListByUserID returned %v, want %v", sessionIDs(sessions), want)
+	}
+	if first := sessions[0]; !sameTime(first.LastSeenAt, seenAt) || first.IPAddress != "10.0.0.9" {
+		t.Errorf("after Touch the session is %+v, want last seen at %s from 10.0.0.9", first, seenAt)
+	}
+
+	if err := repos.Session.DeleteUserSession(ctx, other.ID, phone.ID); !errors.Is(err, custom_errors.ErrSessionNotFound) {
+		t.Errorf("DeleteUserSession of someone else's session returned %v, want %v", err, custom_errors.ErrSessionNotFound)
+	}
+	must(t, repos.Session.DeleteUserSession(ctx, user.ID, phone.ID), "DeleteUserSession")
+	if _, err := repos.Session.GetByToken(ctx, phone.SessionToken); err == nil {
+		t.Errorf("GetByToken found a session after DeleteUserSession")
+	}
+	if err := repos.Session.DeleteUserSession(ctx, user.ID, phone.ID); !errors.Is(err, custom_errors.ErrSessionNotFound) {
+		t.Errorf("DeleteUserSession of a deleted session returned %v, want %v", err, custom_errors.ErrSessionNotFound)
+	}
+
+	must(t, repos.Session.DeleteExpiredUserSessions(ctx, user.ID, now), "DeleteExpiredUserSessions")
+	if _, err := repos.Session.GetByToken(ctx, expired.SessionToken); err == nil {
+		t.Errorf("GetByToken found an expired session after DeleteExpiredUserSessions")
+	}
+	for _, kept := range []*entity.UserSession{laptop, othersExpired} {
+		if _, err := repos.Session.GetByToken(ctx, kept.SessionToken); err != nil {
+			t.Errorf("DeleteExpiredUserSessions deleted session %s: %v", kept.ID, err)
+		}
+	}
+}
+
+func checkEmailVerification(ctx context.Context, t T, repos *repository.Repositories) {
+	user := newUser(ctx, t, repos)
+	stored, err := repos.User.GetByID(ctx, user.ID)
+	must(t, err, "GetByID")
+	if stored.IsEmailVerified() {
+		t.Errorf("a new user is verified: %+v", stored)
+	}
+
+	verifiedAt := time.Now().Add(-time.Minute)
+	must(t, repos.User.MarkEmailVerified(ctx, user.ID, verifiedAt), "MarkEmailVerified")
+	must(t, repos.User.MarkEmailVerified(ctx, user.ID, time.Now()), "MarkEmailVerified again")
+	stored, err = repos.User.GetByEmail(ctx, user.Email)
+	must(t, err, "GetByEmail")
+	if stored.EmailVerifiedAt == nil || !sameTime(*stored.EmailVerifiedAt, verifiedAt) {
+		t.Errorf("after verifying twice EmailVerifiedAt is %v, want the first time %s", stored.EmailVerifiedAt, verifiedAt)
+	}
+
+	if err := repos.User.MarkEmailVerified(ctx, uuid.New(), verifiedAt); !errors.Is(err, custom_errors.ErrUserNotFound) {
+		t.Errorf("MarkEmailVerified of an unknown user returned %v, want %v", err, custom_errors.ErrUserNotFound)
+	}
+}
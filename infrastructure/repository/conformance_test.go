@@ -0,0 +1,48 @@
+package infra_repository_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"forum/config"
+	"forum/infrastructure/database"
+	infra_repository "forum/infrastructure/repository"
+	"forum/infrastructure/repository/conformance"
+)
+
+// newTestDB returns a migrated SQLite database in a temporary directory,
+// opened with the same connection settings as the server.
+func newTestDB(tb testing.TB) *sql.DB {
+	tb.Helper()
+	db := database.SetingUpDB(database.DriverSQLite, filepath.Join(tb.TempDir(), "forum.db"), config.Load().DatabaseOptions())
+	tb.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestConformance(t *testing.T) {
+	repos := infra_repository.NewSQLiteRepositories(newTestDB(t), 5)
+	for _, check := range conformance.Checks {
+		t.Run(check.Name, func(t *testing.T) {
+			check.Run(context.Background(), t, repos)
+		})
+	}
+}
+
+// TestReactionToggleRace runs the reaction toggle hammer several times at
+// once on the same repositories. Run it with -race.
+func TestReactionToggleRace(t *testing.T) {
+	check, ok := conformance.Find("reaction_toggle")
+	if !ok {
+		t.Fatal("there is no reaction_toggle check")
+	}
+	repos := infra_repository.NewSQLiteRepositories(newTestDB(t), 5)
+	for i := 0; i < 4; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+			check.Run(context.Background(), t, repos)
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package infra_repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"forum/domain/entity"
+	"forum/domain/repository"
+	"forum/infrastructure/database"
+	infra_repository "forum/infrastructure/repository"
+)
+
+// counters are the denormalized counts of one post or comment.
+type counters struct {
+	likes, dislikes, comments int
+}
+
+// counterThread is a post p1 with top-level comments c1 and c2 and a reply
+// r1 to c1, and an empty post p2, written by users u1 and u2.
+type counterThread struct {
+	db       *sql.DB
+	repos    *repository.Repositories
+	u1, u2   *entity.User
+	p1, p2   *entity.Post
+	c1, c2   *entity.Comment
+	r1       *entity.Comment
+	rowIDs   map[string]string
+	isPostID map[string]bool
+}
+
+func newCounterThread(t *testing.T) *counterThread {
+	t.Helper()
+	ctx := context.Background()
+	db := newTestDB(t)
+	th := &counterThread{db: db, repos: infra_repository.NewSQLiteRepositories(db, 5)}
+
+	th.u1 = &entity.User{UserName: "u1", Email: "u1@example.com", PasswordHash: "hash"}
+	th.u2 = &entity.User{UserName: "u2", Email: "u2@example.com", PasswordHash: "hash"}
+	for _, user := range []*entity.User{th.u1, th.u2} {
+		if err := th.repos.User.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	th.p1 = &entity.Post{Title: "p1", Content: "content", UserID: th.u1.ID}
+	th.p2 = &entity.Post{Title: "p2", Content: "content", UserID: th.u1.ID}
+	for _, post := range []*entity.Post{th.p1, th.p2} {
+		if err := th.repos.Post.Create(ctx, post); err != nil {
+			t.Fatal(err)
+		}
+	}
+	th.c1 = &entity.Comment{Content: "c1", UserID: th.u2.ID, PostID: th.p1.ID}
+	th.c2 = &entity.Comment{Content: "c2", UserID: th.u2.ID, PostID: th.p1.ID}
+	th.r1 = &entity.Comment{Content: "r1", UserID: th.u1.ID, PostID: th.p1.ID, ParentID: &th.c1.ID}
+	for _, comment := range []*entity.Comment{th.c1, th.c2, th.r1} {
+		if err := th.repos.Comment.Create(ctx, comment); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	th.rowIDs = map[string]string{
+		"p1": th.p1.ID.String(), "p2": th.p2.ID.String(),
+		"c1": th.c1.ID.String(), "c2": th.c2.ID.String(), "r1": th.r1.ID.String(),
+	}
+	th.isPostID = map[string]bool{"p1": true, "p2": true}
+	return th
+}
+
+// counters reads the stored counters of the post or comment called name.
+func (th *counterThread) counters(t *testing.T, name string) counters {
+	t.Helper()
+	table := "comments"
+	if th.isPostID[name] {
+		table = "posts"
+	}
+	var c counters
+	err := th.db.QueryRow(`SELECT like_count, dislike_count, comment_count FROM `+table+` WHERE id = ?`, th.rowIDs[name]).
+		Scan(&c.likes, &c.dislikes, &c.comments)
+	if err != nil {
+		t.Fatalf("read the counters of %s: %v", name, err)
+	}
+	return c
+}
+
+func TestCounterTriggers(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(ctx context.Context, th *counterThread) error
+		want   map[string]counters
+	}{
+		{
+			name:   "new thread",
+			change: func(context.Context, *counterThread) error { return nil },
+			want:   map[string]counters{"p1": {comments: 3}, "p2": {}, "c1": {comments: 1}, "c2": {}, "r1": {}},
+		},
+		{
+			name: "soft-deleted reply is still counted",
+			change: func(ctx context.Context, th *counterThread) error {
+				return th.repos.Comment.SoftDelete(ctx, th.r1.ID)
+			},
+			want: map[string]counters{"p1": {comments: 3}, "c1": {comments: 1}},
+		},
+		{
+			name: "soft-deleted comment keeps its replies and reactions",
+			change: func(ctx context.Context, th *counterThread) error {
+				if _, err := th.repos.CommentReaction.Toggle(ctx, th.u1.ID, th.c1.ID, true); err != nil {
+					return err
+				}
+				return th.repos.Comment.SoftDelete(ctx, th.c1.ID)
+			},
+			want: map[string]counters{"p1": {comments: 3}, "c1": {likes: 1, comments: 1}},
+		},
+		{
+			name: "deleted reply is no longer counted",
+			change: func(ctx context.Context, th *counterThread) error {
+				return th.repos.Comment.Delete(ctx, th.r1.ID)
+			},
+			want: map[string]counters{"p1": {comments: 2}, "c1": {}},
+		},
+		{
+			name: "reply moved to another comment",
+			change: func(ctx context.Context, th *counterThread) error {
+				_, err := th.db.ExecContext(ctx, `UPDATE comments SET parent_id = ? WHERE id = ?`, th.c2.ID.String(), th.r1.ID.String())
+				return err
+			},
+			want: map[string]counters{"p1": {comments: 3}, "c1": {}, "c2": {comments: 1}},
+		},
+		{
+			name: "reply moved to the top level",
+			change: func(ctx context.Context, th *counterThread) error {
+				_, err := th.db.ExecContext(ctx, `UPDATE comments SET parent_id = NULL WHERE id = ?`, th.r1.ID.String())
+				return err
+			},
+			want: map[string]counters{"p1": {comments: 3}, "c1": {}, "c2": {}},
+		},
+		{
+			name: "reply moved to another post",
+			change: func(ctx context.Context, th *counterThread) error {
+				_, err := th.db.ExecContext(ctx, `UPDATE comments SET post_id = ?, parent_id = NULL WHERE id = ?`,
+					th.p2.ID.String(), th.r1.ID.String())
+				return err
+			},
+			want: map[string]counters{"p1": {comments: 2}, "p2": {comments: 1}, "c1": {}},
+		},
+		{
+			name: "soft-deleted reply moved to another comment",
+			change: func(ctx context.Context, th *counterThread) error {
+				if err := th.repos.Comment.SoftDelete(ctx, th.r1.ID); err != nil {
+					return err
+				}
+				_, err := th.db.ExecContext(ctx, `UPDATE comments SET parent_id = ? WHERE id = ?`, th.c2.ID.String(), th.r1.ID.String())
+				return err
+			},
+			want: map[string]counters{"p1": {comments: 3}, "c1": {}, "c2": {comments: 1}},
+		},
+		{
+			name: "post reactions toggled and switched",
+			change: func(ctx context.Context, th *counterThread) error {
+				for _, toggle := range []struct {
+					user *entity.User
+					like bool
+				}{{th.u1, true}, {th.u2, true}, {th.u1, false}, {th.u2, true}} {
+					if _, err := th.repos.PostReaction.Toggle(ctx, toggle.user.ID, th.p1.ID, toggle.like); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			want: map[string]counters{"p1": {dislikes: 1, comments: 3}},
+		},
+		{
+			name: "comment reactions toggled and switched",
+			change: func(ctx context.Context, th *counterThread) error {
+				if _, err := th.repos.CommentReaction.Toggle(ctx, th.u1.ID, th.c2.ID, false); err != nil {
+					return err
+				}
+				if _, err := th.repos.CommentReaction.Toggle(ctx, th.u2.ID, th.c2.ID, true); err != nil {
+					return err
+				}
+				_, err := th.repos.CommentReaction.Toggle(ctx, th.u1.ID, th.c2.ID, true)
+				return err
+			},
+			want: map[string]counters{"c2": {likes: 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			th := newCounterThread(t)
+			if err := tt.change(ctx, th); err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range tt.want {
+				if got := th.counters(t, name); got != want {
+					t.Errorf("%s counters = %+v, want %+v", name, got, want)
+				}
+			}
+
+			// The triggers leave nothing for a recount to repair.
+			posts, comments, err := database.RecountCounters(th.db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if posts != 0 || comments != 0 {
+				t.Errorf("recount repaired %d posts and %d comments, want none", posts, comments)
+			}
+		})
+	}
+}
+
+func TestRecountCountersRepairsDrift(t *testing.T) {
+	th := newCounterThread(t)
+	if _, err := th.db.Exec(`UPDATE posts SET like_count = 7, comment_count = 0 WHERE id = ?`, th.p1.ID.String()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := th.db.Exec(`UPDATE comments SET dislike_count = 2, comment_count = 5`); err != nil {
+		t.Fatal(err)
+	}
+
+	posts, comments, err := database.RecountCounters(th.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posts != 1 || comments != 3 {
+		t.Errorf("recount repaired %d posts and %d comments, want 1 and 3", posts, comments)
+	}
+	for name, want := range map[string]counters{"p1": {comments: 3}, "p2": {}, "c1": {comments: 1}, "c2": {}, "r1": {}} {
+		if got := th.counters(t, name); got != want {
+			t.Errorf("%s counters = %+v, want %+v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package infra_repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+
+	"github.com/google/uuid"
+)
+
+// inClause returns the "?, ?, ?" placeholder list and the matching arguments
+// for an IN (...) condition on the given IDs.
+func inClause(ids []uuid.UUID) (string, []interface{}) {
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id.String()
+	}
+	return strings.Join(placeholders, ", "), args
+}
+
+// reactionTable describes where the reactions to one kind of target are
+// stored.
+type reactionTable struct {
+	name     string // table of the reactions
+	column   string // column of the reactions that references the target
+	target   string // table of the targets, which holds the counters
+	notFound error
+}
+
+var (
+	postReactions    = reactionTable{"post_reaction", "post_id", "posts", custom_errors.ErrPostNotFound}
+	commentReactions = reactionTable{"comment_reaction", "comment_id", "comments", custom_errors.ErrCommentNotFound}
+)
+
+// toggle deletes the user's reaction when it is already like and upserts it
+// otherwise. Both are single statements guarded by UNIQUE(user_id, target).
+// The database is opened with _txlock=immediate, so the transaction holds
+// the write lock from its start and concurrent toggles queue up behind each
+// other.
+func (t reactionTable) toggle(ctx context.Context, db *sql.DB, userID, targetID uuid.UUID, like bool) (*entity.ReactionState, error) {
+	var state *entity.ReactionState
+	err := inTx(ctx, db, func(ctx context.Context) error {
+		q := conn(ctx, db)
+		state = &entity.ReactionState{}
+
+		// The target is looked up first: inserting a reaction to a missing
+		// one would fail on its foreign key, which says nothing about which
+		// row is missing.
+		var exists int
+		err := q.QueryRowContext(ctx, `SELECT 1 FROM `+t.target+` WHERE id = ?`, targetID.String()).Scan(&exists)
+		if err == sql.ErrNoRows {
+			return t.notFound
+		} else if err != nil {
+			return err
+		}
+
+		result, err := q.ExecContext(ctx, `DELETE FROM `+t.name+` WHERE user_id = ? AND `+t.column+` = ? AND reaction = ?`,
+			userID.String(), targetID.String(), like)
+		if err != nil {
+			return err
+		}
+		removed, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if removed == 0 {
+			_, err = q.ExecContext(ctx, `
+				INSERT INTO `+t.name+` (id, user_id, `+t.column+`, reaction, created_at) VALUES (?, ?, ?, ?, ?)
+				ON CONFLICT (user_id, `+t.column+`) DO UPDATE SET reaction = excluded.reaction, created_at = excluded.created_at`,
+				uuid.New().String(), userID.String(), targetID.String(), like, time.Now())
+			if err != nil {
+				return err
+			}
+			state.Reaction = &like
+		}
+
+		return q.QueryRowContext(ctx, `SELECT like_count, dislike_count FROM `+t.target+` WHERE id = ?`,
+			targetID.String()).Scan(&state.LikeCount, &state.DislikeCount)
+	})
+	if err == t.notFound {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return state, nil
+}
@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type MemoryCategoryRepository struct {
+	store *Store
+}
+
+func NewMemoryCategoryRepository(store *Store) repository.CategoryRepository {
+	return &MemoryCategoryRepository{store: store}
+}
+
+func (r *MemoryCategoryRepository) Create(ctx context.Context, category *entity.Category) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.categories {
+		if existing.Name == category.Name {
+			return custom_errors.ErrCategoryExists
+		}
+	}
+
+	category.ID = uuid.New()
+	category.CreatedAt = time.Now()
+	r.store.categories[category.ID] = *category
+	return nil
+}
+
+func (r *MemoryCategoryRepository) GetByID(ctx context.Context, categoryID *uuid.UUID) (*entity.Category, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	category, ok := r.store.categories[*categoryID]
+	if !ok {
+		return nil, custom_errors.ErrCategoryNotFound
+	}
+	return &category, nil
+}
+
+func (r *MemoryCategoryRepository) GetByName(ctx context.Context, name string) (*entity.Category, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, category := range r.store.categories {
+		if category.Name == name {
+			return &category, nil
+		}
+	}
+	return nil, custom_errors.ErrCategoryNotFound
+}
+
+func (r *MemoryCategoryRepository) GetAll(ctx context.Context) ([]*entity.Category, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var categories []*entity.Category
+	for _, category := range r.store.categories {
+		categories = append(categories, &category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Name < categories[j].Name
+	})
+	return categories, nil
+}
+
+func (r *MemoryCategoryRepository) CheckNameExists(ctx context.Context, name string) (bool, error) {
+	_, err := r.GetByName(ctx, name)
+	if err == custom_errors.ErrCategoryNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type MemoryCommentReactionRepository struct {
+	store *Store
+}
+
+func NewMemoryCommentReactionRepository(store *Store) repository.CommentReactionRepository {
+	return &MemoryCommentReactionRepository{store: store}
+}
+
+func (r *MemoryCommentReactionRepository) Create(ctx context.Context, reaction *entity.CommentReaction) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.commentReactions {
+		if existing.UserID == reaction.UserID && existing.CommentID == reaction.CommentID {
+			return custom_errors.ErrReactionExists
+		}
+	}
+
+	reaction.ID = uuid.New()
+	reaction.CreatedAt = time.Now()
+	r.store.commentReactions[reaction.ID] = *reaction
+	return nil
+}
+
+func (r *MemoryCommentReactionRepository) GetByUserAndComment(ctx context.Context, userID, commentID uuid.UUID) (*entity.CommentReaction, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, reaction := range r.store.commentReactions {
+		if reaction.UserID == userID && reaction.CommentID == commentID {
+			return &reaction, nil
+		}
+	}
+	return nil, custom_errors.ErrReactionNotFound
+}
+
+func (r *MemoryCommentReactionRepository) Update(ctx context.Context, reaction *entity.CommentReaction) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.commentReactions[reaction.ID]
+	if !ok {
+		return custom_errors.ErrReactionNotFound
+	}
+	stored.Reaction = reaction.Reaction
+	r.store.commentReactions[reaction.ID] = stored
+	return nil
+}
+
+func (r *MemoryCommentReactionRepository) Delete(ctx context.Context, reactionID uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.commentReactions[reactionID]; !ok {
+		return custom_errors.ErrReactionNotFound
+	}
+	delete(r.store.commentReactions, reactionID)
+	return nil
+}
+
+func (r *MemoryCommentReactionRepository) GetReactionCountsByCommentID(ctx context.Context, commentID uuid.UUID) (likes int, dislikes int, err error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	likes, dislikes = r.store.commentReactionCounts(commentID)
+	return likes, dislikes, nil
+}
+
+func (r *MemoryCommentReactionRepository) Toggle(ctx context.Context, userID, commentID uuid.UUID, like bool) (*entity.ReactionState, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.comments[commentID]; !ok {
+		return nil, custom_errors.ErrCommentNotFound
+	}
+
+	state := &entity.ReactionState{}
+	var existing *entity.CommentReaction
+	for _, reaction := range r.store.commentReactions {
+		if reaction.UserID == userID && reaction.CommentID == commentID {
+			existing = &reaction
+			break
+		}
+	}
+
+	switch {
+	case existing != nil && existing.Reaction == like:
+		delete(r.store.commentReactions, existing.ID)
+	case existing != nil:
+		existing.Reaction = like
+		existing.CreatedAt = time.Now()
+		r.store.commentReactions[existing.ID] = *existing
+		state.Reaction = &like
+	default:
+		reaction := entity.CommentReaction{ID: uuid.New(), UserID: userID, CommentID: commentID, Reaction: like, CreatedAt: time.Now()}
+		r.store.commentReactions[reaction.ID] = reaction
+		state.Reaction = &like
+	}
+
+	state.LikeCount, state.DislikeCount = r.store.commentReactionCounts(commentID)
+	return state, nil
+}
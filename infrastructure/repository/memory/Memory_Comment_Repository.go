@@ -0,0 +1,235 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type MemoryCommentRepository struct {
+	store    *Store
+	maxDepth int
+}
+
+// NewMemoryCommentRepository builds the comment repository. maxDepth is the
+// deepest reply level that still accepts replies when threads are built.
+func NewMemoryCommentRepository(store *Store, maxDepth int) repository.CommentRepository {
+	return &MemoryCommentRepository{store: store, maxDepth: maxDepth}
+}
+
+func (r *MemoryCommentRepository) Create(ctx context.Context, comment *entity.Comment) error {
+	comment.ID = uuid.New()
+	comment.CreatedAt = time.Now()
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.comments[comment.ID] = *comment
+	return nil
+}
+
+func (r *MemoryCommentRepository) GetByID(ctx context.Context, commentID uuid.UUID) (*entity.Comment, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	comment, ok := r.store.comments[commentID]
+	if !ok {
+		return nil, custom_errors.ErrCommentNotFound
+	}
+	return &comment, nil
+}
+
+func (r *MemoryCommentRepository) GetByPostID(ctx context.Context, postID uuid.UUID) ([]entity.Comment, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.postComments(postID), nil
+}
+
+// change applies fn to a comment that has not been deleted.
+func (r *MemoryCommentRepository) change(commentID uuid.UUID, fn func(comment *entity.Comment)) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	comment, ok := r.store.comments[commentID]
+	if !ok || comment.IsDeleted() {
+		return custom_errors.ErrCommentNotFound
+	}
+	fn(&comment)
+	r.store.comments[commentID] = comment
+	return nil
+}
+
+func (r *MemoryCommentRepository) Update(ctx context.Context, comment *entity.Comment) error {
+	now := time.Now()
+	comment.UpdatedAt = &now
+
+	return r.change(comment.ID, func(stored *entity.Comment) {
+		stored.Content = comment.Content
+		stored.UpdatedAt = comment.UpdatedAt
+	})
+}
+
+func (r *MemoryCommentRepository) Delete(ctx context.Context, commentID uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.comments[commentID]; !ok {
+		return custom_errors.ErrCommentNotFound
+	}
+	delete(r.store.comments, commentID)
+	return nil
+}
+
+// SoftDelete turns a comment into a tombstone: its content is wiped and it is
+// marked as deleted, but it stays so the conversation keeps its shape.
+func (r *MemoryCommentRepository) SoftDelete(ctx context.Context, commentID uuid.UUID) error {
+	now := time.Now()
+	return r.change(commentID, func(stored *entity.Comment) {
+		stored.Content = ""
+		stored.DeletedAt = &now
+	})
+}
+
+func (r *MemoryCommentRepository) GetCountByPostID(ctx context.Context, postID uuid.UUID) (int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return len(r.store.postComments(postID)), nil
+}
+
+func (r *MemoryCommentRepository) GetCountByUserID(ctx context.Context, userID uuid.UUID) (int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	count := 0
+	for _, comment := range r.store.comments {
+		if comment.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryCommentRepository) GetWithDetails(ctx context.Context, commentID uuid.UUID) (*entity.CommentWithDetails, error) {
+	comment, err := r.GetByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.CommentWithDetails{
+		Comment: *comment,
+	}, nil
+}
+
+func (r *MemoryCommentRepository) GetByPostIDWithDetails(ctx context.Context, postID uuid.UUID) ([]entity.CommentWithDetails, error) {
+	comments, err := r.GetByPostIDsWithDetails(ctx, []uuid.UUID{postID})
+	if err != nil {
+		return nil, err
+	}
+	return comments[postID], nil
+}
+
+func (r *MemoryCommentRepository) GetByPostIDsWithDetails(ctx context.Context, postIDs []uuid.UUID) (map[uuid.UUID][]entity.CommentWithDetails, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.threadedComments(postIDs, r.maxDepth), nil
+}
+
+// threadedComments returns the comments of some posts with their authors and
+// counters, threaded. The caller holds mu.
+func (s *Store) threadedComments(postIDs []uuid.UUID, maxDepth int) map[uuid.UUID][]entity.CommentWithDetails {
+	replies := make(map[uuid.UUID]int)
+	for _, comment := range s.comments {
+		if comment.ParentID != nil {
+			replies[*comment.ParentID]++
+		}
+	}
+
+	result := make(map[uuid.UUID][]entity.CommentWithDetails)
+	for _, postID := range postIDs {
+		comments := s.postComments(postID)
+		if len(comments) == 0 {
+			continue
+		}
+
+		details := make([]entity.CommentWithDetails, len(comments))
+		for i, comment := range comments {
+			details[i].Comment = comment
+			details[i].Author = s.users[comment.UserID]
+			details[i].Author.PasswordHash = ""
+			details[i].LikeCount, details[i].DislikeCount = s.commentReactionCounts(comment.ID)
+			details[i].ReplyCount = replies[comment.ID]
+		}
+		result[postID] = entity.ThreadComments(details, maxDepth)
+	}
+	return result
+}
+
+func (r *MemoryCommentRepository) GetByUserIDWithDetails(ctx context.Context, userID uuid.UUID, page entity.PageRequest) (*entity.CommentPage, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	// The cursor comment's own timestamp is used if it still exists, like
+	// the SQL backends do.
+	var bound sortKey
+	backward := page.Cursor != nil && page.Cursor.Backward
+	if page.Cursor != nil {
+		bound = sortKey{createdAt: page.Cursor.CreatedAt, id: page.Cursor.ID}
+		if cursorComment, ok := r.store.comments[page.Cursor.ID]; ok {
+			bound.createdAt = cursorComment.CreatedAt
+		}
+	}
+
+	var comments []entity.Comment
+	for _, comment := range r.store.comments {
+		if comment.UserID != userID || comment.IsDeleted() {
+			continue
+		}
+		if page.Cursor != nil {
+			c := sortKey{createdAt: comment.CreatedAt, id: comment.ID}.compare(bound)
+			if (!backward && c >= 0) || (backward && c <= 0) {
+				continue
+			}
+		}
+		comments = append(comments, comment)
+	}
+
+	sort.Slice(comments, func(i, j int) bool {
+		c := sortKey{createdAt: comments[i].CreatedAt, id: comments[i].ID}.
+			compare(sortKey{createdAt: comments[j].CreatedAt, id: comments[j].ID})
+		if backward {
+			return c < 0
+		}
+		return c > 0
+	})
+	if len(comments) > page.Size()+1 {
+		comments = comments[:page.Size()+1]
+	}
+
+	replies := make(map[uuid.UUID]int)
+	for _, comment := range r.store.comments {
+		if comment.ParentID != nil {
+			replies[*comment.ParentID]++
+		}
+	}
+
+	result := make([]*entity.UserComment, len(comments))
+	for i, comment := range comments {
+		result[i] = &entity.UserComment{PostTitle: r.store.posts[comment.PostID].Title}
+		details := &result[i].CommentWithDetails
+		details.Comment = comment
+		details.Author = r.store.users[comment.UserID]
+		details.Author.PasswordHash = ""
+		details.LikeCount, details.DislikeCount = r.store.commentReactionCounts(comment.ID)
+		details.ReplyCount = replies[comment.ID]
+	}
+	return entity.NewCommentPage(page, result), nil
+}
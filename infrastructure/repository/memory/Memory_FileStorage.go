@@ -0,0 +1,42 @@
+package memory
+
+import (
+	"sync"
+
+	"forum/domain/repository"
+)
+
+// FileStorage keeps uploaded files in a map.
+type FileStorage struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+}
+
+func NewMemoryFileStorage() repository.FileStorage {
+	return &FileStorage{files: make(map[string][]byte)}
+}
+
+func (s *FileStorage) Save(name string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.files[name] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *FileStorage) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.files, name)
+	return nil
+}
+
+// File returns a copy of a stored file and whether it exists.
+func (s *FileStorage) File(name string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, ok := s.files[name]
+	return append([]byte(nil), data...), ok
+}
@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type MemoryPasswordResetRepository struct {
+	store *Store
+}
+
+func NewMemoryPasswordResetRepository(store *Store) repository.PasswordResetRepository {
+	return &MemoryPasswordResetRepository{store: store}
+}
+
+func (r *MemoryPasswordResetRepository) Create(ctx context.Context, token *entity.PasswordResetToken) error {
+	token.ID = uuid.New()
+	token.CreatedAt = time.Now()
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.resetTokens[token.ID] = *token
+	return nil
+}
+
+func (r *MemoryPasswordResetRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*entity.PasswordResetToken, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, token := range r.store.resetTokens {
+		if token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+	return nil, custom_errors.ErrResetTokenNotFound
+}
+
+func (r *MemoryPasswordResetRepository) MarkUsed(ctx context.Context, tokenID uuid.UUID, usedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	token, ok := r.store.resetTokens[tokenID]
+	if !ok || token.UsedAt != nil {
+		return custom_errors.ErrResetTokenNotFound
+	}
+	token.UsedAt = &usedAt
+	r.store.resetTokens[tokenID] = token
+	return nil
+}
+
+func (r *MemoryPasswordResetRepository) DeleteUnusedByUserID(ctx context.Context, userID uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, token := range r.store.resetTokens {
+		if token.UserID == userID && token.UsedAt == nil {
+			delete(r.store.resetTokens, id)
+		}
+	}
+	return nil
+}
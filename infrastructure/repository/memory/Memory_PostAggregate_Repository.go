@@ -0,0 +1,323 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type MemoryPostAggregateRepository struct {
+	store    *Store
+	maxDepth int
+}
+
+func NewMemoryPostAggregateRepository(store *Store, maxDepth int) repository.PostAggregateRepository {
+	return &MemoryPostAggregateRepository{store: store, maxDepth: maxDepth}
+}
+
+// CreatePostWithCategories creates a post and associates it with categories
+func (r *MemoryPostAggregateRepository) CreatePostWithCategories(ctx context.Context, post *entity.Post, categoryIDs []*uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[post.UserID]; !ok {
+		return custom_errors.ErrUserNotFound
+	}
+	for _, categoryID := range categoryIDs {
+		if _, ok := r.store.categories[*categoryID]; !ok {
+			return custom_errors.ErrCategoryNotFound
+		}
+	}
+
+	post.ID = uuid.New()
+	post.CreatedAt = time.Now()
+	r.store.posts[post.ID] = *post
+	for _, categoryID := range categoryIDs {
+		r.store.postCategories[entity.PostCategory{PostID: post.ID, CategoryID: *categoryID}] = true
+	}
+	return nil
+}
+
+// withDetails builds the full view of some posts. The caller holds mu.
+func (s *Store) withDetails(posts []*entity.Post, maxDepth int) []*entity.PostWithDetails {
+	ids := make([]uuid.UUID, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	comments := s.threadedComments(ids, maxDepth)
+	attachments := s.postAttachments(ids)
+
+	details := make([]*entity.PostWithDetails, len(posts))
+	for i, post := range posts {
+		author := s.users[post.UserID]
+		author.PasswordHash = ""
+		likes, dislikes := s.postReactionCounts(post.ID)
+		details[i] = &entity.PostWithDetails{
+			Post:         *post,
+			Author:       author,
+			Categories:   s.postCategoryList(post.ID),
+			Comments:     comments[post.ID],
+			Attachments:  attachments[post.ID],
+			LikeCount:    likes,
+			DislikeCount: dislikes,
+			CommentCount: len(s.postComments(post.ID)),
+			IsEdited:     post.UpdatedAt != nil,
+		}
+	}
+	return details
+}
+
+func (r *MemoryPostAggregateRepository) GetPostWithAllDetails(ctx context.Context, postID uuid.UUID) (*entity.PostWithDetails, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	post, ok := r.store.posts[postID]
+	if !ok {
+		return nil, custom_errors.ErrPostNotFound
+	}
+	return r.store.withDetails([]*entity.Post{&post}, r.maxDepth)[0], nil
+}
+
+// GetFeedForUser returns one page of the feed, newest posts first.
+func (r *MemoryPostAggregateRepository) GetFeedForUser(ctx context.Context, page entity.PageRequest) (*entity.PostPage, error) {
+	return r.GetFilteredPostsWithDetails(ctx, entity.PostFilter{}, page)
+}
+
+func (r *MemoryPostAggregateRepository) GetPostsWithDetailsByUser(ctx context.Context, userID uuid.UUID) ([]*entity.PostWithDetails, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var posts []*entity.Post
+	for _, post := range r.store.posts {
+		if post.UserID == userID {
+			posts = append(posts, &post)
+		}
+	}
+	newestFirst(posts)
+	return r.store.withDetails(posts, r.maxDepth), nil
+}
+
+// sortScore computes what a sort mode ranks a post by, with the same
+// formulas as the SQL backends.
+func sortScore(mode entity.SortMode, post entity.Post, likes, dislikes int, now time.Time) float64 {
+	switch mode {
+	case entity.SortTop:
+		return float64(likes - dislikes)
+	case entity.SortHot:
+		age := now.Sub(post.CreatedAt).Hours() + 2
+		return float64(likes-dislikes) / (age * age)
+	case entity.SortControversial:
+		if likes == 0 || dislikes == 0 {
+			return 0
+		}
+		return float64(likes+dislikes) * float64(min(likes, dislikes)) / float64(max(likes, dislikes))
+	}
+	return 0
+}
+
+// sortKey is the position of a post in a post ordering.
+type sortKey struct {
+	score     float64
+	createdAt time.Time
+	id        uuid.UUID
+}
+
+// compare orders keys by score, created_at and id, all ascending.
+func (k sortKey) compare(other sortKey) int {
+	if k.score != other.score {
+		return int(math.Copysign(1, k.score-other.score))
+	}
+	if !k.createdAt.Equal(other.createdAt) {
+		return k.createdAt.Compare(other.createdAt)
+	}
+	return compareIDs(k.id, other.id)
+}
+
+func (r *MemoryPostAggregateRepository) GetFilteredPostsWithDetails(ctx context.Context, filter entity.PostFilter, page entity.PageRequest) (*entity.PostPage, error) {
+	now := filter.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	scored := filter.Sort == entity.SortTop || filter.Sort == entity.SortHot || filter.Sort == entity.SortControversial
+	terms := searchTerms(filter.Query)
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var posts []*entity.Post
+	keys := make(map[uuid.UUID]sortKey)
+	for _, post := range r.store.posts {
+		if !r.store.inAnyCategory(post.ID, filter.CategoryIDs) {
+			continue
+		}
+		if filter.MyPosts && filter.AuthorID != nil && post.UserID != *filter.AuthorID {
+			continue
+		}
+		if filter.LikedPosts && filter.AuthorID != nil && !r.store.likedBy(post.ID, *filter.AuthorID) {
+			continue
+		}
+		if strings.TrimSpace(filter.Query) != "" {
+			if found, _ := r.store.matchesSearch(post, terms); !found {
+				continue
+			}
+		}
+		if filter.Sort == entity.SortTop && filter.Window > 0 && post.CreatedAt.Before(now.Add(-filter.Window)) {
+			continue
+		}
+
+		key := sortKey{createdAt: post.CreatedAt, id: post.ID}
+		if scored {
+			likes, dislikes := r.store.postReactionCounts(post.ID)
+			key.score = sortScore(filter.Sort, post, likes, dislikes, now)
+		}
+		keys[post.ID] = key
+		posts = append(posts, &post)
+	}
+
+	// Only the requested page. The cursor post's own timestamp is used if it
+	// still exists, like the SQL backends do.
+	backward := page.Cursor != nil && page.Cursor.Backward
+	if page.Cursor != nil {
+		bound := sortKey{score: page.Cursor.Score, createdAt: page.Cursor.CreatedAt, id: page.Cursor.ID}
+		if cursorPost, ok := r.store.posts[page.Cursor.ID]; ok {
+			bound.createdAt = cursorPost.CreatedAt
+		}
+		if !scored {
+			bound.score = 0
+		}
+
+		kept := posts[:0]
+		for _, post := range posts {
+			c := keys[post.ID].compare(bound)
+			if (!backward && c < 0) || (backward && c > 0) {
+				kept = append(kept, post)
+			}
+		}
+		posts = kept
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		c := keys[posts[i].ID].compare(keys[posts[j].ID])
+		if backward {
+			return c < 0
+		}
+		return c > 0
+	})
+	if len(posts) > page.Size()+1 {
+		posts = posts[:page.Size()+1]
+	}
+
+	var scores map[uuid.UUID]float64
+	if scored {
+		scores = make(map[uuid.UUID]float64, len(posts))
+		for _, post := range posts {
+			scores[post.ID] = keys[post.ID].score
+		}
+	}
+
+	result := entity.NewPostPage(page, r.store.withDetails(posts, r.maxDepth), scores, now)
+	return result, nil
+}
+
+// likedBy reports whether a user liked a post. The caller holds mu.
+func (s *Store) likedBy(postID, userID uuid.UUID) bool {
+	for _, reaction := range s.postReactions {
+		if reaction.PostID == postID && reaction.UserID == userID {
+			return reaction.Reaction
+		}
+	}
+	return false
+}
+
+// UpdatePostWithRevision stores the current version of a post as a revision
+// and then overwrites it.
+func (r *MemoryPostAggregateRepository) UpdatePostWithRevision(ctx context.Context, post *entity.Post) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.posts[post.ID]
+	if !ok {
+		return custom_errors.ErrPostNotFound
+	}
+
+	version := 1
+	for _, revision := range r.store.revisions {
+		if revision.PostID == post.ID {
+			version++
+		}
+	}
+	revision := entity.PostRevision{
+		ID:        uuid.New(),
+		PostID:    post.ID,
+		Version:   version,
+		Title:     stored.Title,
+		Content:   stored.Content,
+		CreatedAt: stored.CreatedAt,
+	}
+	if stored.UpdatedAt != nil {
+		revision.CreatedAt = *stored.UpdatedAt
+	}
+	r.store.revisions[revision.ID] = revision
+
+	now := time.Now()
+	stored.Title = post.Title
+	stored.Content = post.Content
+	stored.UpdatedAt = &now
+	r.store.posts[post.ID] = stored
+
+	post.UpdatedAt = &now
+	return nil
+}
+
+// DeletePostWithDependencies removes a post together with everything that
+// references it.
+func (r *MemoryPostAggregateRepository) DeletePostWithDependencies(ctx context.Context, postID uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.posts[postID]; !ok {
+		return custom_errors.ErrPostNotFound
+	}
+
+	for id, comment := range r.store.comments {
+		if comment.PostID != postID {
+			continue
+		}
+		for reactionID, reaction := range r.store.commentReactions {
+			if reaction.CommentID == id {
+				delete(r.store.commentReactions, reactionID)
+			}
+		}
+		delete(r.store.comments, id)
+	}
+	for id, reaction := range r.store.postReactions {
+		if reaction.PostID == postID {
+			delete(r.store.postReactions, id)
+		}
+	}
+	for association := range r.store.postCategories {
+		if association.PostID == postID {
+			delete(r.store.postCategories, association)
+		}
+	}
+	for id, revision := range r.store.revisions {
+		if revision.PostID == postID {
+			delete(r.store.revisions, id)
+		}
+	}
+	for id, attachment := range r.store.attachments {
+		if attachment.PostID == postID {
+			delete(r.store.attachments, id)
+		}
+	}
+	delete(r.store.posts, postID)
+	return nil
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"forum/domain/entity"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type MemoryPostAttachmentRepository struct {
+	store *Store
+}
+
+func NewMemoryPostAttachmentRepository(store *Store) repository.PostAttachmentRepository {
+	return &MemoryPostAttachmentRepository{store: store}
+}
+
+func (r *MemoryPostAttachmentRepository) Create(ctx context.Context, attachment *entity.PostAttachment) error {
+	if attachment.ID == uuid.Nil {
+		attachment.ID = uuid.New()
+	}
+	attachment.CreatedAt = time.Now()
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.attachments[attachment.ID] = *attachment
+	return nil
+}
+
+func (r *MemoryPostAttachmentRepository) GetByPostID(ctx context.Context, postID uuid.UUID) ([]*entity.PostAttachment, error) {
+	attachments, err := r.GetByPostIDs(ctx, []uuid.UUID{postID})
+	if err != nil {
+		return nil, err
+	}
+	return attachments[postID], nil
+}
+
+func (r *MemoryPostAttachmentRepository) GetByPostIDs(ctx context.Context, postIDs []uuid.UUID) (map[uuid.UUID][]*entity.PostAttachment, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.postAttachments(postIDs), nil
+}
+
+// postAttachments returns the attachments of some posts, oldest first. The
+// caller holds mu.
+func (s *Store) postAttachments(postIDs []uuid.UUID) map[uuid.UUID][]*entity.PostAttachment {
+	wanted := make(map[uuid.UUID]bool, len(postIDs))
+	for _, postID := range postIDs {
+		wanted[postID] = true
+	}
+
+	attachments := make(map[uuid.UUID][]*entity.PostAttachment)
+	for _, attachment := range s.attachments {
+		if wanted[attachment.PostID] {
+			attachments[attachment.PostID] = append(attachments[attachment.PostID], &attachment)
+		}
+	}
+	for _, list := range attachments {
+		sort.Slice(list, func(i, j int) bool {
+			if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+				return list[i].CreatedAt.Before(list[j].CreatedAt)
+			}
+			return compareIDs(list[i].ID, list[j].ID) < 0
+		})
+	}
+	return attachments
+}
@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"forum/domain/entity"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type MemoryPostCategoryRepository struct {
+	store *Store
+}
+
+func NewMemoryPostCategoryRepository(store *Store) repository.PostCategoryRepository {
+	return &MemoryPostCategoryRepository{store: store}
+}
+
+func (r *MemoryPostCategoryRepository) Create(ctx context.Context, postCategory *entity.PostCategory) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.store.postCategories[*postCategory] {
+		return fmt.Errorf("post %s is already in category %s", postCategory.PostID, postCategory.CategoryID)
+	}
+	r.store.postCategories[*postCategory] = true
+	return nil
+}
+
+func (r *MemoryPostCategoryRepository) Delete(ctx context.Context, postID, categoryID uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.postCategories, entity.PostCategory{PostID: postID, CategoryID: categoryID})
+	return nil
+}
+
+func (r *MemoryPostCategoryRepository) DeleteByPostID(ctx context.Context, postID uuid.UUID) error {
+	return r.deleteWhere(func(association entity.PostCategory) bool { return association.PostID == postID })
+}
+
+func (r *MemoryPostCategoryRepository) DeleteByCategoryID(ctx context.Context, categoryID uuid.UUID) error {
+	return r.deleteWhere(func(association entity.PostCategory) bool { return association.CategoryID == categoryID })
+}
+
+func (r *MemoryPostCategoryRepository) deleteWhere(match func(association entity.PostCategory) bool) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for association := range r.store.postCategories {
+		if match(association) {
+			delete(r.store.postCategories, association)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryPostCategoryRepository) GetCategoriesByPostID(ctx context.Context, postID uuid.UUID) ([]*entity.Category, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.postCategoryList(postID), nil
+}
+
+func (r *MemoryPostCategoryRepository) GetCategoriesByPostIDs(ctx context.Context, postIDs []uuid.UUID) (map[uuid.UUID][]*entity.Category, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	categories := make(map[uuid.UUID][]*entity.Category)
+	for _, postID := range postIDs {
+		if list := r.store.postCategoryList(postID); list != nil {
+			categories[postID] = list
+		}
+	}
+	return categories, nil
+}
+
+func (r *MemoryPostCategoryRepository) GetPostsByCategoryID(ctx context.Context, categoryID uuid.UUID) ([]*entity.Post, error) {
+	return (&MemoryPostRepository{store: r.store}).GetByCategory(ctx, categoryID)
+}
+
+func (r *MemoryPostCategoryRepository) CheckAssociationExists(ctx context.Context, postID, categoryID uuid.UUID) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.postCategories[entity.PostCategory{PostID: postID, CategoryID: categoryID}], nil
+}
+
+func (r *MemoryPostCategoryRepository) GetAllAssociations(ctx context.Context) ([]*entity.PostCategory, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var associations []*entity.PostCategory
+	for association := range r.store.postCategories {
+		associations = append(associations, &association)
+	}
+	return associations, nil
+}
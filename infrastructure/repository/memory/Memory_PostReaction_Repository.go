@@ -0,0 +1,177 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type MemoryPostReactionRepository struct {
+	store *Store
+}
+
+func NewMemoryPostReactionRepository(store *Store) repository.PostReactionRepository {
+	return &MemoryPostReactionRepository{store: store}
+}
+
+func (r *MemoryPostReactionRepository) Create(ctx context.Context, reaction *entity.PostReaction) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.postReactions {
+		if existing.UserID == reaction.UserID && existing.PostID == reaction.PostID {
+			return custom_errors.ErrReactionExists
+		}
+	}
+
+	reaction.ID = uuid.New()
+	reaction.CreatedAt = time.Now()
+	r.store.postReactions[reaction.ID] = *reaction
+	return nil
+}
+
+func (r *MemoryPostReactionRepository) GetByID(ctx context.Context, reactionID uuid.UUID) (*entity.PostReaction, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	reaction, ok := r.store.postReactions[reactionID]
+	if !ok {
+		return nil, custom_errors.ErrReactionNotFound
+	}
+	return &reaction, nil
+}
+
+func (r *MemoryPostReactionRepository) GetByUserAndPost(ctx context.Context, userID, postID uuid.UUID) (*entity.PostReaction, error) {
+	reactions := r.list(func(reaction entity.PostReaction) bool {
+		return reaction.UserID == userID && reaction.PostID == postID
+	})
+	if len(reactions) == 0 {
+		return nil, custom_errors.ErrReactionNotFound
+	}
+	return reactions[0], nil
+}
+
+// list returns the reactions that match, newest first.
+func (r *MemoryPostReactionRepository) list(match func(reaction entity.PostReaction) bool) []*entity.PostReaction {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var reactions []*entity.PostReaction
+	for _, reaction := range r.store.postReactions {
+		if match(reaction) {
+			reactions = append(reactions, &reaction)
+		}
+	}
+	sort.Slice(reactions, func(i, j int) bool {
+		return reactions[i].CreatedAt.After(reactions[j].CreatedAt)
+	})
+	return reactions
+}
+
+func (r *MemoryPostReactionRepository) GetByPostID(ctx context.Context, postID uuid.UUID) ([]*entity.PostReaction, error) {
+	return r.list(func(reaction entity.PostReaction) bool { return reaction.PostID == postID }), nil
+}
+
+func (r *MemoryPostReactionRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.PostReaction, error) {
+	return r.list(func(reaction entity.PostReaction) bool { return reaction.UserID == userID }), nil
+}
+
+func (r *MemoryPostReactionRepository) Update(ctx context.Context, reaction *entity.PostReaction) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.postReactions[reaction.ID]
+	if !ok {
+		return nil
+	}
+	stored.Reaction = reaction.Reaction
+	r.store.postReactions[reaction.ID] = stored
+	return nil
+}
+
+func (r *MemoryPostReactionRepository) Delete(ctx context.Context, reactionID uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.postReactions, reactionID)
+	return nil
+}
+
+func (r *MemoryPostReactionRepository) DeleteByUserAndPost(ctx context.Context, userID, postID uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, reaction := range r.store.postReactions {
+		if reaction.UserID == userID && reaction.PostID == postID {
+			delete(r.store.postReactions, id)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryPostReactionRepository) GetLikeCountByPostID(ctx context.Context, postID uuid.UUID) (int, error) {
+	likes, _, err := r.GetReactionCountsByPostID(ctx, postID)
+	return likes, err
+}
+
+func (r *MemoryPostReactionRepository) GetDislikeCountByPostID(ctx context.Context, postID uuid.UUID) (int, error) {
+	_, dislikes, err := r.GetReactionCountsByPostID(ctx, postID)
+	return dislikes, err
+}
+
+func (r *MemoryPostReactionRepository) GetReactionCountsByPostID(ctx context.Context, postID uuid.UUID) (likes int, dislikes int, err error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	likes, dislikes = r.store.postReactionCounts(postID)
+	return likes, dislikes, nil
+}
+
+func (r *MemoryPostReactionRepository) HasUserReacted(ctx context.Context, userID, postID uuid.UUID) (bool, *bool, error) {
+	reaction, err := r.GetByUserAndPost(ctx, userID, postID)
+	if err == custom_errors.ErrReactionNotFound {
+		return false, nil, nil
+	}
+	return true, &reaction.Reaction, nil
+}
+
+func (r *MemoryPostReactionRepository) Toggle(ctx context.Context, userID, postID uuid.UUID, like bool) (*entity.ReactionState, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.posts[postID]; !ok {
+		return nil, custom_errors.ErrPostNotFound
+	}
+
+	state := &entity.ReactionState{}
+	var existing *entity.PostReaction
+	for _, reaction := range r.store.postReactions {
+		if reaction.UserID == userID && reaction.PostID == postID {
+			existing = &reaction
+			break
+		}
+	}
+
+	switch {
+	case existing != nil && existing.Reaction == like:
+		delete(r.store.postReactions, existing.ID)
+	case existing != nil:
+		existing.Reaction = like
+		existing.CreatedAt = time.Now()
+		r.store.postReactions[existing.ID] = *existing
+		state.Reaction = &like
+	default:
+		reaction := entity.PostReaction{ID: uuid.New(), UserID: userID, PostID: postID, Reaction: like, CreatedAt: time.Now()}
+		r.store.postReactions[reaction.ID] = reaction
+		state.Reaction = &like
+	}
+
+	state.LikeCount, state.DislikeCount = r.store.postReactionCounts(postID)
+	return state, nil
+}
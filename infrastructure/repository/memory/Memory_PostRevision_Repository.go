@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"forum/domain/entity"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type MemoryPostRevisionRepository struct {
+	store *Store
+}
+
+func NewMemoryPostRevisionRepository(store *Store) repository.PostRevisionRepository {
+	return &MemoryPostRevisionRepository{store: store}
+}
+
+func (r *MemoryPostRevisionRepository) GetByPostID(ctx context.Context, postID uuid.UUID) ([]*entity.PostRevision, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var revisions []*entity.PostRevision
+	for _, revision := range r.store.revisions {
+		if revision.PostID == postID {
+			revisions = append(revisions, &revision)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Version < revisions[j].Version
+	})
+	return revisions, nil
+}
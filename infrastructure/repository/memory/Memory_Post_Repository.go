@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type MemoryPostRepository struct {
+	store *Store
+}
+
+func NewMemoryPostRepository(store *Store) repository.PostRepository {
+	return &MemoryPostRepository{store: store}
+}
+
+func (r *MemoryPostRepository) Create(ctx context.Context, post *entity.Post) error {
+	post.ID = uuid.New()
+	post.CreatedAt = time.Now()
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.posts[post.ID] = *post
+	return nil
+}
+
+func (r *MemoryPostRepository) GetByID(ctx context.Context, postID uuid.UUID) (*entity.Post, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	post, ok := r.store.posts[postID]
+	if !ok {
+		return nil, custom_errors.ErrPostNotFound
+	}
+	return &post, nil
+}
+
+// list returns the posts that match, newest first.
+func (r *MemoryPostRepository) list(match func(post entity.Post) bool) []*entity.Post {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var posts []*entity.Post
+	for _, post := range r.store.posts {
+		if match(post) {
+			posts = append(posts, &post)
+		}
+	}
+	newestFirst(posts)
+	return posts
+}
+
+func (r *MemoryPostRepository) GetbyuserId(ctx context.Context, userID uuid.UUID) ([]*entity.Post, error) {
+	return r.list(func(post entity.Post) bool { return post.UserID == userID }), nil
+}
+
+func (r *MemoryPostRepository) GetAll(ctx context.Context) ([]*entity.Post, error) {
+	return r.list(func(entity.Post) bool { return true }), nil
+}
+
+func (r *MemoryPostRepository) GetByCategory(ctx context.Context, categoryID uuid.UUID) ([]*entity.Post, error) {
+	return r.list(func(post entity.Post) bool {
+		return r.store.postCategories[entity.PostCategory{PostID: post.ID, CategoryID: categoryID}]
+	}), nil
+}
+
+func (r *MemoryPostRepository) Update(ctx context.Context, post *entity.Post) error {
+	now := time.Now()
+	post.UpdatedAt = &now
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.posts[post.ID]
+	if !ok {
+		return nil
+	}
+	stored.Title = post.Title
+	stored.Content = post.Content
+	stored.UpdatedAt = post.UpdatedAt
+	r.store.posts[post.ID] = stored
+	return nil
+}
+
+func (r *MemoryPostRepository) Delete(ctx context.Context, postID uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.posts, postID)
+	return nil
+}
+
+func (r *MemoryPostRepository) GetWithDetails(ctx context.Context, postID uuid.UUID) (*entity.PostWithDetails, error) {
+	post, err := r.GetByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.PostWithDetails{
+		Post: *post,
+	}, nil
+}
+
+func (r *MemoryPostRepository) GetFiltered(ctx context.Context, filter entity.PostFilter) ([]*entity.Post, error) {
+	return r.list(func(post entity.Post) bool {
+		return r.store.inAnyCategory(post.ID, filter.CategoryIDs)
+	}), nil
+}
+
+// inAnyCategory reports whether a post is in one of the categories, or true
+// when there are none to check. The caller holds mu.
+func (s *Store) inAnyCategory(postID uuid.UUID, categoryIDs []uuid.UUID) bool {
+	if len(categoryIDs) == 0 {
+		return true
+	}
+	for _, categoryID := range categoryIDs {
+		if s.postCategories[entity.PostCategory{PostID: postID, CategoryID: categoryID}] {
+			return true
+		}
+	}
+	return false
+}
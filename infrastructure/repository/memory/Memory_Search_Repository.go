@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"forum/domain/entity"
+	"forum/domain/repository"
+)
+
+// maxSearchTerms bounds how many words of a query are used.
+const maxSearchTerms = 10
+
+// MemorySearchRepository finds posts whose title, content or comments
+// contain every word of the query, ignoring case. Results are ordered newest
+// first and are not highlighted.
+type MemorySearchRepository struct {
+	store *Store
+}
+
+func NewMemorySearchRepository(store *Store) repository.SearchRepository {
+	return &MemorySearchRepository{store: store}
+}
+
+// searchTerms splits a user query into lowercase words, the same way the
+// database backends do.
+func searchTerms(query string) []string {
+	terms := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	return terms
+}
+
+func containsAll(text string, terms []string) bool {
+	text = strings.ToLower(text)
+	for _, term := range terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesSearch reports whether a post or its comments contain all the
+// terms, and whether the match was only found in the comments. The caller
+// holds mu.
+func (s *Store) matchesSearch(post entity.Post, terms []string) (found, inComment bool) {
+	if len(terms) == 0 {
+		return false, false
+	}
+	if containsAll(post.Title+" "+post.Content, terms) {
+		return true, false
+	}
+	for _, comment := range s.comments {
+		if comment.PostID == post.ID && !comment.IsDeleted() && containsAll(comment.Content, terms) {
+			return true, true
+		}
+	}
+	return false, false
+}
+
+func (r *MemorySearchRepository) Search(ctx context.Context, query string, limit int) ([]*entity.SearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var posts []*entity.Post
+	for _, post := range r.store.posts {
+		posts = append(posts, &post)
+	}
+	newestFirst(posts)
+
+	var results []*entity.SearchResult
+	for _, post := range posts {
+		if len(results) == limit {
+			break
+		}
+		found, inComment := r.store.matchesSearch(*post, terms)
+		if !found {
+			continue
+		}
+		results = append(results, &entity.SearchResult{
+			PostID:     post.ID,
+			Title:      entity.StripHighlight(post.Title),
+			Snippet:    entity.StripHighlight(post.Content),
+			AuthorName: r.store.users[post.UserID].UserName,
+			CreatedAt:  post.CreatedAt,
+			InComment:  inComment,
+		})
+	}
+	return results, nil
+}
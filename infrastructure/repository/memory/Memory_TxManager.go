@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"context"
+	"maps"
+
+	"forum/domain/repository"
+)
+
+// txKey marks a context as running inside WithinTx.
+type txKey struct{}
+
+type MemoryTxManager struct {
+	store *Store
+}
+
+// NewMemoryTxManager runs transactions on store one at a time. A failed
+// transaction puts every table back as it was when it started, which also
+// drops anything written outside of a transaction in the meantime.
+func NewMemoryTxManager(store *Store) repository.TxManager {
+	return &MemoryTxManager{store: store}
+}
+
+func (m *MemoryTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) != nil {
+		return fn(ctx)
+	}
+
+	m.store.txMu.Lock()
+	defer m.store.txMu.Unlock()
+
+	saved := m.store.snapshot()
+	if err := fn(context.WithValue(ctx, txKey{}, true)); err != nil {
+		m.store.restore(saved)
+		return err
+	}
+	return nil
+}
+
+// snapshot copies every table.
+func (s *Store) snapshot() *Store {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return &Store{
+		users:            maps.Clone(s.users),
+		sessions:         maps.Clone(s.sessions),
+		posts:            maps.Clone(s.posts),
+		comments:         maps.Clone(s.comments),
+		categories:       maps.Clone(s.categories),
+		postCategories:   maps.Clone(s.postCategories),
+		postReactions:    maps.Clone(s.postReactions),
+		commentReactions: maps.Clone(s.commentReactions),
+		revisions:        maps.Clone(s.revisions),
+		attachments:      maps.Clone(s.attachments),
+	}
+}
+
+// restore puts back the tables of a snapshot.
+func (s *Store) restore(saved *Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users = saved.users
+	s.sessions = saved.sessions
+	s.posts = saved.posts
+	s.comments = saved.comments
+	s.categories = saved.categories
+	s.postCategories = saved.postCategories
+	s.postReactions = saved.postReactions
+	s.commentReactions = saved.commentReactions
+	s.revisions = saved.revisions
+	s.attachments = saved.attachments
+}
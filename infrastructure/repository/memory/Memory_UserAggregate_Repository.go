@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"forum/domain/entity"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type MemoryUserAggregateRepository struct {
+	userRepo    repository.UserRepository
+	sessionRepo repository.UserSessionRepository
+}
+
+func NewMemoryUserAggregateRepository(store *Store) repository.UserAggregateRepository {
+	return &MemoryUserAggregateRepository{
+		userRepo:    NewMemoryUserRepository(store),
+		sessionRepo: NewMemoryUserSessionRepository(store),
+	}
+}
+
+// CreateUserSession creates a new session for a user
+func (r *MemoryUserAggregateRepository) CreateUserSession(ctx context.Context, user *entity.User) (*entity.UserSession, error) {
+	session := &entity.UserSession{
+		UserID:       user.ID,
+		SessionToken: uuid.New().String(),
+		ExpiresAt:    time.Now().Add(24 * time.Hour),
+	}
+
+	err := r.sessionRepo.Create(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// AuthenticateUser looks a user up by email and creates a session. Like the
+// SQLite version, it leaves checking the password to the caller.
+func (r *MemoryUserAggregateRepository) AuthenticateUser(ctx context.Context, email, password string) (*entity.User, *entity.UserSession, error) {
+	user, err := r.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session, err := r.CreateUserSession(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, session, nil
+}
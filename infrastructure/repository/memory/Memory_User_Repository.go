@@ -0,0 +1,176 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type MemoryUserRepository struct {
+	store *Store
+}
+
+func NewMemoryUserRepository(store *Store) repository.UserRepository {
+	return &MemoryUserRepository{store: store}
+}
+
+func (r *MemoryUserRepository) Create(ctx context.Context, user *entity.User) error {
+	user.ID = uuid.New()
+	user.CreatedAt = time.Now()
+	if user.Role == "" {
+		user.Role = entity.RoleUser
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.nameTaken(user.UserName, user.ID) {
+		return custom_errors.ErrNameTaken
+	}
+	r.store.users[user.ID] = *user
+	return nil
+}
+
+// nameTaken reports whether a user other than userID has name, like the
+// unique index on user names of the databases. The caller holds the lock.
+func (r *MemoryUserRepository) nameTaken(name string, userID uuid.UUID) bool {
+	for _, user := range r.store.users {
+		if user.UserName == name && user.ID != userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryUserRepository) GetByID(ctx context.Context, userID uuid.UUID) (*entity.User, error) {
+	return r.find(func(user entity.User) bool { return user.ID == userID })
+}
+
+func (r *MemoryUserRepository) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	return r.find(func(user entity.User) bool { return user.Email == email })
+}
+
+func (r *MemoryUserRepository) GetByUserName(ctx context.Context, userName string) (*entity.User, error) {
+	return r.find(func(user entity.User) bool { return user.UserName == userName })
+}
+
+func (r *MemoryUserRepository) find(match func(user entity.User) bool) (*entity.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, user := range r.store.users {
+		if match(user) {
+			return &user, nil
+		}
+	}
+	return nil, custom_errors.ErrUserNotFound
+}
+
+func (r *MemoryUserRepository) GetStats(ctx context.Context, userID uuid.UUID) (*entity.UserStats, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	stats := &entity.UserStats{}
+	for _, post := range r.store.posts {
+		if post.UserID != userID {
+			continue
+		}
+		likes, dislikes := r.store.postReactionCounts(post.ID)
+		stats.PostCount++
+		stats.LikesReceived += likes
+		stats.DislikesReceived += dislikes
+	}
+	for _, comment := range r.store.comments {
+		if comment.UserID != userID || comment.IsDeleted() {
+			continue
+		}
+		likes, dislikes := r.store.commentReactionCounts(comment.ID)
+		stats.CommentCount++
+		stats.LikesReceived += likes
+		stats.DislikesReceived += dislikes
+	}
+	return stats, nil
+}
+
+func (r *MemoryUserRepository) UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[userID]
+	if !ok {
+		return custom_errors.ErrUserNotFound
+	}
+	user.PasswordHash = passwordHash
+	r.store.users[userID] = user
+	return nil
+}
+
+func (r *MemoryUserRepository) MarkEmailVerified(ctx context.Context, userID uuid.UUID, verifiedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[userID]
+	if !ok {
+		return custom_errors.ErrUserNotFound
+	}
+	if user.EmailVerifiedAt == nil {
+		user.EmailVerifiedAt = &verifiedAt
+		r.store.users[userID] = user
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) UpdateProfile(ctx context.Context, user *entity.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.users[user.ID]
+	if !ok {
+		return custom_errors.ErrUserNotFound
+	}
+	stored.Bio = user.Bio
+	stored.AvatarName = user.AvatarName
+	r.store.users[user.ID] = stored
+	return nil
+}
+
+func (r *MemoryUserRepository) ChangeUserName(ctx context.Context, userID uuid.UUID, newName string, changedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[userID]
+	if !ok {
+		return custom_errors.ErrUserNotFound
+	}
+	if r.nameTaken(newName, userID) {
+		return custom_errors.ErrNameTaken
+	}
+
+	change := entity.UserNameChange{ID: uuid.New(), UserID: userID, UserName: user.UserName, ChangedAt: changedAt}
+	r.store.userNameChanges[change.ID] = change
+	user.UserName = newName
+	user.UserNameChangedAt = &changedAt
+	r.store.users[userID] = user
+	return nil
+}
+
+func (r *MemoryUserRepository) GetLastUserNameChange(ctx context.Context, userName string) (*entity.UserNameChange, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var last *entity.UserNameChange
+	for _, change := range r.store.userNameChanges {
+		if change.UserName == userName && (last == nil || change.ChangedAt.After(last.ChangedAt)) {
+			last = &change
+		}
+	}
+	if last == nil {
+		return nil, custom_errors.ErrUserNotFound
+	}
+	return last, nil
+}
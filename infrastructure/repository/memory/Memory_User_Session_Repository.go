@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type MemoryUserSessionRepository struct {
+	store *Store
+}
+
+func NewMemoryUserSessionRepository(store *Store) repository.UserSessionRepository {
+	return &MemoryUserSessionRepository{store: store}
+}
+
+func (r *MemoryUserSessionRepository) Create(ctx context.Context, session *entity.UserSession) error {
+	session.ID = uuid.New()
+	session.CreatedAt = time.Now()
+	session.LastSeenAt = session.CreatedAt
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.sessions[session.ID] = *session
+	return nil
+}
+
+func (r *MemoryUserSessionRepository) GetByToken(ctx context.Context, token string) (*entity.UserSession, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, session := range r.store.sessions {
+		if session.SessionToken == token {
+			return &session, nil
+		}
+	}
+	return nil, errors.New("you need to login")
+}
+
+// GetByUserID returns the newest session of a user, or nil if there is none.
+func (r *MemoryUserSessionRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*entity.UserSession, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var newest *entity.UserSession
+	for _, session := range r.store.sessions {
+		if session.UserID != userID {
+			continue
+		}
+		if newest == nil || session.CreatedAt.After(newest.CreatedAt) {
+			newest = &session
+		}
+	}
+	return newest, nil
+}
+
+func (r *MemoryUserSessionRepository) ListByUserID(ctx context.Context, userID uuid.UUID, now time.Time) ([]*entity.UserSession, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var sessions []*entity.UserSession
+	for _, session := range r.store.sessions {
+		if session.UserID == userID && session.ExpiresAt.After(now) {
+			session := session
+			sessions = append(sessions, &session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastSeenAt.Equal(sessions[j].LastSeenAt) {
+			return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+		}
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+func (r *MemoryUserSessionRepository) Update(ctx context.Context, session *entity.UserSession) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.sessions[session.ID]
+	if !ok {
+		return nil
+	}
+	stored.ExpiresAt = session.ExpiresAt
+	r.store.sessions[session.ID] = stored
+	return nil
+}
+
+func (r *MemoryUserSessionRepository) Touch(ctx context.Context, sessionID uuid.UUID, seenAt time.Time, ipAddress string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.sessions[sessionID]
+	if !ok {
+		return nil
+	}
+	stored.LastSeenAt = seenAt
+	stored.IPAddress = ipAddress
+	r.store.sessions[sessionID] = stored
+	return nil
+}
+
+func (r *MemoryUserSessionRepository) Delete(ctx context.Context, sessionID uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.sessions, sessionID)
+	return nil
+}
+
+func (r *MemoryUserSessionRepository) DeleteUserSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	session, ok := r.store.sessions[sessionID]
+	if !ok || session.UserID != userID {
+		return custom_errors.ErrSessionNotFound
+	}
+	delete(r.store.sessions, sessionID)
+	return nil
+}
+
+func (r *MemoryUserSessionRepository) DeleteExpiredUserSessions(ctx context.Context, userID uuid.UUID, now time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, session := range r.store.sessions {
+		if session.UserID == userID && !session.ExpiresAt.After(now) {
+			delete(r.store.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryUserSessionRepository) DeleteAllUserSessions(ctx context.Context, userID uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, session := range r.store.sessions {
+		if session.UserID == userID {
+			delete(r.store.sessions, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"forum/infrastructure/repository/conformance"
+	"forum/infrastructure/repository/memory"
+)
+
+func TestConformance(t *testing.T) {
+	repos := memory.NewMemoryRepositories(5)
+	for _, check := range conformance.Checks {
+		t.Run(check.Name, func(t *testing.T) {
+			check.Run(context.Background(), t, repos)
+		})
+	}
+}
+
+// TestReactionToggleRace runs the reaction toggle hammer several times at
+// once on the same repositories. Run it with -race.
+func TestReactionToggleRace(t *testing.T) {
+	check, ok := conformance.Find("reaction_toggle")
+	if !ok {
+		t.Fatal("there is no reaction_toggle check")
+	}
+	repos := memory.NewMemoryRepositories(5)
+	for i := 0; i < 4; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+			check.Run(context.Background(), t, repos)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// Package memory implements the domain repositories on maps held in memory.
+// It is meant for tests of the use cases and for checking the other backends
+// against: every repository built on the same Store sees the same data, and
+// a Store is safe for concurrent use.
+package memory
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"forum/domain/entity"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+// Store holds the rows of every table. All access goes through mu, so
+// operations that touch several tables happen atomically. txMu lets one
+// transaction run at a time.
+type Store struct {
+	mu               sync.RWMutex
+	txMu             sync.Mutex
+	users            map[uuid.UUID]entity.User
+	userNameChanges  map[uuid.UUID]entity.UserNameChange
+	sessions         map[uuid.UUID]entity.UserSession
+	resetTokens      map[uuid.UUID]entity.PasswordResetToken
+	posts            map[uuid.UUID]entity.Post
+	comments         map[uuid.UUID]entity.Comment
+	categories       map[uuid.UUID]entity.Category
+	postCategories   map[entity.PostCategory]bool
+	postReactions    map[uuid.UUID]entity.PostReaction
+	commentReactions map[uuid.UUID]entity.CommentReaction
+	revisions        map[uuid.UUID]entity.PostRevision
+	attachments      map[uuid.UUID]entity.PostAttachment
+}
+
+func NewStore() *Store {
+	return &Store{
+		users:            make(map[uuid.UUID]entity.User),
+		userNameChanges:  make(map[uuid.UUID]entity.UserNameChange),
+		sessions:         make(map[uuid.UUID]entity.UserSession),
+		resetTokens:      make(map[uuid.UUID]entity.PasswordResetToken),
+		posts:            make(map[uuid.UUID]entity.Post),
+		comments:         make(map[uuid.UUID]entity.Comment),
+		categories:       make(map[uuid.UUID]entity.Category),
+		postCategories:   make(map[entity.PostCategory]bool),
+		postReactions:    make(map[uuid.UUID]entity.PostReaction),
+		commentReactions: make(map[uuid.UUID]entity.CommentReaction),
+		revisions:        make(map[uuid.UUID]entity.PostRevision),
+		attachments:      make(map[uuid.UUID]entity.PostAttachment),
+	}
+}
+
+// NewMemoryRepositories builds every repository on a new, empty Store.
+// maxDepth is passed on to the comment repository.
+func NewMemoryRepositories(maxDepth int) *repository.Repositories {
+	s := NewStore()
+	r := &repository.Repositories{
+		User:            NewMemoryUserRepository(s),
+		Session:         NewMemoryUserSessionRepository(s),
+		PasswordReset:   NewMemoryPasswordResetRepository(s),
+		Post:            NewMemoryPostRepository(s),
+		PostCategory:    NewMemoryPostCategoryRepository(s),
+		Category:        NewMemoryCategoryRepository(s),
+		PostReaction:    NewMemoryPostReactionRepository(s),
+		PostRevision:    NewMemoryPostRevisionRepository(s),
+		Attachment:      NewMemoryPostAttachmentRepository(s),
+		Search:          NewMemorySearchRepository(s),
+		CommentReaction: NewMemoryCommentReactionRepository(s),
+		Comment:         NewMemoryCommentRepository(s, maxDepth),
+	}
+	r.PostAggregate = NewMemoryPostAggregateRepository(s, maxDepth)
+	r.Tx = NewMemoryTxManager(s)
+	return r
+}
+
+// compareIDs orders IDs the way the databases order them as text.
+func compareIDs(a, b uuid.UUID) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+// newestFirst sorts posts by created_at and then id, both descending, like
+// the SQL queries do.
+func newestFirst(posts []*entity.Post) {
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			return posts[i].CreatedAt.After(posts[j].CreatedAt)
+		}
+		return compareIDs(posts[i].ID, posts[j].ID) > 0
+	})
+}
+
+// postReactionCounts counts the likes and dislikes of a post. The caller holds mu.
+func (s *Store) postReactionCounts(postID uuid.UUID) (likes, dislikes int) {
+	for _, reaction := range s.postReactions {
+		if reaction.PostID != postID {
+			continue
+		}
+		if reaction.Reaction {
+			likes++
+		} else {
+			dislikes++
+		}
+	}
+	return likes, dislikes
+}
+
+// commentReactionCounts counts the likes and dislikes of a comment. The
+// caller holds mu.
+func (s *Store) commentReactionCounts(commentID uuid.UUID) (likes, dislikes int) {
+	for _, reaction := range s.commentReactions {
+		if reaction.CommentID != commentID {
+			continue
+		}
+		if reaction.Reaction {
+			likes++
+		} else {
+			dislikes++
+		}
+	}
+	return likes, dislikes
+}
+
+// postComments returns the comments of a post, oldest first. The caller
+// holds mu.
+func (s *Store) postComments(postID uuid.UUID) []entity.Comment {
+	var comments []entity.Comment
+	for _, comment := range s.comments {
+		if comment.PostID == postID {
+			comments = append(comments, comment)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		if !comments[i].CreatedAt.Equal(comments[j].CreatedAt) {
+			return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+		}
+		return compareIDs(comments[i].ID, comments[j].ID) < 0
+	})
+	return comments
+}
+
+// postCategoryList returns the categories of a post by name. The caller
+// holds mu.
+func (s *Store) postCategoryList(postID uuid.UUID) []*entity.Category {
+	var categories []*entity.Category
+	for association := range s.postCategories {
+		if association.PostID != postID {
+			continue
+		}
+		if category, ok := s.categories[association.CategoryID]; ok {
+			categories = append(categories, &category)
+		}
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Name < categories[j].Name
+	})
+	return categories
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type PostgresCategoryRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresCategoryRepository(db *sql.DB) repository.CategoryRepository {
+	return &PostgresCategoryRepository{db: db}
+}
+
+func (r *PostgresCategoryRepository) Create(ctx context.Context, category *entity.Category) error {
+	category.ID = uuid.New()
+	category.CreatedAt = time.Now()
+
+	query := `INSERT INTO categories (id, name, created_at) VALUES (?, ?, ?)`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), category.ID.String(), category.Name, category.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return custom_errors.ErrCategoryExists
+		}
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return nil
+}
+
+func (r *PostgresCategoryRepository) getBy(ctx context.Context, column string, value interface{}) (*entity.Category, error) {
+	query := `SELECT id, name, created_at FROM categories WHERE ` + column + ` = ?`
+
+	category := &entity.Category{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, rebind(query), value).Scan(&category.ID, &category.Name, &category.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, custom_errors.ErrCategoryNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return category, nil
+}
+
+func (r *PostgresCategoryRepository) GetByID(ctx context.Context, categoryID *uuid.UUID) (*entity.Category, error) {
+	return r.getBy(ctx, "id", categoryID.String())
+}
+
+func (r *PostgresCategoryRepository) GetByName(ctx context.Context, name string) (*entity.Category, error) {
+	return r.getBy(ctx, "name", name)
+}
+
+func (r *PostgresCategoryRepository) GetAll(ctx context.Context) ([]*entity.Category, error) {
+	query := `SELECT id, name, created_at FROM categories ORDER BY name ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var categories []*entity.Category
+	for rows.Next() {
+		category := &entity.Category{}
+		if err := rows.Scan(&category.ID, &category.Name, &category.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+func (r *PostgresCategoryRepository) CheckNameExists(ctx context.Context, name string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM categories WHERE name = ?)`
+
+	var exists bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, rebind(query), name).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type PostgresCommentReactionRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresCommentReactionRepository(db *sql.DB) repository.CommentReactionRepository {
+	return &PostgresCommentReactionRepository{db: db}
+}
+
+func (r *PostgresCommentReactionRepository) Create(ctx context.Context, reaction *entity.CommentReaction) error {
+	reaction.ID = uuid.New()
+	reaction.CreatedAt = time.Now()
+
+	query := `INSERT INTO comment_reaction (id, user_id, comment_id, reaction, created_at)
+			  VALUES (?, ?, ?, ?, ?)`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), reaction.ID.String(), reaction.UserID.String(),
+		reaction.CommentID.String(), reactionValue(reaction.Reaction), reaction.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return custom_errors.ErrReactionExists
+		}
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return nil
+}
+
+func (r *PostgresCommentReactionRepository) GetByUserAndComment(ctx context.Context, userID, commentID uuid.UUID) (*entity.CommentReaction, error) {
+	query := `SELECT id, user_id, comment_id, reaction, created_at
+			  FROM comment_reaction WHERE user_id = ? AND comment_id = ?`
+
+	reaction := &entity.CommentReaction{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, rebind(query), userID.String(), commentID.String()).
+		Scan(&reaction.ID, &reaction.UserID, &reaction.CommentID, &reaction.Reaction, &reaction.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, custom_errors.ErrReactionNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return reaction, nil
+}
+
+// exec runs a statement that must change exactly the row it names.
+func (r *PostgresCommentReactionRepository) exec(ctx context.Context, query string, args ...interface{}) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), args...)
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	if rowsAffected == 0 {
+		return custom_errors.ErrReactionNotFound
+	}
+	return nil
+}
+
+func (r *PostgresCommentReactionRepository) Update(ctx context.Context, reaction *entity.CommentReaction) error {
+	return r.exec(ctx, `UPDATE comment_reaction SET reaction = ? WHERE id = ?`,
+		reactionValue(reaction.Reaction), reaction.ID.String())
+}
+
+func (r *PostgresCommentReactionRepository) Delete(ctx context.Context, reactionID uuid.UUID) error {
+	return r.exec(ctx, `DELETE FROM comment_reaction WHERE id = ?`, reactionID.String())
+}
+
+func (r *PostgresCommentReactionRepository) GetReactionCountsByCommentID(ctx context.Context, commentID uuid.UUID) (likes int, dislikes int, err error) {
+	query := `SELECT like_count, dislike_count FROM comments WHERE id = ?`
+
+	err = conn(ctx, r.db).QueryRowContext(ctx, rebind(query), commentID.String()).Scan(&likes, &dislikes)
+	if err == sql.ErrNoRows {
+		return 0, 0, nil
+	} else if err != nil {
+		return 0, 0, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return likes, dislikes, nil
+}
+
+func (r *PostgresCommentReactionRepository) Toggle(ctx context.Context, userID, commentID uuid.UUID, like bool) (*entity.ReactionState, error) {
+	return commentReactions.toggle(ctx, r.db, userID, commentID, like)
+}
@@ -0,0 +1,295 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type PostgresCommentRepository struct {
+	db              *sql.DB
+	userRepo        repository.UserRepository
+	commentReaction repository.CommentReactionRepository
+	maxDepth        int
+}
+
+// NewPostgresCommentRepository builds the comment repository. maxDepth is the
+// deepest reply level that still accepts replies when threads are built.
+func NewPostgresCommentRepository(
+	db *sql.DB,
+	userRepo *repository.UserRepository,
+	commentReaction *repository.CommentReactionRepository,
+	maxDepth int,
+) repository.CommentRepository {
+	return &PostgresCommentRepository{
+		db:              db,
+		userRepo:        *userRepo,
+		commentReaction: *commentReaction,
+		maxDepth:        maxDepth,
+	}
+}
+
+const commentColumns = `c.id, c.content, c.user_id, c.post_id, c.parent_id, c.createdat, c.updated_at, c.deleted_at`
+
+// scanComment reads the commentColumns of a row, followed by any extra
+// columns into extra.
+func scanComment(row rowScanner, comment *entity.Comment, extra ...interface{}) error {
+	var parentID uuid.NullUUID
+	var updatedAt, deletedAt sql.NullTime
+
+	dest := []interface{}{
+		&comment.ID, &comment.Content, &comment.UserID, &comment.PostID, &parentID,
+		&comment.CreatedAt, &updatedAt, &deletedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	if parentID.Valid {
+		comment.ParentID = &parentID.UUID
+	}
+	if updatedAt.Valid {
+		comment.UpdatedAt = &updatedAt.Time
+	}
+	if deletedAt.Valid {
+		comment.DeletedAt = &deletedAt.Time
+	}
+	return nil
+}
+
+func (r *PostgresCommentRepository) Create(ctx context.Context, comment *entity.Comment) error {
+	comment.ID = uuid.New()
+	comment.CreatedAt = time.Now()
+
+	var parentID interface{}
+	if comment.ParentID != nil {
+		parentID = comment.ParentID.String()
+	}
+
+	query := `INSERT INTO comments (id, content, user_id, post_id, parent_id, createdat)
+			  VALUES (?, ?, ?, ?, ?, ?)`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), comment.ID.String(), comment.Content,
+		comment.UserID.String(), comment.PostID.String(), parentID, comment.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return nil
+}
+
+func (r *PostgresCommentRepository) GetByID(ctx context.Context, commentID uuid.UUID) (*entity.Comment, error) {
+	query := `SELECT ` + commentColumns + ` FROM comments c WHERE c.id = ?`
+
+	comment := &entity.Comment{}
+	err := scanComment(conn(ctx, r.db).QueryRowContext(ctx, rebind(query), commentID.String()), comment)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, custom_errors.ErrCommentNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return comment, nil
+}
+
+func (r *PostgresCommentRepository) GetByPostID(ctx context.Context, postID uuid.UUID) ([]entity.Comment, error) {
+	query := `SELECT ` + commentColumns + `
+			  FROM comments c WHERE c.post_id = ? ORDER BY c.createdat ASC, c.id ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, rebind(query), postID.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var comments []entity.Comment
+	for rows.Next() {
+		var comment entity.Comment
+		if err := scanComment(rows, &comment); err != nil {
+			return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return comments, nil
+}
+
+// exec runs a statement that must change exactly the comment it names.
+func (r *PostgresCommentRepository) exec(ctx context.Context, query string, args ...interface{}) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), args...)
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	if rowsAffected == 0 {
+		return custom_errors.ErrCommentNotFound
+	}
+	return nil
+}
+
+func (r *PostgresCommentRepository) Update(ctx context.Context, comment *entity.Comment) error {
+	now := time.Now()
+	comment.UpdatedAt = &now
+
+	return r.exec(ctx, `UPDATE comments SET content = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL`,
+		comment.Content, comment.UpdatedAt, comment.ID.String())
+}
+
+func (r *PostgresCommentRepository) Delete(ctx context.Context, commentID uuid.UUID) error {
+	return r.exec(ctx, `DELETE FROM comments WHERE id = ?`, commentID.String())
+}
+
+// SoftDelete turns a comment into a tombstone: its content is wiped and it is
+// marked as deleted, but the row stays so the conversation keeps its shape.
+func (r *PostgresCommentRepository) SoftDelete(ctx context.Context, commentID uuid.UUID) error {
+	return r.exec(ctx, `UPDATE comments SET content = '', deleted_at = ? WHERE id = ? AND deleted_at IS NULL`,
+		time.Now(), commentID.String())
+}
+
+func (r *PostgresCommentRepository) GetCountByPostID(ctx context.Context, postID uuid.UUID) (int, error) {
+	query := `SELECT comment_count FROM posts WHERE id = ?`
+
+	var count int
+	err := conn(ctx, r.db).QueryRowContext(ctx, rebind(query), postID.String()).Scan(&count)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return count, nil
+}
+
+func (r *PostgresCommentRepository) GetCountByUserID(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM comments WHERE user_id = ?`
+
+	var count int
+	err := conn(ctx, r.db).QueryRowContext(ctx, rebind(query), userID.String()).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return count, nil
+}
+
+func (r *PostgresCommentRepository) GetWithDetails(ctx context.Context, commentID uuid.UUID) (*entity.CommentWithDetails, error) {
+	comment, err := r.GetByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.CommentWithDetails{
+		Comment: *comment,
+	}, nil
+}
+
+func (r *PostgresCommentRepository) GetByPostIDWithDetails(ctx context.Context, postID uuid.UUID) ([]entity.CommentWithDetails, error) {
+	comments, err := r.GetByPostIDsWithDetails(ctx, []uuid.UUID{postID})
+	if err != nil {
+		return nil, err
+	}
+	return comments[postID], nil
+}
+
+// GetByPostIDsWithDetails loads the comments of several posts, with their
+// authors, in a single query and threads each post's comments.
+func (r *PostgresCommentRepository) GetByPostIDsWithDetails(ctx context.Context, postIDs []uuid.UUID) (map[uuid.UUID][]entity.CommentWithDetails, error) {
+	result := make(map[uuid.UUID][]entity.CommentWithDetails)
+	if len(postIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders, args := inClause(postIDs)
+	query := `SELECT ` + commentColumns + `,
+				c.like_count, c.dislike_count, c.comment_count,
+				u.user_name, u.email, u.role, u.created_at
+			  FROM comments c
+			  INNER JOIN "user" u ON u.id = c.user_id
+			  WHERE c.post_id IN (` + placeholders + `)
+			  ORDER BY c.createdat ASC, c.id ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var details entity.CommentWithDetails
+		err := scanComment(rows, &details.Comment,
+			&details.LikeCount, &details.DislikeCount, &details.ReplyCount,
+			&details.Author.UserName, &details.Author.Email, &details.Author.Role, &details.Author.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+		}
+		details.Author.ID = details.UserID
+
+		result[details.PostID] = append(result[details.PostID], details)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+
+	for postID, comments := range result {
+		result[postID] = entity.ThreadComments(comments, r.maxDepth)
+	}
+	return result, nil
+}
+
+// commentOrder orders comments newest first, like postOrder does for posts.
+var commentOrder = []sortKey{
+	{"c.createdat", func(c *entity.PostCursor) (string, []interface{}) {
+		return "COALESCE((SELECT createdat FROM comments WHERE id = ?), ?)", []interface{}{c.ID.String(), c.CreatedAt}
+	}},
+	{"c.id", func(c *entity.PostCursor) (string, []interface{}) {
+		return "?::uuid", []interface{}{c.ID.String()}
+	}},
+}
+
+func (r *PostgresCommentRepository) GetByUserIDWithDetails(ctx context.Context, userID uuid.UUID, page entity.PageRequest) (*entity.CommentPage, error) {
+	condition, order, args := keysetCondition(page, commentOrder)
+	query := `SELECT ` + commentColumns + `,
+				c.like_count, c.dislike_count, c.comment_count,
+				u.user_name, u.email, u.role, u.created_at, p.title
+			  FROM comments c
+			  INNER JOIN "user" u ON u.id = c.user_id
+			  INNER JOIN posts p ON p.id = c.post_id
+			  WHERE c.user_id = ? AND c.deleted_at IS NULL AND ` + condition + `
+			  ORDER BY ` + order + ` LIMIT ?`
+	args = append([]interface{}{userID.String()}, args...)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, rebind(query), append(args, page.Size()+1)...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var comments []*entity.UserComment
+	for rows.Next() {
+		comment := &entity.UserComment{}
+		details := &comment.CommentWithDetails
+		err := scanComment(rows, &details.Comment,
+			&details.LikeCount, &details.DislikeCount, &details.ReplyCount,
+			&details.Author.UserName, &details.Author.Email, &details.Author.Role, &details.Author.CreatedAt,
+			&comment.PostTitle)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+		}
+		details.Author.ID = details.UserID
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return entity.NewCommentPage(page, comments), nil
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type PostgresPasswordResetRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresPasswordResetRepository(db *sql.DB) repository.PasswordResetRepository {
+	return &PostgresPasswordResetRepository{db: db}
+}
+
+func (r *PostgresPasswordResetRepository) Create(ctx context.Context, token *entity.PasswordResetToken) error {
+	token.ID = uuid.New()
+	token.CreatedAt = time.Now()
+	query := `INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at, created_at)
+			  VALUES (?::uuid, ?::uuid, ?, ?, ?)`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), token.ID.String(), token.UserID.String(),
+		token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return nil
+}
+
+func (r *PostgresPasswordResetRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*entity.PasswordResetToken, error) {
+	query := `SELECT id, user_id, token_hash, expires_at, used_at, created_at
+			  FROM password_reset_tokens WHERE token_hash = ?`
+
+	token := &entity.PasswordResetToken{}
+	var usedAt sql.NullTime
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, rebind(query), tokenHash).
+		Scan(&token.ID, &token.UserID, &token.TokenHash, &token.ExpiresAt, &usedAt, &token.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, custom_errors.ErrResetTokenNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	return token, nil
+}
+
+func (r *PostgresPasswordResetRepository) MarkUsed(ctx context.Context, tokenID uuid.UUID, usedAt time.Time) error {
+	query := `UPDATE password_reset_tokens SET used_at = ? WHERE id = ?::uuid AND used_at IS NULL`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), usedAt, tokenID.String())
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	if n == 0 {
+		return custom_errors.ErrResetTokenNotFound
+	}
+	return nil
+}
+
+func (r *PostgresPasswordResetRepository) DeleteUnusedByUserID(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM password_reset_tokens WHERE user_id = ?::uuid AND used_at IS NULL`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), userID.String()); err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return nil
+}
@@ -0,0 +1,503 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type PostgresPostAggregateRepository struct {
+	db               *sql.DB
+	postCategoryRepo repository.PostCategoryRepository
+	commentRepo      repository.CommentRepository
+	attachmentRepo   repository.PostAttachmentRepository
+}
+
+func NewPostgresPostAggregateRepository(
+	db *sql.DB,
+	postCategoryRepo *repository.PostCategoryRepository,
+	commentRepo *repository.CommentRepository,
+	attachmentRepo *repository.PostAttachmentRepository,
+) repository.PostAggregateRepository {
+	return &PostgresPostAggregateRepository{
+		db:               db,
+		postCategoryRepo: *postCategoryRepo,
+		commentRepo:      *commentRepo,
+		attachmentRepo:   *attachmentRepo,
+	}
+}
+
+// CreatePostWithCategories creates a post and associates it with categories
+// inside one transaction.
+func (r *PostgresPostAggregateRepository) CreatePostWithCategories(ctx context.Context, post *entity.Post, categoryIDs []*uuid.UUID) error {
+	post.ID = uuid.New()
+	post.CreatedAt = time.Now()
+
+	return inTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+		_, err := tx.ExecContext(ctx, rebind(`INSERT INTO posts (id, title, content, user_id, created_at) VALUES (?, ?, ?, ?, ?)`),
+			post.ID.String(), post.Title, post.Content, post.UserID.String(), post.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		for _, categoryID := range categoryIDs {
+			_, err = tx.ExecContext(ctx, rebind(`INSERT INTO post_categories (post_id, category_id) VALUES (?, ?)`),
+				post.ID.String(), categoryID.String())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// sortKey is one column of a post ordering, with the SQL value of that
+// column at a page cursor.
+type sortKey struct {
+	column string
+	bound  func(cursor *entity.PostCursor) (string, []interface{})
+}
+
+var (
+	// The cursor post's own timestamp is looked up so that it compares
+	// exactly as stored; the one in the cursor is only used if that post has
+	// been deleted since.
+	createdAtKey = sortKey{"p.created_at", func(c *entity.PostCursor) (string, []interface{}) {
+		return "COALESCE((SELECT created_at FROM posts WHERE id = ?), ?)", []interface{}{c.ID.String(), c.CreatedAt}
+	}}
+	idKey = sortKey{"p.id", func(c *entity.PostCursor) (string, []interface{}) {
+		return "?::uuid", []interface{}{c.ID.String()}
+	}}
+)
+
+// scoreKey orders by a sortScore expression. PostgreSQL does not allow a
+// column alias in WHERE, so the expression itself is the column.
+func scoreKey(score string) sortKey {
+	return sortKey{score, func(c *entity.PostCursor) (string, []interface{}) {
+		return "?::float8", []interface{}{c.Score}
+	}}
+}
+
+// postOrder orders posts by the given keys, then newest first. The id comes
+// last so that no two posts ever tie and every ordering is deterministic.
+func postOrder(keys ...sortKey) []sortKey {
+	return append(keys, createdAtKey, idKey)
+}
+
+// keysetCondition returns the WHERE condition and ORDER BY clause that select
+// the posts after (or before) the page cursor, for posts sorted on keys in
+// descending order.
+func keysetCondition(page entity.PageRequest, keys []sortKey) (string, string, []interface{}) {
+	direction := "DESC"
+	if page.Cursor != nil && page.Cursor.Backward {
+		direction = "ASC"
+	}
+
+	columns := make([]string, len(keys))
+	order := make([]string, len(keys))
+	for i, key := range keys {
+		columns[i] = key.column
+		order[i] = key.column + " " + direction
+	}
+	if page.Cursor == nil {
+		return "TRUE", strings.Join(order, ", "), nil
+	}
+
+	bounds := make([]string, len(keys))
+	var args []interface{}
+	for i, key := range keys {
+		bound, boundArgs := key.bound(page.Cursor)
+		bounds[i] = bound
+		args = append(args, boundArgs...)
+	}
+
+	comparison := " < "
+	if page.Cursor.Backward {
+		comparison = " > "
+	}
+	condition := "(" + strings.Join(columns, ", ") + ")" + comparison + "(" + strings.Join(bounds, ", ") + ")"
+	return condition, strings.Join(order, ", "), args
+}
+
+// timestamp inlines t as a PostgreSQL timestamptz expression.
+func timestamp(t time.Time) string {
+	return "to_timestamp(" + strconv.FormatFloat(float64(t.UnixNano())/float64(time.Second), 'f', -1, 64) + ")"
+}
+
+// sortScore returns the SQL expression that a sort mode ranks posts by, or
+// an empty string for SortNew. The expressions are the same as for SQLite:
+// they use the stored reaction counters and measure ages from now, which is
+// inlined so the expression needs no arguments.
+func sortScore(mode entity.SortMode, now time.Time) string {
+	likes := "p.like_count"
+	dislikes := "p.dislike_count"
+
+	switch mode {
+	case entity.SortTop:
+		return "(" + likes + " - " + dislikes + ")::float8"
+	case entity.SortHot:
+		// Net votes divided by (age in hours + 2)^2, so new posts need few
+		// votes to rise and old ones sink steadily.
+		age := "(EXTRACT(EPOCH FROM (" + timestamp(now) + " - p.created_at))::float8 / 3600 + 2)"
+		return "((" + likes + " - " + dislikes + ")::float8 / (" + age + " * " + age + "))"
+	case entity.SortControversial:
+		// Many votes, split as evenly as possible.
+		return "(CASE WHEN " + likes + " = 0 OR " + dislikes + " = 0 THEN 0.0 ELSE (" + likes + " + " + dislikes + ")::float8 * LEAST(" +
+			likes + ", " + dislikes + ") / GREATEST(" + likes + ", " + dislikes + ") END)"
+	}
+	return ""
+}
+
+// postWithAuthorColumns are the columns read by scanPostWithAuthor, for a
+// query on posts p joined with their author u.
+const postWithAuthorColumns = `p.id, p.title, p.content, p.user_id, p.created_at, p.updated_at,
+			p.like_count, p.dislike_count, p.comment_count,
+			u.id as author_id, u.user_name, u.email, u.role, u.created_at as user_created_at`
+
+// scanPostWithAuthor reads the postWithAuthorColumns of a row, followed by
+// any extra columns into extra.
+func scanPostWithAuthor(row rowScanner, extra ...interface{}) (*entity.PostWithDetails, error) {
+	var post entity.Post
+	var author entity.User
+	var updatedAt sql.NullTime
+	var likes, dislikes, comments int
+
+	dest := []interface{}{
+		&post.ID, &post.Title, &post.Content, &post.UserID, &post.CreatedAt, &updatedAt,
+		&likes, &dislikes, &comments,
+		&author.ID, &author.UserName, &author.Email, &author.Role, &author.CreatedAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if updatedAt.Valid {
+		post.UpdatedAt = &updatedAt.Time
+	}
+
+	return &entity.PostWithDetails{
+		Post:         post,
+		Author:       author,
+		LikeCount:    likes,
+		DislikeCount: dislikes,
+		CommentCount: comments,
+		IsEdited:     post.UpdatedAt != nil,
+	}, nil
+}
+
+func (r *PostgresPostAggregateRepository) queryPostsWithAuthors(ctx context.Context, query string, args ...interface{}) ([]*entity.PostWithDetails, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var posts []*entity.PostWithDetails
+	for rows.Next() {
+		post, err := scanPostWithAuthor(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return posts, nil
+}
+
+// loadPostDetails fills in the categories, comments and attachments of a set
+// of posts, with one query for each kind of detail.
+func (r *PostgresPostAggregateRepository) loadPostDetails(ctx context.Context, posts []*entity.PostWithDetails) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	categories, err := r.postCategoryRepo.GetCategoriesByPostIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	comments, err := r.commentRepo.GetByPostIDsWithDetails(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	attachments, err := r.attachmentRepo.GetByPostIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, post := range posts {
+		post.Categories = categories[post.ID]
+		post.Comments = comments[post.ID]
+		post.Attachments = attachments[post.ID]
+	}
+	return nil
+}
+
+// GetFeedForUser returns one page of the feed, newest posts first.
+func (r *PostgresPostAggregateRepository) GetFeedForUser(ctx context.Context, page entity.PageRequest) (*entity.PostPage, error) {
+	condition, order, args := keysetCondition(page, postOrder())
+	query := "SELECT " + postWithAuthorColumns + `
+		FROM posts p
+		INNER JOIN "user" u ON p.user_id = u.id
+		WHERE ` + condition + " ORDER BY " + order + " LIMIT ?"
+
+	posts, err := r.queryPostsWithAuthors(ctx, query, append(args, page.Size()+1)...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := entity.NewPostPage(page, posts, nil, time.Time{})
+	if err := r.loadPostDetails(ctx, result.Posts); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *PostgresPostAggregateRepository) GetPostWithAllDetails(ctx context.Context, postID uuid.UUID) (*entity.PostWithDetails, error) {
+	query := "SELECT " + postWithAuthorColumns + `
+		FROM posts p
+		INNER JOIN "user" u ON p.user_id = u.id
+		WHERE p.id = ?`
+
+	post, err := scanPostWithAuthor(conn(ctx, r.db).QueryRowContext(ctx, rebind(query), postID.String()))
+	if err == sql.ErrNoRows {
+		return nil, custom_errors.ErrPostNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+
+	if err := r.loadPostDetails(ctx, []*entity.PostWithDetails{post}); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+func (r *PostgresPostAggregateRepository) GetPostsWithDetailsByUser(ctx context.Context, userID uuid.UUID) ([]*entity.PostWithDetails, error) {
+	query := "SELECT " + postWithAuthorColumns + `
+		FROM posts p
+		INNER JOIN "user" u ON p.user_id = u.id
+		WHERE p.user_id = ?
+		ORDER BY p.created_at DESC, p.id DESC`
+
+	posts, err := r.queryPostsWithAuthors(ctx, query, userID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadPostDetails(ctx, posts); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (r *PostgresPostAggregateRepository) GetFilteredPostsWithDetails(ctx context.Context, filter entity.PostFilter, page entity.PageRequest) (*entity.PostPage, error) {
+	now := filter.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	// Every condition below matches a post at most once, so each row is a
+	// distinct post and the rows come back exactly in the ORDER BY order.
+	columns := postWithAuthorColumns
+	joins := ` INNER JOIN "user" u ON p.user_id = u.id`
+	order := postOrder()
+	var joinArgs []interface{}
+
+	score := sortScore(filter.Sort, now)
+	if score != "" {
+		columns += ", " + score
+		order = postOrder(scoreKey(score))
+	}
+
+	// Liked posts; a user has at most one reaction per post
+	if filter.LikedPosts && filter.AuthorID != nil {
+		joins += " INNER JOIN post_reaction pr ON p.id = pr.post_id AND pr.user_id = ? AND pr.reaction = 1"
+		joinArgs = append(joinArgs, filter.AuthorID.String())
+	}
+
+	conditions := []string{}
+	args := []interface{}{}
+
+	// Filter by categories
+	if len(filter.CategoryIDs) > 0 {
+		placeholders, categoryArgs := inClause(filter.CategoryIDs)
+		conditions = append(conditions,
+			"EXISTS (SELECT 1 FROM post_categories pc WHERE pc.post_id = p.id AND pc.category_id IN ("+placeholders+"))")
+		args = append(args, categoryArgs...)
+	}
+
+	// Filter by user's own posts
+	if filter.MyPosts && filter.AuthorID != nil {
+		conditions = append(conditions, "p.user_id = ?")
+		args = append(args, filter.AuthorID.String())
+	}
+
+	// Filter by search text in the post or its comments
+	if strings.TrimSpace(filter.Query) != "" {
+		condition, searchArgs := searchCondition(filter.Query)
+		conditions = append(conditions, condition)
+		args = append(args, searchArgs...)
+	}
+
+	// Only posts from the top window
+	if filter.Sort == entity.SortTop && filter.Window > 0 {
+		conditions = append(conditions, "p.created_at >= ?")
+		args = append(args, now.Add(-filter.Window))
+	}
+
+	// Only the requested page
+	keyset, orderBy, keysetArgs := keysetCondition(page, order)
+	conditions = append(conditions, keyset)
+	args = append(args, keysetArgs...)
+
+	query := "SELECT " + columns + " FROM posts p" + joins +
+		" WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY " + orderBy + " LIMIT ?"
+	args = append(append(joinArgs, args...), page.Size()+1)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute filter query: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []*entity.PostWithDetails
+	var scores map[uuid.UUID]float64
+	if score != "" {
+		scores = make(map[uuid.UUID]float64)
+	}
+
+	for rows.Next() {
+		var postScore float64
+		var extra []interface{}
+		if score != "" {
+			extra = append(extra, &postScore)
+		}
+		post, err := scanPostWithAuthor(rows, extra...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		posts = append(posts, post)
+		if scores != nil {
+			scores[post.ID] = postScore
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read posts: %w", err)
+	}
+
+	result := entity.NewPostPage(page, posts, scores, now)
+	if err := r.loadPostDetails(ctx, result.Posts); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdatePostWithRevision stores the current version of a post in
+// post_revisions and then overwrites it, all inside one transaction.
+func (r *PostgresPostAggregateRepository) UpdatePostWithRevision(ctx context.Context, post *entity.Post) error {
+	now := time.Now()
+	err := inTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+
+		// Concurrent edits of the post wait here, so each one numbers its
+		// revision after the ones committed before it.
+		var id string
+		err := tx.QueryRowContext(ctx, rebind(`SELECT id FROM posts WHERE id = ? FOR UPDATE`), post.ID.String()).Scan(&id)
+		if err == sql.ErrNoRows {
+			return custom_errors.ErrPostNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, rebind(`
+			INSERT INTO post_revisions (id, post_id, version, title, content, created_at)
+			SELECT ?, id,
+				(SELECT COUNT(*) + 1 FROM post_revisions WHERE post_id = posts.id),
+				title, content, COALESCE(updated_at, created_at)
+			FROM posts WHERE id = ?`),
+			uuid.New().String(), post.ID.String())
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, rebind(`UPDATE posts SET title = ?, content = ?, updated_at = ? WHERE id = ?`),
+			post.Title, post.Content, now, post.ID.String())
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return custom_errors.ErrPostNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	post.UpdatedAt = &now
+	return nil
+}
+
+// DeletePostWithDependencies removes a post together with everything that
+// references it, children first, inside one transaction.
+func (r *PostgresPostAggregateRepository) DeletePostWithDependencies(ctx context.Context, postID uuid.UUID) error {
+	id := postID.String()
+	dependents := []string{
+		`DELETE FROM comment_reaction WHERE comment_id IN (SELECT id FROM comments WHERE post_id = ?)`,
+		`DELETE FROM comments WHERE post_id = ?`,
+		`DELETE FROM post_reaction WHERE post_id = ?`,
+		`DELETE FROM post_categories WHERE post_id = ?`,
+		`DELETE FROM post_revisions WHERE post_id = ?`,
+		`DELETE FROM post_attachments WHERE post_id = ?`,
+	}
+
+	return inTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+		for _, query := range dependents {
+			if _, err := tx.ExecContext(ctx, rebind(query), id); err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.ExecContext(ctx, rebind(`DELETE FROM posts WHERE id = ?`), id)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return custom_errors.ErrPostNotFound
+		}
+		return nil
+	})
+}
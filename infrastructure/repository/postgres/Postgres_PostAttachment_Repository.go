@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"forum/domain/entity"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type PostgresPostAttachmentRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresPostAttachmentRepository(db *sql.DB) repository.PostAttachmentRepository {
+	return &PostgresPostAttachmentRepository{db: db}
+}
+
+func (r *PostgresPostAttachmentRepository) Create(ctx context.Context, attachment *entity.PostAttachment) error {
+	if attachment.ID == uuid.Nil {
+		attachment.ID = uuid.New()
+	}
+	attachment.CreatedAt = time.Now()
+
+	query := `INSERT INTO post_attachments (id, post_id, file_name, thumbnail_name, content_type, size, width, height, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), attachment.ID.String(), attachment.PostID.String(), attachment.FileName,
+		attachment.ThumbnailName, attachment.ContentType, attachment.Size, attachment.Width, attachment.Height,
+		attachment.CreatedAt)
+	return err
+}
+
+func (r *PostgresPostAttachmentRepository) GetByPostID(ctx context.Context, postID uuid.UUID) ([]*entity.PostAttachment, error) {
+	attachments, err := r.GetByPostIDs(ctx, []uuid.UUID{postID})
+	if err != nil {
+		return nil, err
+	}
+	return attachments[postID], nil
+}
+
+// GetByPostIDs loads the attachments of several posts in one query.
+func (r *PostgresPostAttachmentRepository) GetByPostIDs(ctx context.Context, postIDs []uuid.UUID) (map[uuid.UUID][]*entity.PostAttachment, error) {
+	attachments := make(map[uuid.UUID][]*entity.PostAttachment)
+	if len(postIDs) == 0 {
+		return attachments, nil
+	}
+
+	placeholders, args := inClause(postIDs)
+	query := `SELECT id, post_id, file_name, thumbnail_name, content_type, size, width, height, created_at
+			  FROM post_attachments WHERE post_id IN (` + placeholders + `) ORDER BY created_at ASC, id ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		attachment := &entity.PostAttachment{}
+		err := rows.Scan(&attachment.ID, &attachment.PostID, &attachment.FileName, &attachment.ThumbnailName,
+			&attachment.ContentType, &attachment.Size, &attachment.Width, &attachment.Height, &attachment.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		attachments[attachment.PostID] = append(attachments[attachment.PostID], attachment)
+	}
+	return attachments, rows.Err()
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"forum/domain/entity"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type PostgresPostCategoryRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresPostCategoryRepository(db *sql.DB) repository.PostCategoryRepository {
+	return &PostgresPostCategoryRepository{db: db}
+}
+
+func (r *PostgresPostCategoryRepository) Create(ctx context.Context, postCategory *entity.PostCategory) error {
+	query := `INSERT INTO post_categories (post_id, category_id) VALUES (?, ?)`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), postCategory.PostID.String(), postCategory.CategoryID.String())
+	return err
+}
+
+func (r *PostgresPostCategoryRepository) Delete(ctx context.Context, postID, categoryID uuid.UUID) error {
+	query := `DELETE FROM post_categories WHERE post_id = ? AND category_id = ?`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), postID.String(), categoryID.String())
+	return err
+}
+
+func (r *PostgresPostCategoryRepository) DeleteByPostID(ctx context.Context, postID uuid.UUID) error {
+	query := `DELETE FROM post_categories WHERE post_id = ?`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), postID.String())
+	return err
+}
+
+func (r *PostgresPostCategoryRepository) DeleteByCategoryID(ctx context.Context, categoryID uuid.UUID) error {
+	query := `DELETE FROM post_categories WHERE category_id = ?`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), categoryID.String())
+	return err
+}
+
+func (r *PostgresPostCategoryRepository) GetCategoriesByPostID(ctx context.Context, postID uuid.UUID) ([]*entity.Category, error) {
+	categories, err := r.GetCategoriesByPostIDs(ctx, []uuid.UUID{postID})
+	if err != nil {
+		return nil, err
+	}
+	return categories[postID], nil
+}
+
+// GetCategoriesByPostIDs loads the categories of several posts in one query.
+func (r *PostgresPostCategoryRepository) GetCategoriesByPostIDs(ctx context.Context, postIDs []uuid.UUID) (map[uuid.UUID][]*entity.Category, error) {
+	categories := make(map[uuid.UUID][]*entity.Category)
+	if len(postIDs) == 0 {
+		return categories, nil
+	}
+
+	placeholders, args := inClause(postIDs)
+	query := `SELECT pc.post_id, c.id, c.name, c.created_at
+			  FROM categories c
+			  INNER JOIN post_categories pc ON c.id = pc.category_id
+			  WHERE pc.post_id IN (` + placeholders + `)
+			  ORDER BY c.name ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		category := &entity.Category{}
+		var postID uuid.UUID
+		if err := rows.Scan(&postID, &category.ID, &category.Name, &category.CreatedAt); err != nil {
+			return nil, err
+		}
+		categories[postID] = append(categories[postID], category)
+	}
+	return categories, rows.Err()
+}
+
+func (r *PostgresPostCategoryRepository) GetPostsByCategoryID(ctx context.Context, categoryID uuid.UUID) ([]*entity.Post, error) {
+	return (&PostgresPostRepository{db: r.db}).GetByCategory(ctx, categoryID)
+}
+
+func (r *PostgresPostCategoryRepository) CheckAssociationExists(ctx context.Context, postID, categoryID uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM post_categories WHERE post_id = ? AND category_id = ?)`
+
+	var exists bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, rebind(query), postID.String(), categoryID.String()).Scan(&exists)
+	return exists, err
+}
+
+func (r *PostgresPostCategoryRepository) GetAllAssociations(ctx context.Context) ([]*entity.PostCategory, error) {
+	query := `SELECT post_id, category_id FROM post_categories`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var postCategories []*entity.PostCategory
+	for rows.Next() {
+		postCategory := &entity.PostCategory{}
+		if err := rows.Scan(&postCategory.PostID, &postCategory.CategoryID); err != nil {
+			return nil, err
+		}
+		postCategories = append(postCategories, postCategory)
+	}
+	return postCategories, rows.Err()
+}
@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type PostgresPostReactionRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresPostReactionRepository(db *sql.DB) repository.PostReactionRepository {
+	return &PostgresPostReactionRepository{db: db}
+}
+
+const postReactionColumns = `id, user_id, post_id, reaction, created_at`
+
+func scanPostReaction(row rowScanner) (*entity.PostReaction, error) {
+	reaction := &entity.PostReaction{}
+	err := row.Scan(&reaction.ID, &reaction.UserID, &reaction.PostID, &reaction.Reaction, &reaction.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return reaction, nil
+}
+
+func (r *PostgresPostReactionRepository) queryReactions(ctx context.Context, query string, args ...interface{}) ([]*entity.PostReaction, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reactions []*entity.PostReaction
+	for rows.Next() {
+		reaction, err := scanPostReaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		reactions = append(reactions, reaction)
+	}
+	return reactions, rows.Err()
+}
+
+func (r *PostgresPostReactionRepository) Create(ctx context.Context, reaction *entity.PostReaction) error {
+	reaction.ID = uuid.New()
+	reaction.CreatedAt = time.Now()
+
+	query := `INSERT INTO post_reaction (id, user_id, post_id, reaction, created_at)
+			  VALUES (?, ?, ?, ?, ?)`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), reaction.ID.String(), reaction.UserID.String(),
+		reaction.PostID.String(), reactionValue(reaction.Reaction), reaction.CreatedAt)
+	return err
+}
+
+func (r *PostgresPostReactionRepository) GetByID(ctx context.Context, reactionID uuid.UUID) (*entity.PostReaction, error) {
+	query := `SELECT ` + postReactionColumns + ` FROM post_reaction WHERE id = ?`
+
+	return scanPostReaction(conn(ctx, r.db).QueryRowContext(ctx, rebind(query), reactionID.String()))
+}
+
+func (r *PostgresPostReactionRepository) GetByUserAndPost(ctx context.Context, userID, postID uuid.UUID) (*entity.PostReaction, error) {
+	query := `SELECT ` + postReactionColumns + ` FROM post_reaction WHERE user_id = ? AND post_id = ?`
+
+	return scanPostReaction(conn(ctx, r.db).QueryRowContext(ctx, rebind(query), userID.String(), postID.String()))
+}
+
+func (r *PostgresPostReactionRepository) GetByPostID(ctx context.Context, postID uuid.UUID) ([]*entity.PostReaction, error) {
+	return r.queryReactions(ctx, `SELECT `+postReactionColumns+`
+			  FROM post_reaction WHERE post_id = ? ORDER BY created_at DESC`, postID.String())
+}
+
+func (r *PostgresPostReactionRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.PostReaction, error) {
+	return r.queryReactions(ctx, `SELECT `+postReactionColumns+`
+			  FROM post_reaction WHERE user_id = ? ORDER BY created_at DESC`, userID.String())
+}
+
+func (r *PostgresPostReactionRepository) Update(ctx context.Context, reaction *entity.PostReaction) error {
+	query := `UPDATE post_reaction SET reaction = ? WHERE id = ?`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), reactionValue(reaction.Reaction), reaction.ID.String())
+	return err
+}
+
+func (r *PostgresPostReactionRepository) Delete(ctx context.Context, reactionID uuid.UUID) error {
+	query := `DELETE FROM post_reaction WHERE id = ?`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), reactionID.String())
+	return err
+}
+
+func (r *PostgresPostReactionRepository) DeleteByUserAndPost(ctx context.Context, userID, postID uuid.UUID) error {
+	query := `DELETE FROM post_reaction WHERE user_id = ? AND post_id = ?`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), userID.String(), postID.String())
+	return err
+}
+
+func (r *PostgresPostReactionRepository) GetLikeCountByPostID(ctx context.Context, postID uuid.UUID) (int, error) {
+	likes, _, err := r.GetReactionCountsByPostID(ctx, postID)
+	return likes, err
+}
+
+func (r *PostgresPostReactionRepository) GetDislikeCountByPostID(ctx context.Context, postID uuid.UUID) (int, error) {
+	_, dislikes, err := r.GetReactionCountsByPostID(ctx, postID)
+	return dislikes, err
+}
+
+func (r *PostgresPostReactionRepository) GetReactionCountsByPostID(ctx context.Context, postID uuid.UUID) (likes int, dislikes int, err error) {
+	query := `SELECT like_count, dislike_count FROM posts WHERE id = ?`
+
+	err = conn(ctx, r.db).QueryRowContext(ctx, rebind(query), postID.String()).Scan(&likes, &dislikes)
+	if err == sql.ErrNoRows {
+		return 0, 0, nil
+	} else if err != nil {
+		return 0, 0, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return likes, dislikes, nil
+}
+
+func (r *PostgresPostReactionRepository) HasUserReacted(ctx context.Context, userID, postID uuid.UUID) (bool, *bool, error) {
+	query := `SELECT reaction FROM post_reaction WHERE user_id = ? AND post_id = ?`
+
+	var reaction bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, rebind(query), userID.String(), postID.String()).Scan(&reaction)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil, nil
+		}
+		return false, nil, err
+	}
+
+	return true, &reaction, nil
+}
+
+func (r *PostgresPostReactionRepository) Toggle(ctx context.Context, userID, postID uuid.UUID, like bool) (*entity.ReactionState, error) {
+	return postReactions.toggle(ctx, r.db, userID, postID, like)
+}
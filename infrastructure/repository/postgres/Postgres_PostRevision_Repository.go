@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"forum/domain/entity"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type PostgresPostRevisionRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresPostRevisionRepository(db *sql.DB) repository.PostRevisionRepository {
+	return &PostgresPostRevisionRepository{db: db}
+}
+
+func (r *PostgresPostRevisionRepository) GetByPostID(ctx context.Context, postID uuid.UUID) ([]*entity.PostRevision, error) {
+	query := `SELECT id, post_id, version, title, content, created_at
+			  FROM post_revisions WHERE post_id = ? ORDER BY version ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, rebind(query), postID.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*entity.PostRevision
+	for rows.Next() {
+		revision := &entity.PostRevision{}
+		err := rows.Scan(&revision.ID, &revision.PostID, &revision.Version, &revision.Title, &revision.Content, &revision.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type PostgresPostRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresPostRepository(db *sql.DB) repository.PostRepository {
+	return &PostgresPostRepository{db: db}
+}
+
+const postColumns = `p.id, p.title, p.content, p.user_id, p.created_at, p.updated_at`
+
+func scanPost(row rowScanner) (*entity.Post, error) {
+	post := &entity.Post{}
+	var updatedAt sql.NullTime
+
+	err := row.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.CreatedAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if updatedAt.Valid {
+		post.UpdatedAt = &updatedAt.Time
+	}
+	return post, nil
+}
+
+func (r *PostgresPostRepository) queryPosts(ctx context.Context, query string, args ...interface{}) ([]*entity.Post, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []*entity.Post
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+func (r *PostgresPostRepository) Create(ctx context.Context, post *entity.Post) error {
+	post.ID = uuid.New()
+	post.CreatedAt = time.Now()
+
+	query := `INSERT INTO posts (id, title, content, user_id, created_at)
+			  VALUES (?, ?, ?, ?, ?)`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), post.ID.String(), post.Title, post.Content,
+		post.UserID.String(), post.CreatedAt)
+	return err
+}
+
+func (r *PostgresPostRepository) GetByID(ctx context.Context, postID uuid.UUID) (*entity.Post, error) {
+	query := `SELECT ` + postColumns + ` FROM posts p WHERE p.id = ?`
+
+	post, err := scanPost(conn(ctx, r.db).QueryRowContext(ctx, rebind(query), postID.String()))
+	if err == sql.ErrNoRows {
+		return nil, custom_errors.ErrPostNotFound
+	}
+	return post, err
+}
+
+func (r *PostgresPostRepository) GetbyuserId(ctx context.Context, userID uuid.UUID) ([]*entity.Post, error) {
+	return r.queryPosts(ctx, `SELECT `+postColumns+` FROM posts p WHERE p.user_id = ? ORDER BY p.created_at DESC, p.id DESC`,
+		userID.String())
+}
+
+func (r *PostgresPostRepository) GetAll(ctx context.Context) ([]*entity.Post, error) {
+	return r.queryPosts(ctx, `SELECT `+postColumns+` FROM posts p ORDER BY p.created_at DESC, p.id DESC`)
+}
+
+func (r *PostgresPostRepository) GetByCategory(ctx context.Context, categoryID uuid.UUID) ([]*entity.Post, error) {
+	return r.queryPosts(ctx, `SELECT `+postColumns+`
+			  FROM posts p
+			  INNER JOIN post_categories pc ON p.id = pc.post_id
+			  WHERE pc.category_id = ?
+			  ORDER BY p.created_at DESC, p.id DESC`, categoryID.String())
+}
+
+func (r *PostgresPostRepository) Update(ctx context.Context, post *entity.Post) error {
+	now := time.Now()
+	post.UpdatedAt = &now
+
+	query := `UPDATE posts SET title = ?, content = ?, updated_at = ? WHERE id = ?`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), post.Title, post.Content, post.UpdatedAt, post.ID.String())
+	return err
+}
+
+func (r *PostgresPostRepository) Delete(ctx context.Context, postID uuid.UUID) error {
+	query := `DELETE FROM posts WHERE id = ?`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), postID.String())
+	return err
+}
+
+func (r *PostgresPostRepository) GetWithDetails(ctx context.Context, postID uuid.UUID) (*entity.PostWithDetails, error) {
+	post, err := r.GetByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.PostWithDetails{
+		Post: *post,
+	}, nil
+}
+
+func (r *PostgresPostRepository) GetFiltered(ctx context.Context, filter entity.PostFilter) ([]*entity.Post, error) {
+	conditions := []string{"TRUE"}
+	args := []interface{}{}
+
+	if len(filter.CategoryIDs) > 0 {
+		placeholders, categoryArgs := inClause(filter.CategoryIDs)
+		conditions = append(conditions,
+			"EXISTS (SELECT 1 FROM post_categories pc WHERE pc.post_id = p.id AND pc.category_id IN ("+placeholders+"))")
+		args = append(args, categoryArgs...)
+	}
+
+	return r.queryPosts(ctx, `SELECT `+postColumns+` FROM posts p WHERE `+strings.Join(conditions, " AND ")+
+		` ORDER BY p.created_at DESC, p.id DESC`, args...)
+}
@@ -0,0 +1,178 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+// maxSearchTerms bounds how many words of a query are used.
+const maxSearchTerms = 10
+
+// commentRankWeight makes a match in a comment count for less than the same
+// match in the post itself. Ranks are negated ts_rank values, lower is
+// better, as with SQLite's bm25.
+const commentRankWeight = 0.5
+
+// headlineOptions makes ts_headline mark matches the way entity.SearchResult
+// expects.
+const headlineOptions = `'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', HighlightAll=true'`
+
+// snippetOptions is headlineOptions for a short excerpt of a longer text.
+const snippetOptions = `'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxWords=24, MinWords=12, ShortWord=2'`
+
+// unmarked strips the highlight markers from a text column before
+// ts_headline adds its own.
+func unmarked(column string) string {
+	return `translate(` + column + `, chr(2) || chr(3), '')`
+}
+
+type PostgresSearchRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresSearchRepository(db *sql.DB) repository.SearchRepository {
+	return &PostgresSearchRepository{db: db}
+}
+
+// searchTerms splits a user query into words. Everything but letters and
+// digits is dropped, so the tsquery syntax can never be injected.
+func searchTerms(query string) []string {
+	terms := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	return terms
+}
+
+// tsQuery requires every term, matching the last one as a prefix so that
+// partly typed words still find something.
+func tsQuery(terms []string) string {
+	return strings.Join(terms, " & ") + ":*"
+}
+
+// searchCondition returns a WHERE condition on the post alias p that matches
+// posts whose title, content or comments contain all the query terms.
+func searchCondition(query string) (string, []interface{}) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return "FALSE", nil
+	}
+
+	match := tsQuery(terms)
+	return `(p.search_vector @@ to_tsquery('simple', ?)
+		OR EXISTS (SELECT 1 FROM comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL
+			AND c.search_vector @@ to_tsquery('simple', ?)))`, []interface{}{match, match}
+}
+
+func (r *PostgresSearchRepository) Search(ctx context.Context, query string, limit int) ([]*entity.SearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	match := tsQuery(terms)
+	results := make(map[uuid.UUID]*entity.SearchResult)
+
+	postQuery := `
+		SELECT p.id,
+			ts_headline('simple', ` + unmarked("p.title") + `, q, ` + headlineOptions + `),
+			ts_headline('simple', ` + unmarked("p.content") + `, q, ` + snippetOptions + `),
+			-ts_rank(p.search_vector, q)::float8,
+			u.user_name, p.created_at
+		FROM posts p
+		INNER JOIN "user" u ON u.id = p.user_id,
+			to_tsquery('simple', ?) q
+		WHERE p.search_vector @@ q
+		ORDER BY 4
+		LIMIT ?`
+	err := r.collect(ctx, results, postQuery, match, limit, false)
+	if err != nil {
+		return nil, err
+	}
+
+	commentQuery := `
+		SELECT p.id,
+			` + unmarked("p.title") + `,
+			ts_headline('simple', ` + unmarked("c.content") + `, q, ` + snippetOptions + `),
+			-ts_rank(c.search_vector, q)::float8,
+			u.user_name, p.created_at
+		FROM comments c
+		INNER JOIN posts p ON p.id = c.post_id
+		INNER JOIN "user" u ON u.id = p.user_id,
+			to_tsquery('simple', ?) q
+		WHERE c.search_vector @@ q AND c.deleted_at IS NULL
+		ORDER BY 4
+		LIMIT ?`
+	err = r.collect(ctx, results, commentQuery, match, limit, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return rankResults(results, limit), nil
+}
+
+// collect adds the rows of a search query to results, keeping one result per
+// post. A match in the post itself is preferred over one in its comments.
+func (r *PostgresSearchRepository) collect(ctx context.Context, results map[uuid.UUID]*entity.SearchResult, query, match string, limit int, inComment bool) error {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, rebind(query), match, limit)
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		result := &entity.SearchResult{InComment: inComment}
+		err := rows.Scan(&result.PostID, &result.Title, &result.Snippet, &result.Rank, &result.AuthorName, &result.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+		}
+		if inComment {
+			result.Rank *= commentRankWeight
+		}
+
+		existing, ok := results[result.PostID]
+		if !ok {
+			results[result.PostID] = result
+			continue
+		}
+		if !existing.InComment {
+			existing.Rank = min(existing.Rank, result.Rank)
+			continue
+		}
+		result.Rank = min(existing.Rank, result.Rank)
+		results[result.PostID] = result
+	}
+	return rows.Err()
+}
+
+func rankResults(results map[uuid.UUID]*entity.SearchResult, limit int) []*entity.SearchResult {
+	ranked := make([]*entity.SearchResult, 0, len(results))
+	for _, result := range results {
+		ranked = append(ranked, result)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Rank != ranked[j].Rank {
+			return ranked[i].Rank < ranked[j].Rank
+		}
+		if !ranked[i].CreatedAt.Equal(ranked[j].CreatedAt) {
+			return ranked[i].CreatedAt.After(ranked[j].CreatedAt)
+		}
+		return ranked[i].PostID.String() > ranked[j].PostID.String()
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
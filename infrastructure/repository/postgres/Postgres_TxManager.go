@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"forum/domain/repository"
+)
+
+// maxTxAttempts is how often a transaction is tried when PostgreSQL aborts
+// it because of a conflict with another one.
+const maxTxAttempts = 5
+
+// txRetryDelay is the wait before the second attempt; it doubles after that.
+const txRetryDelay = 10 * time.Millisecond
+
+// txKey is the context key of the transaction started by WithinTx.
+type txKey struct{}
+
+// querier is what *sql.DB and *sql.Tx have in common.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// conn returns the transaction that ctx carries, or db outside of one. Every
+// query goes through it so that repositories join a running transaction.
+func conn(ctx context.Context, db *sql.DB) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// inTx runs fn in the transaction that ctx carries, or in a new one that is
+// committed when fn succeeds.
+func inTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// isConflict reports whether err is a serialization failure or a deadlock,
+// after which the whole transaction may simply be tried again.
+func isConflict(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "could not serialize access") ||
+		strings.Contains(err.Error(), "deadlock detected"))
+}
+
+type PostgresTxManager struct {
+	db *sql.DB
+}
+
+func NewPostgresTxManager(db *sql.DB) repository.TxManager {
+	return &PostgresTxManager{db: db}
+}
+
+// WithinTx runs fn in a transaction and starts it over when PostgreSQL
+// aborts it because of a conflicting transaction.
+func (m *PostgresTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	delay := txRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := inTx(ctx, m.db, fn)
+		if !isConflict(err) || attempt == maxTxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,185 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type PostgresUserRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresUserRepository(db *sql.DB) repository.UserRepository {
+	return &PostgresUserRepository{db: db}
+}
+
+func (r *PostgresUserRepository) Create(ctx context.Context, user *entity.User) error {
+	user.ID = uuid.New()
+	user.CreatedAt = time.Now()
+	if user.Role == "" {
+		user.Role = entity.RoleUser
+	}
+
+	query := `INSERT INTO "user" (id, user_name, email, password_hash, role, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?)`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), user.ID.String(), user.UserName, user.Email, user.PasswordHash, user.Role, user.CreatedAt)
+	if isUserNameTaken(err) {
+		return custom_errors.ErrNameTaken
+	}
+	return err
+}
+
+// isUserNameTaken reports whether err is the unique index on user names
+// refusing a name that someone already has.
+func isUserNameTaken(err error) bool {
+	return isUniqueViolation(err) && strings.Contains(err.Error(), "idx_user_user_name")
+}
+
+func (r *PostgresUserRepository) getBy(ctx context.Context, column string, value interface{}) (*entity.User, error) {
+	query := `SELECT id, user_name, email, password_hash, role, created_at, bio, avatar_name, user_name_changed_at, email_verified_at
+			  FROM "user" WHERE ` + column + ` = ?`
+
+	user := &entity.User{}
+	var changedAt, verifiedAt sql.NullTime
+	err := conn(ctx, r.db).QueryRowContext(ctx, rebind(query), value).Scan(&user.ID, &user.UserName, &user.Email, &user.PasswordHash, &user.Role, &user.CreatedAt,
+		&user.Bio, &user.AvatarName, &changedAt, &verifiedAt)
+	if err == sql.ErrNoRows {
+		return nil, custom_errors.ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if changedAt.Valid {
+		user.UserNameChangedAt = &changedAt.Time
+	}
+	if verifiedAt.Valid {
+		user.EmailVerifiedAt = &verifiedAt.Time
+	}
+	return user, nil
+}
+
+func (r *PostgresUserRepository) GetByID(ctx context.Context, userID uuid.UUID) (*entity.User, error) {
+	return r.getBy(ctx, "id", userID.String())
+}
+
+func (r *PostgresUserRepository) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	return r.getBy(ctx, "email", email)
+}
+
+func (r *PostgresUserRepository) GetByUserName(ctx context.Context, userName string) (*entity.User, error) {
+	return r.getBy(ctx, "user_name", userName)
+}
+
+// GetStats sums up the posts and the comments of a user that are not
+// deleted, with the reactions they received.
+func (r *PostgresUserRepository) GetStats(ctx context.Context, userID uuid.UUID) (*entity.UserStats, error) {
+	query := `
+		SELECT COALESCE(SUM(is_post), 0), COALESCE(SUM(1 - is_post), 0),
+			COALESCE(SUM(like_count), 0), COALESCE(SUM(dislike_count), 0)
+		FROM (
+			SELECT 1 AS is_post, like_count, dislike_count FROM posts WHERE user_id = ?
+			UNION ALL
+			SELECT 0, like_count, dislike_count FROM comments WHERE user_id = ? AND deleted_at IS NULL
+		) AS contributions`
+
+	stats := &entity.UserStats{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, rebind(query), userID.String(), userID.String()).
+		Scan(&stats.PostCount, &stats.CommentCount, &stats.LikesReceived, &stats.DislikesReceived)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return stats, nil
+}
+
+func (r *PostgresUserRepository) UpdatePasswordHash(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	query := `UPDATE "user" SET password_hash = ? WHERE id = ?::uuid`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), passwordHash, userID.String())
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return custom_errors.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *PostgresUserRepository) MarkEmailVerified(ctx context.Context, userID uuid.UUID, verifiedAt time.Time) error {
+	query := `UPDATE "user" SET email_verified_at = COALESCE(email_verified_at, ?) WHERE id = ?::uuid`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), verifiedAt, userID.String())
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return custom_errors.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *PostgresUserRepository) UpdateProfile(ctx context.Context, user *entity.User) error {
+	query := `UPDATE "user" SET bio = ?, avatar_name = ? WHERE id = ?::uuid`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), user.Bio, user.AvatarName, user.ID.String())
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return custom_errors.ErrUserNotFound
+	}
+	return nil
+}
+
+// ChangeUserName locks the user's row while it records the old name, so two
+// renames of the same user cannot both record the same name.
+func (r *PostgresUserRepository) ChangeUserName(ctx context.Context, userID uuid.UUID, newName string, changedAt time.Time) error {
+	return inTx(ctx, r.db, func(ctx context.Context) error {
+		var oldName string
+		err := conn(ctx, r.db).QueryRowContext(ctx, rebind(`SELECT user_name FROM "user" WHERE id = ?::uuid FOR UPDATE`), userID.String()).Scan(&oldName)
+		if err == sql.ErrNoRows {
+			return custom_errors.ErrUserNotFound
+		} else if err != nil {
+			return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+		}
+
+		_, err = conn(ctx, r.db).ExecContext(ctx,
+			rebind(`INSERT INTO user_name_history (id, user_id, user_name, changed_at) VALUES (?::uuid, ?::uuid, ?, ?)`),
+			uuid.New().String(), userID.String(), oldName, changedAt)
+		if err != nil {
+			return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+		}
+
+		_, err = conn(ctx, r.db).ExecContext(ctx,
+			rebind(`UPDATE "user" SET user_name = ?, user_name_changed_at = ? WHERE id = ?::uuid`),
+			newName, changedAt, userID.String())
+		if isUserNameTaken(err) {
+			return custom_errors.ErrNameTaken
+		} else if err != nil {
+			return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+		}
+		return nil
+	})
+}
+
+func (r *PostgresUserRepository) GetLastUserNameChange(ctx context.Context, userName string) (*entity.UserNameChange, error) {
+	query := `SELECT id, user_id, user_name, changed_at FROM user_name_history
+			  WHERE user_name = ? ORDER BY changed_at DESC LIMIT 1`
+
+	change := &entity.UserNameChange{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, rebind(query), userName).Scan(&change.ID, &change.UserID, &change.UserName, &change.ChangedAt)
+	if err == sql.ErrNoRows {
+		return nil, custom_errors.ErrUserNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return change, nil
+}
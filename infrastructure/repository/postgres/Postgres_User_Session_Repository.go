@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+type PostgresUserSessionRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresUserSessionRepository(db *sql.DB) repository.UserSessionRepository {
+	return &PostgresUserSessionRepository{db: db}
+}
+
+// sessionColumns are the columns scanSession reads, in order.
+const sessionColumns = `id, user_id, session_token, user_agent, ip_address, last_seen_at, expires_at, created_at`
+
+// scanSession reads one session from a row or from the current row of rows.
+func scanSession(row rowScanner) (*entity.UserSession, error) {
+	session := &entity.UserSession{}
+	var lastSeenAt sql.NullTime
+
+	err := row.Scan(&session.ID, &session.UserID, &session.SessionToken, &session.UserAgent, &session.IPAddress,
+		&lastSeenAt, &session.ExpiresAt, &session.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	session.LastSeenAt = session.CreatedAt
+	if lastSeenAt.Valid {
+		session.LastSeenAt = lastSeenAt.Time
+	}
+	return session, nil
+}
+
+func (r *PostgresUserSessionRepository) Create(ctx context.Context, session *entity.UserSession) error {
+	session.ID = uuid.New()
+	session.CreatedAt = time.Now()
+	session.LastSeenAt = session.CreatedAt
+	query := `INSERT INTO user_sessions (id, user_id, session_token, user_agent, ip_address, last_seen_at, expires_at, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), session.ID.String(), session.UserID.String(),
+		session.SessionToken, session.UserAgent, session.IPAddress, session.LastSeenAt, session.ExpiresAt, session.CreatedAt)
+	return err
+}
+
+func (r *PostgresUserSessionRepository) GetByToken(ctx context.Context, token string) (*entity.UserSession, error) {
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions WHERE session_token = ?`
+
+	session, err := scanSession(conn(ctx, r.db).QueryRowContext(ctx, rebind(query), token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("you need to login")
+		}
+		return nil, fmt.Errorf("failed to scan session: %v", err)
+	}
+	return session, nil
+}
+
+// GetByUserID returns the newest session of a user, or nil if there is none.
+func (r *PostgresUserSessionRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*entity.UserSession, error) {
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions WHERE user_id = ? ORDER BY created_at DESC LIMIT 1`
+
+	session, err := scanSession(conn(ctx, r.db).QueryRowContext(ctx, rebind(query), userID.String()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *PostgresUserSessionRepository) ListByUserID(ctx context.Context, userID uuid.UUID, now time.Time) ([]*entity.UserSession, error) {
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions
+			  WHERE user_id = ?::uuid AND expires_at > ?
+			  ORDER BY last_seen_at DESC, created_at DESC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, rebind(query), userID.String(), now)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	defer rows.Close()
+
+	var sessions []*entity.UserSession
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (r *PostgresUserSessionRepository) Update(ctx context.Context, session *entity.UserSession) error {
+	query := `UPDATE user_sessions SET expires_at = ? WHERE id = ?`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), session.ExpiresAt, session.ID.String())
+	return err
+}
+
+func (r *PostgresUserSessionRepository) Touch(ctx context.Context, sessionID uuid.UUID, seenAt time.Time, ipAddress string) error {
+	query := `UPDATE user_sessions SET last_seen_at = ?, ip_address = ? WHERE id = ?::uuid`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), seenAt, ipAddress, sessionID.String())
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return nil
+}
+
+func (r *PostgresUserSessionRepository) Delete(ctx context.Context, sessionID uuid.UUID) error {
+	query := `DELETE FROM user_sessions WHERE id = ?`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), sessionID.String())
+	return err
+}
+
+func (r *PostgresUserSessionRepository) DeleteUserSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	query := `DELETE FROM user_sessions WHERE id = ?::uuid AND user_id = ?::uuid`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), sessionID.String(), userID.String())
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return custom_errors.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *PostgresUserSessionRepository) DeleteExpiredUserSessions(ctx context.Context, userID uuid.UUID, now time.Time) error {
+	query := `DELETE FROM user_sessions WHERE user_id = ?::uuid AND expires_at <= ?`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), userID.String(), now)
+	if err != nil {
+		return fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return nil
+}
+
+func (r *PostgresUserSessionRepository) DeleteAllUserSessions(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM user_sessions WHERE user_id = ?`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, rebind(query), userID.String())
+	return err
+}
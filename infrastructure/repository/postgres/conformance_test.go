@@ -0,0 +1,128 @@
+//go:build postgres
+
+package postgres_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"testing"
+
+	"forum/config"
+	"forum/infrastructure/database"
+	"forum/infrastructure/repository/conformance"
+	"forum/infrastructure/repository/postgres"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+)
+
+// testDB is the database the tests run against, or nil when there is none
+// and skipReason tells why.
+var (
+	testDB     *sql.DB
+	skipReason string
+)
+
+// TestMain connects to POSTGRES_TEST_URL, which should name a scratch
+// database since the checks leave their rows behind. Without it, it starts
+// an embedded PostgreSQL, which downloads its binaries on first use and
+// refuses to run as root. When POSTGRES_TEST_REQUIRED is set, as it is in
+// CI, having no database fails the tests instead of skipping them.
+func TestMain(m *testing.M) {
+	url := os.Getenv("POSTGRES_TEST_URL")
+	stop := func() {}
+	if url == "" {
+		embeddedURL, stopEmbedded, err := startEmbedded()
+		if err != nil {
+			skipReason = fmt.Sprintf("set POSTGRES_TEST_URL, the embedded PostgreSQL did not start: %v", err)
+		} else {
+			url, stop = embeddedURL, stopEmbedded
+		}
+	}
+	if skipReason != "" && os.Getenv("POSTGRES_TEST_REQUIRED") != "" {
+		log.Fatalf("POSTGRES_TEST_REQUIRED is set but there is no database: %s", skipReason)
+	}
+
+	if skipReason == "" {
+		db, err := database.Open(database.DriverPostgres, url, config.Load().DatabaseOptions())
+		if err != nil {
+			log.Fatalf("Failed to connect to %s: %v", url, err)
+		}
+		database.RunMigrations(db, database.DriverPostgres)
+		testDB = db
+	}
+
+	code := m.Run()
+	if testDB != nil {
+		testDB.Close()
+	}
+	stop()
+	os.Exit(code)
+}
+
+// startEmbedded starts a throwaway PostgreSQL on a free port. It returns the
+// URL of its database and a function that stops it and removes its files.
+func startEmbedded() (string, func(), error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, err
+	}
+	port := uint32(listener.Addr().(*net.TCPAddr).Port)
+	listener.Close()
+
+	dir, err := os.MkdirTemp("", "forum-postgres")
+	if err != nil {
+		return "", nil, err
+	}
+	var logs bytes.Buffer
+	cfg := embeddedpostgres.DefaultConfig().
+		Port(port).
+		Database("forum_test").
+		RuntimePath(dir).
+		Logger(&logs)
+	embedded := embeddedpostgres.NewDatabase(cfg)
+	if err := embedded.Start(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+	stop := func() {
+		embedded.Stop()
+		os.RemoveAll(dir)
+	}
+	return cfg.GetConnectionURL() + "?sslmode=disable", stop, nil
+}
+
+func TestConformance(t *testing.T) {
+	if testDB == nil {
+		t.Skip(skipReason)
+	}
+	repos := postgres.NewPostgresRepositories(testDB, 5)
+	for _, check := range conformance.Checks {
+		t.Run(check.Name, func(t *testing.T) {
+			check.Run(context.Background(), t, repos)
+		})
+	}
+}
+
+// TestReactionToggleRace runs the reaction toggle hammer several times at
+// once on the same repositories.
+func TestReactionToggleRace(t *testing.T) {
+	if testDB == nil {
+		t.Skip(skipReason)
+	}
+	check, ok := conformance.Find("reaction_toggle")
+	if !ok {
+		t.Fatal("there is no reaction_toggle check")
+	}
+	repos := postgres.NewPostgresRepositories(testDB, 5)
+	for i := 0; i < 4; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+			check.Run(context.Background(), t, repos)
+		})
+	}
+}
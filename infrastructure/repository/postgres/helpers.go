@@ -0,0 +1,131 @@
+// Package postgres implements the domain repositories on PostgreSQL. The
+// queries are written with ? placeholders like the SQLite ones and rewritten
+// by rebind before they are run.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+
+	"github.com/google/uuid"
+)
+
+// rebind replaces the ? placeholders of a query with $1, $2, ... Queries
+// here never contain a literal question mark.
+func rebind(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// inClause returns the "?, ?, ?" placeholder list and the matching arguments
+// for an IN (...) condition on the given IDs.
+func inClause(ids []uuid.UUID) (string, []interface{}) {
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id.String()
+	}
+	return strings.Join(placeholders, ", "), args
+}
+
+// reactionValue is how a like (true) or dislike (false) is stored.
+func reactionValue(like bool) int {
+	if like {
+		return 1
+	}
+	return 0
+}
+
+func isUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint")
+}
+
+// rowScanner is what *sql.Row and *sql.Rows have in common.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// reactionTable describes where the reactions to one kind of target are
+// stored.
+type reactionTable struct {
+	name     string // table of the reactions
+	column   string // column of the reactions that references the target
+	target   string // table of the targets, which holds the counters
+	notFound error
+}
+
+var (
+	postReactions    = reactionTable{"post_reaction", "post_id", "posts", custom_errors.ErrPostNotFound}
+	commentReactions = reactionTable{"comment_reaction", "comment_id", "comments", custom_errors.ErrCommentNotFound}
+)
+
+// toggle deletes the user's reaction when it is already like and upserts it
+// otherwise. Both are single statements guarded by UNIQUE(user_id, target):
+// a concurrent toggle waits for the row lock and then sees the new state.
+func (t reactionTable) toggle(ctx context.Context, db *sql.DB, userID, targetID uuid.UUID, like bool) (*entity.ReactionState, error) {
+	var state *entity.ReactionState
+	err := inTx(ctx, db, func(ctx context.Context) error {
+		q := conn(ctx, db)
+		state = &entity.ReactionState{}
+
+		// The target is looked up first: inserting a reaction to a missing
+		// one would fail on its foreign key, which says nothing about which
+		// row is missing.
+		var exists int
+		err := q.QueryRowContext(ctx, rebind(`SELECT 1 FROM `+t.target+` WHERE id = ?`), targetID.String()).Scan(&exists)
+		if err == sql.ErrNoRows {
+			return t.notFound
+		} else if err != nil {
+			return err
+		}
+
+		result, err := q.ExecContext(ctx, rebind(`DELETE FROM `+t.name+` WHERE user_id = ? AND `+t.column+` = ? AND reaction = ?`),
+			userID.String(), targetID.String(), reactionValue(like))
+		if err != nil {
+			return err
+		}
+		removed, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if removed == 0 {
+			_, err = q.ExecContext(ctx, rebind(`
+				INSERT INTO `+t.name+` (id, user_id, `+t.column+`, reaction, created_at) VALUES (?, ?, ?, ?, ?)
+				ON CONFLICT (user_id, `+t.column+`) DO UPDATE SET reaction = EXCLUDED.reaction, created_at = EXCLUDED.created_at`),
+				uuid.New().String(), userID.String(), targetID.String(), reactionValue(like), time.Now())
+			if err != nil {
+				return err
+			}
+			state.Reaction = &like
+		}
+
+		return q.QueryRowContext(ctx, rebind(`SELECT like_count, dislike_count FROM `+t.target+` WHERE id = ?`),
+			targetID.String()).Scan(&state.LikeCount, &state.DislikeCount)
+	})
+	if err == t.notFound {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_errors.ErrDatabaseError, err)
+	}
+	return state, nil
+}
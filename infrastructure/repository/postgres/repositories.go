@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"forum/domain/repository"
+)
+
+// NewPostgresRepositories builds every PostgreSQL repository on db. maxDepth
+// is passed on to the comment repository.
+func NewPostgresRepositories(db *sql.DB, maxDepth int) *repository.Repositories {
+	r := &repository.Repositories{
+		User:            NewPostgresUserRepository(db),
+		Session:         NewPostgresUserSessionRepository(db),
+		PasswordReset:   NewPostgresPasswordResetRepository(db),
+		Post:            NewPostgresPostRepository(db),
+		PostCategory:    NewPostgresPostCategoryRepository(db),
+		Category:        NewPostgresCategoryRepository(db),
+		PostReaction:    NewPostgresPostReactionRepository(db),
+		PostRevision:    NewPostgresPostRevisionRepository(db),
+		Attachment:      NewPostgresPostAttachmentRepository(db),
+		Search:          NewPostgresSearchRepository(db),
+		CommentReaction: NewPostgresCommentReactionRepository(db),
+	}
+	r.Comment = NewPostgresCommentRepository(db, &r.User, &r.CommentReaction, maxDepth)
+	r.PostAggregate = NewPostgresPostAggregateRepository(db, &r.PostCategory, &r.Comment, &r.Attachment)
+	r.Tx = NewPostgresTxManager(db)
+	return r
+}
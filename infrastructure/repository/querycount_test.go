@@ -0,0 +1,236 @@
+package infra_repository_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"forum/config"
+	"forum/domain/entity"
+	"forum/domain/repository"
+	"forum/infrastructure/database"
+	infra_repository "forum/infrastructure/repository"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+)
+
+// countingDriverName is the SQLite driver that counts the statements run
+// through it.
+const countingDriverName = "sqlite3_counting"
+
+// statements counts every statement run or prepared on a countingDriver
+// connection.
+var statements atomic.Int64
+
+func init() {
+	sql.Register(countingDriverName, countingDriver{&sqlite3.SQLiteDriver{}})
+}
+
+type countingDriver struct {
+	driver.Driver
+}
+
+func (d countingDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return countingConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+type countingConn struct {
+	conn *sqlite3.SQLiteConn
+}
+
+func (c countingConn) Prepare(query string) (driver.Stmt, error) {
+	statements.Add(1)
+	return c.conn.Prepare(query)
+}
+
+func (c countingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	statements.Add(1)
+	return c.conn.PrepareContext(ctx, query)
+}
+
+func (c countingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	statements.Add(1)
+	return c.conn.ExecContext(ctx, query, args)
+}
+
+func (c countingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	statements.Add(1)
+	return c.conn.QueryContext(ctx, query, args)
+}
+
+func (c countingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.conn.BeginTx(ctx, opts)
+}
+
+func (c countingConn) Begin() (driver.Tx, error) {
+	return c.conn.Begin()
+}
+
+func (c countingConn) Close() error {
+	return c.conn.Close()
+}
+
+// feedSizes are the numbers of posts the query counts are compared over.
+var feedSizes = []int{10, 100, 1000}
+
+// seedFeed fills a new database with postCount posts by one author. Every
+// post has a category, a reaction, and a comment with a reply that is liked.
+func seedFeed(tb testing.TB, postCount int) (*repository.Repositories, *entity.User, *entity.Category) {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), "forum.db")
+	db, err := database.Open(countingDriverName, path, config.Load().DatabaseOptions())
+	if err != nil {
+		tb.Fatalf("open database: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+	database.RunMigrations(db, database.DriverSQLite)
+	repos := infra_repository.NewSQLiteRepositories(db, 5)
+
+	ctx := context.Background()
+	author := &entity.User{UserName: "author", Email: "author@example.com", PasswordHash: "hash"}
+	fan := &entity.User{UserName: "fan", Email: "fan@example.com", PasswordHash: "hash"}
+	category := &entity.Category{Name: "benchmarks"}
+	err = repos.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		for _, user := range []*entity.User{author, fan} {
+			if err := repos.User.Create(ctx, user); err != nil {
+				return err
+			}
+		}
+		if err := repos.Category.Create(ctx, category); err != nil {
+			return err
+		}
+		for i := 0; i < postCount; i++ {
+			post := &entity.Post{Title: fmt.Sprintf("Post %d", i), Content: "content", UserID: author.ID}
+			if err := repos.PostAggregate.CreatePostWithCategories(ctx, post, []*uuid.UUID{&category.ID}); err != nil {
+				return err
+			}
+			if _, err := repos.PostReaction.Toggle(ctx, fan.ID, post.ID, true); err != nil {
+				return err
+			}
+			comment := &entity.Comment{Content: "comment", UserID: fan.ID, PostID: post.ID}
+			if err := repos.Comment.Create(ctx, comment); err != nil {
+				return err
+			}
+			reply := &entity.Comment{Content: "reply", UserID: author.ID, PostID: post.ID, ParentID: &comment.ID}
+			if err := repos.Comment.Create(ctx, reply); err != nil {
+				return err
+			}
+			if _, err := repos.CommentReaction.Toggle(ctx, author.ID, comment.ID, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		tb.Fatalf("seed %d posts: %v", postCount, err)
+	}
+	return repos, author, category
+}
+
+// feedLoads are the ways of loading posts with their details. Each loads
+// every post of the database in one call.
+var feedLoads = []struct {
+	name string
+	load func(ctx context.Context, repos *repository.Repositories, author *entity.User, category *entity.Category, postCount int) (int, error)
+}{
+	{"feed", func(ctx context.Context, repos *repository.Repositories, _ *entity.User, _ *entity.Category, postCount int) (int, error) {
+		page, err := repos.PostAggregate.GetFeedForUser(ctx, entity.PageRequest{Limit: postCount})
+		if err != nil {
+			return 0, err
+		}
+		return len(page.Posts), nil
+	}},
+	{"filtered", func(ctx context.Context, repos *repository.Repositories, _ *entity.User, category *entity.Category, postCount int) (int, error) {
+		filter := entity.PostFilter{CategoryIDs: []uuid.UUID{category.ID}, Sort: entity.SortTop}
+		page, err := repos.PostAggregate.GetFilteredPostsWithDetails(ctx, filter, entity.PageRequest{Limit: postCount})
+		if err != nil {
+			return 0, err
+		}
+		return len(page.Posts), nil
+	}},
+	{"by_user", func(ctx context.Context, repos *repository.Repositories, author *entity.User, _ *entity.Category, _ int) (int, error) {
+		posts, err := repos.PostAggregate.GetPostsWithDetailsByUser(ctx, author.ID)
+		return len(posts), err
+	}},
+}
+
+// countStatements returns how many statements one load of postCount posts
+// runs. It fails when the load misses posts or details.
+func countStatements(tb testing.TB, repos *repository.Repositories, author *entity.User, category *entity.Category,
+	postCount int, load func(context.Context, *repository.Repositories, *entity.User, *entity.Category, int) (int, error),
+) int64 {
+	tb.Helper()
+	before := statements.Load()
+	loaded, err := load(context.Background(), repos, author, category, postCount)
+	if err != nil {
+		tb.Fatalf("load %d posts: %v", postCount, err)
+	}
+	if loaded != postCount {
+		tb.Fatalf("loaded %d posts, want %d", loaded, postCount)
+	}
+	return statements.Load() - before
+}
+
+// TestFeedQueryCountIsConstant checks that loading posts with their details
+// runs as many statements for 1000 posts as for 10.
+func TestFeedQueryCountIsConstant(t *testing.T) {
+	seeded := make(map[int]*repository.Repositories)
+	authors := make(map[int]*entity.User)
+	categories := make(map[int]*entity.Category)
+	for _, size := range feedSizes {
+		seeded[size], authors[size], categories[size] = seedFeed(t, size)
+	}
+
+	for _, load := range feedLoads {
+		t.Run(load.name, func(t *testing.T) {
+			counts := make([]int64, len(feedSizes))
+			for i, size := range feedSizes {
+				counts[i] = countStatements(t, seeded[size], authors[size], categories[size], size, load.load)
+			}
+			for i := range counts {
+				if counts[i] != counts[0] {
+					t.Errorf("statements per load for %v posts: %v, want the same for every size", feedSizes, counts)
+					break
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkFeedQueries loads 10, 100 and 1000 posts with their details and
+// reports the statements run per load. It fails when a size runs a different
+// number of statements than the smallest one.
+func BenchmarkFeedQueries(b *testing.B) {
+	for _, load := range feedLoads {
+		b.Run(load.name, func(b *testing.B) {
+			var baseline int64
+			for _, size := range feedSizes {
+				repos, author, category := seedFeed(b, size)
+				want := countStatements(b, repos, author, category, size, load.load)
+				if baseline == 0 {
+					baseline = want
+				} else if want != baseline {
+					b.Fatalf("loading %d posts runs %d statements, %d posts run %d", size, want, feedSizes[0], baseline)
+				}
+
+				b.Run(fmt.Sprintf("posts=%d", size), func(b *testing.B) {
+					var total int64
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						total += countStatements(b, repos, author, category, size, load.load)
+					}
+					b.ReportMetric(float64(total)/float64(b.N), "queries/op")
+				})
+			}
+		})
+	}
+}
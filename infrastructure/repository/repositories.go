@@ -0,0 +1,30 @@
+package infra_repository
+
+import (
+	"database/sql"
+
+	"forum/domain/repository"
+)
+
+// NewSQLiteRepositories builds every SQLite repository on db. maxDepth is
+// passed on to the comment repository.
+func NewSQLiteRepositories(db *sql.DB, maxDepth int) *repository.Repositories {
+	r := &repository.Repositories{
+		User:            NewSQLiteUserRepository(db),
+		Session:         NewSQLiteUserSessionRepository(db),
+		PasswordReset:   NewSQLitePasswordResetRepository(db),
+		Post:            NewSQLitePostRepository(db),
+		PostCategory:    NewSQLitePostCategoryRepository(db),
+		Category:        NewSQLiteCategoryRepository(db),
+		PostReaction:    NewSQLitePostReactionRepository(db),
+		PostRevision:    NewSQLitePostRevisionRepository(db),
+		Attachment:      NewSQLitePostAttachmentRepository(db),
+		Search:          NewSQLiteSearchRepository(db),
+		CommentReaction: NewSQLiteCommentReactionRepository(db),
+	}
+	r.Comment = NewSQLiteCommentRepository(db, &r.User, &r.CommentReaction, maxDepth)
+	r.PostAggregate = NewSQLitePostAggregateRepository(db, &r.Post, &r.PostCategory,
+		&r.User, &r.PostReaction, &r.Comment, &r.Attachment)
+	r.Tx = NewSQLiteTxManager(db)
+	return r
+}
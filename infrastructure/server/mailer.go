@@ -0,0 +1,15 @@
+package server
+
+import (
+	"forum/config"
+	"forum/domain/repository"
+	"forum/infrastructure/mail"
+)
+
+// newMailer builds the mailer of the configured driver.
+func newMailer(cfg *config.Config) (repository.Mailer, error) {
+	if cfg.MailDriver == "smtp" {
+		return mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	}
+	return mail.NewOutboxMailer(cfg.MailOutboxDir, cfg.MailFrom)
+}
@@ -0,0 +1,19 @@
+package server
+
+import (
+	"database/sql"
+
+	"forum/config"
+	"forum/domain/repository"
+	"forum/infrastructure/database"
+	infra_repository "forum/infrastructure/repository"
+	"forum/infrastructure/repository/postgres"
+)
+
+// newRepositories builds the repositories of the configured database.
+func newRepositories(db *sql.DB, cfg *config.Config) *repository.Repositories {
+	if cfg.DatabaseDriver == database.DriverPostgres {
+		return postgres.NewPostgresRepositories(db, cfg.MaxCommentDepth)
+	}
+	return infra_repository.NewSQLiteRepositories(db, cfg.MaxCommentDepth)
+}
@@ -1,12 +1,16 @@
 package server
 
 import (
+	"crypto/rand"
 	"database/sql"
 	"html/template"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
-	infra_repository "forum/infrastructure/repository"
+	"forum/config"
+	"forum/infrastructure/storage"
 	"forum/interface/controller"
 	"forum/interface/middleware"
 	"forum/usecase"
@@ -22,52 +26,133 @@ func init() {
 	}
 }
 
-func MyServer(db *sql.DB) *http.Server {
+func MyServer(db *sql.DB, cfg *config.Config) *http.Server {
 	mux := http.NewServeMux()
 
 	// Entity layer
-	user_infra_repo := infra_repository.NewSQLiteUserRepository(db)
-	session_infra_repo := infra_repository.NewSQLiteUserSessionRepository(db)
-	post_infra_repo := infra_repository.NewSQLitePostRepository(db)
-	postCategory_infra_repo := infra_repository.NewSQLitePostCategoryRepository(db)
-	category_infra_repo := infra_repository.NewSQLiteCategoryRepository(db)
-	post_reaction_infra_repo := infra_repository.NewSQLitePostReactionRepository(db)
+	repos := newRepositories(db, cfg)
 
-	comment_reaction_infra_repo := infra_repository.NewSQLiteCommentReactionRepository(db)
+	file_storage, err := storage.NewLocalFileStorage(cfg.UploadDir)
+	if err != nil {
+		log.Fatalf("Failed to initialize upload storage: %v", err)
+	}
 
-	comment_infra_repo := infra_repository.NewSQLiteCommentRepository(db, &user_infra_repo, &comment_reaction_infra_repo)
+	mailer, err := newMailer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize the mailer: %v", err)
+	}
 
-	post_category_infra_repo := infra_repository.NewSQLitePostAggregateRepository(db, &post_infra_repo, &postCategory_infra_repo,
-		&user_infra_repo, &post_reaction_infra_repo, &comment_infra_repo)
+	// The services that show posts and comments share the rendered Markdown.
+	markdown_renderer := usecase.NewMarkdownRenderer(cfg.MarkdownCacheSize)
 
-	auth_usecase := usecase.NewAuthService(user_infra_repo, session_infra_repo)
+	auth_usecase := usecase.NewAuthService(repos.User, repos.Session, cfg.UserNameReservation)
 	post_rate_limiter := usecase.NewPostRateLimiter()
-	post_usecase := usecase.NewPostService(&post_infra_repo, &user_infra_repo, &category_infra_repo, &post_category_infra_repo, &post_reaction_infra_repo, &session_infra_repo, post_rate_limiter)
+	post_usecase := usecase.NewPostService(&repos.Post, &repos.User, &repos.Category, &repos.PostAggregate, &repos.PostReaction, &repos.PostRevision,
+		&repos.Attachment, file_storage, usecase.UploadLimits{
+			MaxFileSize:   cfg.MaxUploadSize,
+			MaxFiles:      cfg.MaxAttachments,
+			ThumbnailSize: cfg.ThumbnailSize,
+		}, markdown_renderer, &repos.Session, repos.Tx, post_rate_limiter, cfg.PageSize)
 	comment_rate_limiter := usecase.NewCommentRateLimiter()
-	comment_usecase := usecase.NewCommentService(user_infra_repo, comment_infra_repo, post_infra_repo, session_infra_repo, comment_reaction_infra_repo, comment_rate_limiter)
-	category_usecase := usecase.NewCategoryService(category_infra_repo, postCategory_infra_repo, session_infra_repo, user_infra_repo)
-	auth_controller := controller.NewAuthController(auth_usecase, post_usecase, tmpl1)
+	comment_usecase := usecase.NewCommentService(repos.User, repos.Comment, repos.Post, repos.Session, repos.CommentReaction, comment_rate_limiter, markdown_renderer, cfg.MaxCommentDepth)
+	search_usecase := usecase.NewSearchService(repos.Search)
+	category_usecase := usecase.NewCategoryService(repos.Category, repos.PostCategory, repos.Session, repos.User)
+	user_usecase := usecase.NewUserService(repos.User, repos.Comment, file_storage, markdown_renderer, usecase.ProfileSettings{
+		MaxAvatarSize:       cfg.MaxUploadSize,
+		AvatarSize:          cfg.AvatarSize,
+		UserNameCooldown:    cfg.UserNameCooldown,
+		UserNameReservation: cfg.UserNameReservation,
+	}, cfg.PageSize)
+	reset_rate_limiter := usecase.NewResetRateLimiter(cfg.PasswordResetInterval, cfg.PasswordResetIPInterval)
+	password_usecase := usecase.NewPasswordService(repos.User, repos.Session, repos.PasswordReset, repos.Tx, mailer, reset_rate_limiter, usecase.PasswordResetSettings{
+		BaseURL:  cfg.BaseURL,
+		TokenTTL: cfg.PasswordResetTTL,
+	})
+	verification_rate_limiter := usecase.NewVerificationRateLimiter(cfg.VerificationResendInterval)
+	verification_usecase := usecase.NewVerificationService(repos.User, mailer, verification_rate_limiter, usecase.EmailVerificationSettings{
+		Secret:   verificationSecret(cfg),
+		BaseURL:  cfg.BaseURL,
+		TokenTTL: cfg.VerificationTTL,
+	})
+	auth_controller := controller.NewAuthController(auth_usecase, post_usecase, password_usecase, verification_usecase, tmpl1)
 
 	post_controller := controller.NewPostController(post_usecase, comment_usecase, category_usecase, auth_usecase, tmpl1)
 
 	comment_controller := controller.NewCommentController(post_usecase, comment_usecase, category_usecase, tmpl1)
 
+	search_controller := controller.NewSearchController(search_usecase, post_usecase, tmpl1)
+
+	user_controller := controller.NewUserController(user_usecase, post_usecase, password_usecase, auth_usecase, tmpl1)
+
+	timeout_middleware := middleware.NewTimeoutMiddleware(cfg.RequestTimeout, tmpl1)
 	middleware := middleware.NewAuthMiddleware(auth_usecase)
 
 	mux.HandleFunc("/signup", middleware.GuestOnly(auth_controller.HandleSignup))
 	mux.HandleFunc("/login", middleware.GuestOnly(auth_controller.HandleLogin))
 	mux.HandleFunc("/logout", auth_controller.HandleLogout)
+	mux.HandleFunc("/password/forgot", middleware.GuestOnly(auth_controller.HandleForgotPassword))
+	mux.HandleFunc("/password/reset", auth_controller.HandleResetPassword)
+	mux.HandleFunc("/verify-email", middleware.Auth(auth_controller.HandleVerifyEmail))
+	mux.HandleFunc("/verify-email/resend", middleware.Auth(auth_controller.HandleResendVerification))
+	mux.HandleFunc("/verify-email/confirm", auth_controller.HandleConfirmEmail)
 	mux.HandleFunc("/post/create", middleware.VerifiedAuth(post_controller.HandleCreatePost))
 	mux.HandleFunc("/post/filter", post_controller.HandleFilteredPosts)
+	mux.HandleFunc("/search", search_controller.HandleSearch)
+	mux.HandleFunc("/u/{username}", user_controller.HandleProfile)
+	mux.HandleFunc("/settings", middleware.VerifiedAuth(user_controller.HandleSettings))
+	mux.HandleFunc("/settings/username", middleware.VerifiedAuth(user_controller.HandleChangeUserName))
+	mux.HandleFunc("/settings/password", middleware.Auth(user_controller.HandleChangePassword))
+	mux.HandleFunc("/settings/devices", middleware.Auth(user_controller.HandleDevices))
+	mux.HandleFunc("/settings/devices/revoke", middleware.Auth(user_controller.HandleRevokeDevice))
+	mux.HandleFunc("/post/delete", middleware.VerifiedAuth(post_controller.HandleDeletePost))
+	mux.HandleFunc("/post/{id}", post_controller.HandleViewPost)
+	mux.HandleFunc("/post/{id}/edit", middleware.VerifiedAuth(post_controller.HandleEditPost))
+	mux.HandleFunc("/post/{id}/history", post_controller.HandlePostHistory)
 	mux.HandleFunc("/post/reaction", middleware.VerifiedAuth(post_controller.HandleReactToPost))
 	mux.HandleFunc("/comment/reaction", middleware.VerifiedAuth(comment_controller.HandleReactToComment))
 	mux.HandleFunc("/comment/create", middleware.VerifiedAuth(comment_controller.HandleCreateComment))
+	mux.HandleFunc("/comment/edit", middleware.VerifiedAuth(comment_controller.HandleEditComment))
+	mux.HandleFunc("/comment/delete", middleware.VerifiedAuth(comment_controller.HandleDeleteComment))
+	mux.Handle("/uploads/", http.StripPrefix("/uploads/", uploadsHandler(cfg.UploadDir)))
 	mux.HandleFunc("/", auth_controller.HandleRoot)
 
 	server := &http.Server{
 		Addr:    ":8080",
-		Handler: middleware.Log(mux),
+		Handler: middleware.Log(timeout_middleware.Timeout(middleware.Track(mux))),
+	}
+	if cfg.RequestTimeout > 0 {
+		// Leave the handlers time to write the timeout page before the
+		// connection is cut.
+		server.WriteTimeout = cfg.RequestTimeout + 5*time.Second
 	}
 
 	return server
 }
+
+// uploadsHandler serves the stored images. Directory listings are refused and
+// browsers are told not to guess a different content type for the files.
+func uploadsHandler(dir string) http.Handler {
+	files := http.FileServer(http.Dir(dir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "" || strings.Contains(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		files.ServeHTTP(w, r)
+	})
+}
+
+// verificationSecret returns the configured key for email verification
+// links, or a random one when none is set.
+func verificationSecret(cfg *config.Config) []byte {
+	if cfg.VerificationSecret != "" {
+		return []byte(cfg.VerificationSecret)
+	}
+	log.Printf("Warning: EMAIL_VERIFICATION_SECRET is not set, verification links will stop working when the server restarts")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatalf("Failed to generate a verification secret: %v", err)
+	}
+	return secret
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"forum/domain/repository"
+)
+
+// LocalFileStorage keeps uploads in a directory on the local disk.
+type LocalFileStorage struct {
+	dir string
+}
+
+func NewLocalFileStorage(dir string) (repository.FileStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalFileStorage{dir: dir}, nil
+}
+
+func (s *LocalFileStorage) Save(name string, data []byte) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a failed upload never leaves a
+	// truncated image behind under its final name.
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalFileStorage) Delete(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *LocalFileStorage) path(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || name[0] == '.' {
+		return "", errors.New("invalid file name")
+	}
+	return filepath.Join(s.dir, name), nil
+}
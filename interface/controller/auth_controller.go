@@ -1,25 +1,35 @@
 package controller
 
 import (
+	"errors"
 	"html/template"
+	"log"
+	"net"
 	"net/http"
 	"strings"
 
 	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
 	"forum/usecase"
 )
 
 type AuthController struct {
-	authService *usecase.AuthService
-	postService *usecase.PostService
-	templates   *template.Template
+	authService         *usecase.AuthService
+	postService         *usecase.PostService
+	passwordService     *usecase.PasswordService
+	verificationService *usecase.VerificationService
+	templates           *template.Template
 }
 
-func NewAuthController(authService *usecase.AuthService, postService *usecase.PostService, templates *template.Template) *AuthController {
+func NewAuthController(authService *usecase.AuthService, postService *usecase.PostService, passwordService *usecase.PasswordService,
+	verificationService *usecase.VerificationService, templates *template.Template,
+) *AuthController {
 	return &AuthController{
-		authService: authService,
-		postService: postService,
-		templates:   templates,
+		authService:         authService,
+		postService:         postService,
+		passwordService:     passwordService,
+		verificationService: verificationService,
+		templates:           templates,
 	}
 }
 
@@ -45,7 +55,7 @@ func (c *AuthController) HandleSignup(w http.ResponseWriter, r *http.Request) {
 	email := r.PostFormValue("email")
 	password := r.PostFormValue("password")
 
-	_, err := c.authService.Signup(name, email, password)
+	user, err := c.authService.Signup(r.Context(), name, email, password)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		c.renderTemplate(w, "register.html", map[string]interface{}{
@@ -56,8 +66,16 @@ func (c *AuthController) HandleSignup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Redirect to login page
-	http.Redirect(w, r, "/login", http.StatusSeeOther)
+	// The account works without the email; the user can ask for another
+	// one once logged in.
+	if err := c.verificationService.SendVerification(r.Context(), user); err != nil {
+		log.Printf("Failed to send the verification email to %s: %v", user.Email, err)
+	}
+
+	c.renderTemplate(w, "login.html", map[string]interface{}{
+		"notice": "Your account was created. Follow the link we sent to " + user.Email +
+			" to verify your email address; until then you can log in and read.",
+	})
 }
 
 func (c *AuthController) HandleLogin(w http.ResponseWriter, r *http.Request) {
@@ -82,7 +100,7 @@ func (c *AuthController) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	email := r.PostFormValue("email")
 	password := r.PostFormValue("password")
 
-	token, user, err := c.authService.Login(email, password)
+	token, user, err := c.authService.Login(r.Context(), email, password, clientInfo(r))
 	if err != nil {
 		w.WriteHeader(http.StatusUnauthorized)
 		c.renderTemplate(w, "login.html", map[string]interface{}{
@@ -92,14 +110,7 @@ func (c *AuthController) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set session cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
-		Value:    token,
-		Path:     "/",
-		MaxAge:   86400,
-		HttpOnly: true,
-	})
+	setSessionCookie(w, token)
 
 	_ = user
 
@@ -138,12 +149,32 @@ func (c *AuthController) HandleRoot(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// HandleLogout signs the browser out. The other devices of the user stay
+// signed in.
 func (c *AuthController) HandleLogout(w http.ResponseWriter, r *http.Request) {
-	user, ok := r.Context().Value("user").(*entity.User)
-	if ok {
-		c.authService.Logout(user.ID)
+	if cookie, err := r.Cookie("session_token"); err == nil {
+		if err := c.authService.Logout(r.Context(), cookie.Value); err != nil {
+			log.Printf("Failed to end a session: %v", err)
+		}
 	}
 
+	clearSessionCookie(w)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// setSessionCookie signs the browser in with a session token.
+func setSessionCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session_token",
+		Value:    token,
+		Path:     "/",
+		MaxAge:   86400,
+		HttpOnly: true,
+	})
+}
+
+// clearSessionCookie makes the browser forget its session token.
+func clearSessionCookie(w http.ResponseWriter) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "session_token",
 		Value:    "",
@@ -151,6 +182,243 @@ func (c *AuthController) HandleLogout(w http.ResponseWriter, r *http.Request) {
 		MaxAge:   -1,
 		HttpOnly: true,
 	})
+}
 
-	http.Redirect(w, r, "/login", http.StatusSeeOther)
+// clientInfo describes the device a request came from, for the session it
+// opens.
+func clientInfo(r *http.Request) usecase.ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return usecase.ClientInfo{UserAgent: r.UserAgent(), IPAddress: ip}
+}
+
+// HandleForgotPassword asks for the email of an account and mails it a
+// password reset link. The answer is the same whether or not the account
+// exists.
+func (c *AuthController) HandleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		c.renderTemplate(w, "forgot_password.html", nil)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		c.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusMethodNotAllowed,
+			Error:      "Method Not Allowed",
+		})
+		return
+	}
+
+	email := r.PostFormValue("email")
+	err := c.passwordService.RequestReset(r.Context(), email, clientInfo(r))
+	if errors.Is(err, usecase.ErrInvalidEmail) {
+		w.WriteHeader(http.StatusBadRequest)
+		c.renderTemplate(w, "forgot_password.html", map[string]interface{}{
+			"formError": "Invalid email format. Make sure it follows the pattern: name@domain.com",
+			"email":     email,
+		})
+		return
+	} else if err != nil {
+		log.Printf("Failed to send a password reset link: %v", err)
+		c.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Something went wrong while sending the email, please try again later",
+		})
+		return
+	}
+
+	c.renderTemplate(w, "forgot_password.html", map[string]interface{}{"sent": true})
+}
+
+// HandleResetPassword sets a new password with the token of a reset link.
+func (c *AuthController) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		c.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusMethodNotAllowed,
+			Error:      "Method Not Allowed",
+		})
+		return
+	}
+
+	// The token is in the address of the page: keep it out of the Referer
+	// header sent to the font server.
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	token := r.FormValue("token")
+
+	if r.Method == http.MethodGet {
+		err := c.passwordService.CheckResetToken(r.Context(), token)
+		if err != nil {
+			c.showResetError(w, err)
+			return
+		}
+		c.renderTemplate(w, "reset_password.html", map[string]interface{}{"token": token})
+		return
+	}
+
+	password := r.PostFormValue("password")
+	if password != r.PostFormValue("confirm_password") {
+		w.WriteHeader(http.StatusBadRequest)
+		c.renderTemplate(w, "reset_password.html", map[string]interface{}{
+			"token":     token,
+			"formError": "The passwords do not match",
+		})
+		return
+	}
+
+	err := c.passwordService.ResetPassword(r.Context(), token, password)
+	if errors.Is(err, usecase.ErrPasswordLength) {
+		w.WriteHeader(http.StatusBadRequest)
+		c.renderTemplate(w, "reset_password.html", map[string]interface{}{
+			"token":     token,
+			"formError": err.Error(),
+		})
+		return
+	} else if err != nil {
+		c.showResetError(w, err)
+		return
+	}
+
+	c.renderTemplate(w, "login.html", map[string]interface{}{
+		"notice": "Your password was changed, you can log in with it now.",
+	})
+}
+
+func (c *AuthController) showResetError(w http.ResponseWriter, err error) {
+	if errors.Is(err, custom_errors.ErrResetTokenNotFound) {
+		c.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      "This password reset link is invalid, expired or already used. Ask for a new one.",
+		})
+		return
+	}
+	c.ShowErrorPage(w, ErrorMessage{
+		StatusCode: http.StatusInternalServerError,
+		Error:      "Something went wrong while resetting your password",
+	})
+}
+
+// HandleVerifyEmail tells signed in users whether their email address is
+// verified, and lets them ask for another verification link.
+func (c *AuthController) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		c.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusMethodNotAllowed,
+			Error:      "Method Not Allowed",
+		})
+		return
+	}
+
+	user, ok := c.sessionUser(w, r)
+	if !ok {
+		return
+	}
+	c.renderVerifyEmail(w, http.StatusOK, user, nil)
+}
+
+// HandleResendVerification mails a new verification link to the signed in
+// user.
+func (c *AuthController) HandleResendVerification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		c.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusMethodNotAllowed,
+			Error:      "Method Not Allowed",
+		})
+		return
+	}
+
+	user, ok := c.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	err := c.verificationService.SendVerification(r.Context(), user)
+	switch {
+	case err == nil:
+		c.renderVerifyEmail(w, http.StatusOK, user, map[string]interface{}{
+			"notice": "A new verification link is on its way to " + user.Email + ".",
+		})
+	case errors.Is(err, usecase.ErrVerificationRateLimited):
+		c.renderVerifyEmail(w, http.StatusTooManyRequests, user, map[string]interface{}{"formError": err.Error()})
+	case errors.Is(err, usecase.ErrEmailAlreadyVerified):
+		c.renderVerifyEmail(w, http.StatusOK, user, nil)
+	default:
+		log.Printf("Failed to send the verification email to %s: %v", user.Email, err)
+		c.renderVerifyEmail(w, http.StatusInternalServerError, user, map[string]interface{}{
+			"formError": "Something went wrong while sending the email, please try again later",
+		})
+	}
+}
+
+// HandleConfirmEmail verifies the email address of the user a link was sent
+// to. It works whether or not anyone is signed in.
+func (c *AuthController) HandleConfirmEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		c.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusMethodNotAllowed,
+			Error:      "Method Not Allowed",
+		})
+		return
+	}
+
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	user, err := c.verificationService.VerifyEmail(r.Context(), r.URL.Query().Get("token"))
+	if errors.Is(err, usecase.ErrInvalidVerificationLink) {
+		c.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      "This verification link is invalid or has expired. Log in to ask for a new one.",
+		})
+		return
+	} else if err != nil {
+		c.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Something went wrong while verifying your email address",
+		})
+		return
+	}
+
+	// The link may be opened in a browser where no one is signed in.
+	signedIn := false
+	if cookie, err := r.Cookie("session_token"); err == nil {
+		viewer, err := c.postService.GetUserFromSessionToken(r.Context(), cookie.Value)
+		signedIn = err == nil && viewer != nil && viewer.ID == user.ID
+	}
+	c.renderVerifyEmail(w, http.StatusOK, user, map[string]interface{}{
+		"confirmed":       true,
+		"isAuthenticated": signedIn,
+	})
+}
+
+func (c *AuthController) renderVerifyEmail(w http.ResponseWriter, statusCode int, user *entity.User, extra map[string]interface{}) {
+	data := map[string]interface{}{
+		"user":            user,
+		"username":        user.UserName,
+		"isAuthenticated": true,
+	}
+	for key, value := range extra {
+		data[key] = value
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(statusCode)
+	if err := c.templates.ExecuteTemplate(w, "verify_email.html", data); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+// sessionUser returns the user the session cookie belongs to. When there is
+// none it sends them to the login page and returns false.
+func (c *AuthController) sessionUser(w http.ResponseWriter, r *http.Request) (*entity.User, bool) {
+	cookie, err := r.Cookie("session_token")
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, false
+	}
+	user, err := c.postService.GetUserFromSessionToken(r.Context(), cookie.Value)
+	if err != nil || user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, false
+	}
+	return user, true
 }
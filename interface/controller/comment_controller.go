@@ -1,11 +1,13 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
 	"strings"
 
+	custom_errors "forum/domain/errors"
 	"forum/usecase"
 
 	"github.com/google/uuid"
@@ -52,7 +54,7 @@ func (cc *CommentController) HandleCreateComment(w http.ResponseWriter, r *http.
 		return
 	}
 
-	user, err := cc.postService.GetUserFromSessionToken(cookie.Value)
+	user, err := cc.postService.GetUserFromSessionToken(r.Context(), cookie.Value)
 	if err == nil && user != nil {
 		username = user.UserName
 		isAuthenticated = true
@@ -69,7 +71,7 @@ func (cc *CommentController) HandleCreateComment(w http.ResponseWriter, r *http.
 		return
 	}
 
-	posts, err := cc.postService.GetPosts()
+	posts, err := cc.postService.GetPosts(r.Context())
 	if err != nil {
 		cc.ShowErrorPage(w, ErrorMessage{
 			StatusCode: http.StatusInternalServerError,
@@ -90,12 +92,26 @@ func (cc *CommentController) HandleCreateComment(w http.ResponseWriter, r *http.
 		return
 	}
 
-	_, err = cc.commentService.CreateComment(&postID, cookie.Value, content)
+	var parentID *uuid.UUID
+	if parentIDStr := r.FormValue("parentId"); parentIDStr != "" {
+		id, err := uuid.Parse(parentIDStr)
+		if err != nil {
+			cc.ShowErrorPage(w, ErrorMessage{
+				StatusCode: http.StatusBadRequest,
+				Error:      "Invalid comment ID",
+			})
+			return
+		}
+		parentID = &id
+	}
+
+	_, err = cc.commentService.CreateComment(r.Context(), &postID, parentID, cookie.Value, content)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if strings.Contains(err.Error(), "not found") {
 			statusCode = http.StatusNotFound
-		} else if strings.Contains(err.Error(), "character") || strings.Contains(err.Error(), "empty") {
+		} else if strings.Contains(err.Error(), "character") || strings.Contains(err.Error(), "empty") ||
+			strings.Contains(err.Error(), "reply") || strings.Contains(err.Error(), "nested") {
 			statusCode = http.StatusBadRequest
 		}
 		w.WriteHeader(statusCode)
@@ -111,6 +127,109 @@ func (cc *CommentController) HandleCreateComment(w http.ResponseWriter, r *http.
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
+// HandleEditComment saves a new version of a comment written by the current user.
+func (cc *CommentController) HandleEditComment(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie("session_token")
+	if err == http.ErrNoCookie {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	} else if err != nil {
+		cc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Unexpected Error While Reading Cookie",
+		})
+		return
+	}
+	if r.Method != http.MethodPost {
+		cc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusMethodNotAllowed,
+			Error:      "Method not allowed",
+		})
+		return
+	}
+
+	commentID, err := uuid.Parse(r.FormValue("commentId"))
+	if err != nil {
+		cc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid comment ID",
+		})
+		return
+	}
+
+	comment, err := cc.commentService.UpdateComment(r.Context(), commentID, cookie.Value, r.FormValue("content"))
+	if err != nil {
+		cc.showCommentError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/post/"+comment.PostID.String(), http.StatusSeeOther)
+}
+
+// HandleDeleteComment replaces a comment written by the current user with a tombstone.
+func (cc *CommentController) HandleDeleteComment(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie("session_token")
+	if err == http.ErrNoCookie {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	} else if err != nil {
+		cc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Unexpected Error While Reading Cookie",
+		})
+		return
+	}
+	if r.Method != http.MethodPost {
+		cc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusMethodNotAllowed,
+			Error:      "Method not allowed",
+		})
+		return
+	}
+
+	commentID, err := uuid.Parse(r.FormValue("commentId"))
+	if err != nil {
+		cc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid comment ID",
+		})
+		return
+	}
+
+	comment, err := cc.commentService.DeleteComment(r.Context(), commentID, cookie.Value)
+	if err != nil {
+		cc.showCommentError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/post/"+comment.PostID.String(), http.StatusSeeOther)
+}
+
+func (cc *CommentController) showCommentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, custom_errors.ErrCommentNotFound):
+		cc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusNotFound,
+			Error:      "Comment Not Found",
+		})
+	case errors.Is(err, usecase.ErrUnauthorizedAccess):
+		cc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusForbidden,
+			Error:      "You can only change your own comments",
+		})
+	case strings.Contains(err.Error(), "character"):
+		cc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      err.Error(),
+		})
+	default:
+		cc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Something Went Wrong While Saving The Comment",
+		})
+	}
+}
+
 func (cc *CommentController) renderTemplate(w http.ResponseWriter, template string, data interface{}) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	err := cc.templates.ExecuteTemplate(w, template, data)
@@ -161,7 +280,7 @@ func (cc *CommentController) HandleReactToComment(w http.ResponseWriter, r *http
 	if r.FormValue("isLike") == "0" {
 		like = false
 	}
-	cc.commentService.ReactToComment(&ID, token.Value, like)
+	cc.commentService.ReactToComment(r.Context(), &ID, token.Value, like)
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -1,8 +1,11 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+
+	"forum/domain/entity"
 )
 
 type ErrorMessage struct {
@@ -31,20 +34,28 @@ func (c *AuthController) ShowLoginPage(w http.ResponseWriter, r *http.Request) {
 }
 
 func (c *AuthController) ShowMainPage(w http.ResponseWriter, r *http.Request) {
-	var username string
-	var isAuthenticated bool
+	var username, currentUserID string
+	var isAuthenticated, isModerator bool
 
 	cookie, err := r.Cookie("session_token")
 	if err == nil {
-		user, err := c.authService.GetUserFromSessionToken(cookie.Value)
+		user, err := c.authService.GetUserFromSessionToken(r.Context(), cookie.Value)
 		if err == nil && user != nil {
 			username = user.UserName
+			currentUserID = user.ID.String()
+			isModerator = user.IsModerator()
 			isAuthenticated = true
 		}
 	}
 
-	posts, err := c.postService.GetPosts()
-	if err != nil {
+	page, err := c.postService.GetFeedPage(r.Context(), r.URL.Query().Get("cursor"))
+	if errors.Is(err, entity.ErrInvalidCursor) {
+		c.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid page",
+		})
+		return
+	} else if err != nil {
 		c.ShowErrorPage(w, ErrorMessage{
 			StatusCode: http.StatusInternalServerError,
 			Error:      "Something Went Wrong While Loading Posts",
@@ -52,11 +63,15 @@ func (c *AuthController) ShowMainPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	c.renderTemplate(w, "layout.html", map[string]interface{}{
-		"posts":           posts,
+	data := map[string]interface{}{
+		"posts":           page.Posts,
 		"username":        username,
+		"currentUserID":   currentUserID,
+		"isModerator":     isModerator,
 		"isAuthenticated": isAuthenticated,
-	})
+	}
+	addPageLinks(data, r, page)
+	c.renderTemplate(w, "layout.html", data)
 }
 
 
@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"net/http"
+
+	"forum/domain/entity"
+)
+
+// addPageLinks adds the URLs of the previous and next pages to the template
+// data. The links keep the current query string, such as the active filters,
+// and only replace the cursor.
+func addPageLinks(data map[string]interface{}, r *http.Request, page *entity.PostPage) {
+	addCursorLinks(data, r, page.Prev, page.Next)
+}
+
+// addCursorLinks is addPageLinks for any list paged with cursors.
+func addCursorLinks(data map[string]interface{}, r *http.Request, prev, next *entity.PostCursor) {
+	link := func(cursor *entity.PostCursor) string {
+		query := r.URL.Query()
+		query.Set("cursor", cursor.Encode())
+		return r.URL.Path + "?" + query.Encode()
+	}
+
+	if prev != nil {
+		data["prevPage"] = link(prev)
+	}
+	if next != nil {
+		data["nextPage"] = link(next)
+	}
+}
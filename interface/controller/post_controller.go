@@ -4,10 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
 	"forum/usecase"
 
 	"github.com/google/uuid"
@@ -67,14 +70,45 @@ func (pc *PostController) HandleCreatePost(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	limits := pc.postService.UploadLimits()
+	r.Body = http.MaxBytesReader(w, r.Body, limits.MaxFileSize*int64(limits.MaxFiles)+1<<20)
+	err = r.ParseMultipartForm(limits.MaxFileSize)
+	if errors.Is(err, http.ErrNotMultipart) {
+		err = r.ParseForm()
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		statusCode := http.StatusBadRequest
+		message := "Invalid form submission"
+		if errors.As(err, &maxBytesErr) {
+			statusCode = http.StatusRequestEntityTooLarge
+			message = "The uploaded images are too large"
+		}
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: statusCode,
+			Error:      message,
+		})
+		return
+	}
+
+	images, err := readImageUploads(r, limits.MaxFiles)
+	if err != nil {
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	title := r.FormValue("title")
 	content := r.FormValue("content")
 	categories := r.Form["categories"]
-	user, err := pc.postService.GetUserFromSessionToken(cookie.Value)
+	user, err := pc.postService.GetUserFromSessionToken(r.Context(), cookie.Value)
 	if err == nil && user != nil {
 		username = user.UserName
 		isAuthenticated = true
 	}
-	posts, err := pc.postService.GetPosts()
+	posts, err := pc.postService.GetPosts(r.Context())
 	if err != nil {
 		pc.ShowErrorPage(w, ErrorMessage{
 			StatusCode: http.StatusInternalServerError,
@@ -107,7 +141,7 @@ func (pc *PostController) HandleCreatePost(w http.ResponseWriter, r *http.Reques
 
 	categoriesIDs := make([]*uuid.UUID, 0, len(categories))
 	for _, category := range categories {
-		c, err := pc.categoryService.GetCategoryByName(category)
+		c, err := pc.categoryService.GetCategoryByName(r.Context(), category)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			pc.renderTemplate(w, "layout.html", map[string]interface{}{
@@ -121,17 +155,21 @@ func (pc *PostController) HandleCreatePost(w http.ResponseWriter, r *http.Reques
 		categoriesIDs = append(categoriesIDs, &c.ID)
 	}
 
-	_, err = pc.postService.CreatePost(cookie.Value, content, categoriesIDs)
+	_, err = pc.postService.CreatePost(r.Context(), cookie.Value, title, content, categoriesIDs, images)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if strings.Contains(err.Error(), "wait a bit") {
 			statusCode = http.StatusTooManyRequests
-		} else if strings.Contains(err.Error(), "content") {
+		} else if errors.Is(err, usecase.ErrImageTooLarge) {
+			statusCode = http.StatusRequestEntityTooLarge
+		} else if strings.Contains(err.Error(), "content") || strings.Contains(err.Error(), "title") ||
+			strings.Contains(err.Error(), "image") {
 			statusCode = http.StatusBadRequest
 		}
 		w.WriteHeader(statusCode)
 		pc.renderTemplate(w, "layout.html", map[string]interface{}{
 			"form_error":      err.Error(),
+			"Title":           title,
 			"Content":         content,
 			"posts":           posts,
 			"username":        username,
@@ -143,6 +181,338 @@ func (pc *PostController) HandleCreatePost(w http.ResponseWriter, r *http.Reques
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
+// readImageUploads reads the files sent in the "images" field of a
+// multipart form. Empty file inputs are skipped.
+func readImageUploads(r *http.Request, maxFiles int) ([]usecase.ImageUpload, error) {
+	if r.MultipartForm == nil {
+		return nil, nil
+	}
+
+	headers := r.MultipartForm.File["images"]
+	if len(headers) > maxFiles {
+		return nil, fmt.Errorf("you can attach at most %d images", maxFiles)
+	}
+
+	images := make([]usecase.ImageUpload, 0, len(headers))
+	for _, header := range headers {
+		if header.Size == 0 {
+			continue
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, fmt.Errorf("could not read image %s", header.Filename)
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not read image %s", header.Filename)
+		}
+		images = append(images, usecase.ImageUpload{Name: header.Filename, Data: data})
+	}
+	return images, nil
+}
+
+// HandleViewPost renders a single post on its own page so it can be linked to.
+func (pc *PostController) HandleViewPost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusMethodNotAllowed,
+			Error:      "Method not allowed",
+		})
+		return
+	}
+
+	postID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusNotFound,
+			Error:      "Post Not Found",
+		})
+		return
+	}
+
+	var username, currentUserID string
+	var isAuthenticated, isModerator bool
+
+	cookie, err := r.Cookie("session_token")
+	if err == nil {
+		user, err := pc.postService.GetUserFromSessionToken(r.Context(), cookie.Value)
+		if err == nil && user != nil {
+			username = user.UserName
+			currentUserID = user.ID.String()
+			isModerator = user.IsModerator()
+			isAuthenticated = true
+		}
+	}
+
+	post, err := pc.postService.GetPost(r.Context(), postID)
+	if err != nil {
+		if errors.Is(err, custom_errors.ErrPostNotFound) {
+			pc.ShowErrorPage(w, ErrorMessage{
+				StatusCode: http.StatusNotFound,
+				Error:      "Post Not Found",
+			})
+			return
+		}
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Something went wrong while loading the post",
+		})
+		return
+	}
+
+	pc.renderTemplate(w, "post.html", map[string]interface{}{
+		"post":            post,
+		"posts":           []*entity.PostWithDetails{post},
+		"postsTitle":      post.Title,
+		"username":        username,
+		"currentUserID":   currentUserID,
+		"isModerator":     isModerator,
+		"isAuthenticated": isAuthenticated,
+	})
+}
+
+// HandleEditPost shows the edit form of a post to its author and saves the
+// submitted changes.
+func (pc *PostController) HandleEditPost(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie("session_token")
+	if err == http.ErrNoCookie {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	} else if err != nil {
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Unexpected Error While Reading Cookie",
+		})
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusMethodNotAllowed,
+			Error:      "Method not allowed",
+		})
+		return
+	}
+
+	postID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusNotFound,
+			Error:      "Post Not Found",
+		})
+		return
+	}
+
+	user, err := pc.postService.GetUserFromSessionToken(r.Context(), cookie.Value)
+	if err != nil || user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	post, err := pc.postService.GetPost(r.Context(), postID)
+	if err != nil {
+		if errors.Is(err, custom_errors.ErrPostNotFound) {
+			pc.ShowErrorPage(w, ErrorMessage{
+				StatusCode: http.StatusNotFound,
+				Error:      "Post Not Found",
+			})
+			return
+		}
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Something went wrong while loading the post",
+		})
+		return
+	}
+
+	if post.UserID != user.ID {
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusForbidden,
+			Error:      "You can only edit your own posts",
+		})
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		pc.renderTemplate(w, "edit_post.html", map[string]interface{}{
+			"post":            post,
+			"Title":           post.Title,
+			"Content":         post.Content,
+			"username":        user.UserName,
+			"isAuthenticated": true,
+		})
+		return
+	}
+
+	title := r.FormValue("title")
+	content := r.FormValue("content")
+
+	_, err = pc.postService.UpdatePost(r.Context(), cookie.Value, postID, title, content)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, usecase.ErrUnauthorizedAccess) {
+			statusCode = http.StatusForbidden
+		} else if strings.Contains(err.Error(), "content") || strings.Contains(err.Error(), "title") {
+			statusCode = http.StatusBadRequest
+		}
+		w.WriteHeader(statusCode)
+		pc.renderTemplate(w, "edit_post.html", map[string]interface{}{
+			"post":            post,
+			"form_error":      err.Error(),
+			"Title":           title,
+			"Content":         content,
+			"username":        user.UserName,
+			"isAuthenticated": true,
+		})
+		return
+	}
+
+	http.Redirect(w, r, "/post/"+postID.String(), http.StatusSeeOther)
+}
+
+// HandlePostHistory lists the revisions of a post and shows the diff between
+// two of them, selected with the "from" and "to" query parameters.
+func (pc *PostController) HandlePostHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusMethodNotAllowed,
+			Error:      "Method not allowed",
+		})
+		return
+	}
+
+	postID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusNotFound,
+			Error:      "Post Not Found",
+		})
+		return
+	}
+
+	var username string
+	var isAuthenticated bool
+
+	cookie, err := r.Cookie("session_token")
+	if err == nil {
+		user, err := pc.postService.GetUserFromSessionToken(r.Context(), cookie.Value)
+		if err == nil && user != nil {
+			username = user.UserName
+			isAuthenticated = true
+		}
+	}
+
+	revisions, err := pc.postService.GetPostHistory(r.Context(), postID)
+	if err != nil {
+		if errors.Is(err, custom_errors.ErrPostNotFound) {
+			pc.ShowErrorPage(w, ErrorMessage{
+				StatusCode: http.StatusNotFound,
+				Error:      "Post Not Found",
+			})
+			return
+		}
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Something went wrong while loading the post history",
+		})
+		return
+	}
+
+	// By default compare the current version with the one before it.
+	to := len(revisions)
+	from := to - 1
+	if from < 1 {
+		from = 1
+	}
+	if v := r.URL.Query().Get("from"); v != "" {
+		from, err = strconv.Atoi(v)
+		if err != nil || from < 1 || from > len(revisions) {
+			pc.ShowErrorPage(w, ErrorMessage{
+				StatusCode: http.StatusBadRequest,
+				Error:      "Invalid revision",
+			})
+			return
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		to, err = strconv.Atoi(v)
+		if err != nil || to < 1 || to > len(revisions) {
+			pc.ShowErrorPage(w, ErrorMessage{
+				StatusCode: http.StatusBadRequest,
+				Error:      "Invalid revision",
+			})
+			return
+		}
+	}
+
+	current := revisions[len(revisions)-1]
+	pc.renderTemplate(w, "post_history.html", map[string]interface{}{
+		"postID":          postID,
+		"current":         current,
+		"revisions":       revisions,
+		"diff":            pc.postService.DiffRevisions(revisions[from-1], revisions[to-1]),
+		"username":        username,
+		"isAuthenticated": isAuthenticated,
+	})
+}
+
+// HandleDeletePost deletes a post on behalf of its author or a moderator.
+func (pc *PostController) HandleDeletePost(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie("session_token")
+	if err == http.ErrNoCookie {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	} else if err != nil {
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Unexpected Error While Reading Cookie",
+		})
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusMethodNotAllowed,
+			Error:      "Method not allowed",
+		})
+		return
+	}
+
+	postID, err := uuid.Parse(r.FormValue("postId"))
+	if err != nil {
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid post ID",
+		})
+		return
+	}
+
+	err = pc.postService.DeletePost(r.Context(), cookie.Value, postID)
+	if err != nil {
+		switch {
+		case errors.Is(err, custom_errors.ErrPostNotFound):
+			pc.ShowErrorPage(w, ErrorMessage{
+				StatusCode: http.StatusNotFound,
+				Error:      "Post Not Found",
+			})
+		case errors.Is(err, usecase.ErrUnauthorizedAccess):
+			pc.ShowErrorPage(w, ErrorMessage{
+				StatusCode: http.StatusForbidden,
+				Error:      "You can only delete your own posts",
+			})
+		default:
+			pc.ShowErrorPage(w, ErrorMessage{
+				StatusCode: http.StatusInternalServerError,
+				Error:      "Something went wrong while deleting the post",
+			})
+		}
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
 func (pc PostController) HandleReactToPost(w http.ResponseWriter, r *http.Request) {
 	token, err := r.Cookie("session_token")
 	if err == http.ErrNoCookie {
@@ -173,7 +543,7 @@ func (pc PostController) HandleReactToPost(w http.ResponseWriter, r *http.Reques
 	if r.FormValue("isLike") == "0" {
 		like = false
 	}
-	pc.postService.ReactToPost(ID, token.Value, like)
+	pc.postService.ReactToPost(r.Context(), ID, token.Value, like)
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
@@ -187,28 +557,21 @@ func (pc *PostController) HandleFilteredPosts(w http.ResponseWriter, r *http.Req
 	}
 
 	var userID *uuid.UUID
-	var isAuthenticated bool
-	var username string
+	var isAuthenticated, isModerator bool
+	var username, currentUserID string
 
 	cookie, err := r.Cookie("session_token")
 	if err == nil {
-		user, err := pc.postService.GetUserFromSessionToken(cookie.Value)
+		user, err := pc.postService.GetUserFromSessionToken(r.Context(), cookie.Value)
 		if err == nil && user != nil {
 			isAuthenticated = true
 			userID = &user.ID
 			username = user.UserName
+			currentUserID = user.ID.String()
+			isModerator = user.IsModerator()
 		}
 	}
 
-	posts, err := pc.postService.GetPosts()
-	if err != nil {
-		pc.ShowErrorPage(w, ErrorMessage{
-			StatusCode: http.StatusInternalServerError,
-			Error:      "Something went wrong while loading posts",
-		})
-		return
-	}
-
 	selectedCategoryNames := r.URL.Query()["category-filter"]
 	Radio := r.URL.Query().Get("postFilter")
 	var likedPosts, myPosts bool = false, false
@@ -225,18 +588,51 @@ func (pc *PostController) HandleFilteredPosts(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	hasFilters := len(selectedCategoryNames) > 0 || myPosts || likedPosts
+	sortMode, ok := entity.ParseSortMode(r.URL.Query().Get("sort"))
+	if !ok {
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Unavailable sort mode",
+		})
+		return
+	}
+	windowName := r.URL.Query().Get("window")
+	if windowName == "" {
+		windowName = "all"
+	}
+	window, ok := entity.TopWindows[windowName]
+	if !ok {
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Unavailable time window",
+		})
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	hasFilters := len(selectedCategoryNames) > 0 || myPosts || likedPosts || query != "" || sortMode != entity.SortNew
 	if !hasFilters {
+		// Only the unfiltered page shows the whole feed.
+		posts, err := pc.postService.GetPosts(r.Context())
+		if err != nil {
+			pc.ShowErrorPage(w, ErrorMessage{
+				StatusCode: http.StatusInternalServerError,
+				Error:      "Something went wrong while loading posts",
+			})
+			return
+		}
 		pc.renderTemplate(w, "layout.html", map[string]interface{}{
 			"form_error":      errors.New("No filter is selected"),
 			"posts":           posts,
 			"username":        username,
+			"currentUserID":   currentUserID,
+			"isModerator":     isModerator,
 			"isAuthenticated": isAuthenticated,
 		})
 		return
 	}
 
-	categories, err := pc.categoryService.GetAllCategories()
+	categories, err := pc.categoryService.GetAllCategories(r.Context())
 	if err != nil {
 		pc.ShowErrorPage(w, ErrorMessage{
 			StatusCode: http.StatusInternalServerError,
@@ -272,10 +668,19 @@ func (pc *PostController) HandleFilteredPosts(w http.ResponseWriter, r *http.Req
 		MyPosts:     myPosts,
 		LikedPosts:  likedPosts,
 		AuthorID:    userID,
+		Query:       query,
+		Sort:        sortMode,
+		Window:      window,
 	}
 
-	filteredPosts, err := pc.postService.GetFilteredPostsWithDetails(*filter)
-	if err != nil {
+	page, err := pc.postService.GetFilteredPostsWithDetails(r.Context(), *filter, r.URL.Query().Get("cursor"))
+	if errors.Is(err, entity.ErrInvalidCursor) {
+		pc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid page",
+		})
+		return
+	} else if err != nil {
 		pc.ShowErrorPage(w, ErrorMessage{
 			StatusCode: http.StatusInternalServerError,
 			Error:      "Error filtering posts: " + err.Error(),
@@ -283,12 +688,19 @@ func (pc *PostController) HandleFilteredPosts(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	pc.renderTemplate(w, "layout.html", map[string]interface{}{
+	data := map[string]interface{}{
 		"username":           username,
+		"currentUserID":      currentUserID,
+		"isModerator":        isModerator,
 		"isAuthenticated":    isAuthenticated,
-		"posts":              filteredPosts,
+		"posts":              page.Posts,
 		"selectedCategories": selectedMap,
-	})
+		"filterQuery":        query,
+		"sortMode":           string(sortMode),
+		"topWindow":          windowName,
+	}
+	addPageLinks(data, r, page)
+	pc.renderTemplate(w, "layout.html", data)
 }
 
 func (c *PostController) ShowErrorPage(w http.ResponseWriter, data ErrorMessage) {
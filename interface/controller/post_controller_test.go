@@ -0,0 +1,201 @@
+package controller_test
+
+import (
+	"context"
+	"html"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"forum/config"
+	"forum/domain/entity"
+	"forum/domain/repository"
+	"forum/infrastructure/database"
+	infra_repository "forum/infrastructure/repository"
+	"forum/infrastructure/storage"
+	"forum/interface/controller"
+	"forum/usecase"
+
+	"github.com/google/uuid"
+)
+
+// listTemplates render the IDs of the listed posts one per line, followed by
+// the link to the next page.
+const listTemplates = `
+{{define "layout.html"}}{{range .posts}}{{.ID}}
+{{end}}{{with .nextPage}}next {{.}}{{end}}{{end}}
+{{define "error.html"}}{{.Error}}{{end}}`
+
+// filterPageSize is small enough that the seeded posts span several pages.
+const filterPageSize = 4
+
+// feedCounter counts the loads of the unfiltered feed.
+type feedCounter struct {
+	repository.PostAggregateRepository
+	loads atomic.Int64
+}
+
+func (f *feedCounter) GetFeedForUser(ctx context.Context, page entity.PageRequest) (*entity.PostPage, error) {
+	f.loads.Add(1)
+	return f.PostAggregateRepository.GetFeedForUser(ctx, page)
+}
+
+// newFilterController returns a PostController over a new SQLite database
+// holding ten posts: six in category "first" and four in "second". Every
+// post has the same creation time and no reactions, so only the id tells
+// posts apart. The returned feedCounter counts the loads of the feed.
+func newFilterController(t *testing.T) (*controller.PostController, map[string][]string, *feedCounter) {
+	t.Helper()
+	db := database.SetingUpDB(database.DriverSQLite, filepath.Join(t.TempDir(), "forum.db"), config.Load().DatabaseOptions())
+	t.Cleanup(func() { db.Close() })
+	repos := infra_repository.NewSQLiteRepositories(db, 5)
+	feed := &feedCounter{PostAggregateRepository: repos.PostAggregate}
+	repos.PostAggregate = feed
+	files, err := storage.NewLocalFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	author := &entity.User{UserName: "author", Email: "author@example.com", PasswordHash: "hash"}
+	if err := repos.User.Create(ctx, author); err != nil {
+		t.Fatal(err)
+	}
+	categories := []*entity.Category{{Name: "first"}, {Name: "second"}}
+	for _, category := range categories {
+		if err := repos.Category.Create(ctx, category); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids := make(map[string][]string)
+	for i := 0; i < 10; i++ {
+		category := categories[0]
+		if i >= 6 {
+			category = categories[1]
+		}
+		post := &entity.Post{Title: "Same time", Content: "content", UserID: author.ID}
+		if err := repos.PostAggregate.CreatePostWithCategories(ctx, post, []*uuid.UUID{&category.ID}); err != nil {
+			t.Fatal(err)
+		}
+		ids[""] = append(ids[""], post.ID.String())
+		ids[category.Name] = append(ids[category.Name], post.ID.String())
+	}
+	if _, err := db.Exec(`UPDATE posts SET created_at = ?`, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	markdown := usecase.NewMarkdownRenderer(100)
+	posts := usecase.NewPostService(&repos.Post, &repos.User, &repos.Category, &repos.PostAggregate, &repos.PostReaction,
+		&repos.PostRevision, &repos.Attachment, files, usecase.UploadLimits{}, markdown, &repos.Session, repos.Tx,
+		usecase.NewPostRateLimiter(), filterPageSize)
+	comments := usecase.NewCommentService(repos.User, repos.Comment, repos.Post, repos.Session, repos.CommentReaction,
+		usecase.NewCommentRateLimiter(), markdown, 5)
+	categoryService := usecase.NewCategoryService(repos.Category, repos.PostCategory, repos.Session, repos.User)
+	auth := usecase.NewAuthService(repos.User, repos.Session, 0)
+	templates := template.Must(template.New("").Parse(listTemplates))
+	return controller.NewPostController(posts, comments, categoryService, auth, templates), ids, feed
+}
+
+// filterAll requests target and the pages after it, and returns the IDs of
+// every post listed.
+func filterAll(t *testing.T, pc *controller.PostController, target string) []string {
+	t.Helper()
+	var ids []string
+	for pages := 0; target != ""; pages++ {
+		if pages > 10 {
+			t.Fatalf("more than 10 pages of %d posts", filterPageSize)
+		}
+		rec := httptest.NewRecorder()
+		pc.HandleFilteredPosts(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d: %s", target, rec.Code, rec.Body)
+		}
+
+		target = ""
+		for _, line := range strings.Split(rec.Body.String(), "\n") {
+			if next, ok := strings.CutPrefix(line, "next "); ok {
+				target = html.UnescapeString(next)
+			} else if line != "" {
+				ids = append(ids, line)
+			}
+		}
+	}
+	return ids
+}
+
+func TestFilteredPostsOrderIsStable(t *testing.T) {
+	pc, ids, _ := newFilterController(t)
+
+	tests := []struct {
+		sort     string
+		category string
+	}{
+		{"new", "first"},
+		{"new", "second"},
+		{"top", ""},
+		{"top", "first"},
+		{"hot", ""},
+		{"hot", "second"},
+		{"controversial", ""},
+		{"controversial", "first"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort+"/"+tt.category, func(t *testing.T) {
+			query := url.Values{"sort": {tt.sort}}
+			if tt.category != "" {
+				query.Set("category-filter", tt.category)
+			}
+
+			// Ties on time and score fall back to the id, in descending order.
+			want := slices.Clone(ids[tt.category])
+			slices.Sort(want)
+			slices.Reverse(want)
+
+			for i := 0; i < 5; i++ {
+				got := filterAll(t, pc, "/post/filter?"+query.Encode())
+				if !slices.Equal(got, want) {
+					t.Fatalf("request %d listed\n%v\nwant\n%v", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFilteredPostsSkipTheFeed(t *testing.T) {
+	pc, ids, feed := newFilterController(t)
+
+	tests := []struct {
+		name  string
+		query string
+		// wantFeed is whether the request loads the unfiltered feed.
+		wantFeed bool
+	}{
+		{name: "no filter", query: "", wantFeed: true},
+		{name: "category", query: "category-filter=first"},
+		{name: "sort", query: "sort=top"},
+		{name: "search", query: "q=same"},
+		{name: "category and sort", query: "category-filter=second&sort=hot"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := feed.loads.Load()
+			got := filterAll(t, pc, "/post/filter?"+tt.query)
+			if loads := feed.loads.Load() - before; (loads > 0) != tt.wantFeed {
+				t.Errorf("the request loaded the feed %d times, want it loaded: %v", loads, tt.wantFeed)
+			}
+			if tt.wantFeed && len(got) != filterPageSize {
+				t.Errorf("the unfiltered page lists %d posts, want the first %d of the feed", len(got), filterPageSize)
+			}
+			if !tt.wantFeed && len(got) == 0 {
+				t.Errorf("the filtered request listed no posts, want some of %d", len(ids[""]))
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+
+	"forum/usecase"
+)
+
+type SearchController struct {
+	searchService *usecase.SearchService
+	postService   *usecase.PostService
+	templates     *template.Template
+}
+
+func NewSearchController(searchService *usecase.SearchService, postService *usecase.PostService,
+	templates *template.Template,
+) *SearchController {
+	return &SearchController{
+		searchService: searchService,
+		postService:   postService,
+		templates:     templates,
+	}
+}
+
+// HandleSearch shows the posts matching the q query parameter.
+func (sc *SearchController) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusMethodNotAllowed,
+			Error:      "Method not allowed",
+		})
+		return
+	}
+
+	var username string
+	var isAuthenticated bool
+	cookie, err := r.Cookie("session_token")
+	if err == nil {
+		user, err := sc.postService.GetUserFromSessionToken(r.Context(), cookie.Value)
+		if err == nil && user != nil {
+			username = user.UserName
+			isAuthenticated = true
+		}
+	}
+
+	query := r.URL.Query().Get("q")
+	data := map[string]interface{}{
+		"query":           query,
+		"username":        username,
+		"isAuthenticated": isAuthenticated,
+	}
+
+	if query == "" {
+		sc.renderTemplate(w, "search.html", data)
+		return
+	}
+
+	results, err := sc.searchService.Search(r.Context(), query)
+	if err != nil {
+		if errors.Is(err, usecase.ErrSearchQueryTooShort) || errors.Is(err, usecase.ErrSearchQueryTooLong) {
+			w.WriteHeader(http.StatusBadRequest)
+			data["form_error"] = err.Error()
+			sc.renderTemplate(w, "search.html", data)
+			return
+		}
+		sc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Something went wrong while searching",
+		})
+		return
+	}
+
+	data["results"] = results
+	sc.renderTemplate(w, "search.html", data)
+}
+
+func (sc *SearchController) renderTemplate(w http.ResponseWriter, template string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := sc.templates.ExecuteTemplate(w, template, data)
+	if err != nil {
+		sc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Error rendering page",
+		})
+	}
+}
+
+func (sc *SearchController) ShowErrorPage(w http.ResponseWriter, data ErrorMessage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(data.StatusCode)
+	err := sc.templates.ExecuteTemplate(w, "error.html", data)
+	if err != nil {
+		http.Error(w, data.Error, data.StatusCode)
+	}
+}
@@ -0,0 +1,447 @@
+package controller
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/usecase"
+
+	"github.com/google/uuid"
+)
+
+type UserController struct {
+	userService     *usecase.UserService
+	postService     *usecase.PostService
+	passwordService *usecase.PasswordService
+	authService     *usecase.AuthService
+	templates       *template.Template
+}
+
+func NewUserController(userService *usecase.UserService, postService *usecase.PostService,
+	passwordService *usecase.PasswordService, authService *usecase.AuthService, templates *template.Template,
+) *UserController {
+	return &UserController{
+		userService:     userService,
+		postService:     postService,
+		passwordService: passwordService,
+		authService:     authService,
+		templates:       templates,
+	}
+}
+
+// HandleProfile shows the public page of the member named in the path, with
+// either their posts or, for ?tab=comments, their comments.
+func (uc *UserController) HandleProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		uc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusMethodNotAllowed,
+			Error:      "Method not allowed",
+		})
+		return
+	}
+
+	profile, err := uc.userService.GetProfile(r.Context(), r.PathValue("username"))
+	if errors.Is(err, custom_errors.ErrUserNotFound) {
+		// Links to a name its owner gave up lead to their new one. The
+		// redirect is temporary because the name may be taken again later.
+		if current, err := uc.userService.ResolveOldUserName(r.Context(), r.PathValue("username")); err == nil {
+			target := "/u/" + url.PathEscape(current)
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusFound)
+			return
+		}
+		uc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusNotFound,
+			Error:      "User Not Found",
+		})
+		return
+	} else if err != nil {
+		uc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Something went wrong while loading the profile",
+		})
+		return
+	}
+
+	var username, currentUserID string
+	var isAuthenticated, isModerator bool
+	cookie, err := r.Cookie("session_token")
+	if err == nil {
+		user, err := uc.postService.GetUserFromSessionToken(r.Context(), cookie.Value)
+		if err == nil && user != nil {
+			username = user.UserName
+			currentUserID = user.ID.String()
+			isModerator = user.IsModerator()
+			isAuthenticated = true
+		}
+	}
+
+	data := map[string]interface{}{
+		"profile":         profile,
+		"username":        username,
+		"currentUserID":   currentUserID,
+		"isModerator":     isModerator,
+		"isAuthenticated": isAuthenticated,
+	}
+
+	cursor := r.URL.Query().Get("cursor")
+	if r.URL.Query().Get("tab") == "comments" {
+		data["tab"] = "comments"
+		page, err := uc.userService.GetUserComments(r.Context(), profile.User.ID, cursor)
+		if err != nil {
+			uc.showPageError(w, err)
+			return
+		}
+		data["comments"] = page.Comments
+		addCursorLinks(data, r, page.Prev, page.Next)
+	} else {
+		data["tab"] = "posts"
+		filter := entity.PostFilter{MyPosts: true, AuthorID: &profile.User.ID}
+		page, err := uc.postService.GetFilteredPostsWithDetails(r.Context(), filter, cursor)
+		if err != nil {
+			uc.showPageError(w, err)
+			return
+		}
+		data["posts"] = page.Posts
+		data["postsTitle"] = "Posts by " + profile.User.UserName
+		addPageLinks(data, r, page)
+	}
+
+	uc.renderTemplate(w, "profile.html", data)
+}
+
+// HandleSettings shows the settings page of the signed in member and saves
+// their bio and avatar.
+func (uc *UserController) HandleSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		uc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusMethodNotAllowed,
+			Error:      "Method not allowed",
+		})
+		return
+	}
+
+	user, ok := uc.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		uc.renderSettings(w, http.StatusOK, user, map[string]interface{}{
+			"passwordChanged": r.URL.Query().Get("password") == "changed",
+		})
+		return
+	}
+
+	maxSize := uc.userService.Settings().MaxAvatarSize
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+	err := r.ParseMultipartForm(maxSize)
+	if errors.Is(err, http.ErrNotMultipart) {
+		err = r.ParseForm()
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		statusCode := http.StatusBadRequest
+		message := "Invalid form submission"
+		if errors.As(err, &maxBytesErr) {
+			statusCode = http.StatusRequestEntityTooLarge
+			message = "The avatar is too large"
+		}
+		uc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: statusCode,
+			Error:      message,
+		})
+		return
+	}
+
+	avatar, err := readAvatarUpload(r)
+	if err != nil {
+		uc.renderSettings(w, http.StatusBadRequest, user, map[string]interface{}{"form_error": err.Error()})
+		return
+	}
+
+	bio := r.FormValue("bio")
+	err = uc.userService.UpdateProfile(r.Context(), user, bio, avatar, r.FormValue("remove_avatar") == "on")
+	if err != nil {
+		statusCode, message := http.StatusInternalServerError, "Something went wrong while saving your profile"
+		if isProfileInputError(err) {
+			statusCode, message = http.StatusBadRequest, err.Error()
+		}
+		uc.renderSettings(w, statusCode, user, map[string]interface{}{"form_error": message, "bio": bio})
+		return
+	}
+
+	http.Redirect(w, r, "/u/"+url.PathEscape(user.UserName), http.StatusSeeOther)
+}
+
+// HandleChangeUserName renames the signed in member.
+func (uc *UserController) HandleChangeUserName(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		uc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusMethodNotAllowed,
+			Error:      "Method not allowed",
+		})
+		return
+	}
+
+	user, ok := uc.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	newName := r.FormValue("username")
+	err := uc.userService.ChangeUserName(r.Context(), user, newName)
+	if err != nil {
+		statusCode, message := http.StatusInternalServerError, "Something went wrong while changing your username"
+		if isProfileInputError(err) {
+			statusCode, message = http.StatusBadRequest, err.Error()
+		}
+		uc.renderSettings(w, statusCode, user, map[string]interface{}{"name_error": message, "newUserName": newName})
+		return
+	}
+
+	http.Redirect(w, r, "/u/"+url.PathEscape(user.UserName), http.StatusSeeOther)
+}
+
+// HandleChangePassword replaces the password of the signed in member. Their
+// other devices are signed out, this one gets a new session.
+func (uc *UserController) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		uc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusMethodNotAllowed,
+			Error:      "Method not allowed",
+		})
+		return
+	}
+
+	user, ok := uc.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	newPassword := r.FormValue("new_password")
+	if newPassword != r.FormValue("confirm_password") {
+		uc.renderSettings(w, http.StatusBadRequest, user, map[string]interface{}{"password_error": "The new passwords do not match"})
+		return
+	}
+
+	token, err := uc.passwordService.ChangePassword(r.Context(), user, r.FormValue("current_password"), newPassword, clientInfo(r))
+	if err != nil {
+		statusCode, message := http.StatusInternalServerError, "Something went wrong while changing your password"
+		if errors.Is(err, usecase.ErrIncorrectPassword) || errors.Is(err, usecase.ErrPasswordLength) {
+			statusCode, message = http.StatusBadRequest, err.Error()
+		}
+		uc.renderSettings(w, statusCode, user, map[string]interface{}{"password_error": message})
+		return
+	}
+
+	setSessionCookie(w, token)
+	http.Redirect(w, r, "/settings?password=changed", http.StatusSeeOther)
+}
+
+// HandleDevices lists the devices the signed in member is signed in on.
+func (uc *UserController) HandleDevices(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		uc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusMethodNotAllowed,
+			Error:      "Method not allowed",
+		})
+		return
+	}
+
+	user, ok := uc.sessionUser(w, r)
+	if !ok {
+		return
+	}
+	current, _ := r.Context().Value("session").(*entity.UserSession)
+
+	sessions, err := uc.authService.ListSessions(r.Context(), user.ID)
+	if err != nil {
+		uc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Something went wrong while loading your devices",
+		})
+		return
+	}
+
+	currentID := uuid.Nil
+	if current != nil {
+		currentID = current.ID
+	}
+	uc.renderTemplate(w, "devices.html", map[string]interface{}{
+		"user":            user,
+		"sessions":        sessions,
+		"currentID":       currentID,
+		"revoked":         r.URL.Query().Get("revoked") == "1",
+		"username":        user.UserName,
+		"isAuthenticated": true,
+	})
+}
+
+// HandleRevokeDevice signs the signed in member out on one of their
+// devices. Revoking the device in use signs this browser out too.
+func (uc *UserController) HandleRevokeDevice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		uc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusMethodNotAllowed,
+			Error:      "Method not allowed",
+		})
+		return
+	}
+
+	user, ok := uc.sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	sessionID, err := uuid.Parse(r.FormValue("session_id"))
+	if err != nil {
+		uc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid device",
+		})
+		return
+	}
+
+	err = uc.authService.RevokeSession(r.Context(), user.ID, sessionID)
+	if errors.Is(err, custom_errors.ErrSessionNotFound) {
+		uc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusNotFound,
+			Error:      "That device is already signed out",
+		})
+		return
+	} else if err != nil {
+		uc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Something went wrong while signing the device out",
+		})
+		return
+	}
+
+	if current, ok := r.Context().Value("session").(*entity.UserSession); ok && current.ID == sessionID {
+		clearSessionCookie(w)
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/settings/devices?revoked=1", http.StatusSeeOther)
+}
+
+// sessionUser returns the member the session cookie belongs to. When there
+// is none it sends them to the login page and returns false.
+func (uc *UserController) sessionUser(w http.ResponseWriter, r *http.Request) (*entity.User, bool) {
+	cookie, err := r.Cookie("session_token")
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, false
+	}
+	user, err := uc.postService.GetUserFromSessionToken(r.Context(), cookie.Value)
+	if err != nil || user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, false
+	}
+	return user, true
+}
+
+// renderSettings shows the settings page with extra merged into its data.
+func (uc *UserController) renderSettings(w http.ResponseWriter, statusCode int, user *entity.User, extra map[string]interface{}) {
+	settings := uc.userService.Settings()
+	data := map[string]interface{}{
+		"user":            user,
+		"bio":             user.Bio,
+		"newUserName":     user.UserName,
+		"nextNameChange":  uc.userService.NextUserNameChange(user),
+		"maxBioLength":    usecase.MaxBioLength,
+		"avatarSize":      settings.AvatarSize,
+		"username":        user.UserName,
+		"isAuthenticated": true,
+	}
+	for key, value := range extra {
+		data[key] = value
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(statusCode)
+	if err := uc.templates.ExecuteTemplate(w, "settings.html", data); err != nil {
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
+
+// readAvatarUpload returns the file sent in the "avatar" field, or nil when
+// none was chosen.
+func readAvatarUpload(r *http.Request) (*usecase.ImageUpload, error) {
+	if r.MultipartForm == nil || len(r.MultipartForm.File["avatar"]) == 0 {
+		return nil, nil
+	}
+
+	header := r.MultipartForm.File["avatar"][0]
+	if header.Size == 0 {
+		return nil, nil
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, errors.New("could not read the avatar")
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, errors.New("could not read the avatar")
+	}
+	return &usecase.ImageUpload{Name: header.Filename, Data: data}, nil
+}
+
+// isProfileInputError reports whether err is a problem with what the member
+// entered rather than with the server.
+func isProfileInputError(err error) bool {
+	for _, target := range []error{
+		usecase.ErrBioTooLong, usecase.ErrUserNameCooldown, usecase.ErrUserNameUnchanged,
+		usecase.ErrUserNameLength, usecase.ErrUserNameCharacters, usecase.ErrNameTaken,
+		usecase.ErrUnsupportedImage, usecase.ErrImageTooLarge, usecase.ErrImageDimensions, usecase.ErrInvalidImage,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// showPageError shows the error of loading one page of a profile tab.
+func (uc *UserController) showPageError(w http.ResponseWriter, err error) {
+	if errors.Is(err, entity.ErrInvalidCursor) {
+		uc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid page",
+		})
+		return
+	}
+	uc.ShowErrorPage(w, ErrorMessage{
+		StatusCode: http.StatusInternalServerError,
+		Error:      "Something went wrong while loading the profile",
+	})
+}
+
+func (uc *UserController) renderTemplate(w http.ResponseWriter, template string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := uc.templates.ExecuteTemplate(w, template, data)
+	if err != nil {
+		uc.ShowErrorPage(w, ErrorMessage{
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Error rendering page",
+		})
+	}
+}
+
+func (uc *UserController) ShowErrorPage(w http.ResponseWriter, data ErrorMessage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(data.StatusCode)
+	err := uc.templates.ExecuteTemplate(w, "error.html", data)
+	if err != nil {
+		http.Error(w, data.Error, data.StatusCode)
+	}
+}
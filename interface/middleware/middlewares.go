@@ -3,8 +3,11 @@ package middleware
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
+	"strings"
 
+	"forum/domain/entity"
 	"forum/usecase"
 )
 
@@ -16,34 +19,79 @@ func NewAuthMiddleware(authService *usecase.AuthService) *AuthMiddleware {
 	return &AuthMiddleware{authService: authService}
 }
 
-func (m *AuthMiddleware) VerifiedAuth(next http.HandlerFunc) http.HandlerFunc {
+// Auth lets only signed in users through, whether or not they verified
+// their email address.
+func (m *AuthMiddleware) Auth(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("session_token")
-		if err != nil {
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
+		session, ok := m.authenticate(w, r)
+		if !ok {
 			return
 		}
+		// Add user to request context
+		ctx := context.WithValue(r.Context(), "session", session)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		// Validate session
-		session, err := m.authService.ValidateSession(cookie.Value)
-		if err != nil {
-			http.SetCookie(w, &http.Cookie{
-				Name:     "session_token",
-				Value:    "",
-				Path:     "/",
-				MaxAge:   -1,
-				HttpOnly: true,
-				Secure:   false,
-			})
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
+// VerifiedAuth lets only signed in users through. Users who did not verify
+// their email address yet can read, but anything that changes data sends
+// them to the page that asks them to verify it.
+func (m *AuthMiddleware) VerifiedAuth(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, ok := m.authenticate(w, r)
+		if !ok {
 			return
 		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			verified, err := m.authService.IsEmailVerified(r.Context(), session.UserID)
+			if err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if !verified {
+				http.Redirect(w, r, "/verify-email", http.StatusSeeOther)
+				return
+			}
+		}
+
 		// Add user to request context
 		ctx := context.WithValue(r.Context(), "session", session)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// authenticate returns the session of the request. Without a valid one it
+// sends the browser to the login page and returns false.
+func (m *AuthMiddleware) authenticate(w http.ResponseWriter, r *http.Request) (*entity.UserSession, bool) {
+	cookie, err := r.Cookie("session_token")
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, false
+	}
+
+	// Validate session
+	session, err := m.authService.ValidateSession(r.Context(), cookie.Value)
+	if err != nil && r.Context().Err() != nil {
+		// The lookup was cut short, the session may still be valid.
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if err != nil {
+		http.SetCookie(w, &http.Cookie{
+			Name:     "session_token",
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   false,
+		})
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, false
+	}
+	return session, true
+}
+
 func (m *AuthMiddleware) GuestOnly(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		cookie, err := r.Cookie("session_token")
@@ -53,7 +101,11 @@ func (m *AuthMiddleware) GuestOnly(next http.HandlerFunc) http.HandlerFunc {
 		}
 
 		// Validate session
-		_, err = m.authService.ValidateSession(cookie.Value)
+		_, err = m.authService.ValidateSession(r.Context(), cookie.Value)
+		if err != nil && r.Context().Err() != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
 		if err != nil {
 			http.SetCookie(w, &http.Cookie{
 				Name:     "session_token",
@@ -71,6 +123,34 @@ func (m *AuthMiddleware) GuestOnly(next http.HandlerFunc) http.HandlerFunc {
 	})
 }
 
+// Track records when and from where a signed in user was last seen. Static
+// files and uploads are left out, pages are enough to tell.
+func (m *AuthMiddleware) Track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session_token")
+		if err != nil || strings.HasPrefix(r.URL.Path, "/static/") || strings.HasPrefix(r.URL.Path, "/uploads/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if session, err := m.authService.ValidateSession(r.Context(), cookie.Value); err == nil {
+			if err := m.authService.TouchSession(r.Context(), session, clientIP(r)); err != nil {
+				log.Printf("Failed to record the use of session %s: %v", session.ID, err)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the address the request came from, without its port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func (m *AuthMiddleware) Log(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("--> MethodType[ %s ] | Path[ %s ]", r.Method, r.URL.Path)
@@ -0,0 +1,111 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"forum/domain/entity"
+	"forum/infrastructure/repository/memory"
+	"forum/interface/middleware"
+	"forum/usecase"
+)
+
+// signedIn returns the middleware over a member who is signed in with the
+// returned session token and verified their email when verified is true.
+func signedIn(t *testing.T, verified bool) (*middleware.AuthMiddleware, string) {
+	t.Helper()
+	ctx := context.Background()
+	repos := memory.NewMemoryRepositories(5)
+	auth := usecase.NewAuthService(repos.User, repos.Session, 0)
+	user, err := auth.Signup(ctx, "alice", "alice@example.com", "secret12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified {
+		if err := repos.User.MarkEmailVerified(ctx, user.ID, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	token, _, err := auth.Login(ctx, "alice@example.com", "secret12", usecase.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return middleware.NewAuthMiddleware(auth), token
+}
+
+func TestVerifiedAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		verified bool
+		cookie   string
+		// wantLocation is where the browser is sent instead of the page, or
+		// "" when the page is served.
+		wantLocation string
+	}{
+		{name: "unverified reads", method: http.MethodGet, cookie: "session"},
+		{name: "unverified checks a page", method: http.MethodHead, cookie: "session"},
+		{name: "unverified posts", method: http.MethodPost, cookie: "session", wantLocation: "/verify-email"},
+		{name: "unverified deletes", method: http.MethodDelete, cookie: "session", wantLocation: "/verify-email"},
+		{name: "verified reads", method: http.MethodGet, verified: true, cookie: "session"},
+		{name: "verified posts", method: http.MethodPost, verified: true, cookie: "session"},
+		{name: "signed out reads", method: http.MethodGet, wantLocation: "/login"},
+		{name: "signed out posts", method: http.MethodPost, wantLocation: "/login"},
+		{name: "unknown session posts", method: http.MethodPost, cookie: "unknown", wantLocation: "/login"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, token := signedIn(t, tt.verified)
+			var session *entity.UserSession
+			served := false
+			handler := m.VerifiedAuth(func(w http.ResponseWriter, r *http.Request) {
+				served = true
+				session, _ = r.Context().Value("session").(*entity.UserSession)
+			})
+
+			req := httptest.NewRequest(tt.method, "/post/create", nil)
+			switch tt.cookie {
+			case "session":
+				req.AddCookie(&http.Cookie{Name: "session_token", Value: token})
+			case "unknown":
+				req.AddCookie(&http.Cookie{Name: "session_token", Value: "unknown"})
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if tt.wantLocation == "" {
+				if !served || session == nil {
+					t.Fatalf("%s was not served with the session (status %d, location %q)",
+						tt.method, rec.Code, rec.Header().Get("Location"))
+				}
+				return
+			}
+			if served {
+				t.Fatalf("%s was served, want a redirect to %s", tt.method, tt.wantLocation)
+			}
+			if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != tt.wantLocation {
+				t.Errorf("got status %d to %q, want %d to %q",
+					rec.Code, rec.Header().Get("Location"), http.StatusSeeOther, tt.wantLocation)
+			}
+		})
+	}
+}
+
+// TestAuthLetsUnverifiedPost checks that pages behind Auth, such as the
+// ones to verify the email, stay open to members who did not verify it.
+func TestAuthLetsUnverifiedPost(t *testing.T) {
+	m, token := signedIn(t, false)
+	served := false
+	handler := m.Auth(func(w http.ResponseWriter, r *http.Request) { served = true })
+
+	req := httptest.NewRequest(http.MethodPost, "/verify-email/resend", nil)
+	req.AddCookie(&http.Cookie{Name: "session_token", Value: token})
+	handler(httptest.NewRecorder(), req)
+	if !served {
+		t.Error("Auth did not serve a POST of an unverified member")
+	}
+}
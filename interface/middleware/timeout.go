@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"forum/interface/controller"
+)
+
+// TimeoutMiddleware gives every request a deadline. Repositories run their
+// queries with the request context, so a query stops as soon as the deadline
+// passes or the client goes away.
+type TimeoutMiddleware struct {
+	timeout   time.Duration
+	templates *template.Template
+}
+
+func NewTimeoutMiddleware(timeout time.Duration, templates *template.Template) *TimeoutMiddleware {
+	return &TimeoutMiddleware{timeout: timeout, templates: templates}
+}
+
+// Timeout runs next with a deadline. Handlers show a 500 page when a query
+// fails; if the query failed because the request context ended, that page is
+// replaced with a 504 for a passed deadline or a 503 for a cancelled request.
+// A timeout of zero or less disables the deadline.
+func (m *TimeoutMiddleware) Timeout(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if m.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, m.timeout)
+			defer cancel()
+		}
+
+		next.ServeHTTP(&timeoutWriter{ResponseWriter: w, ctx: ctx, m: m, path: r.URL.Path}, r.WithContext(ctx))
+	})
+}
+
+func (m *TimeoutMiddleware) showErrorPage(w http.ResponseWriter, cause error) {
+	data := controller.ErrorMessage{
+		StatusCode: http.StatusServiceUnavailable,
+		Error:      "The request was cancelled, please try again",
+	}
+	if errors.Is(cause, context.DeadlineExceeded) {
+		data = controller.ErrorMessage{
+			StatusCode: http.StatusGatewayTimeout,
+			Error:      "The server took too long to answer, please try again",
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(data.StatusCode)
+	if m.templates == nil || m.templates.ExecuteTemplate(w, "error.html", data) != nil {
+		http.Error(w, data.Error, data.StatusCode)
+	}
+}
+
+// timeoutWriter swaps a server error written after the request context ended
+// for the timeout page, and drops whatever the handler writes after it.
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	m           *TimeoutMiddleware
+	path        string
+	wroteHeader bool
+	replaced    bool
+}
+
+func (tw *timeoutWriter) WriteHeader(statusCode int) {
+	if tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+
+	if statusCode >= http.StatusInternalServerError && tw.ctx.Err() != nil {
+		tw.replaced = true
+		log.Printf("Request for %s ended early: %v", tw.path, tw.ctx.Err())
+		tw.m.showErrorPage(tw.ResponseWriter, tw.ctx.Err())
+		return
+	}
+	tw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	if !tw.wroteHeader {
+		tw.WriteHeader(http.StatusOK)
+	}
+	if tw.replaced {
+		return len(b), nil
+	}
+	return tw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
@@ -1,53 +1,98 @@
 package usecase
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"log"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
 	"forum/domain/repository"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// sessionDuration is how long a login lasts.
+const sessionDuration = 24 * time.Hour
+
+// sessionTouchInterval is how often the last use of a session is written
+// down, so that browsing does not write to the database on every page.
+const sessionTouchInterval = time.Minute
+
+// maxUserAgentLength is how much of a user agent a session keeps.
+const maxUserAgentLength = 512
+
+// ClientInfo is what a session remembers about the device it was opened
+// from.
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// newSession returns a session for userID on the device client.
+func newSession(userID uuid.UUID, token string, client ClientInfo) *entity.UserSession {
+	userAgent := client.UserAgent
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+		for !utf8.ValidString(userAgent) {
+			userAgent = userAgent[:len(userAgent)-1]
+		}
+	}
+	return &entity.UserSession{
+		UserID:       userID,
+		SessionToken: token,
+		UserAgent:    userAgent,
+		IPAddress:    client.IPAddress,
+		ExpiresAt:    time.Now().Add(sessionDuration),
+	}
+}
+
+var ErrPasswordLength = errors.New("password should be between 6 and 64 characters")
+
 type AuthService struct {
 	userRepo    repository.UserRepository
 	sessionRepo repository.UserSessionRepository
+	// nameReservation is how long a name that someone gave up stays
+	// out of reach of new accounts.
+	nameReservation time.Duration
 }
 
-func NewAuthService(userRepo repository.UserRepository, sessionRepo repository.UserSessionRepository) *AuthService {
+func NewAuthService(userRepo repository.UserRepository, sessionRepo repository.UserSessionRepository, nameReservation time.Duration) *AuthService {
 	return &AuthService{
-		userRepo:    userRepo,
-		sessionRepo: sessionRepo,
+		userRepo:        userRepo,
+		sessionRepo:     sessionRepo,
+		nameReservation: nameReservation,
 	}
 }
 
-func (s *AuthService) Signup(name, email, password string) (*entity.User, error) {
+func (s *AuthService) Signup(ctx context.Context, name, email, password string) (*entity.User, error) {
 	email = strings.ToLower(strings.TrimSpace(email))
 	if !isValidEmail(email) {
 		return nil, errors.New("invalid email format. Make sure it follows the pattern: name@domain.com")
 	}
 
-	_, err := s.userRepo.GetByEmail(email)
+	_, err := s.userRepo.GetByEmail(ctx, email)
 	if err == nil {
 		return nil, errors.New("user already exists")
 	}
 
-	if len(name) < 3 || len(name) > 9 {
-		return nil, errors.New("name should be between 3 and 9 characters")
+	name, err = validateUserName(name)
+	if err != nil {
+		return nil, err
 	}
-	name = strings.TrimSpace(name)
-	if !isValidName(name) {
-		return nil, errors.New("name should contain only letters and numbers")
+	if err := checkUserNameAvailable(ctx, s.userRepo, name, uuid.Nil, s.nameReservation); err != nil {
+		return nil, err
 	}
 
-	password = strings.TrimSpace(password)
-	if len(password) < 6 || len(password) > 64 {
-		return nil, errors.New("password should be between 4 and 64 characters")
+	password, err = validatePassword(password)
+	if err != nil {
+		return nil, err
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -62,20 +107,25 @@ func (s *AuthService) Signup(name, email, password string) (*entity.User, error)
 		CreatedAt:    time.Now(),
 	}
 
-	err = s.userRepo.Create(user)
-	if err != nil {
+	// Someone may have taken the name since it was checked.
+	err = s.userRepo.Create(ctx, user)
+	if errors.Is(err, custom_errors.ErrNameTaken) {
+		return nil, ErrNameTaken
+	} else if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (s *AuthService) Login(email, password string) (string, *entity.User, error) {
+// Login signs a user in on the device client. Their sessions on other
+// devices stay open.
+func (s *AuthService) Login(ctx context.Context, email, password string, client ClientInfo) (string, *entity.User, error) {
 	email = strings.ToLower(strings.TrimSpace(email))
 	if !isValidEmail(email) {
 		return "", nil, errors.New("invalid email format. Make sure it follows the pattern: name@domain.com")
 	}
 
-	user, err := s.userRepo.GetByEmail(email)
+	user, err := s.userRepo.GetByEmail(ctx, email)
 	if err != nil {
 		return "", nil, errors.New("invalid credentials")
 	}
@@ -85,21 +135,16 @@ func (s *AuthService) Login(email, password string) (string, *entity.User, error
 		return "", nil, errors.New("incorrect password")
 	}
 
-	s.sessionRepo.DeleteAllUserSessions(user.ID)
+	if err := s.sessionRepo.DeleteExpiredUserSessions(ctx, user.ID, time.Now()); err != nil {
+		log.Printf("Warning: Failed to delete the expired sessions of %s: %v", user.ID, err)
+	}
 
-	token, err := s.generateSessionToken()
+	token, err := generateToken()
 	if err != nil {
 		return "", nil, err
 	}
 
-	session := &entity.UserSession{
-		UserID:       user.ID,
-		SessionToken: token,
-		ExpiresAt:    time.Now().Add(24 * time.Hour),
-		CreatedAt:    time.Now(),
-	}
-
-	err = s.sessionRepo.Create(session)
+	err = s.sessionRepo.Create(ctx, newSession(user.ID, token, client))
 	if err != nil {
 		return "", nil, err
 	}
@@ -107,12 +152,18 @@ func (s *AuthService) Login(email, password string) (string, *entity.User, error
 	return token, user, nil
 }
 
-func (s *AuthService) Logout(userID uuid.UUID) error {
-	return s.sessionRepo.DeleteAllUserSessions(userID)
+// Logout ends the session with this token. The other devices of the user
+// stay signed in.
+func (s *AuthService) Logout(ctx context.Context, token string) error {
+	session, err := s.sessionRepo.GetByToken(ctx, token)
+	if err != nil || session == nil {
+		return nil
+	}
+	return s.sessionRepo.Delete(ctx, session.ID)
 }
 
-func (s *AuthService) ValidateSession(token string) (*entity.UserSession, error) {
-	session, err := s.sessionRepo.GetByToken(token)
+func (s *AuthService) ValidateSession(ctx context.Context, token string) (*entity.UserSession, error) {
+	session, err := s.sessionRepo.GetByToken(ctx, token)
 	if err != nil || session == nil {
 		return nil, errors.New("invalid session")
 	}
@@ -122,8 +173,8 @@ func (s *AuthService) ValidateSession(token string) (*entity.UserSession, error)
 	return session, nil
 }
 
-func (s *AuthService) GetUserFromSessionToken(token string) (*entity.User, error) {
-	session, err := s.sessionRepo.GetByToken(token)
+func (s *AuthService) GetUserFromSessionToken(ctx context.Context, token string) (*entity.User, error) {
+	session, err := s.sessionRepo.GetByToken(ctx, token)
 	if err != nil || session == nil {
 		return nil, err
 	}
@@ -132,7 +183,7 @@ func (s *AuthService) GetUserFromSessionToken(token string) (*entity.User, error
 		return nil, errors.New("session expired")
 	}
 
-	user, err := s.userRepo.GetByID(session.UserID)
+	user, err := s.userRepo.GetByID(ctx, session.UserID)
 	if err != nil {
 		return nil, err
 	}
@@ -140,7 +191,47 @@ func (s *AuthService) GetUserFromSessionToken(token string) (*entity.User, error
 	return user, nil
 }
 
-func (s *AuthService) generateSessionToken() (string, error) {
+// TouchSession records that session was just used from ipAddress. Uses
+// closer together than sessionTouchInterval from the same address are not
+// written down.
+func (s *AuthService) TouchSession(ctx context.Context, session *entity.UserSession, ipAddress string) error {
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) < sessionTouchInterval && session.IPAddress == ipAddress {
+		return nil
+	}
+	if err := s.sessionRepo.Touch(ctx, session.ID, now, ipAddress); err != nil {
+		return err
+	}
+	session.LastSeenAt, session.IPAddress = now, ipAddress
+	return nil
+}
+
+// ListSessions returns the devices a user is signed in on, the most recently
+// used first.
+func (s *AuthService) ListSessions(ctx context.Context, userID uuid.UUID) ([]*entity.UserSession, error) {
+	return s.sessionRepo.ListByUserID(ctx, userID, time.Now())
+}
+
+// RevokeSession signs a user out on one of their devices. It returns
+// custom_errors.ErrSessionNotFound when the session is not theirs or is
+// already gone.
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	return s.sessionRepo.DeleteUserSession(ctx, userID, sessionID)
+}
+
+// IsEmailVerified reports whether the user followed the link that verifies
+// their email address.
+func (s *AuthService) IsEmailVerified(ctx context.Context, userID uuid.UUID) (bool, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.IsEmailVerified(), nil
+}
+
+// generateToken returns 32 random bytes as hex, for session tokens and the
+// tokens of emailed links.
+func generateToken() (string, error) {
 	bytes := make([]byte, 32)
 	_, err := rand.Read(bytes)
 	if err != nil {
@@ -148,3 +239,13 @@ func (s *AuthService) generateSessionToken() (string, error) {
 	}
 	return hex.EncodeToString(bytes), nil
 }
+
+// validatePassword checks the length of a new password and returns it
+// trimmed.
+func validatePassword(password string) (string, error) {
+	password = strings.TrimSpace(password)
+	if len(password) < 6 || len(password) > 64 {
+		return "", ErrPasswordLength
+	}
+	return password, nil
+}
@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+	"forum/infrastructure/repository/memory"
+
+	"github.com/google/uuid"
+)
+
+var (
+	laptop = ClientInfo{UserAgent: "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0", IPAddress: "192.0.2.1"}
+	phone  = ClientInfo{UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0) Safari/604.1", IPAddress: "198.51.100.7"}
+)
+
+// newAuthTest returns an AuthService over memory repositories with members
+// alice and bob, whose password is secret12.
+func newAuthTest(t *testing.T) (*AuthService, *repository.Repositories) {
+	t.Helper()
+	repos := memory.NewMemoryRepositories(5)
+	auth := NewAuthService(repos.User, repos.Session, 0)
+	for _, name := range []string{"alice", "bob"} {
+		if _, err := auth.Signup(context.Background(), name, name+"@example.com", "secret12"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return auth, repos
+}
+
+// login signs name in on client and returns the session.
+func login(t *testing.T, auth *AuthService, name string, client ClientInfo) *entity.UserSession {
+	t.Helper()
+	ctx := context.Background()
+	token, _, err := auth.Login(ctx, name+"@example.com", "secret12", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := auth.ValidateSession(ctx, token)
+	if err != nil {
+		t.Fatalf("the session of a new login is not valid: %v", err)
+	}
+	return session
+}
+
+// isOpen reports whether the session can still be used.
+func isOpen(auth *AuthService, session *entity.UserSession) bool {
+	_, err := auth.ValidateSession(context.Background(), session.SessionToken)
+	return err == nil
+}
+
+func TestLoginKeepsOtherSessions(t *testing.T) {
+	ctx := context.Background()
+	auth, repos := newAuthTest(t)
+	onLaptop := login(t, auth, "alice", laptop)
+
+	// A session that ran out before the next login.
+	expired := &entity.UserSession{UserID: onLaptop.UserID, SessionToken: "expired", ExpiresAt: time.Now().Add(-time.Minute)}
+	if err := repos.Session.Create(ctx, expired); err != nil {
+		t.Fatal(err)
+	}
+
+	onPhone := login(t, auth, "alice", phone)
+	if !isOpen(auth, onLaptop) {
+		t.Error("logging in on the phone ended the session on the laptop")
+	}
+	if _, err := repos.Session.GetByToken(ctx, expired.SessionToken); err == nil {
+		t.Error("logging in kept an expired session")
+	}
+
+	sessions, err := auth.ListSessions(ctx, onLaptop.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 || sessions[0].ID != onPhone.ID || sessions[1].ID != onLaptop.ID {
+		t.Fatalf("ListSessions returned %d sessions, want the phone then the laptop", len(sessions))
+	}
+	for i, client := range []ClientInfo{phone, laptop} {
+		if sessions[i].UserAgent != client.UserAgent || sessions[i].IPAddress != client.IPAddress {
+			t.Errorf("session %d is from %q at %s, want %q at %s",
+				i, sessions[i].UserAgent, sessions[i].IPAddress, client.UserAgent, client.IPAddress)
+		}
+	}
+
+	// Logging out ends only the session that logs out.
+	if err := auth.Logout(ctx, onPhone.SessionToken); err != nil {
+		t.Fatal(err)
+	}
+	if isOpen(auth, onPhone) || !isOpen(auth, onLaptop) {
+		t.Errorf("after logging out on the phone, phone open = %v and laptop open = %v; want false and true",
+			isOpen(auth, onPhone), isOpen(auth, onLaptop))
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	tests := []struct {
+		name string
+		// revoke returns the session alice revokes.
+		revoke  func(ctx context.Context, t *testing.T, auth *AuthService, alicePhone, bobLaptop *entity.UserSession) uuid.UUID
+		wantErr error
+	}{
+		{
+			name: "own session on another device",
+			revoke: func(_ context.Context, _ *testing.T, _ *AuthService, alicePhone, _ *entity.UserSession) uuid.UUID {
+				return alicePhone.ID
+			},
+		},
+		{
+			name: "session of another member",
+			revoke: func(_ context.Context, _ *testing.T, _ *AuthService, _, bobLaptop *entity.UserSession) uuid.UUID {
+				return bobLaptop.ID
+			},
+			wantErr: custom_errors.ErrSessionNotFound,
+		},
+		{
+			name: "session already revoked",
+			revoke: func(ctx context.Context, t *testing.T, auth *AuthService, alicePhone, _ *entity.UserSession) uuid.UUID {
+				if err := auth.RevokeSession(ctx, alicePhone.UserID, alicePhone.ID); err != nil {
+					t.Fatal(err)
+				}
+				return alicePhone.ID
+			},
+			wantErr: custom_errors.ErrSessionNotFound,
+		},
+		{
+			name: "unknown session",
+			revoke: func(context.Context, *testing.T, *AuthService, *entity.UserSession, *entity.UserSession) uuid.UUID {
+				return uuid.New()
+			},
+			wantErr: custom_errors.ErrSessionNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			auth, _ := newAuthTest(t)
+			aliceLaptop := login(t, auth, "alice", laptop)
+			alicePhone := login(t, auth, "alice", phone)
+			bobLaptop := login(t, auth, "bob", laptop)
+
+			sessionID := tt.revoke(ctx, t, auth, alicePhone, bobLaptop)
+			err := auth.RevokeSession(ctx, aliceLaptop.UserID, sessionID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RevokeSession returned %v, want %v", err, tt.wantErr)
+			}
+
+			if !isOpen(auth, aliceLaptop) {
+				t.Error("the session alice revoked from was ended")
+			}
+			if !isOpen(auth, bobLaptop) {
+				t.Error("the session of bob was ended")
+			}
+			if tt.wantErr == nil && isOpen(auth, alicePhone) {
+				t.Error("the revoked session is still open")
+			}
+		})
+	}
+}
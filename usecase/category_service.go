@@ -1,6 +1,7 @@
 package usecase
 
 import (
+	"context"
 	"errors"
 	"strings"
 
@@ -29,13 +30,13 @@ func NewCategoryService(
 	}
 }
 
-func (cs *CategoryService) GetCategoryByName(name string) (*entity.Category, error) {
+func (cs *CategoryService) GetCategoryByName(ctx context.Context, name string) (*entity.Category, error) {
 	name = strings.TrimSpace(name)
 	if name == "" {
 		return nil, errors.New("category name cannot be empty")
 	}
 
-	category, err := cs.categoryRepo.GetByName(name)
+	category, err := cs.categoryRepo.GetByName(ctx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -43,6 +44,6 @@ func (cs *CategoryService) GetCategoryByName(name string) (*entity.Category, err
 	return category, nil
 }
 
-func (cs *CategoryService) GetAllCategories() ([]*entity.Category, error) {
-	return cs.categoryRepo.GetAll()
+func (cs *CategoryService) GetAllCategories(ctx context.Context) ([]*entity.Category, error) {
+	return cs.categoryRepo.GetAll(ctx)
 }
\ No newline at end of file
@@ -1,12 +1,14 @@
 package usecase
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"sync"
 	"time"
 
 	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
 	"forum/domain/repository"
 
 	"github.com/google/uuid"
@@ -32,11 +34,14 @@ type CommentService struct {
 	commentReactionRepo repository.CommentReactionRepository
 	sessionRepo         repository.UserSessionRepository
 	rateLimiter         *CommentRateLimiter
+	markdown            *MarkdownRenderer
+	maxDepth            int
 }
 
 func NewCommentService(userRepo repository.UserRepository, commentRepo repository.CommentRepository,
 	postRepo repository.PostRepository, sessionRepo repository.UserSessionRepository,
-	commentReactionRepo repository.CommentReactionRepository, commentRateLimit *CommentRateLimiter,
+	commentReactionRepo repository.CommentReactionRepository,
+	commentRateLimit *CommentRateLimiter, markdown *MarkdownRenderer, maxDepth int,
 ) *CommentService {
 	return &CommentService{
 		userRepo:            userRepo,
@@ -45,6 +50,8 @@ func NewCommentService(userRepo repository.UserRepository, commentRepo repositor
 		commentReactionRepo: commentReactionRepo,
 		sessionRepo:         sessionRepo,
 		rateLimiter:         commentRateLimit,
+		markdown:            markdown,
+		maxDepth:            maxDepth,
 	}
 }
 
@@ -63,12 +70,14 @@ func (cs *CommentRateLimiter) CanUserComment(userID uuid.UUID) bool {
 	return false
 }
 
-func (cs *CommentService) CreateComment(postID *uuid.UUID, token, content string) (*entity.Comment, error) {
-	session, err := cs.sessionRepo.GetByToken(token)
+// CreateComment adds a comment to a post. When parentID is set the comment is
+// a reply to another comment of the same post.
+func (cs *CommentService) CreateComment(ctx context.Context, postID, parentID *uuid.UUID, token, content string) (*entity.Comment, error) {
+	session, err := cs.sessionRepo.GetByToken(ctx, token)
 	if err != nil {
 		return nil, err
 	}
-	user, err := cs.userRepo.GetByID(session.UserID)
+	user, err := cs.userRepo.GetByID(ctx, session.UserID)
 	if err != nil {
 		return nil, err
 	}
@@ -81,24 +90,31 @@ func (cs *CommentService) CreateComment(postID *uuid.UUID, token, content string
 		return nil, errors.New("you can't create a comment now, wait a bit")
 	}
 
-	content = strings.TrimSpace(content)
-	if len(content) > 100 {
-		return nil, errors.New("comment length excceds 250 characters")
-	} else if content == "" {
-		return nil, errors.New("comment should have at least 1 character")
+	content, err = validateCommentContent(content)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err = cs.postRepo.GetByID(*postID)
+	_, err = cs.postRepo.GetByID(ctx, *postID)
 	if err != nil {
 		return nil, errors.New("post not found")
 	}
+
+	if parentID != nil {
+		err = cs.checkCanReply(ctx, *postID, *parentID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	comment := &entity.Comment{
-		Content: content,
-		UserID:  user.ID,
-		PostID:  *postID,
+		Content:  content,
+		UserID:   user.ID,
+		PostID:   *postID,
+		ParentID: parentID,
 	}
 
-	err = cs.commentRepo.Create(comment)
+	err = cs.commentRepo.Create(ctx, comment)
 	if err != nil {
 		return nil, err
 	}
@@ -110,60 +126,138 @@ func (cs *CommentService) CreateComment(postID *uuid.UUID, token, content string
 	return comment, nil
 }
 
-// ReactToComment - Like/dislike a comment with toggle support.
-// Same reaction twice = remove (toggle), different reaction = update.
-// Returns nil when reaction is removed, reaction entity when created/updated.
-func (cs *CommentService) ReactToComment(commentID *uuid.UUID, token string, reaction bool) (*entity.CommentReaction, error) {
-	session, err := cs.sessionRepo.GetByToken(token)
+// checkCanReply makes sure the parent comment belongs to the post, is still
+// there and is not already nested at the maximum depth.
+func (cs *CommentService) checkCanReply(ctx context.Context, postID, parentID uuid.UUID) error {
+	parent, err := cs.commentRepo.GetByID(ctx, parentID)
+	if err != nil {
+		return errors.New("parent comment not found")
+	}
+	if parent.PostID != postID {
+		return errors.New("parent comment not found")
+	}
+	if parent.IsDeleted() {
+		return errors.New("you can't reply to a deleted comment")
+	}
+
+	// The depth of the parent is the number of its ancestors; stop counting as
+	// soon as the limit is reached.
+	depth := 0
+	for ancestor := parent; ancestor.ParentID != nil && depth < cs.maxDepth; depth++ {
+		ancestor, err = cs.commentRepo.GetByID(ctx, *ancestor.ParentID)
+		if err != nil {
+			return err
+		}
+	}
+	if depth >= cs.maxDepth {
+		return errors.New("this thread can't be nested any deeper")
+	}
+	return nil
+}
+
+func validateCommentContent(content string) (string, error) {
+	content = strings.TrimSpace(entity.StripHighlight(content))
+	if len(content) > 100 {
+		return "", errors.New("comment length excceds 250 characters")
+	} else if content == "" {
+		return "", errors.New("comment should have at least 1 character")
+	}
+	return content, nil
+}
+
+// getOwnComment loads a comment and makes sure it belongs to the session's user
+// and has not been deleted yet.
+func (cs *CommentService) getOwnComment(ctx context.Context, commentID uuid.UUID, token string) (*entity.Comment, error) {
+	session, err := cs.sessionRepo.GetByToken(ctx, token)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = cs.userRepo.GetByID(session.UserID)
+	comment, err := cs.commentRepo.GetByID(ctx, commentID)
 	if err != nil {
 		return nil, err
 	}
+	if comment.UserID != session.UserID {
+		return nil, ErrUnauthorizedAccess
+	}
+	if comment.IsDeleted() {
+		return nil, custom_errors.ErrCommentNotFound
+	}
+	return comment, nil
+}
 
-	_, err = cs.commentRepo.GetByID(*commentID)
+// UpdateComment changes the content of a comment written by the session's user.
+func (cs *CommentService) UpdateComment(ctx context.Context, commentID uuid.UUID, token, content string) (*entity.Comment, error) {
+	comment, err := cs.getOwnComment(ctx, commentID, token)
 	if err != nil {
-		return nil, errors.New("comment not found")
+		return nil, err
 	}
 
-	cr, err := cs.commentReactionRepo.GetByUserAndComment(session.UserID, *commentID)
-	if err == nil {
-		// user reacted, should update the reaction
-		if cr.Reaction == reaction {
-			cs.commentReactionRepo.Delete(cr.ID)
-			return cr, nil
-		} else if cr.Reaction != reaction {
-			cr.Reaction = reaction
-			cr.CreatedAt = time.Now()
-			cs.commentReactionRepo.Update(cr)
-			return cr, nil
-		}
+	content, err = validateCommentContent(content)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Content == content {
+		return comment, nil
+	}
+
+	comment.Content = content
+	err = cs.commentRepo.Update(ctx, comment)
+	if err != nil {
+		return nil, err
 	}
-	// no reaction of the user on the post, need to create a reaction
-	commentReaction := &entity.CommentReaction{
-		UserID:    session.UserID,
-		CommentID: *commentID,
-		Reaction:  reaction,
-		CreatedAt: time.Now(),
+	return comment, nil
+}
+
+// DeleteComment replaces a comment written by the session's user with a
+// "[deleted]" tombstone.
+func (cs *CommentService) DeleteComment(ctx context.Context, commentID uuid.UUID, token string) (*entity.Comment, error) {
+	comment, err := cs.getOwnComment(ctx, commentID, token)
+	if err != nil {
+		return nil, err
 	}
-	err = cs.commentReactionRepo.Create(commentReaction)
+
+	err = cs.commentRepo.SoftDelete(ctx, commentID)
 	if err != nil {
 		return nil, err
 	}
-	return commentReaction, nil
+	cs.markdown.Forget("comment:" + commentID.String())
+	return comment, nil
+}
+
+// ReactToComment - Like/dislike a comment with toggle support.
+// Same reaction twice = remove (toggle), different reaction = update.
+// Returns the user's reaction and the comment's counts after the change.
+func (cs *CommentService) ReactToComment(ctx context.Context, commentID *uuid.UUID, token string, reaction bool) (*entity.ReactionState, error) {
+	session, err := cs.sessionRepo.GetByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = cs.userRepo.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := cs.commentRepo.GetByID(ctx, *commentID)
+	if err != nil {
+		return nil, errors.New("comment not found")
+	}
+	if comment.IsDeleted() {
+		return nil, errors.New("comment has been deleted")
+	}
+
+	return cs.commentReactionRepo.Toggle(ctx, session.UserID, *commentID, reaction)
 }
 
 // temperoraly until we have a proper middleware
-func (s *CommentService) GetUserFromSessionToken(token string) (*entity.User, error) {
-	session, err := s.sessionRepo.GetByToken(token)
+func (s *CommentService) GetUserFromSessionToken(ctx context.Context, token string) (*entity.User, error) {
+	session, err := s.sessionRepo.GetByToken(ctx, token)
 	if err != nil || session == nil {
 		return nil, err
 	}
 
-	user, err := s.userRepo.GetByID(session.UserID)
+	user, err := s.userRepo.GetByID(ctx, session.UserID)
 	if err != nil {
 		return nil, err
 	}
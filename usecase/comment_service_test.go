@@ -0,0 +1,304 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+)
+
+// commentTest is a CommentService next to the PostService of newPostTest,
+// with a post by alice to comment on.
+type commentTest struct {
+	*postTest
+	comments *CommentService
+	post     *entity.Post
+}
+
+func newCommentTest(t *testing.T, maxDepth int) *commentTest {
+	t.Helper()
+	pt := newPostTest(t, UploadLimits{})
+	ct := &commentTest{postTest: pt, post: &entity.Post{Title: "hello", Content: "first post", UserID: pt.alice.ID}}
+	if err := pt.repos.Post.Create(context.Background(), ct.post); err != nil {
+		t.Fatal(err)
+	}
+	ct.comments = NewCommentService(pt.repos.User, pt.repos.Comment, pt.repos.Post, pt.repos.Session,
+		pt.repos.CommentReaction, NewCommentRateLimiter(), pt.markdown, maxDepth)
+	return ct
+}
+
+// addComment stores a comment by user on the post, replying to parent when
+// it is set, without going through the rate limit.
+func (ct *commentTest) addComment(t *testing.T, user *entity.User, parent *entity.Comment) *entity.Comment {
+	t.Helper()
+	comment := &entity.Comment{Content: "comment by " + user.UserName, UserID: user.ID, PostID: ct.post.ID}
+	if parent != nil {
+		comment.ParentID = &parent.ID
+	}
+	if err := ct.repos.Comment.Create(context.Background(), comment); err != nil {
+		t.Fatal(err)
+	}
+	return comment
+}
+
+func TestEditAndDeleteComment(t *testing.T) {
+	tests := []struct {
+		name string
+		// change edits or deletes the comment of alice.
+		change      func(ctx context.Context, cs *CommentService, comment *entity.Comment) error
+		wantErr     error
+		wantContent string
+		wantDeleted bool
+	}{
+		{
+			name: "edited by the author",
+			change: func(ctx context.Context, cs *CommentService, comment *entity.Comment) error {
+				_, err := cs.UpdateComment(ctx, comment.ID, "alice", " edited ")
+				return err
+			},
+			wantContent: "edited",
+		},
+		{
+			name: "deleted by the author",
+			change: func(ctx context.Context, cs *CommentService, comment *entity.Comment) error {
+				_, err := cs.DeleteComment(ctx, comment.ID, "alice")
+				return err
+			},
+			wantDeleted: true,
+		},
+		{
+			name: "edited by another member",
+			change: func(ctx context.Context, cs *CommentService, comment *entity.Comment) error {
+				_, err := cs.UpdateComment(ctx, comment.ID, "bob", "edited")
+				return err
+			},
+			wantErr: ErrUnauthorizedAccess,
+		},
+		{
+			name: "deleted by another member",
+			change: func(ctx context.Context, cs *CommentService, comment *entity.Comment) error {
+				_, err := cs.DeleteComment(ctx, comment.ID, "bob")
+				return err
+			},
+			wantErr: ErrUnauthorizedAccess,
+		},
+		{
+			name: "deleted by a moderator",
+			change: func(ctx context.Context, cs *CommentService, comment *entity.Comment) error {
+				_, err := cs.DeleteComment(ctx, comment.ID, "mod")
+				return err
+			},
+			wantErr: ErrUnauthorizedAccess,
+		},
+		{
+			name: "edited after it was deleted",
+			change: func(ctx context.Context, cs *CommentService, comment *entity.Comment) error {
+				if _, err := cs.DeleteComment(ctx, comment.ID, "alice"); err != nil {
+					return err
+				}
+				_, err := cs.UpdateComment(ctx, comment.ID, "alice", "edited")
+				return err
+			},
+			wantErr: custom_errors.ErrCommentNotFound,
+		},
+		{
+			name: "deleted twice",
+			change: func(ctx context.Context, cs *CommentService, comment *entity.Comment) error {
+				if _, err := cs.DeleteComment(ctx, comment.ID, "alice"); err != nil {
+					return err
+				}
+				_, err := cs.DeleteComment(ctx, comment.ID, "alice")
+				return err
+			},
+			wantErr: custom_errors.ErrCommentNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			ct := newCommentTest(t, 5)
+			comment := ct.addComment(t, ct.alice, nil)
+			reply := ct.addComment(t, ct.bob, comment)
+
+			err := tt.change(ctx, ct.comments, comment)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			stored, err := ct.repos.Comment.GetByID(ctx, comment.ID)
+			if err != nil {
+				t.Fatalf("the comment is gone: %v", err)
+			}
+			switch {
+			case tt.wantDeleted:
+				if !stored.IsDeleted() || stored.Content != "" {
+					t.Errorf("the deleted comment is stored as %q, deleted %v; want an empty tombstone", stored.Content, stored.IsDeleted())
+				}
+			case tt.wantContent != "":
+				if stored.Content != tt.wantContent || !stored.IsEdited() || stored.IsDeleted() {
+					t.Errorf("the edited comment is stored as %q, edited %v, deleted %v; want %q, edited",
+						stored.Content, stored.IsEdited(), stored.IsDeleted(), tt.wantContent)
+				}
+			case tt.wantErr == ErrUnauthorizedAccess:
+				if stored.Content != comment.Content || stored.IsEdited() || stored.IsDeleted() {
+					t.Errorf("a refused change left the comment as %q, edited %v, deleted %v",
+						stored.Content, stored.IsEdited(), stored.IsDeleted())
+				}
+			}
+
+			// The thread keeps its shape: the reply still hangs under the
+			// comment, deleted or not.
+			thread, err := ct.repos.Comment.GetByPostIDWithDetails(ctx, ct.post.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(thread) != 2 || thread[0].ID != comment.ID || thread[1].ID != reply.ID ||
+				thread[1].ParentID == nil || *thread[1].ParentID != comment.ID {
+				t.Errorf("the thread has %d comments, want the comment and the reply under it", len(thread))
+			}
+		})
+	}
+}
+
+func TestReplyDepth(t *testing.T) {
+	// The memory repositories thread comments five deep, like the service.
+	const maxDepth = 5
+	tests := []struct {
+		name string
+		// parent returns the comment bob replies to.
+		parent  func(t *testing.T, ct *commentTest) *entity.Comment
+		wantErr string
+	}{
+		{
+			name: "reply to a comment",
+			parent: func(t *testing.T, ct *commentTest) *entity.Comment {
+				return ct.addComment(t, ct.alice, nil)
+			},
+		},
+		{
+			name: "reply one level above the limit",
+			parent: func(t *testing.T, ct *commentTest) *entity.Comment {
+				return chain(t, ct, maxDepth-1)
+			},
+		},
+		{
+			name: "reply at the limit",
+			parent: func(t *testing.T, ct *commentTest) *entity.Comment {
+				return chain(t, ct, maxDepth)
+			},
+			wantErr: "this thread can't be nested any deeper",
+		},
+		{
+			name: "reply to a deleted comment",
+			parent: func(t *testing.T, ct *commentTest) *entity.Comment {
+				comment := ct.addComment(t, ct.alice, nil)
+				if err := ct.repos.Comment.SoftDelete(context.Background(), comment.ID); err != nil {
+					t.Fatal(err)
+				}
+				return comment
+			},
+			wantErr: "you can't reply to a deleted comment",
+		},
+		{
+			name: "reply to a comment on another post",
+			parent: func(t *testing.T, ct *commentTest) *entity.Comment {
+				other := &entity.Post{Title: "other", Content: "another post", UserID: ct.alice.ID}
+				if err := ct.repos.Post.Create(context.Background(), other); err != nil {
+					t.Fatal(err)
+				}
+				comment := &entity.Comment{Content: "elsewhere", UserID: ct.alice.ID, PostID: other.ID}
+				if err := ct.repos.Comment.Create(context.Background(), comment); err != nil {
+					t.Fatal(err)
+				}
+				return comment
+			},
+			wantErr: "parent comment not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			ct := newCommentTest(t, maxDepth)
+			parent := tt.parent(t, ct)
+
+			reply, err := ct.comments.CreateComment(ctx, &ct.post.ID, &parent.ID, "bob", "a reply")
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("got error %v, want none", err)
+				}
+				if reply.ParentID == nil || *reply.ParentID != parent.ID {
+					t.Errorf("the reply hangs under %v, want %v", reply.ParentID, parent.ID)
+				}
+			}
+
+			// The reply form is shown exactly where a reply is accepted.
+			thread, err := ct.repos.Comment.GetByPostIDWithDetails(ctx, ct.post.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, comment := range thread {
+				if comment.ID == parent.ID && comment.CanReply != (tt.wantErr == "") {
+					t.Errorf("the thread offers a reply form %v, want %v", comment.CanReply, tt.wantErr == "")
+				}
+			}
+		})
+	}
+}
+
+// chain adds a thread of replies to a new comment and returns the last one,
+// which has depth ancestors.
+func chain(t *testing.T, ct *commentTest, depth int) *entity.Comment {
+	t.Helper()
+	comment := ct.addComment(t, ct.alice, nil)
+	for i := 0; i < depth; i++ {
+		comment = ct.addComment(t, ct.alice, comment)
+	}
+	return comment
+}
+
+// cached reports whether the renderer holds HTML for key.
+func cached(r *MarkdownRenderer, key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.cache[key]
+	return ok
+}
+
+func TestDeleteForgetsRenderedContent(t *testing.T) {
+	ctx := context.Background()
+	ct := newCommentTest(t, 5)
+	kept := ct.addComment(t, ct.alice, nil)
+	deleted := ct.addComment(t, ct.bob, nil)
+	if _, err := ct.posts.GetPost(ctx, ct.post.ID); err != nil {
+		t.Fatal(err)
+	}
+	postKey, keptKey, deletedKey := "post:"+ct.post.ID.String(), "comment:"+kept.ID.String(), "comment:"+deleted.ID.String()
+	if !cached(ct.markdown, postKey) || !cached(ct.markdown, keptKey) || !cached(ct.markdown, deletedKey) {
+		t.Fatal("showing the post did not render it and its comments")
+	}
+
+	if _, err := ct.comments.DeleteComment(ctx, deleted.ID, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if cached(ct.markdown, deletedKey) {
+		t.Error("the deleted comment is still rendered")
+	}
+	if !cached(ct.markdown, keptKey) {
+		t.Error("deleting a comment forgot another one")
+	}
+
+	if err := ct.posts.DeletePost(ctx, "alice", ct.post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if cached(ct.markdown, postKey) || cached(ct.markdown, keptKey) {
+		t.Error("the deleted post or its comment is still rendered")
+	}
+}
@@ -0,0 +1,75 @@
+package usecase
+
+import "strings"
+
+type DiffOp int
+
+const (
+	DiffEqual DiffOp = iota
+	DiffInsert
+	DiffDelete
+)
+
+// DiffLine is one line of a line-based diff between two texts.
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+func (l DiffLine) IsInsert() bool { return l.Op == DiffInsert }
+func (l DiffLine) IsDelete() bool { return l.Op == DiffDelete }
+
+// diffLines computes a line diff using the longest common subsequence of the
+// two texts. Posts are short, so the quadratic table is not a concern.
+func diffLines(oldText, newText string) []DiffLine {
+	a := splitLines(oldText)
+	b := splitLines(newText)
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{Op: DiffDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{Op: DiffInsert, Text: b[j]})
+	}
+	return lines
+}
+
+func splitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,36 @@
+package usecase
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	eq := func(text string) DiffLine { return DiffLine{Op: DiffEqual, Text: text} }
+	ins := func(text string) DiffLine { return DiffLine{Op: DiffInsert, Text: text} }
+	del := func(text string) DiffLine { return DiffLine{Op: DiffDelete, Text: text} }
+
+	tests := []struct {
+		name     string
+		old, new string
+		want     []DiffLine
+	}{
+		{name: "both empty", want: nil},
+		{name: "empty old text", new: "a\nb", want: []DiffLine{ins("a"), ins("b")}},
+		{name: "empty new text", old: "a\nb", want: []DiffLine{del("a"), del("b")}},
+		{name: "identical", old: "a\nb\nc", new: "a\nb\nc", want: []DiffLine{eq("a"), eq("b"), eq("c")}},
+		{name: "windows line endings", old: "a\r\nb", new: "a\nb", want: []DiffLine{eq("a"), eq("b")}},
+		{name: "changed line", old: "a\nb\nc", new: "a\nB\nc", want: []DiffLine{eq("a"), del("b"), ins("B"), eq("c")}},
+		{name: "reordered lines", old: "a\nb", new: "b\na", want: []DiffLine{del("a"), eq("b"), ins("a")}},
+		{name: "trailing newline added", old: "a", new: "a\n", want: []DiffLine{eq("a"), ins("")}},
+		{name: "trailing newline removed", old: "a\n", new: "a", want: []DiffLine{eq("a"), del("")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.old, tt.new); !slices.Equal(got, tt.want) {
+				t.Errorf("diffLines(%q, %q) = %v, want %v", tt.old, tt.new, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+var (
+	ErrUnsupportedImage = errors.New("only JPEG, PNG and GIF images are allowed")
+	ErrImageTooLarge    = errors.New("image file is too large")
+	ErrImageDimensions  = errors.New("image dimensions are too large")
+	ErrInvalidImage     = errors.New("image file is corrupted or invalid")
+)
+
+// maxImagePixels bounds the decoded size of an upload so a small, highly
+// compressed file cannot make the server allocate gigabytes of memory.
+const maxImagePixels = 40_000_000
+
+// processedImage is an uploaded image that has been re-encoded without any of
+// its metadata (EXIF, comments, color profiles), plus a thumbnail of it.
+type processedImage struct {
+	ContentType   string
+	Extension     string
+	Data          []byte
+	Width         int
+	Height        int
+	Thumbnail     []byte
+	ThumbnailType string
+	ThumbnailExt  string
+}
+
+// processImage sniffs the real type of an upload, decodes it and encodes it
+// again from the pixels only, which drops EXIF and every other metadata block.
+// The declared content type of the upload is never trusted.
+func processImage(data []byte, maxSize int64, thumbnailSize int) (*processedImage, error) {
+	contentType, cfg, err := sniffImage(data, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &processedImage{ContentType: contentType}
+	var frame image.Image
+	var out bytes.Buffer
+
+	switch contentType {
+	case "image/jpeg":
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+		// The orientation lives in the EXIF block we are about to drop,
+		// so apply it to the pixels first.
+		frame = applyOrientation(img, jpegOrientation(data))
+		if err := jpeg.Encode(&out, frame, &jpeg.Options{Quality: 90}); err != nil {
+			return nil, err
+		}
+		result.Extension = ".jpg"
+
+	case "image/png":
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+		frame = img
+		if err := png.Encode(&out, frame); err != nil {
+			return nil, err
+		}
+		result.Extension = ".png"
+
+	case "image/gif":
+		// Keep animations, but only the frames and timing survive re-encoding.
+		// Every frame is decoded at once, so count them before decoding any.
+		frames, ok := gifFrameCount(data)
+		if !ok || frames == 0 {
+			return nil, ErrInvalidImage
+		}
+		if frames*cfg.Width*cfg.Height > maxImagePixels*4 {
+			return nil, ErrImageDimensions
+		}
+		anim, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil || len(anim.Image) == 0 {
+			return nil, ErrInvalidImage
+		}
+		frame = anim.Image[0]
+		if err := gif.EncodeAll(&out, anim); err != nil {
+			return nil, err
+		}
+		result.Extension = ".gif"
+	}
+
+	result.Data = out.Bytes()
+	result.Width = frame.Bounds().Dx()
+	result.Height = frame.Bounds().Dy()
+
+	var thumb bytes.Buffer
+	small := resizeToFit(frame, thumbnailSize)
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&thumb, small, &jpeg.Options{Quality: 80})
+		result.ThumbnailType, result.ThumbnailExt = "image/jpeg", ".jpg"
+	} else {
+		err = png.Encode(&thumb, small)
+		result.ThumbnailType, result.ThumbnailExt = "image/png", ".png"
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Thumbnail = thumb.Bytes()
+
+	return result, nil
+}
+
+// sniffImage checks the size, the real type and the dimensions of an upload
+// before anything decodes its pixels.
+func sniffImage(data []byte, maxSize int64) (string, image.Config, error) {
+	if int64(len(data)) > maxSize {
+		return "", image.Config{}, ErrImageTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return "", image.Config{}, ErrUnsupportedImage
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", image.Config{}, ErrInvalidImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxImagePixels {
+		return "", image.Config{}, ErrImageDimensions
+	}
+	return contentType, cfg, nil
+}
+
+// processedAvatar is an avatar cropped to a square, scaled down and
+// re-encoded without metadata.
+type processedAvatar struct {
+	Data      []byte
+	Extension string
+}
+
+// processAvatar crops the largest centered square out of an upload and
+// scales it down to size x size. Only the first frame of a GIF is kept.
+// Photos stay JPEG; everything else becomes PNG so transparency survives.
+func processAvatar(data []byte, maxSize int64, size int) (*processedAvatar, error) {
+	contentType, _, err := sniffImage(data, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if contentType == "image/jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+	img = resizeToFit(cropSquare(img), size)
+
+	var out bytes.Buffer
+	result := &processedAvatar{}
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: 85})
+		result.Extension = ".jpg"
+	} else {
+		err = png.Encode(&out, img)
+		result.Extension = ".png"
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Data = out.Bytes()
+	return result, nil
+}
+
+// cropSquare returns the largest square in the middle of img.
+func cropSquare(img image.Image) image.Image {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	x0 := bounds.Min.X + (bounds.Dx()-side)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-side)/2
+
+	dst := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(dst, dst.Bounds(), img, image.Pt(x0, y0), draw.Src)
+	return dst
+}
+
+// resizeToFit scales img down so that it fits in a size x size box, keeping
+// its aspect ratio. Every destination pixel is the average of the source
+// pixels it covers, which gives clean results for downscaling.
+func resizeToFit(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	dstW, dstH := srcW, srcH
+	if srcW > size || srcH > size {
+		if srcW >= srcH {
+			dstW, dstH = size, max(1, srcH*size/srcW)
+		} else {
+			dstW, dstH = max(1, srcW*size/srcH), size
+		}
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	if dstW == srcW && dstH == srcH {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0, y1 := y*srcH/dstH, max((y+1)*srcH/dstH, y*srcH/dstH+1)
+		for x := 0; x < dstW; x++ {
+			x0, x1 := x*srcW/dstW, max((x+1)*srcW/dstW, x*srcW/dstW+1)
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(src.Pix[i])
+					g += uint32(src.Pix[i+1])
+					b += uint32(src.Pix[i+2])
+					a += uint32(src.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+// gifFrameCount returns the number of frames in a GIF file by walking its
+// blocks, without decompressing any of them. It returns false when the file
+// ends before its trailer or holds a block it does not know.
+func gifFrameCount(data []byte) (int, bool) {
+	// Header and logical screen descriptor.
+	if len(data) < 13 {
+		return 0, false
+	}
+	i := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		i += 3 << (flags&0x07 + 1)
+	}
+
+	// skipSubBlocks returns the index past the data sub-blocks at j.
+	skipSubBlocks := func(j int) (int, bool) {
+		for j < len(data) {
+			size := int(data[j])
+			j++
+			if size == 0 {
+				return j, true
+			}
+			j += size
+		}
+		return 0, false
+	}
+
+	frames := 0
+	for i < len(data) {
+		var ok bool
+		switch data[i] {
+		case 0x3B: // Trailer.
+			return frames, true
+		case 0x21: // Extension: label, then sub-blocks.
+			if i, ok = skipSubBlocks(i + 2); !ok {
+				return 0, false
+			}
+		case 0x2C: // Image descriptor, local color table, LZW code size, sub-blocks.
+			if i+10 > len(data) {
+				return 0, false
+			}
+			frames++
+			next := i + 10
+			if flags := data[i+9]; flags&0x80 != 0 {
+				next += 3 << (flags&0x07 + 1)
+			}
+			if i, ok = skipSubBlocks(next + 1); !ok {
+				return 0, false
+			}
+		default:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// jpegOrientation returns the EXIF orientation (1-8) stored in a JPEG file,
+// or 1 when there is none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if marker == 0xDA || length < 2 || i+2+length > len(data) {
+			// Start of scan: no metadata past this point.
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+	for n := 0; n < entries; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// applyOrientation rotates and flips img so that it is displayed upright
+// without the EXIF orientation tag.
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		dstW, dstH = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return dst
+}
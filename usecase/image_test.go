@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+// encodeGIF returns a GIF of frames frames of w x h pixels each.
+func encodeGIF(t *testing.T, frames, w, h int) []byte {
+	t.Helper()
+	palette := color.Palette{color.Black, color.White}
+	anim := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, w, h), palette)
+		frame.SetColorIndex(i%w, 0, 1)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatalf("encode GIF: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestGIFFrameCount(t *testing.T) {
+	three := encodeGIF(t, 3, 8, 8)
+	tests := []struct {
+		name   string
+		data   []byte
+		frames int
+		ok     bool
+	}{
+		{"one frame", encodeGIF(t, 1, 8, 8), 1, true},
+		{"three frames", three, 3, true},
+		{"no trailer", three[:len(three)-1], 0, false},
+		{"cut in a frame", three[:len(three)/2], 0, false},
+		{"header only", three[:13], 0, false},
+		{"unknown block", append(append([]byte{}, three[:len(three)-1]...), 0x99, 0x3B), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames, ok := gifFrameCount(tt.data)
+			if frames != tt.frames || ok != tt.ok {
+				t.Errorf("gifFrameCount() = %d, %v; want %d, %v", frames, ok, tt.frames, tt.ok)
+			}
+		})
+	}
+}
+
+func TestProcessImageRejectsManyFrames(t *testing.T) {
+	// 1200 frames of 400x400 are 192 million pixels, over four times the budget,
+	// in a file of a few hundred kilobytes.
+	data := encodeGIF(t, 1200, 400, 400)
+	if _, err := processImage(data, int64(len(data)), 64); !errors.Is(err, ErrImageDimensions) {
+		t.Fatalf("processImage of %d frames returned %v, want %v", 1200, err, ErrImageDimensions)
+	}
+
+	data = encodeGIF(t, 3, 40, 40)
+	img, err := processImage(data, int64(len(data)), 16)
+	if err != nil {
+		t.Fatalf("processImage of a small animation: %v", err)
+	}
+	anim, err := gif.DecodeAll(bytes.NewReader(img.Data))
+	if err != nil || len(anim.Image) != 3 {
+		t.Errorf("the re-encoded animation has %v frames (%v), want 3", len(anim.Image), err)
+	}
+}
@@ -0,0 +1,528 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"html"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MarkdownRenderer turns post and comment content into safe HTML and keeps
+// the result in memory, so the feed does not parse every post again on each
+// request. Entries are keyed by the owner of the content and checked against
+// a hash of it, so an edit simply replaces the cached HTML. The services share
+// one renderer, so content that is deleted through one of them is forgotten
+// by all.
+type MarkdownRenderer struct {
+	mu         sync.Mutex
+	cache      map[string]markdownEntry
+	maxEntries int
+}
+
+type markdownEntry struct {
+	sum  [sha256.Size]byte
+	html template.HTML
+}
+
+func NewMarkdownRenderer(maxEntries int) *MarkdownRenderer {
+	return &MarkdownRenderer{
+		cache:      make(map[string]markdownEntry),
+		maxEntries: maxEntries,
+	}
+}
+
+// Render returns the sanitized HTML for content, using the cached copy for
+// key when the content has not changed since it was rendered.
+func (r *MarkdownRenderer) Render(key, content string) template.HTML {
+	sum := sha256.Sum256([]byte(content))
+
+	r.mu.Lock()
+	entry, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok && entry.sum == sum {
+		return entry.html
+	}
+
+	rendered := template.HTML(renderMarkdown(content))
+
+	r.mu.Lock()
+	if _, exists := r.cache[key]; !exists && len(r.cache) >= r.maxEntries {
+		// Drop an arbitrary entry; it will be rendered again when needed.
+		for k := range r.cache {
+			delete(r.cache, k)
+			break
+		}
+	}
+	r.cache[key] = markdownEntry{sum: sum, html: rendered}
+	r.mu.Unlock()
+
+	return rendered
+}
+
+// Forget removes the cached HTML for key, e.g. once its content is deleted.
+func (r *MarkdownRenderer) Forget(key string) {
+	r.mu.Lock()
+	delete(r.cache, key)
+	r.mu.Unlock()
+}
+
+// maxQuoteDepth stops "> > > > ..." from recursing without bound.
+const maxQuoteDepth = 8
+
+var (
+	headingPattern     = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.*?)[ \t#]*$`)
+	ruleLinePattern    = regexp.MustCompile(`^ {0,3}((\*[ \t]*){3,}|(-[ \t]*){3,}|(_[ \t]*){3,})$`)
+	bulletItemPattern  = regexp.MustCompile(`^ {0,3}[-*+][ \t]+`)
+	orderedItemPattern = regexp.MustCompile(`^ {0,3}(\d{1,9})[.)][ \t]+`)
+	fenceLangPattern   = regexp.MustCompile(`^[A-Za-z0-9_+-]{1,20}$`)
+)
+
+// renderMarkdown converts a small, forum friendly subset of Markdown to HTML:
+// paragraphs, headings, emphasis, inline and fenced code, block quotes, lists,
+// rules and links. Raw HTML in the source is always shown as text, and the
+// output goes through sanitizeHTML before it is returned.
+func renderMarkdown(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(src, "\n"), 0)
+	return sanitizeHTML(b.String())
+}
+
+func renderBlocks(b *strings.Builder, lines []string, depth int) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case isFence(trimmed):
+			fence := trimmed[:3]
+			lang := strings.Fields(trimmed[3:])
+			i++
+			start := i
+			for i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+				i++
+			}
+			code := strings.Join(lines[start:i], "\n")
+			i++ // closing fence
+
+			b.WriteString("<pre><code")
+			if len(lang) > 0 && fenceLangPattern.MatchString(lang[0]) {
+				b.WriteString(` class="language-` + lang[0] + `"`)
+			}
+			b.WriteString(">")
+			b.WriteString(html.EscapeString(code))
+			b.WriteString("</code></pre>\n")
+
+		case headingPattern.MatchString(line):
+			m := headingPattern.FindStringSubmatch(line)
+			level := strconv.Itoa(len(m[1]))
+			b.WriteString("<h" + level + ">")
+			renderInline(b, m[2], true)
+			b.WriteString("</h" + level + ">\n")
+			i++
+
+		case ruleLinePattern.MatchString(line):
+			b.WriteString("<hr>\n")
+			i++
+
+		case strings.HasPrefix(trimmed, ">"):
+			var quoted []string
+			for i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">") {
+				q := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quoted = append(quoted, strings.TrimPrefix(q, " "))
+				i++
+			}
+			b.WriteString("<blockquote>\n")
+			if depth < maxQuoteDepth {
+				renderBlocks(b, quoted, depth+1)
+			} else {
+				renderParagraph(b, quoted)
+			}
+			b.WriteString("</blockquote>\n")
+
+		case bulletItemPattern.MatchString(line), orderedItemPattern.MatchString(line):
+			i = renderList(b, lines, i)
+
+		default:
+			start := i
+			i++
+			for i < len(lines) && strings.TrimSpace(lines[i]) != "" && !startsBlock(lines[i]) {
+				i++
+			}
+			renderParagraph(b, lines[start:i])
+		}
+	}
+}
+
+// renderList writes the list starting at lines[i] and returns the index of
+// the first line after it. Items may wrap onto following lines.
+func renderList(b *strings.Builder, lines []string, i int) int {
+	pattern := bulletItemPattern
+	tag := "ul"
+	if !bulletItemPattern.MatchString(lines[i]) {
+		pattern = orderedItemPattern
+		tag = "ol"
+	}
+
+	b.WriteString("<" + tag)
+	if tag == "ol" {
+		if start, _ := strconv.Atoi(orderedItemPattern.FindStringSubmatch(lines[i])[1]); start != 1 {
+			b.WriteString(` start="` + strconv.Itoa(start) + `"`)
+		}
+	}
+	b.WriteString(">\n")
+
+	var item []string
+	flush := func() {
+		if item != nil {
+			b.WriteString("<li>")
+			renderInline(b, strings.Join(item, "\n"), true)
+			b.WriteString("</li>\n")
+			item = nil
+		}
+	}
+
+	for i < len(lines) {
+		line := lines[i]
+		switch {
+		case pattern.MatchString(line):
+			flush()
+			item = []string{strings.TrimSpace(line[len(pattern.FindString(line)):])}
+			i++
+		case strings.TrimSpace(line) == "":
+			// A blank line only continues the list if another item follows.
+			if i+1 < len(lines) && pattern.MatchString(lines[i+1]) {
+				i++
+				continue
+			}
+			flush()
+			b.WriteString("</" + tag + ">\n")
+			return i
+		case startsBlock(line):
+			flush()
+			b.WriteString("</" + tag + ">\n")
+			return i
+		default:
+			item = append(item, strings.TrimSpace(line))
+			i++
+		}
+	}
+
+	flush()
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+func renderParagraph(b *strings.Builder, lines []string) {
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	b.WriteString("<p>")
+	renderInline(b, strings.Join(lines, "\n"), true)
+	b.WriteString("</p>\n")
+}
+
+func isFence(trimmed string) bool {
+	return strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+}
+
+// startsBlock reports whether line ends the paragraph before it.
+func startsBlock(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return isFence(trimmed) ||
+		strings.HasPrefix(trimmed, ">") ||
+		headingPattern.MatchString(line) ||
+		ruleLinePattern.MatchString(line) ||
+		bulletItemPattern.MatchString(line) ||
+		orderedItemPattern.MatchString(line)
+}
+
+// renderInline writes the inline markup of s: code spans, links, emphasis,
+// bare URLs and line breaks. Everything else is HTML escaped. Links are not
+// allowed inside link text.
+func renderInline(b *strings.Builder, s string, allowLinks bool) {
+	var text strings.Builder
+	flush := func() {
+		b.WriteString(html.EscapeString(text.String()))
+		text.Reset()
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			text.WriteByte(s[i+1])
+			i += 2
+			continue
+
+		case c == '\n':
+			flush()
+			b.WriteString("<br>\n")
+			i++
+			continue
+
+		case c == '`':
+			n := runLength(s, i, '`')
+			ticks := s[i : i+n]
+			if end := strings.Index(s[i+n:], ticks); end >= 0 {
+				code := s[i+n : i+n+end]
+				if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' {
+					code = code[1 : len(code)-1]
+				}
+				flush()
+				b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				i += n + end + n
+				continue
+			}
+			text.WriteString(ticks)
+			i += n
+			continue
+
+		case c == '[' && allowLinks:
+			if label, dest, next, ok := parseLink(s, i); ok {
+				flush()
+				if safeURL(dest) {
+					b.WriteString(`<a href="` + html.EscapeString(dest) + `" rel="nofollow ugc">`)
+					renderInline(b, label, false)
+					b.WriteString("</a>")
+				} else {
+					renderInline(b, label, false)
+				}
+				i = next
+				continue
+			}
+
+		case (c == 'h') && allowLinks && (i == 0 || !isWordByte(s[i-1])) &&
+			(strings.HasPrefix(s[i:], "http://") || strings.HasPrefix(s[i:], "https://")):
+			end := i
+			for end < len(s) && s[end] != ' ' && s[end] != '\n' && s[end] != '\t' && s[end] != '<' {
+				end++
+			}
+			for end > i && strings.ContainsRune(".,;:!?)'\"", rune(s[end-1])) {
+				end--
+			}
+			link := s[i:end]
+			if safeURL(link) && len(link) > len("https://") {
+				flush()
+				escaped := html.EscapeString(link)
+				b.WriteString(`<a href="` + escaped + `" rel="nofollow ugc">` + escaped + "</a>")
+				i = end
+				continue
+			}
+
+		case c == '*' || c == '_':
+			if inner, next, strong, ok := parseEmphasis(s, i); ok {
+				flush()
+				tag := "em"
+				if strong {
+					tag = "strong"
+				}
+				b.WriteString("<" + tag + ">")
+				renderInline(b, inner, allowLinks)
+				b.WriteString("</" + tag + ">")
+				i = next
+				continue
+			}
+			n := runLength(s, i, c)
+			text.WriteString(s[i : i+n])
+			i += n
+			continue
+		}
+
+		text.WriteByte(c)
+		i++
+	}
+	flush()
+}
+
+// parseLink parses "[label](destination)" at s[i].
+func parseLink(s string, i int) (label, dest string, next int, ok bool) {
+	depth := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			if j+1 >= len(s) || s[j+1] != '(' {
+				return "", "", 0, false
+			}
+			// The destination may itself contain balanced parentheses.
+			end, open := -1, 0
+			for k := j + 2; k < len(s) && end < 0; k++ {
+				switch s[k] {
+				case '(':
+					open++
+				case ')':
+					if open == 0 {
+						end = k - (j + 2)
+					}
+					open--
+				}
+			}
+			if end < 0 {
+				return "", "", 0, false
+			}
+			dest = strings.TrimSpace(s[j+2 : j+2+end])
+			if dest == "" || strings.ContainsAny(dest, " \n\t") {
+				return "", "", 0, false
+			}
+			return s[i+1 : j], dest, j + 2 + end + 1, true
+		}
+	}
+	return "", "", 0, false
+}
+
+// parseEmphasis parses *em*, _em_, **strong** and __strong__ at s[i].
+// Underscores inside words (snake_case) are left alone.
+func parseEmphasis(s string, i int) (inner string, next int, strong bool, ok bool) {
+	c := s[i]
+	if c == '_' && i > 0 && isWordByte(s[i-1]) {
+		return "", 0, false, false
+	}
+
+	n := 1
+	if runLength(s, i, c) >= 2 {
+		n = 2
+	}
+	delim := s[i : i+n]
+
+	body := s[i+n:]
+	for from := 0; from < len(body); {
+		end := strings.Index(body[from:], delim)
+		if end < 0 {
+			return "", 0, false, false
+		}
+		end += from
+		// A single delimiter must not be the start of a double one.
+		if n == 1 && end+1 < len(body) && body[end+1] == c {
+			from = end + 2
+			continue
+		}
+		inner = body[:end]
+		next = i + n + end + n
+		if inner == "" || inner[0] == ' ' || inner[len(inner)-1] == ' ' {
+			return "", 0, false, false
+		}
+		if c == '_' && next < len(s) && isWordByte(s[next]) {
+			return "", 0, false, false
+		}
+		return inner, next, n == 2, true
+	}
+	return "", 0, false, false
+}
+
+// safeURL allows web and mail links and links within the forum. Everything
+// else, including javascript: and data: URLs, is rejected.
+func safeURL(raw string) bool {
+	if strings.ContainsAny(raw, "\x00\r\n\t<>\"'`") {
+		return false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return u.Opaque != ""
+	case "":
+		return u.Host == "" && !strings.HasPrefix(raw, "//") &&
+			(strings.HasPrefix(raw, "/") || strings.HasPrefix(raw, "#"))
+	}
+	return false
+}
+
+func runLength(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+// allowedTags lists the elements and attributes that may appear in rendered
+// content. Anything else is removed by sanitizeHTML.
+var allowedTags = map[string]map[string]bool{
+	"p": {}, "br": {}, "hr": {}, "strong": {}, "em": {}, "pre": {}, "blockquote": {},
+	"h1": {}, "h2": {}, "h3": {}, "h4": {}, "h5": {}, "h6": {},
+	"ul": {}, "ol": {"start": true}, "li": {},
+	"code": {"class": true},
+	"a":    {"href": true},
+}
+
+var (
+	tagPattern       = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9]*)([^<>]*)>`)
+	attrPattern      = regexp.MustCompile(`([a-zA-Z-]+)="([^"]*)"`)
+	codeClassPattern = regexp.MustCompile(`^language-[A-Za-z0-9_+-]{1,20}$`)
+)
+
+// sanitizeHTML keeps only allowlisted tags and attributes and rebuilds each
+// tag from scratch. Links always get rel="nofollow ugc". Text between tags
+// is passed through, so it must already be escaped.
+func sanitizeHTML(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range tagPattern.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(strings.NewReplacer("<", "&lt;", ">", "&gt;").Replace(s[last:m[0]]))
+		last = m[1]
+
+		closing := s[m[2]:m[3]] == "/"
+		name := strings.ToLower(s[m[4]:m[5]])
+		attrs, ok := allowedTags[name]
+		if !ok {
+			continue
+		}
+		if closing {
+			b.WriteString("</" + name + ">")
+			continue
+		}
+
+		b.WriteString("<" + name)
+		for _, a := range attrPattern.FindAllStringSubmatch(s[m[6]:m[7]], -1) {
+			key, value := strings.ToLower(a[1]), html.UnescapeString(a[2])
+			if !attrs[key] {
+				continue
+			}
+			switch {
+			case key == "href" && !safeURL(value):
+				continue
+			case key == "class" && !codeClassPattern.MatchString(value):
+				continue
+			case key == "start":
+				if _, err := strconv.Atoi(value); err != nil {
+					continue
+				}
+			}
+			b.WriteString(" " + key + `="` + html.EscapeString(value) + `"`)
+		}
+		if name == "a" {
+			b.WriteString(` rel="nofollow ugc"`)
+		}
+		b.WriteString(">")
+	}
+	b.WriteString(strings.NewReplacer("<", "&lt;", ">", "&gt;").Replace(s[last:]))
+	return b.String()
+}
@@ -0,0 +1,197 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+)
+
+// checkAllowed fails if html holds a tag or an attribute that sanitizeHTML
+// does not allow, or a link that safeURL refuses.
+func checkAllowed(t *testing.T, html string) {
+	t.Helper()
+	for _, m := range tagPattern.FindAllStringSubmatch(html, -1) {
+		attrs, ok := allowedTags[strings.ToLower(m[2])]
+		if !ok {
+			t.Errorf("the output has a <%s> tag: %q", m[2], html)
+			continue
+		}
+		rest := attrPattern.ReplaceAllStringFunc(m[3], func(attr string) string {
+			a := attrPattern.FindStringSubmatch(attr)
+			switch {
+			case a[1] == "rel" && m[2] == "a":
+			case !attrs[a[1]]:
+				t.Errorf("the output has a %s attribute on <%s>: %q", a[1], m[2], html)
+			case a[1] == "href" && !safeURL(a[2]):
+				t.Errorf("the output links to %q", a[2])
+			}
+			return ""
+		})
+		if strings.TrimSpace(rest) != "" {
+			t.Errorf("the output has %q in a <%s> tag: %q", rest, m[2], html)
+		}
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		// Link destinations.
+		{"web link", "[x](https://example.com/a?b=1&c=2)", `<p><a href="https://example.com/a?b=1&amp;c=2" rel="nofollow ugc">x</a></p>` + "\n"},
+		{"forum link", "[x](/post?id=1)", `<p><a href="/post?id=1" rel="nofollow ugc">x</a></p>` + "\n"},
+		{"mail link", "[x](mailto:a@example.com)", `<p><a href="mailto:a@example.com" rel="nofollow ugc">x</a></p>` + "\n"},
+		{"javascript link", "[x](javascript:alert(1))", "<p>x</p>\n"},
+		{"mixed case javascript link", "[x](JaVaScRiPt:alert(1))", "<p>x</p>\n"},
+		{"data link", "[x](data:text/html;base64,PHNjcmlwdD4=)", "<p>x</p>\n"},
+		{"entity encoded scheme", "[x](&#106;avascript:alert(1))", "<p>x</p>\n"},
+		{"entity encoded colon", "[x](javascript&#58;alert(1))", "<p>x</p>\n"},
+		{"entity encoded tab in the scheme", "[x](jav&#x09;ascript:alert(1))", "<p>x</p>\n"},
+		{"protocol relative link", "[x](//evil.example)", "<p>x</p>\n"},
+
+		// Raw HTML is shown as text.
+		{"script tag", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"img onerror", "<img src=x onerror=alert(1)>", "<p>&lt;img src=x onerror=alert(1)&gt;</p>\n"},
+		{"raw link", `<a href="javascript:alert(1)">x</a>`, "<p>&lt;a href=&#34;javascript:alert(1)&#34;&gt;x&lt;/a&gt;</p>\n"},
+
+		// Quotes and tags in link labels and destinations.
+		{"quote in a label", `[a" onmouseover="alert(1)](https://example.com)`,
+			`<p><a href="https://example.com" rel="nofollow ugc">a&#34; onmouseover=&#34;alert(1)</a></p>` + "\n"},
+		{"tag in a label", "[<b>bold</b>](https://example.com)",
+			`<p><a href="https://example.com" rel="nofollow ugc">&lt;b&gt;bold&lt;/b&gt;</a></p>` + "\n"},
+		{"quote in a destination", `[x](https://example.com/"onmouseover="alert(1))`, "<p>x</p>\n"},
+		// Entities in the source are not decoded, so this is a literal "&#34;".
+		{"entity quote in a destination", "[x](https://example.com/&#34;onmouseover=alert(1))",
+			`<p><a href="https://example.com/&amp;#34;onmouseover=alert(1)" rel="nofollow ugc">x</a></p>` + "\n"},
+		{"tag in a destination", "[x](https://example.com/?a=<b>)", "<p>x</p>\n"},
+		{"quote in a bare URL", `https://example.com/a"onclick=x`, "<p>https://example.com/a&#34;onclick=x</p>\n"},
+
+		// The language class of fenced code.
+		{"fence language", "```go\ncode\n```", `<pre><code class="language-go">code</code></pre>` + "\n"},
+		{"quote in the fence language", "```js\" onclick=\"x\ncode\n```", "<pre><code>code</code></pre>\n"},
+		{"tag in the fence language", "```\"><script>\n<b>code</b>\n```", "<pre><code>&lt;b&gt;code&lt;/b&gt;</code></pre>\n"},
+
+		// Emphasis.
+		{"nested strong in em", "*a **b** c*", "<p><em>a <strong>b</strong> c</em></p>\n"},
+		{"nested em in strong", "**a *b* c**", "<p><strong>a <em>b</em> c</strong></p>\n"},
+		{"unclosed strong", "**unclosed", "<p>**unclosed</p>\n"},
+		{"crossed delimiters", "*a **b* c**", "<p><em>a **b</em> c**</p>\n"},
+		{"triple delimiters", "***x***", "<p><strong>*x</strong>*</p>\n"},
+		{"underscores in a word", "snake_case_name", "<p>snake_case_name</p>\n"},
+
+		// Lists do not nest: indented items continue the item above.
+		{"indented list", "- a\n  - b\n    - c\n      - d", "<ul>\n<li>a</li>\n<li>b<br>\n- c<br>\n- d</li>\n</ul>\n"},
+		{"ordered list start", "3. a\n4. b", "<ol start=\"3\">\n<li>a</li>\n<li>b</li>\n</ol>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderMarkdown(tt.src)
+			if got != tt.want {
+				t.Errorf("renderMarkdown(%q)\n got %q\nwant %q", tt.src, got, tt.want)
+			}
+			checkAllowed(t, got)
+		})
+	}
+}
+
+func TestRenderMarkdownDeepNesting(t *testing.T) {
+	var list strings.Builder
+	for i := 0; i < 200; i++ {
+		list.WriteString(strings.Repeat(" ", i) + "- item\n")
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		// tag and want are the element and how many times it opens, or -1
+		// when only its balance matters.
+		tag  string
+		want int
+	}{
+		{"deep list", list.String(), "<ul>", 1},
+		{"deep quote", strings.Repeat(">", 1000) + " deep", "<blockquote>", maxQuoteDepth + 1},
+		{"deep emphasis", strings.Repeat("*a _", 500) + "b" + strings.Repeat("_ a*", 500), "<em>", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderMarkdown(tt.src)
+			if n := strings.Count(got, tt.tag); tt.want >= 0 && n != tt.want {
+				t.Errorf("%s opens %d times, want %d", tt.tag, n, tt.want)
+			}
+			closing := "</" + tt.tag[1:]
+			if strings.Count(got, closing) != strings.Count(got, tt.tag) {
+				t.Errorf("%s opens %d times and closes %d times", tt.tag, strings.Count(got, tt.tag), strings.Count(got, closing))
+			}
+			checkAllowed(t, got)
+		})
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://example.com/", true},
+		{"HTTP://example.com/", true},
+		{"/post?id=1", true},
+		{"#comment-1", true},
+		{"mailto:a@example.com", true},
+		{"https://", false},
+		{"mailto:", false},
+		{"javascript:alert(1)", false},
+		{"JaVaScRiPt:alert(1)", false},
+		{" javascript:alert(1)", false},
+		{"java\tscript:alert(1)", false},
+		{"vbscript:msgbox(1)", false},
+		{"data:text/html,<script>", false},
+		{"data:image/png;base64,AAAA", false},
+		{"//evil.example", false},
+		{"relative/path", false},
+		{"https://example.com/\"onclick=x", false},
+		{"https://example.com/'x", false},
+		{"https://example.com/\x00", false},
+	}
+
+	for _, tt := range tests {
+		if got := safeURL(tt.url); got != tt.want {
+			t.Errorf("safeURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"allowed tags", "<p><strong>a</strong></p>", "<p><strong>a</strong></p>"},
+		{"script", "<script>alert(1)</script>", "alert(1)"},
+		{"img onerror", `<img src="x" onerror="alert(1)">`, ""},
+		{"event handler", `<p onclick="alert(1)">a</p>`, "<p>a</p>"},
+		{"javascript link", `<a href="javascript:alert(1)">a</a>`, `<a rel="nofollow ugc">a</a>`},
+		{"entity encoded javascript link", `<a href="&#106;avascript:alert(1)">a</a>`, `<a rel="nofollow ugc">a</a>`},
+		{"link with its own rel", `<a href="/a" rel="opener" target="_blank">a</a>`, `<a href="/a" rel="nofollow ugc">a</a>`},
+		{"entity quote in an href", `<a href="/a&#34; onclick=&#34;x">a</a>`, `<a rel="nofollow ugc">a</a>`},
+		{"code class", `<code class="language-go">a</code>`, `<code class="language-go">a</code>`},
+		{"injected code class", `<code class="language-go&#34; onclick=&#34;x">a</code>`, "<code>a</code>"},
+		{"ordered list start", `<ol start="2 onclick">`, "<ol>"},
+		{"upper case tag", `<SCRIPT>x</SCRIPT><P>a</P>`, "x<p>a</p>"},
+		{"stray angle brackets", "a < b > c", "a &lt; b &gt; c"},
+		{"unfinished tag", `<a href="/a"`, `&lt;a href="/a"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sanitizeHTML(tt.in)
+			if got != tt.want {
+				t.Errorf("sanitizeHTML(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+			checkAllowed(t, got)
+		})
+	}
+}
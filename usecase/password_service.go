@@ -0,0 +1,273 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrIncorrectPassword = errors.New("current password is incorrect")
+
+// PasswordResetSettings configure the links mailed to users who forgot their
+// password.
+type PasswordResetSettings struct {
+	// BaseURL is where users reach the forum, such as https://forum.example.com.
+	BaseURL string
+	// TokenTTL is how long a link can be used.
+	TokenTTL time.Duration
+}
+
+// resetLimiterSweep is how many entries ResetRateLimiter holds before it
+// drops the ones that no longer limit anything.
+const resetLimiterSweep = 1024
+
+// ResetRateLimiter spaces out the password reset links sent to each email
+// address and requested from each client address. Requests for any address
+// count, registered or not, so the limits do not tell who has an account.
+type ResetRateLimiter struct {
+	lastSent       map[string]time.Time
+	mutex          sync.Mutex
+	emailInterval  time.Duration
+	clientInterval time.Duration
+}
+
+func NewResetRateLimiter(emailInterval, clientInterval time.Duration) *ResetRateLimiter {
+	return &ResetRateLimiter{
+		lastSent:       make(map[string]time.Time),
+		emailInterval:  emailInterval,
+		clientInterval: clientInterval,
+	}
+}
+
+// Reserve records a request for email from the client at ip now and returns
+// true, or returns false when the last request for either was too recent.
+// An empty ip is not limited.
+func (r *ResetRateLimiter) Reserve(email, ip string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	if len(r.lastSent) >= resetLimiterSweep {
+		longest := max(r.emailInterval, r.clientInterval)
+		for key, lastSent := range r.lastSent {
+			if now.Sub(lastSent) >= longest {
+				delete(r.lastSent, key)
+			}
+		}
+	}
+
+	emailKey, clientKey := "email:"+email, "client:"+ip
+	if lastSent, exists := r.lastSent[emailKey]; exists && now.Sub(lastSent) < r.emailInterval {
+		return false
+	}
+	if lastSent, exists := r.lastSent[clientKey]; ip != "" && exists && now.Sub(lastSent) < r.clientInterval {
+		return false
+	}
+	r.lastSent[emailKey] = now
+	if ip != "" {
+		r.lastSent[clientKey] = now
+	}
+	return true
+}
+
+// PasswordService changes the passwords of users, either while they are
+// signed in or through a link mailed to them.
+type PasswordService struct {
+	userRepo    repository.UserRepository
+	sessionRepo repository.UserSessionRepository
+	resetRepo   repository.PasswordResetRepository
+	txManager   repository.TxManager
+	mailer      repository.Mailer
+	rateLimiter *ResetRateLimiter
+	settings    PasswordResetSettings
+}
+
+func NewPasswordService(userRepo repository.UserRepository, sessionRepo repository.UserSessionRepository,
+	resetRepo repository.PasswordResetRepository, txManager repository.TxManager, mailer repository.Mailer,
+	rateLimiter *ResetRateLimiter, settings PasswordResetSettings,
+) *PasswordService {
+	return &PasswordService{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		resetRepo:   resetRepo,
+		txManager:   txManager,
+		mailer:      mailer,
+		rateLimiter: rateLimiter,
+		settings:    settings,
+	}
+}
+
+// ChangePassword replaces the password of a signed in user after checking
+// their current one. Every session of the user ends, so other devices have
+// to log in again; the session token returned replaces the one of the
+// device that made the change.
+func (ps *PasswordService) ChangePassword(ctx context.Context, user *entity.User, currentPassword, newPassword string, client ClientInfo) (string, error) {
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)) != nil {
+		return "", ErrIncorrectPassword
+	}
+	newPassword, err := validatePassword(newPassword)
+	if err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = ps.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := ps.setPassword(ctx, user.ID, string(hash)); err != nil {
+			return err
+		}
+		return ps.sessionRepo.Create(ctx, newSession(user.ID, token, client))
+	})
+	if err != nil {
+		return "", err
+	}
+	user.PasswordHash = string(hash)
+	return token, nil
+}
+
+// RequestReset mails a password reset link to the user with this email,
+// asked for from client. It does nothing for addresses without an account
+// or when the address or the client asked too recently, so the answer does
+// not tell who is registered or who is limited. A new link replaces the
+// ones sent before.
+func (ps *PasswordService) RequestReset(ctx context.Context, email string, client ClientInfo) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !isValidEmail(email) {
+		return ErrInvalidEmail
+	}
+	if !ps.rateLimiter.Reserve(email, client.IPAddress) {
+		return nil
+	}
+
+	user, err := ps.userRepo.GetByEmail(ctx, email)
+	if errors.Is(err, custom_errors.ErrUserNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+	err = ps.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := ps.resetRepo.DeleteUnusedByUserID(ctx, user.ID); err != nil {
+			return err
+		}
+		return ps.resetRepo.Create(ctx, &entity.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: hashToken(token),
+			ExpiresAt: time.Now().Add(ps.settings.TokenTTL),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(ps.settings.BaseURL, "/") + "/password/reset?token=" + token
+	return ps.mailer.Send(ctx, &entity.Email{
+		To:      user.Email,
+		Subject: "Reset your forum password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Someone asked to reset the password of your forum account. "+
+			"Open this link within %s to choose a new one:\n\n%s\n\n"+
+			"If it was not you, ignore this email and your password stays the same.\n",
+			user.UserName, formatDuration(ps.settings.TokenTTL), link),
+	})
+}
+
+// CheckResetToken returns custom_errors.ErrResetTokenNotFound unless token
+// can still reset a password.
+func (ps *PasswordService) CheckResetToken(ctx context.Context, token string) error {
+	reset, err := ps.resetRepo.GetByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		return err
+	}
+	if !reset.IsUsable(time.Now()) {
+		return custom_errors.ErrResetTokenNotFound
+	}
+	return nil
+}
+
+// ResetPassword sets a new password with the token of a reset link. The
+// token is used up, and every session of the user ends.
+func (ps *PasswordService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	newPassword, err := validatePassword(newPassword)
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return ps.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		reset, err := ps.resetRepo.GetByTokenHash(ctx, hashToken(token))
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if !reset.IsUsable(now) {
+			return custom_errors.ErrResetTokenNotFound
+		}
+		if err := ps.resetRepo.MarkUsed(ctx, reset.ID, now); err != nil {
+			return err
+		}
+		return ps.setPassword(ctx, reset.UserID, string(hash))
+	})
+}
+
+// setPassword stores a new password hash and ends what the old password
+// gave access to: the sessions of the user and their unused reset links.
+func (ps *PasswordService) setPassword(ctx context.Context, userID uuid.UUID, hash string) error {
+	if err := ps.userRepo.UpdatePasswordHash(ctx, userID, hash); err != nil {
+		return err
+	}
+	if err := ps.sessionRepo.DeleteAllUserSessions(ctx, userID); err != nil {
+		return err
+	}
+	return ps.resetRepo.DeleteUnusedByUserID(ctx, userID)
+}
+
+// hashToken is what is stored of an emailed token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// formatDuration writes d in words for emails, such as "1 hour" or
+// "30 minutes".
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		if d == time.Hour {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	case d >= time.Minute:
+		if d < 2*time.Minute {
+			return "1 minute"
+		}
+		return fmt.Sprintf("%d minutes", d/time.Minute)
+	default:
+		return d.String()
+	}
+}
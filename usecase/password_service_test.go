@@ -0,0 +1,280 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+	"forum/infrastructure/repository/memory"
+)
+
+// outbox is a Mailer that keeps what it is asked to send.
+type outbox struct {
+	mu     sync.Mutex
+	emails []*entity.Email
+}
+
+func (o *outbox) Send(_ context.Context, email *entity.Email) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.emails = append(o.emails, email)
+	return nil
+}
+
+var resetLink = regexp.MustCompile(`/password/reset\?token=(\S+)`)
+
+// lastResetToken returns the token of the last reset link sent.
+func (o *outbox) lastResetToken(t *testing.T) string {
+	t.Helper()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.emails) == 0 {
+		t.Fatal("no email was sent")
+	}
+	match := resetLink.FindStringSubmatch(o.emails[len(o.emails)-1].Body)
+	if match == nil {
+		t.Fatalf("the email has no reset link:\n%s", o.emails[len(o.emails)-1].Body)
+	}
+	return match[1]
+}
+
+// passwordTest is a member alice, signed in with password secret12, and
+// the services that change the password.
+type passwordTest struct {
+	repos     *repository.Repositories
+	auth      *AuthService
+	passwords *PasswordService
+	mail      *outbox
+	alice     *entity.User
+	session   string
+}
+
+func newPasswordTest(t *testing.T, ttl time.Duration) *passwordTest {
+	t.Helper()
+	ctx := context.Background()
+	pt := &passwordTest{repos: memory.NewMemoryRepositories(5), mail: &outbox{}}
+	pt.auth = NewAuthService(pt.repos.User, pt.repos.Session, 0)
+	pt.passwords = NewPasswordService(pt.repos.User, pt.repos.Session, pt.repos.PasswordReset, pt.repos.Tx, pt.mail,
+		NewResetRateLimiter(0, 0), PasswordResetSettings{BaseURL: "https://forum.example.com/", TokenTTL: ttl})
+
+	if _, err := pt.auth.Signup(ctx, "alice", "alice@example.com", "secret12"); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	pt.session, pt.alice, err = pt.auth.Login(ctx, "alice@example.com", "secret12", ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pt
+}
+
+// signsIn reports whether alice can sign in with password.
+func (pt *passwordTest) signsIn(password string) bool {
+	_, _, err := pt.auth.Login(context.Background(), "alice@example.com", password, ClientInfo{})
+	return err == nil
+}
+
+func TestResetPassword(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  time.Duration
+		// link returns the token to reset alice's password with.
+		link    func(ctx context.Context, t *testing.T, pt *passwordTest) string
+		wantErr error
+	}{
+		{
+			name: "new link",
+			ttl:  time.Hour,
+			link: func(ctx context.Context, t *testing.T, pt *passwordTest) string {
+				if err := pt.passwords.RequestReset(ctx, " Alice@Example.com ", ClientInfo{}); err != nil {
+					t.Fatal(err)
+				}
+				return pt.mail.lastResetToken(t)
+			},
+		},
+		{
+			name: "expired link",
+			// Links expire a minute before they are sent.
+			ttl: -time.Minute,
+			link: func(ctx context.Context, t *testing.T, pt *passwordTest) string {
+				if err := pt.passwords.RequestReset(ctx, "alice@example.com", ClientInfo{}); err != nil {
+					t.Fatal(err)
+				}
+				return pt.mail.lastResetToken(t)
+			},
+			wantErr: custom_errors.ErrResetTokenNotFound,
+		},
+		{
+			name: "link replaced by a newer one",
+			ttl:  time.Hour,
+			link: func(ctx context.Context, t *testing.T, pt *passwordTest) string {
+				if err := pt.passwords.RequestReset(ctx, "alice@example.com", ClientInfo{}); err != nil {
+					t.Fatal(err)
+				}
+				first := pt.mail.lastResetToken(t)
+				if err := pt.passwords.RequestReset(ctx, "alice@example.com", ClientInfo{}); err != nil {
+					t.Fatal(err)
+				}
+				return first
+			},
+			wantErr: custom_errors.ErrResetTokenNotFound,
+		},
+		{
+			name: "link sent before a password change",
+			ttl:  time.Hour,
+			link: func(ctx context.Context, t *testing.T, pt *passwordTest) string {
+				if err := pt.passwords.RequestReset(ctx, "alice@example.com", ClientInfo{}); err != nil {
+					t.Fatal(err)
+				}
+				token := pt.mail.lastResetToken(t)
+				if _, err := pt.passwords.ChangePassword(ctx, pt.alice, "secret12", "changed1", ClientInfo{}); err != nil {
+					t.Fatal(err)
+				}
+				return token
+			},
+			wantErr: custom_errors.ErrResetTokenNotFound,
+		},
+		{
+			name:    "made up token",
+			ttl:     time.Hour,
+			link:    func(context.Context, *testing.T, *passwordTest) string { return "made-up" },
+			wantErr: custom_errors.ErrResetTokenNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			pt := newPasswordTest(t, tt.ttl)
+			token := tt.link(ctx, t, pt)
+			before, err := pt.repos.User.GetByID(ctx, pt.alice.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := pt.passwords.CheckResetToken(ctx, token); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckResetToken returned %v, want %v", err, tt.wantErr)
+			}
+			err = pt.passwords.ResetPassword(ctx, token, "newpass1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ResetPassword returned %v, want %v", err, tt.wantErr)
+			}
+
+			stored, err := pt.repos.User.GetByID(ctx, pt.alice.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != nil {
+				if stored.PasswordHash != before.PasswordHash {
+					t.Error("a refused reset changed the password")
+				}
+				return
+			}
+
+			if !pt.signsIn("newpass1") || pt.signsIn("secret12") {
+				t.Error("alice cannot sign in with the new password only")
+			}
+			if _, err := pt.repos.Session.GetByToken(ctx, pt.session); err == nil {
+				t.Error("the session from before the reset is still open")
+			}
+
+			// The link works once.
+			if err := pt.passwords.CheckResetToken(ctx, token); !errors.Is(err, custom_errors.ErrResetTokenNotFound) {
+				t.Errorf("CheckResetToken of a used link returned %v, want %v", err, custom_errors.ErrResetTokenNotFound)
+			}
+			if err := pt.passwords.ResetPassword(ctx, token, "another1"); !errors.Is(err, custom_errors.ErrResetTokenNotFound) {
+				t.Errorf("ResetPassword with a used link returned %v, want %v", err, custom_errors.ErrResetTokenNotFound)
+			}
+			if !pt.signsIn("newpass1") {
+				t.Error("reusing the link changed the password")
+			}
+		})
+	}
+}
+
+func TestRequestResetForUnknownEmail(t *testing.T) {
+	pt := newPasswordTest(t, time.Hour)
+	if err := pt.passwords.RequestReset(context.Background(), "nobody@example.com", ClientInfo{}); err != nil {
+		t.Fatalf("RequestReset returned %v, want no error", err)
+	}
+	if len(pt.mail.emails) != 0 {
+		t.Errorf("%d emails were sent for an unknown address", len(pt.mail.emails))
+	}
+}
+
+func TestRequestResetRateLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		// The first request is for first from the client at firstIP, the
+		// second for alice from the client at ip.
+		first, firstIP string
+		ip             string
+		wantSent       bool
+	}{
+		{name: "same address from the same client", first: "alice@example.com", firstIP: "192.0.2.1", ip: "192.0.2.1"},
+		{name: "same address from another client", first: "alice@example.com", firstIP: "192.0.2.1", ip: "192.0.2.2"},
+		{name: "same address from unknown clients", first: "alice@example.com", ip: ""},
+		{name: "unknown address from the same client", first: "nobody@example.com", firstIP: "192.0.2.1", ip: "192.0.2.1"},
+		{name: "unknown address from another client", first: "nobody@example.com", firstIP: "192.0.2.1", ip: "192.0.2.2", wantSent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			pt := newPasswordTest(t, time.Hour)
+			pt.passwords.rateLimiter = NewResetRateLimiter(time.Hour, time.Hour)
+
+			if err := pt.passwords.RequestReset(ctx, tt.first, ClientInfo{IPAddress: tt.firstIP}); err != nil {
+				t.Fatal(err)
+			}
+			before := len(pt.mail.emails)
+			// A limited request answers like any other.
+			if err := pt.passwords.RequestReset(ctx, "alice@example.com", ClientInfo{IPAddress: tt.ip}); err != nil {
+				t.Fatalf("RequestReset returned %v, want no error", err)
+			}
+			if sent := len(pt.mail.emails) > before; sent != tt.wantSent {
+				t.Errorf("the second request sent a link: %v, want %v", sent, tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestResetPasswordConcurrentUse(t *testing.T) {
+	ctx := context.Background()
+	pt := newPasswordTest(t, time.Hour)
+	if err := pt.passwords.RequestReset(ctx, "alice@example.com", ClientInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	token := pt.mail.lastResetToken(t)
+
+	const resets = 8
+	errs := make(chan error, resets)
+	var wg sync.WaitGroup
+	for i := 0; i < resets; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- pt.passwords.ResetPassword(ctx, token, "newpass1")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+		} else if !errors.Is(err, custom_errors.ErrResetTokenNotFound) {
+			t.Errorf("ResetPassword returned %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d of %d concurrent resets with one link succeeded, want 1", succeeded, resets)
+	}
+}
@@ -1,9 +1,14 @@
 package usecase
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"log"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"forum/domain/entity"
 	"forum/domain/repository"
@@ -30,13 +35,35 @@ type PostService struct {
 	categoryRepo      repository.CategoryRepository
 	postAggregateRepo repository.PostAggregateRepository
 	postReactionRepo  repository.PostReactionRepository
+	postRevisionRepo  repository.PostRevisionRepository
+	attachmentRepo    repository.PostAttachmentRepository
+	fileStorage       repository.FileStorage
+	uploadLimits      UploadLimits
+	markdown          *MarkdownRenderer
+	pageSize          int
 	sessionRepo       repository.UserSessionRepository
+	txManager         repository.TxManager
 	rateLimiter       *PostRateLimiter
 }
 
+// UploadLimits bounds the images that can be attached to a single post.
+type UploadLimits struct {
+	MaxFileSize   int64
+	MaxFiles      int
+	ThumbnailSize int
+}
+
+// ImageUpload is an image file received with a new post.
+type ImageUpload struct {
+	Name string
+	Data []byte
+}
+
 func NewPostService(postRepo *repository.PostRepository, userRepo *repository.UserRepository,
 	categoryRepo *repository.CategoryRepository, postCategoryRepo *repository.PostAggregateRepository,
-	postReactionRepo *repository.PostReactionRepository, sessionRepo *repository.UserSessionRepository, postRateLimit *PostRateLimiter,
+	postReactionRepo *repository.PostReactionRepository, postRevisionRepo *repository.PostRevisionRepository,
+	attachmentRepo *repository.PostAttachmentRepository, fileStorage repository.FileStorage, uploadLimits UploadLimits,
+	markdown *MarkdownRenderer, sessionRepo *repository.UserSessionRepository, txManager repository.TxManager, postRateLimit *PostRateLimiter, pageSize int,
 ) *PostService {
 	return &PostService{
 		postRepo:          *postRepo,
@@ -44,7 +71,14 @@ func NewPostService(postRepo *repository.PostRepository, userRepo *repository.Us
 		categoryRepo:      *categoryRepo,
 		postAggregateRepo: *postCategoryRepo,
 		postReactionRepo:  *postReactionRepo,
+		postRevisionRepo:  *postRevisionRepo,
+		attachmentRepo:    *attachmentRepo,
+		fileStorage:       fileStorage,
+		uploadLimits:      uploadLimits,
+		markdown:          markdown,
+		pageSize:          pageSize,
 		sessionRepo:       *sessionRepo,
+		txManager:         txManager,
 		rateLimiter:       postRateLimit,
 	}
 }
@@ -64,13 +98,13 @@ func (r *PostRateLimiter) CanUserPost(userID uuid.UUID) bool {
 	return false
 }
 
-func (ps *PostService) CreatePost(token string, content string, categoryIDs []*uuid.UUID) (*entity.Post, error) {
-	session, err := ps.sessionRepo.GetByToken(token)
+func (ps *PostService) CreatePost(ctx context.Context, token string, title, content string, categoryIDs []*uuid.UUID, images []ImageUpload) (*entity.Post, error) {
+	session, err := ps.sessionRepo.GetByToken(ctx, token)
 	if err != nil || session == nil {
 		return nil, err
 	}
 
-	user, err := ps.userRepo.GetByID(session.UserID)
+	user, err := ps.userRepo.GetByID(ctx, session.UserID)
 	if err != nil {
 		return nil, err
 	}
@@ -83,12 +117,9 @@ func (ps *PostService) CreatePost(token string, content string, categoryIDs []*u
 		return nil, errors.New("you can't create a post now, wait a bit")
 	}
 
-	// Validate content
-	if content == "" {
-		return nil, errors.New("post content cannot be empty")
-	}
-	if len(content) > 450 {
-		return nil, errors.New("post content too long (max: 5000 characters)")
+	title, content, err = validatePostInput(title, content)
+	if err != nil {
+		return nil, err
 	}
 
 	// Validate categories
@@ -97,7 +128,7 @@ func (ps *PostService) CreatePost(token string, content string, categoryIDs []*u
 	}
 
 	for _, categoryID := range categoryIDs {
-		category, err := ps.categoryRepo.GetByID(categoryID)
+		category, err := ps.categoryRepo.GetByID(ctx, categoryID)
 		if err != nil {
 			return nil, err
 		}
@@ -106,15 +137,38 @@ func (ps *PostService) CreatePost(token string, content string, categoryIDs []*u
 		}
 	}
 
+	// Validate and store the images before the post exists, so a bad upload
+	// never leaves a post behind.
+	attachments, err := ps.storeImages(images)
+	if err != nil {
+		return nil, err
+	}
+
 	post := &entity.Post{
 		UserID:    user.ID,
+		Title:     title,
 		Content:   content,
 		CreatedAt: time.Now(),
 	}
 
-	// Create and associate the categories to the post
-	err = ps.postAggregateRepo.CreatePostWithCategories(post, categoryIDs)
+	// Create the post with its categories and attachments, or nothing at all
+	err = ps.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		err := ps.postAggregateRepo.CreatePostWithCategories(ctx, post, categoryIDs)
+		if err != nil {
+			return err
+		}
+
+		for _, attachment := range attachments {
+			attachment.PostID = post.ID
+			err = ps.attachmentRepo.Create(ctx, attachment)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
+		ps.removeAttachmentFiles(attachments)
 		return nil, err
 	}
 
@@ -125,69 +179,298 @@ func (ps *PostService) CreatePost(token string, content string, categoryIDs []*u
 	return post, nil
 }
 
-func (ps PostService) ReactToPost(postID uuid.UUID, token string, reaction bool) (*entity.PostReaction, error) {
-	session, err := ps.sessionRepo.GetByToken(token)
+// storeImages checks, cleans and thumbnails the uploaded images and saves
+// them, returning the attachments to link to the post.
+func (ps *PostService) storeImages(images []ImageUpload) ([]*entity.PostAttachment, error) {
+	if len(images) > ps.uploadLimits.MaxFiles {
+		return nil, fmt.Errorf("you can attach at most %d images", ps.uploadLimits.MaxFiles)
+	}
+
+	processed := make([]*processedImage, 0, len(images))
+	for _, upload := range images {
+		img, err := processImage(upload.Data, ps.uploadLimits.MaxFileSize, ps.uploadLimits.ThumbnailSize)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", upload.Name, err)
+		}
+		processed = append(processed, img)
+	}
+
+	attachments := make([]*entity.PostAttachment, 0, len(processed))
+	for _, img := range processed {
+		id := uuid.New()
+		attachment := &entity.PostAttachment{
+			ID:            id,
+			FileName:      id.String() + img.Extension,
+			ThumbnailName: id.String() + "_thumb" + img.ThumbnailExt,
+			ContentType:   img.ContentType,
+			Size:          int64(len(img.Data)),
+			Width:         img.Width,
+			Height:        img.Height,
+		}
+
+		err := ps.fileStorage.Save(attachment.FileName, img.Data)
+		if err == nil {
+			err = ps.fileStorage.Save(attachment.ThumbnailName, img.Thumbnail)
+		}
+		if err != nil {
+			ps.removeAttachmentFiles(append(attachments, attachment))
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, nil
+}
+
+// UploadLimits returns the limits applied to images attached to new posts.
+func (ps *PostService) UploadLimits() UploadLimits {
+	return ps.uploadLimits
+}
+
+func (ps *PostService) removeAttachmentFiles(attachments []*entity.PostAttachment) {
+	for _, attachment := range attachments {
+		for _, name := range []string{attachment.FileName, attachment.ThumbnailName} {
+			if err := ps.fileStorage.Delete(name); err != nil {
+				log.Printf("Warning: Failed to remove upload %s: %v", name, err)
+			}
+		}
+	}
+}
+
+// validatePostInput checks the title and content of a post and returns them
+// as they are stored: the title trimmed, and both without the search
+// highlight markers.
+func validatePostInput(title, content string) (string, string, error) {
+	title = strings.TrimSpace(entity.StripHighlight(title))
+	content = entity.StripHighlight(content)
+	if title == "" {
+		return "", "", errors.New("post title cannot be empty")
+	}
+	if utf8.RuneCountInString(title) > 100 {
+		return "", "", errors.New("post title too long (max: 100 characters)")
+	}
+
+	if content == "" {
+		return "", "", errors.New("post content cannot be empty")
+	}
+	if len(content) > 450 {
+		return "", "", errors.New("post content too long (max: 5000 characters)")
+	}
+	return title, content, nil
+}
+
+// UpdatePost lets the author of a post change its title and content.
+// The previous version is kept in the post's revision history.
+func (ps *PostService) UpdatePost(ctx context.Context, token string, postID uuid.UUID, title, content string) (*entity.Post, error) {
+	session, err := ps.sessionRepo.GetByToken(ctx, token)
 	if err != nil || session == nil {
 		return nil, err
 	}
 
-	_, err = ps.userRepo.GetByID(session.UserID)
+	post, err := ps.postRepo.GetByID(ctx, postID)
 	if err != nil {
 		return nil, err
 	}
+	if post.UserID != session.UserID {
+		return nil, ErrUnauthorizedAccess
+	}
 
-	_, err = ps.postRepo.GetByID(postID)
+	title, content, err = validatePostInput(title, content)
 	if err != nil {
 		return nil, err
 	}
 
-	pr, err := ps.postReactionRepo.GetByUserAndPost(session.UserID, postID)
-	if err == nil {
-		if pr.Reaction == reaction {
-			err := ps.postReactionRepo.Delete(pr.ID)
-			if err != nil {
-				return nil, errors.New("mistake in updating the post reaction")
-			}
-			return pr, nil
-		} else if pr.Reaction != reaction {
-			pr.Reaction = reaction
-			pr.CreatedAt = time.Now()
-			err := ps.postReactionRepo.Update(pr)
-			if err != nil {
-				return nil, errors.New("mistake in updating the post reaction")
-			}
-			return pr, nil
-		}
+	if post.Title == title && post.Content == content {
+		return post, nil
 	}
-	PostReaction := &entity.PostReaction{
-		UserID:    session.UserID,
-		PostID:    postID,
-		Reaction:  reaction,
-		CreatedAt: time.Now(),
+
+	post.Title = title
+	post.Content = content
+	err = ps.postAggregateRepo.UpdatePostWithRevision(ctx, post)
+	if err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// DeletePost removes a post with its comments, reactions, categories and
+// revisions. Only the author of the post or a moderator may delete it.
+func (ps *PostService) DeletePost(ctx context.Context, token string, postID uuid.UUID) error {
+	session, err := ps.sessionRepo.GetByToken(ctx, token)
+	if err != nil || session == nil {
+		return err
 	}
 
-	ps.postReactionRepo.Create(PostReaction)
+	user, err := ps.userRepo.GetByID(ctx, session.UserID)
+	if err != nil {
+		return err
+	}
+
+	// The details list the files and the rendered comments that go with it.
+	post, err := ps.postAggregateRepo.GetPostWithAllDetails(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if post.UserID != user.ID && !user.IsModerator() {
+		return ErrUnauthorizedAccess
+	}
+
+	err = ps.postAggregateRepo.DeletePostWithDependencies(ctx, postID)
+	if err != nil {
+		return err
+	}
+
+	ps.removeAttachmentFiles(post.Attachments)
+	ps.markdown.Forget("post:" + postID.String())
+	for _, comment := range post.Comments {
+		ps.markdown.Forget("comment:" + comment.ID.String())
+	}
+	return nil
+}
+
+// GetPostHistory returns every version of a post, oldest first.
+// The last entry is the current version of the post.
+func (ps *PostService) GetPostHistory(ctx context.Context, postID uuid.UUID) ([]*entity.PostRevision, error) {
+	post, err := ps.postRepo.GetByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := ps.postRevisionRepo.GetByPostID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	current := &entity.PostRevision{
+		PostID:    post.ID,
+		Version:   len(revisions) + 1,
+		Title:     post.Title,
+		Content:   post.Content,
+		CreatedAt: post.CreatedAt,
+	}
+	if post.UpdatedAt != nil {
+		current.CreatedAt = *post.UpdatedAt
+	}
+
+	return append(revisions, current), nil
+}
+
+// PostDiff describes the changes between two versions of a post.
+type PostDiff struct {
+	From  *entity.PostRevision
+	To    *entity.PostRevision
+	Lines []DiffLine
+}
+
+func (d PostDiff) TitleChanged() bool {
+	return d.From.Title != d.To.Title
+}
+
+func (ps *PostService) DiffRevisions(from, to *entity.PostRevision) *PostDiff {
+	return &PostDiff{
+		From:  from,
+		To:    to,
+		Lines: diffLines(from.Content, to.Content),
+	}
+}
+
+// ReactToPost toggles the user's reaction on a post: the same reaction twice
+// removes it, the other one replaces it. It returns the user's reaction and
+// the post's counts after the change.
+func (ps PostService) ReactToPost(ctx context.Context, postID uuid.UUID, token string, reaction bool) (*entity.ReactionState, error) {
+	session, err := ps.sessionRepo.GetByToken(ctx, token)
+	if err != nil || session == nil {
+		return nil, err
+	}
+
+	_, err = ps.userRepo.GetByID(ctx, session.UserID)
 	if err != nil {
 		return nil, err
 	}
-	return PostReaction, nil
+
+	_, err = ps.postRepo.GetByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := ps.postReactionRepo.Toggle(ctx, session.UserID, postID, reaction)
+	if err != nil {
+		log.Printf("Failed to update the reaction on post %s: %v", postID, err)
+		return nil, errors.New("mistake in updating the post reaction")
+	}
+	return state, nil
 }
 
-func (pc *PostService) GetPosts() ([]*entity.PostWithDetails, error) {
-	posts, err := pc.postAggregateRepo.GetFeedForUser()
+// GetPosts returns the first page of the feed.
+func (pc *PostService) GetPosts(ctx context.Context) ([]*entity.PostWithDetails, error) {
+	page, err := pc.GetFeedPage(ctx, "")
 	if err != nil {
 		return nil, err
 	}
-	return posts, nil
+	return page.Posts, nil
 }
 
-func (pc *PostService) GetUserFromSessionToken(token string) (*entity.User, error) {
-	session, err := pc.sessionRepo.GetByToken(token)
+// GetFeedPage returns the page of the feed at cursor, or the first page when
+// cursor is empty.
+func (ps *PostService) GetFeedPage(ctx context.Context, cursor string) (*entity.PostPage, error) {
+	request, err := ps.pageRequest(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := ps.postAggregateRepo.GetFeedForUser(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	ps.renderContent(page.Posts...)
+	return page, nil
+}
+
+func (ps *PostService) pageRequest(cursor string) (entity.PageRequest, error) {
+	request := entity.PageRequest{Limit: ps.pageSize}
+	if cursor == "" {
+		return request, nil
+	}
+
+	c, err := entity.DecodePostCursor(cursor)
+	if err != nil {
+		return request, err
+	}
+	request.Cursor = c
+	return request, nil
+}
+
+// GetPost returns a single post with its author, categories, reactions and comments.
+func (ps *PostService) GetPost(ctx context.Context, postID uuid.UUID) (*entity.PostWithDetails, error) {
+	post, err := ps.postAggregateRepo.GetPostWithAllDetails(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	ps.renderContent(post)
+	return post, nil
+}
+
+// renderContent fills in the rendered Markdown of the posts and their comments.
+func (ps *PostService) renderContent(posts ...*entity.PostWithDetails) {
+	for _, post := range posts {
+		post.ContentHTML = ps.markdown.Render("post:"+post.ID.String(), post.Content)
+		for i := range post.Comments {
+			comment := &post.Comments[i]
+			if comment.IsDeleted() {
+				continue
+			}
+			comment.ContentHTML = ps.markdown.Render("comment:"+comment.ID.String(), comment.Content)
+		}
+	}
+}
+
+func (pc *PostService) GetUserFromSessionToken(ctx context.Context, token string) (*entity.User, error) {
+	session, err := pc.sessionRepo.GetByToken(ctx, token)
 	if err != nil || session == nil {
 		return nil, err
 	}
 
-	user, err := pc.userRepo.GetByID(session.UserID)
+	user, err := pc.userRepo.GetByID(ctx, session.UserID)
 	if err != nil {
 		return nil, err
 	}
@@ -195,28 +478,51 @@ func (pc *PostService) GetUserFromSessionToken(token string) (*entity.User, erro
 	return user, nil
 }
 
-func (ps *PostService) GetPostsWithDetailsByCategoryID(categoryID uuid.UUID) ([]*entity.PostWithDetails, error) {
-	posts, err := ps.postRepo.GetByCategory(categoryID)
+func (ps *PostService) GetPostsWithDetailsByCategoryID(ctx context.Context, categoryID uuid.UUID) ([]*entity.PostWithDetails, error) {
+	posts, err := ps.postRepo.GetByCategory(ctx, categoryID)
 	if err != nil {
 		return nil, err
 	}
 
 	var result []*entity.PostWithDetails
 	for _, post := range posts {
-		details, err := ps.postAggregateRepo.GetPostWithAllDetails(post.ID)
+		details, err := ps.postAggregateRepo.GetPostWithAllDetails(ctx, post.ID)
 		if err != nil {
 			continue
 		}
 		result = append(result, details)
 	}
 
+	ps.renderContent(result...)
 	return result, nil
 }
 
-func (s *PostService) GetPostsByUser(userID uuid.UUID) ([]*entity.PostWithDetails, error) {
-	return s.postAggregateRepo.GetPostsWithDetailsByUser(userID)
+func (s *PostService) GetPostsByUser(ctx context.Context, userID uuid.UUID) ([]*entity.PostWithDetails, error) {
+	posts, err := s.postAggregateRepo.GetPostsWithDetailsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.renderContent(posts...)
+	return posts, nil
 }
 
-func (ps *PostService) GetFilteredPostsWithDetails(filter entity.PostFilter) ([]*entity.PostWithDetails, error) {
-	return ps.postAggregateRepo.GetFilteredPostsWithDetails(filter)
+func (ps *PostService) GetFilteredPostsWithDetails(ctx context.Context, filter entity.PostFilter, cursor string) (*entity.PostPage, error) {
+	request, err := ps.pageRequest(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Later pages keep ranking posts as of the time the first one was loaded.
+	if request.Cursor != nil && !request.Cursor.Now.IsZero() {
+		filter.Now = request.Cursor.Now
+	} else if filter.Now.IsZero() {
+		filter.Now = time.Now()
+	}
+
+	page, err := ps.postAggregateRepo.GetFilteredPostsWithDetails(ctx, filter, request)
+	if err != nil {
+		return nil, err
+	}
+	ps.renderContent(page.Posts...)
+	return page, nil
 }
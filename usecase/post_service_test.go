@@ -0,0 +1,219 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+	"forum/infrastructure/repository/memory"
+	"forum/infrastructure/storage"
+
+	"github.com/google/uuid"
+)
+
+// postTest is a PostService over memory repositories with the members alice
+// and bob and the moderator mod, each signed in with their name as token. The
+// uploaded images are stored in dir.
+type postTest struct {
+	repos           *repository.Repositories
+	posts           *PostService
+	markdown        *MarkdownRenderer
+	dir             string
+	alice, bob, mod *entity.User
+}
+
+func newPostTest(t *testing.T, limits UploadLimits) *postTest {
+	t.Helper()
+	ctx := context.Background()
+	repos := memory.NewMemoryRepositories(5)
+	dir := t.TempDir()
+	files, err := storage.NewLocalFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt := &postTest{repos: repos, markdown: NewMarkdownRenderer(100), dir: dir}
+	pt.posts = NewPostService(&repos.Post, &repos.User, &repos.Category, &repos.PostAggregate, &repos.PostReaction,
+		&repos.PostRevision, &repos.Attachment, files, limits, pt.markdown, &repos.Session, repos.Tx, NewPostRateLimiter(), 10)
+
+	for _, member := range []struct {
+		user **entity.User
+		name string
+		role string
+	}{{&pt.alice, "alice", entity.RoleUser}, {&pt.bob, "bob", entity.RoleUser}, {&pt.mod, "mod", entity.RoleModerator}} {
+		user := &entity.User{UserName: member.name, Email: member.name + "@example.com", PasswordHash: "hash", Role: member.role}
+		if err := repos.User.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		session := &entity.UserSession{UserID: user.ID, SessionToken: member.name, ExpiresAt: time.Now().Add(time.Hour)}
+		if err := repos.Session.Create(ctx, session); err != nil {
+			t.Fatal(err)
+		}
+		*member.user = user
+	}
+	return pt
+}
+
+func TestDeletePost(t *testing.T) {
+	tests := []struct {
+		name string
+		// token is the session of the member who deletes the post of alice.
+		token   string
+		unknown bool
+		wantErr error
+	}{
+		{name: "by the author", token: "alice"},
+		{name: "by a moderator", token: "mod"},
+		{name: "by another member", token: "bob", wantErr: ErrUnauthorizedAccess},
+		{name: "unknown post", token: "alice", unknown: true, wantErr: custom_errors.ErrPostNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			pt := newPostTest(t, UploadLimits{})
+			post := &entity.Post{Title: "hello", Content: "first post", UserID: pt.alice.ID}
+			if err := pt.repos.Post.Create(ctx, post); err != nil {
+				t.Fatal(err)
+			}
+			comment := &entity.Comment{Content: "welcome", UserID: pt.bob.ID, PostID: post.ID}
+			if err := pt.repos.Comment.Create(ctx, comment); err != nil {
+				t.Fatal(err)
+			}
+
+			postID := post.ID
+			if tt.unknown {
+				postID = uuid.New()
+			}
+			err := pt.posts.DeletePost(ctx, tt.token, postID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeletePost returned %v, want %v", err, tt.wantErr)
+			}
+
+			_, postErr := pt.repos.Post.GetByID(ctx, post.ID)
+			_, commentErr := pt.repos.Comment.GetByID(ctx, comment.ID)
+			if tt.wantErr != nil {
+				if postErr != nil || commentErr != nil {
+					t.Errorf("a refused delete removed the post (%v) or its comment (%v)", postErr, commentErr)
+				}
+				return
+			}
+			if !errors.Is(postErr, custom_errors.ErrPostNotFound) || !errors.Is(commentErr, custom_errors.ErrCommentNotFound) {
+				t.Errorf("after DeletePost the post returns %v and its comment %v, want not found", postErr, commentErr)
+			}
+		})
+	}
+}
+
+func TestCreatePostUploadLimits(t *testing.T) {
+	small := encodePNG(t, 40, 40)
+	tests := []struct {
+		name   string
+		images [][]byte
+		// wantErr ends the error message, or is "" when the post is created.
+		wantErr string
+	}{
+		{name: "no image"},
+		{name: "as many images as allowed", images: [][]byte{small, small}},
+		{name: "one image too many", images: [][]byte{small, small, small}, wantErr: "you can attach at most 2 images"},
+		{name: "image over the size limit", images: [][]byte{small, encodePNG(t, 400, 400)}, wantErr: ErrImageTooLarge.Error()},
+		{name: "file that is not an image", images: [][]byte{small, []byte("not an image")}, wantErr: ErrUnsupportedImage.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			// The small image fits in the size limit, the large one does not.
+			pt := newPostTest(t, UploadLimits{MaxFileSize: int64(len(small)) * 2, MaxFiles: 2, ThumbnailSize: 16})
+			category := &entity.Category{Name: "general"}
+			if err := pt.repos.Category.Create(ctx, category); err != nil {
+				t.Fatal(err)
+			}
+			var uploads []ImageUpload
+			for i, data := range tt.images {
+				uploads = append(uploads, ImageUpload{Name: string(rune('a'+i)) + ".png", Data: data})
+			}
+
+			post, err := pt.posts.CreatePost(ctx, "alice", "hello", "first post", []*uuid.UUID{&category.ID}, uploads)
+			files, readErr := os.ReadDir(pt.dir)
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+			posts, listErr := pt.repos.Post.GetAll(ctx)
+			if listErr != nil {
+				t.Fatal(listErr)
+			}
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasSuffix(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one ending in %q", err, tt.wantErr)
+				}
+				if len(posts) != 0 || len(files) != 0 {
+					t.Errorf("a refused upload left %d posts and %d files behind", len(posts), len(files))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v, want none", err)
+			}
+			attachments, err := pt.repos.Attachment.GetByPostID(ctx, post.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Every image is stored with its thumbnail.
+			if len(attachments) != len(tt.images) || len(files) != 2*len(tt.images) {
+				t.Errorf("the post has %d attachments in %d files, want %d in %d",
+					len(attachments), len(files), len(tt.images), 2*len(tt.images))
+			}
+		})
+	}
+}
+
+func TestSavedTextDropsHighlightMarkers(t *testing.T) {
+	ctx := context.Background()
+	ct := newCommentTest(t, 5)
+	category := &entity.Category{Name: "general"}
+	if err := ct.repos.Category.Create(ctx, category); err != nil {
+		t.Fatal(err)
+	}
+	marked := func(s string) string { return entity.HighlightStart + s + entity.HighlightEnd }
+
+	post, err := ct.posts.CreatePost(ctx, "alice", marked("title"), marked("content"), []*uuid.UUID{&category.ID}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := ct.repos.Post.GetByID(ctx, post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Title != "title" || stored.Content != "content" {
+		t.Errorf("CreatePost stored %q and %q, want them without markers", stored.Title, stored.Content)
+	}
+
+	if _, err := ct.posts.UpdatePost(ctx, "alice", post.ID, marked("new title"), marked("new content")); err != nil {
+		t.Fatal(err)
+	}
+	if stored, err = ct.repos.Post.GetByID(ctx, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Title != "new title" || stored.Content != "new content" {
+		t.Errorf("UpdatePost stored %q and %q, want them without markers", stored.Title, stored.Content)
+	}
+
+	comment, err := ct.comments.CreateComment(ctx, &post.ID, nil, "bob", marked("reply"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment.Content != "reply" {
+		t.Errorf("CreateComment stored %q, want it without markers", comment.Content)
+	}
+	// Only markers make the content empty.
+	if _, err := ct.comments.UpdateComment(ctx, comment.ID, "bob", marked("")); err == nil {
+		t.Error("UpdateComment accepted content made of markers only")
+	}
+}
@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"html"
+	"html/template"
+	"strings"
+	"unicode/utf8"
+
+	"forum/domain/entity"
+	"forum/domain/repository"
+)
+
+var (
+	ErrSearchQueryTooShort = errors.New("search query must be at least 2 characters")
+	ErrSearchQueryTooLong  = errors.New("search query too long (max: 100 characters)")
+)
+
+// searchResultLimit is the number of results shown for a search.
+const searchResultLimit = 50
+
+type SearchService struct {
+	searchRepo repository.SearchRepository
+}
+
+func NewSearchService(searchRepo repository.SearchRepository) *SearchService {
+	return &SearchService{
+		searchRepo: searchRepo,
+	}
+}
+
+// Search returns the posts matching query, best matches first, with the
+// matched words highlighted in their title and snippet.
+func (ss *SearchService) Search(ctx context.Context, query string) ([]*entity.SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if utf8.RuneCountInString(query) < 2 {
+		return nil, ErrSearchQueryTooShort
+	}
+	if utf8.RuneCountInString(query) > 100 {
+		return nil, ErrSearchQueryTooLong
+	}
+
+	results, err := ss.searchRepo.Search(ctx, query, searchResultLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		result.TitleHTML = highlightHTML(result.Title)
+		result.SnippetHTML = highlightHTML(result.Snippet)
+	}
+	return results, nil
+}
+
+// highlightHTML escapes text and turns the highlight markers into <mark>
+// elements. Unbalanced markers are dropped instead.
+func highlightHTML(text string) template.HTML {
+	escaped := html.EscapeString(text)
+	if strings.Count(escaped, entity.HighlightStart) != strings.Count(escaped, entity.HighlightEnd) {
+		return template.HTML(strings.NewReplacer(entity.HighlightStart, "", entity.HighlightEnd, "").Replace(escaped))
+	}
+	return template.HTML(strings.NewReplacer(entity.HighlightStart, "<mark>", entity.HighlightEnd, "</mark>").Replace(escaped))
+}
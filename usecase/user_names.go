@@ -0,0 +1,55 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUserNameLength     = errors.New("name should be between 3 and 9 characters")
+	ErrUserNameCharacters = errors.New("name should contain only letters and numbers")
+)
+
+// validateUserName checks the format of a new user name and returns it
+// trimmed.
+func validateUserName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if len(name) < 3 || len(name) > 9 {
+		return "", ErrUserNameLength
+	}
+	if !isValidName(name) {
+		return "", ErrUserNameCharacters
+	}
+	return name, nil
+}
+
+// checkUserNameAvailable returns ErrNameTaken when name belongs to a user
+// other than userID, or when another user gave it up less than reservation
+// ago. Pass uuid.Nil for someone who has no account yet. Users can always
+// take back a name they gave up themselves.
+func checkUserNameAvailable(ctx context.Context, userRepo repository.UserRepository, name string, userID uuid.UUID, reservation time.Duration) error {
+	owner, err := userRepo.GetByUserName(ctx, name)
+	if err == nil && owner.ID != userID {
+		return ErrNameTaken
+	} else if err != nil && !errors.Is(err, custom_errors.ErrUserNotFound) {
+		return err
+	}
+
+	change, err := userRepo.GetLastUserNameChange(ctx, name)
+	if errors.Is(err, custom_errors.ErrUserNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if change.UserID != userID && time.Since(change.ChangedAt) < reservation {
+		return ErrNameTaken
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+// MaxBioLength is the number of characters a bio may have.
+const MaxBioLength = 300
+
+var (
+	ErrBioTooLong        = fmt.Errorf("bio should be at most %d characters", MaxBioLength)
+	ErrUserNameCooldown  = errors.New("username was changed too recently")
+	ErrUserNameUnchanged = errors.New("that is already your username")
+)
+
+// ProfileSettings are the rules for what members can change about
+// themselves.
+type ProfileSettings struct {
+	MaxAvatarSize int64
+	// AvatarSize is the width and height avatars are scaled down to.
+	AvatarSize int
+	// UserNameCooldown is how long a member has to wait between two
+	// changes of their name.
+	UserNameCooldown time.Duration
+	// UserNameReservation is how long a name that a member gave up stays
+	// reserved for them.
+	UserNameReservation time.Duration
+}
+
+// UserProfile is what the public page of a member shows about them.
+type UserProfile struct {
+	User  *entity.User
+	Stats *entity.UserStats
+}
+
+type UserService struct {
+	userRepo    repository.UserRepository
+	commentRepo repository.CommentRepository
+	fileStorage repository.FileStorage
+	settings    ProfileSettings
+	markdown    *MarkdownRenderer
+	pageSize    int
+}
+
+func NewUserService(userRepo repository.UserRepository, commentRepo repository.CommentRepository,
+	fileStorage repository.FileStorage, markdown *MarkdownRenderer, settings ProfileSettings, pageSize int,
+) *UserService {
+	return &UserService{
+		userRepo:    userRepo,
+		commentRepo: commentRepo,
+		fileStorage: fileStorage,
+		settings:    settings,
+		markdown:    markdown,
+		pageSize:    pageSize,
+	}
+}
+
+// GetProfile returns the member called userName with their activity. It
+// returns custom_errors.ErrUserNotFound when there is no such member.
+func (us *UserService) GetProfile(ctx context.Context, userName string) (*UserProfile, error) {
+	user, err := us.userRepo.GetByUserName(ctx, userName)
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = ""
+
+	stats, err := us.userRepo.GetStats(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &UserProfile{User: user, Stats: stats}, nil
+}
+
+// GetUserComments returns the page of a member's comments at cursor, or the
+// first page when cursor is empty.
+func (us *UserService) GetUserComments(ctx context.Context, userID uuid.UUID, cursor string) (*entity.CommentPage, error) {
+	request := entity.PageRequest{Limit: us.pageSize}
+	if cursor != "" {
+		c, err := entity.DecodePostCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		request.Cursor = c
+	}
+
+	page, err := us.commentRepo.GetByUserIDWithDetails(ctx, userID, request)
+	if err != nil {
+		return nil, err
+	}
+	for _, comment := range page.Comments {
+		comment.ContentHTML = us.markdown.Render("comment:"+comment.ID.String(), comment.Content)
+	}
+	return page, nil
+}
+
+// ResolveOldUserName returns the current name of the member who last gave up
+// oldName, so that links to their old profile keep working. It returns
+// custom_errors.ErrUserNotFound when no one ever had that name.
+func (us *UserService) ResolveOldUserName(ctx context.Context, oldName string) (string, error) {
+	change, err := us.userRepo.GetLastUserNameChange(ctx, oldName)
+	if err != nil {
+		return "", err
+	}
+	user, err := us.userRepo.GetByID(ctx, change.UserID)
+	if err != nil {
+		return "", err
+	}
+	return user.UserName, nil
+}
+
+// Settings returns the rules for profile changes.
+func (us *UserService) Settings() ProfileSettings {
+	return us.settings
+}
+
+// UpdateProfile saves a new bio for user and, when avatar is not nil,
+// replaces their avatar with it. removeAvatar drops the current avatar
+// instead. The old avatar file is deleted once the profile is saved.
+func (us *UserService) UpdateProfile(ctx context.Context, user *entity.User, bio string, avatar *ImageUpload, removeAvatar bool) error {
+	bio = strings.TrimSpace(strings.ReplaceAll(bio, "\r\n", "\n"))
+	if utf8.RuneCountInString(bio) > MaxBioLength {
+		return ErrBioTooLong
+	}
+
+	oldAvatar := user.AvatarName
+	newAvatar := oldAvatar
+	if avatar != nil {
+		img, err := processAvatar(avatar.Data, us.settings.MaxAvatarSize, us.settings.AvatarSize)
+		if err != nil {
+			return err
+		}
+		newAvatar = uuid.New().String() + "_avatar" + img.Extension
+		if err := us.fileStorage.Save(newAvatar, img.Data); err != nil {
+			return err
+		}
+	} else if removeAvatar {
+		newAvatar = ""
+	}
+
+	updated := *user
+	updated.Bio = bio
+	updated.AvatarName = newAvatar
+	if err := us.userRepo.UpdateProfile(ctx, &updated); err != nil {
+		if newAvatar != oldAvatar {
+			us.removeAvatarFile(newAvatar)
+		}
+		return err
+	}
+
+	if newAvatar != oldAvatar {
+		us.removeAvatarFile(oldAvatar)
+	}
+	user.Bio, user.AvatarName = updated.Bio, updated.AvatarName
+	return nil
+}
+
+func (us *UserService) removeAvatarFile(name string) {
+	if name == "" {
+		return
+	}
+	if err := us.fileStorage.Delete(name); err != nil {
+		log.Printf("Warning: Failed to remove avatar %s: %v", name, err)
+	}
+}
+
+// NextUserNameChange returns when user may change their name again, or the
+// zero time when they may do so now.
+func (us *UserService) NextUserNameChange(user *entity.User) time.Time {
+	if user.UserNameChangedAt == nil {
+		return time.Time{}
+	}
+	next := user.UserNameChangedAt.Add(us.settings.UserNameCooldown)
+	if !next.After(time.Now()) {
+		return time.Time{}
+	}
+	return next
+}
+
+// ChangeUserName renames user to newName. The old name is recorded, keeps
+// leading to their profile and stays reserved for them for a while.
+func (us *UserService) ChangeUserName(ctx context.Context, user *entity.User, newName string) error {
+	newName, err := validateUserName(newName)
+	if err != nil {
+		return err
+	}
+	if newName == user.UserName {
+		return ErrUserNameUnchanged
+	}
+	if next := us.NextUserNameChange(user); !next.IsZero() {
+		return fmt.Errorf("%w, you can change it again on %s", ErrUserNameCooldown, next.Format("Jan 02, 2006 15:04"))
+	}
+	if err := checkUserNameAvailable(ctx, us.userRepo, newName, user.ID, us.settings.UserNameReservation); err != nil {
+		return err
+	}
+
+	// Someone may have taken the name since it was checked.
+	now := time.Now()
+	err = us.userRepo.ChangeUserName(ctx, user.ID, newName, now)
+	if errors.Is(err, custom_errors.ErrNameTaken) {
+		return ErrNameTaken
+	} else if err != nil {
+		return err
+	}
+	user.UserName = newName
+	user.UserNameChangedAt = &now
+	return nil
+}
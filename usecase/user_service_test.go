@@ -0,0 +1,344 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"forum/domain/entity"
+	"forum/domain/repository"
+	"forum/infrastructure/repository/memory"
+	"forum/infrastructure/storage"
+)
+
+// profileSettings are the profile rules the tests run with, apart from the
+// name reservation, which each test chooses.
+var profileSettings = ProfileSettings{
+	MaxAvatarSize:    1 << 20,
+	AvatarSize:       32,
+	UserNameCooldown: 24 * time.Hour,
+}
+
+// newUserService returns a UserService over memory repositories, with
+// members alice and bob, storing avatars in dir.
+func newUserService(t *testing.T, dir string, reservation time.Duration) (*UserService, *repository.Repositories, *entity.User, *entity.User) {
+	t.Helper()
+	repos := memory.NewMemoryRepositories(5)
+	files, err := storage.NewLocalFileStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := profileSettings
+	settings.UserNameReservation = reservation
+
+	var users []*entity.User
+	for _, name := range []string{"alice", "bob"} {
+		user := &entity.User{UserName: name, Email: name + "@example.com", PasswordHash: "hash"}
+		if err := repos.User.Create(context.Background(), user); err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, user)
+	}
+	return NewUserService(repos.User, repos.Comment, files, NewMarkdownRenderer(100), settings, 10), repos, users[0], users[1]
+}
+
+// cooledDown moves the last name change of user back past the cooldown.
+func cooledDown(user *entity.User) {
+	changed := user.UserNameChangedAt.Add(-profileSettings.UserNameCooldown - time.Minute)
+	user.UserNameChangedAt = &changed
+}
+
+func TestChangeUserName(t *testing.T) {
+	reserved := 90 * 24 * time.Hour
+	tests := []struct {
+		name        string
+		reservation time.Duration
+		change      func(ctx context.Context, us *UserService, repos *repository.Repositories, alice, bob *entity.User) error
+		wantErr     error
+		// wantName is what alice is called at the end, when there is no error.
+		wantName string
+	}{
+		{
+			name: "new name",
+			change: func(ctx context.Context, us *UserService, _ *repository.Repositories, alice, _ *entity.User) error {
+				return us.ChangeUserName(ctx, alice, "alicia")
+			},
+			wantName: "alicia",
+		},
+		{
+			name: "same name",
+			change: func(ctx context.Context, us *UserService, _ *repository.Repositories, alice, _ *entity.User) error {
+				return us.ChangeUserName(ctx, alice, "alice")
+			},
+			wantErr: ErrUserNameUnchanged,
+		},
+		{
+			name: "invalid name",
+			change: func(ctx context.Context, us *UserService, _ *repository.Repositories, alice, _ *entity.User) error {
+				return us.ChangeUserName(ctx, alice, "a")
+			},
+			wantErr: ErrUserNameLength,
+		},
+		{
+			name: "name of another member",
+			change: func(ctx context.Context, us *UserService, _ *repository.Repositories, alice, _ *entity.User) error {
+				return us.ChangeUserName(ctx, alice, "bob")
+			},
+			wantErr: ErrNameTaken,
+		},
+		{
+			name: "second change within the cooldown",
+			change: func(ctx context.Context, us *UserService, _ *repository.Repositories, alice, _ *entity.User) error {
+				if err := us.ChangeUserName(ctx, alice, "alicia"); err != nil {
+					return err
+				}
+				return us.ChangeUserName(ctx, alice, "ali")
+			},
+			wantErr: ErrUserNameCooldown,
+		},
+		{
+			name: "second change after the cooldown",
+			change: func(ctx context.Context, us *UserService, _ *repository.Repositories, alice, _ *entity.User) error {
+				if err := us.ChangeUserName(ctx, alice, "alicia"); err != nil {
+					return err
+				}
+				cooledDown(alice)
+				return us.ChangeUserName(ctx, alice, "ali")
+			},
+			wantName: "ali",
+		},
+		{
+			name:        "reserved name taken by another member",
+			reservation: reserved,
+			change: func(ctx context.Context, us *UserService, _ *repository.Repositories, alice, bob *entity.User) error {
+				if err := us.ChangeUserName(ctx, alice, "alicia"); err != nil {
+					return err
+				}
+				return us.ChangeUserName(ctx, bob, "alice")
+			},
+			wantErr: ErrNameTaken,
+		},
+		{
+			name:        "reserved name taken at signup",
+			reservation: reserved,
+			change: func(ctx context.Context, us *UserService, repos *repository.Repositories, alice, _ *entity.User) error {
+				if err := us.ChangeUserName(ctx, alice, "alicia"); err != nil {
+					return err
+				}
+				_, err := NewAuthService(repos.User, repos.Session, reserved).Signup(ctx, "alice", "new@example.com", "secret12")
+				return err
+			},
+			wantErr: ErrNameTaken,
+		},
+		{
+			name:        "reserved name taken back by its owner",
+			reservation: reserved,
+			change: func(ctx context.Context, us *UserService, _ *repository.Repositories, alice, _ *entity.User) error {
+				if err := us.ChangeUserName(ctx, alice, "alicia"); err != nil {
+					return err
+				}
+				cooledDown(alice)
+				return us.ChangeUserName(ctx, alice, "alice")
+			},
+			wantName: "alice",
+		},
+		{
+			name: "old name taken after the reservation",
+			change: func(ctx context.Context, us *UserService, _ *repository.Repositories, alice, bob *entity.User) error {
+				if err := us.ChangeUserName(ctx, alice, "alicia"); err != nil {
+					return err
+				}
+				return us.ChangeUserName(ctx, bob, "alice")
+			},
+			wantName: "alicia",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			us, repos, alice, bob := newUserService(t, t.TempDir(), tt.reservation)
+			err := tt.change(ctx, us, repos, alice, bob)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			stored, err := repos.User.GetByID(ctx, alice.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.UserName != tt.wantName || alice.UserName != tt.wantName {
+				t.Errorf("alice is called %q, stored as %q, want %q", alice.UserName, stored.UserName, tt.wantName)
+			}
+			if tt.wantName != "alice" {
+				// The old profile link leads to the new name.
+				if current, err := us.ResolveOldUserName(ctx, "alice"); err != nil || current != tt.wantName {
+					t.Errorf("ResolveOldUserName(alice) = %q, %v; want %q", current, err, tt.wantName)
+				}
+			}
+		})
+	}
+}
+
+func TestChangeUserNameRace(t *testing.T) {
+	ctx := context.Background()
+	us, repos, alice, bob := newUserService(t, t.TempDir(), 0)
+
+	// Both may pass the availability check before either is stored, in
+	// which case the repository refuses the second.
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, user := range []*entity.User{alice, bob} {
+		user := &entity.User{ID: user.ID, UserName: user.UserName}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = us.ChangeUserName(ctx, user, "carol")
+		}()
+	}
+	wg.Wait()
+
+	if (errs[0] == nil) == (errs[1] == nil) {
+		t.Fatalf("the renames returned %v and %v, want one to succeed", errs[0], errs[1])
+	}
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, ErrNameTaken) {
+			t.Errorf("the losing rename returned %v, want %v", err, ErrNameTaken)
+		}
+	}
+	winner, err := repos.User.GetByUserName(ctx, "carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loser := bob
+	if winner.ID == bob.ID {
+		loser = alice
+	}
+	if stored, err := repos.User.GetByID(ctx, loser.ID); err != nil || stored.UserName != loser.UserName {
+		t.Errorf("the losing user is %+v, %v; want the name %q", stored, err, loser.UserName)
+	}
+}
+
+// encodePNG returns a w x h PNG, white on the left half and black on the
+// right.
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			if x < w/2 {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUpdateProfile(t *testing.T) {
+	tests := []struct {
+		name       string
+		bio        string
+		avatar     []byte
+		remove     bool
+		wantErr    error
+		wantAvatar bool
+	}{
+		{name: "bio of 300 characters", bio: strings.Repeat("é", MaxBioLength)},
+		{name: "bio of 301 characters", bio: strings.Repeat("é", MaxBioLength+1), wantErr: ErrBioTooLong},
+		{name: "new avatar", bio: "hi", avatar: encodePNG(t, 120, 80), wantAvatar: true},
+		{name: "avatar that is not an image", avatar: []byte("not an image"), wantErr: ErrUnsupportedImage},
+		{name: "avatar removed", remove: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			us, repos, alice, _ := newUserService(t, dir, 0)
+
+			// Every case starts with an avatar.
+			if err := us.UpdateProfile(ctx, alice, "", &ImageUpload{Data: encodePNG(t, 40, 40)}, false); err != nil {
+				t.Fatal(err)
+			}
+			oldAvatar := alice.AvatarName
+
+			var upload *ImageUpload
+			if tt.avatar != nil {
+				upload = &ImageUpload{Data: tt.avatar}
+			}
+			err := us.UpdateProfile(ctx, alice, tt.bio, upload, tt.remove)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			stored, err := repos.User.GetByID(ctx, alice.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case tt.wantErr != nil:
+				if stored.AvatarName != oldAvatar || len(files) != 1 {
+					t.Errorf("a failed update changed the avatar to %q, %d files are stored", stored.AvatarName, len(files))
+				}
+			case tt.wantAvatar:
+				if stored.AvatarName == oldAvatar || len(files) != 1 || files[0].Name() != stored.AvatarName {
+					t.Fatalf("avatar %q replaced %q, stored files %v", stored.AvatarName, oldAvatar, files)
+				}
+				checkAvatar(t, filepath.Join(dir, stored.AvatarName))
+			case tt.remove:
+				if stored.AvatarName != "" || len(files) != 0 {
+					t.Errorf("removed avatar is %q, %d files are stored", stored.AvatarName, len(files))
+				}
+			default:
+				if stored.Bio != tt.bio || stored.AvatarName != oldAvatar {
+					t.Errorf("stored bio %q and avatar %q, want %q and %q", stored.Bio, stored.AvatarName, tt.bio, oldAvatar)
+				}
+			}
+		})
+	}
+}
+
+// checkAvatar fails unless the file at path is a square of the avatar size
+// cut from the middle of the half white, half black upload.
+func checkAvatar(t *testing.T, path string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := profileSettings.AvatarSize
+	if b := img.Bounds(); b.Dx() != size || b.Dy() != size {
+		t.Fatalf("avatar is %dx%d, want %dx%d", b.Dx(), b.Dy(), size, size)
+	}
+	left, _, _, _ := img.At(0, size/2).RGBA()
+	right, _, _, _ := img.At(size-1, size/2).RGBA()
+	if left < 0xf000 || right > 0x0fff {
+		t.Errorf("avatar edges are %#x and %#x, want white and black", left, right)
+	}
+}
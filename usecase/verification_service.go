@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"forum/domain/entity"
+	custom_errors "forum/domain/errors"
+	"forum/domain/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmailAlreadyVerified    = errors.New("your email address is already verified")
+	ErrInvalidVerificationLink = errors.New("verification link is invalid or has expired")
+	ErrVerificationRateLimited = errors.New("a verification email was sent recently")
+)
+
+// VerificationRateLimiter spaces out the verification emails sent to each
+// user.
+type VerificationRateLimiter struct {
+	userLastSent map[uuid.UUID]time.Time
+	mutex        sync.Mutex
+	limitTime    time.Duration
+}
+
+func NewVerificationRateLimiter(limitTime time.Duration) *VerificationRateLimiter {
+	return &VerificationRateLimiter{
+		userLastSent: make(map[uuid.UUID]time.Time),
+		limitTime:    limitTime,
+	}
+}
+
+// Reserve records a send to userID now and returns true, or returns false
+// with the time left to wait when the last send was too recent.
+func (r *VerificationRateLimiter) Reserve(userID uuid.UUID) (time.Duration, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if lastSent, exists := r.userLastSent[userID]; exists {
+		if wait := r.limitTime - time.Since(lastSent); wait > 0 {
+			return wait, false
+		}
+	}
+	r.userLastSent[userID] = time.Now()
+	return 0, true
+}
+
+// EmailVerificationSettings configure the links that verify email addresses.
+type EmailVerificationSettings struct {
+	// Secret signs the links. Links signed with another secret are refused.
+	Secret []byte
+	// BaseURL is where users reach the forum, such as https://forum.example.com.
+	BaseURL string
+	// TokenTTL is how long a link can be used.
+	TokenTTL time.Duration
+}
+
+// VerificationService mails users a signed link that proves they own their
+// email address. Nothing is stored until the link is followed: the link
+// carries the user and its expiry, signed with HMAC-SHA256 together with the
+// address it was sent to, so it stops working if the address changes.
+type VerificationService struct {
+	userRepo    repository.UserRepository
+	mailer      repository.Mailer
+	rateLimiter *VerificationRateLimiter
+	settings    EmailVerificationSettings
+}
+
+func NewVerificationService(userRepo repository.UserRepository, mailer repository.Mailer,
+	rateLimiter *VerificationRateLimiter, settings EmailVerificationSettings,
+) *VerificationService {
+	return &VerificationService{
+		userRepo:    userRepo,
+		mailer:      mailer,
+		rateLimiter: rateLimiter,
+		settings:    settings,
+	}
+}
+
+// SendVerification mails user a link to verify their email address. It
+// returns ErrVerificationRateLimited when the last link went out too
+// recently.
+func (vs *VerificationService) SendVerification(ctx context.Context, user *entity.User) error {
+	if user.IsEmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+	if wait, ok := vs.rateLimiter.Reserve(user.ID); !ok {
+		return fmt.Errorf("%w, you can ask for another one in %s", ErrVerificationRateLimited,
+			formatDuration((wait + time.Minute - 1).Truncate(time.Minute)))
+	}
+
+	expires := time.Now().Add(vs.settings.TokenTTL).Unix()
+	token := user.ID.String() + "." + strconv.FormatInt(expires, 10) + "." + vs.sign(user, expires)
+	link := strings.TrimRight(vs.settings.BaseURL, "/") + "/verify-email/confirm?token=" + token
+
+	return vs.mailer.Send(ctx, &entity.Email{
+		To:      user.Email,
+		Subject: "Verify your forum email address",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Open this link within %s to verify your email address and start posting:\n\n%s\n\n"+
+			"If you did not create a forum account, ignore this email.\n",
+			user.UserName, formatDuration(vs.settings.TokenTTL), link),
+	})
+}
+
+// VerifyEmail checks a link sent by SendVerification and marks the email
+// of its user verified. It returns ErrInvalidVerificationLink for links
+// that are malformed, expired, forged or sent to an older address.
+func (vs *VerificationService) VerifyEmail(ctx context.Context, token string) (*entity.User, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidVerificationLink
+	}
+	userID, err := uuid.Parse(parts[0])
+	if err != nil {
+		return nil, ErrInvalidVerificationLink
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return nil, ErrInvalidVerificationLink
+	}
+
+	user, err := vs.userRepo.GetByID(ctx, userID)
+	if errors.Is(err, custom_errors.ErrUserNotFound) {
+		return nil, ErrInvalidVerificationLink
+	} else if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(vs.sign(user, expires))) {
+		return nil, ErrInvalidVerificationLink
+	}
+
+	if user.IsEmailVerified() {
+		return user, nil
+	}
+	now := time.Now()
+	if err := vs.userRepo.MarkEmailVerified(ctx, user.ID, now); err != nil {
+		return nil, err
+	}
+	user.EmailVerifiedAt = &now
+	return user, nil
+}
+
+// sign returns the signature of a link for user that expires at the Unix
+// time expires.
+func (vs *VerificationService) sign(user *entity.User, expires int64) string {
+	mac := hmac.New(sha256.New, vs.settings.Secret)
+	fmt.Fprintf(mac, "verify-email\n%s\n%s\n%d", user.ID, user.Email, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}